@@ -0,0 +1,271 @@
+package wallethub
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+)
+
+// Allowance error definitions
+var (
+	ErrAllowanceNotFound = errors.New("allowance not found")
+	ErrAllowanceExceeded = errors.New("allowance exceeded")
+	ErrInvalidDelegate   = errors.New("invalid delegate")
+)
+
+// Allowance lets a delegate spend from a wallet of another user up to a remaining amount
+type Allowance struct {
+	TenantID   string    `json:"tenant_id,omitempty"` // Set by the store from the context
+	WalletID   string    `json:"wallet_id"`
+	DelegateID string    `json:"delegate_id"` // User allowed to spend from the wallet
+	Amount     int64     `json:"amount"`      // Remaining amount the delegate may spend
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// spendAllowance decrements the allowance of a delegate on a wallet within an open store transaction.
+// The wallet must already be locked, so concurrent spends of the allowance are serialized. Without a
+// delegate, nothing is spent.
+func (m *DefaultWalletManager) spendAllowance(txn Txn, walletID string, delegateID string, amount int64) error {
+	if delegateID == "" {
+		return nil
+	}
+
+	allowance, err := txn.FindAllowance(walletID, delegateID)
+	if err != nil {
+		return err
+	}
+	if allowance == nil {
+		return ErrAllowanceNotFound
+	}
+	if allowance.Amount < amount {
+		return ErrAllowanceExceeded
+	}
+
+	allowance.Amount -= amount
+	return txn.SaveAllowance(allowance)
+}
+
+// lockWallet locks a wallet within an open store transaction and returns it
+func lockWallet(txn Txn, walletID string) (*Wallet, error) {
+	wallets, err := txn.LockWallets([]string{walletID})
+	if err != nil {
+		return nil, err
+	}
+	if len(wallets) == 0 {
+		return nil, ErrWalletNotFound
+	}
+	return &wallets[0], nil
+}
+
+// GrantAllowance lets a delegate spend up to an amount from a wallet, replacing any earlier allowance
+func (m *DefaultWalletManager) GrantAllowance(ctx context.Context, walletID string, delegateID string, amount int64) (allowance *Allowance, err error) {
+	err = m.WithinTx(ctx, func(ops WalletOps) error {
+		allowance, err = ops.GrantAllowance(walletID, delegateID, amount)
+		return err
+	})
+	if err != nil {
+		return nil, newWalletError(err, walletID)
+	}
+	return allowance, nil
+}
+
+// GrantAllowance lets a delegate spend up to an amount from a wallet, replacing any earlier allowance
+func (o *walletOps) GrantAllowance(walletID string, delegateID string, amount int64) (allowance *Allowance, err error) {
+	defer func() {
+		err = newWalletError(err, walletID)
+		o.m.log.operation(o.ctx, "grant_allowance", err,
+			slog.String("wallet_id", walletID),
+			slog.String("delegate_id", delegateID),
+			slog.Int64("amount", amount),
+		)
+	}()
+
+	if amount <= 0 {
+		return nil, ErrInvalidAmount
+	}
+
+	// Lock the wallet so the allowance is not replaced while it is being spent
+	wallet, err := lockWallet(o.txn, walletID)
+	if err != nil {
+		return nil, err
+	}
+	if delegateID == "" || delegateID == wallet.UserID {
+		return nil, ErrInvalidDelegate
+	}
+
+	allowance, err = o.txn.FindAllowance(walletID, delegateID)
+	if err != nil {
+		return nil, err
+	}
+	if allowance == nil {
+		allowance = &Allowance{WalletID: walletID, DelegateID: delegateID}
+	}
+	allowance.Amount = amount
+
+	if err := o.txn.SaveAllowance(allowance); err != nil {
+		return nil, err
+	}
+	return allowance, nil
+}
+
+// RevokeAllowance withdraws the allowance of a delegate on a wallet
+func (m *DefaultWalletManager) RevokeAllowance(ctx context.Context, walletID string, delegateID string) error {
+	err := m.WithinTx(ctx, func(ops WalletOps) error {
+		return ops.RevokeAllowance(walletID, delegateID)
+	})
+	return newWalletError(err, walletID)
+}
+
+// RevokeAllowance withdraws the allowance of a delegate on a wallet
+func (o *walletOps) RevokeAllowance(walletID string, delegateID string) (err error) {
+	defer func() {
+		err = newWalletError(err, walletID)
+		o.m.log.operation(o.ctx, "revoke_allowance", err,
+			slog.String("wallet_id", walletID),
+			slog.String("delegate_id", delegateID),
+		)
+	}()
+
+	// Lock the wallet so the allowance is not revoked while it is being spent
+	if _, err := lockWallet(o.txn, walletID); err != nil {
+		return err
+	}
+
+	allowance, err := o.txn.FindAllowance(walletID, delegateID)
+	if err != nil {
+		return err
+	}
+	if allowance == nil {
+		return ErrAllowanceNotFound
+	}
+	return o.txn.DeleteAllowance(walletID, delegateID)
+}
+
+// GetAllowance gets the allowance of a delegate on a wallet, nil if there is none
+func (m *DefaultWalletManager) GetAllowance(ctx context.Context, walletID string, delegateID string) (*Allowance, error) {
+	allowance, err := m.store.FindAllowance(ctx, walletID, delegateID)
+	if err != nil {
+		return nil, newWalletError(err, walletID)
+	}
+	return allowance, nil
+}
+
+// GetAllowance gets the allowance of a delegate on a wallet, nil if there is none
+func (o *walletOps) GetAllowance(walletID string, delegateID string) (*Allowance, error) {
+	allowance, err := o.txn.FindAllowance(walletID, delegateID)
+	if err != nil {
+		return nil, newWalletError(err, walletID)
+	}
+	return allowance, nil
+}
+
+// ListAllowances lists the allowances on a wallet
+func (m *DefaultWalletManager) ListAllowances(ctx context.Context, walletID string) ([]Allowance, error) {
+	allowances, err := m.store.FindAllowancesByWalletID(ctx, walletID)
+	if err != nil {
+		return nil, newWalletError(err, walletID)
+	}
+	return allowances, nil
+}
+
+// DebitAsDelegate removes points from a wallet on behalf of a delegate, spending its allowance on the wallet
+func (m *DefaultWalletManager) DebitAsDelegate(ctx context.Context, walletID string, delegateID string, amount int64, description string, note string, reference string, data map[string]interface{}) (transaction *Transaction, err error) {
+	unlock, err := m.lockWallets(ctx, walletID)
+	if err != nil {
+		return nil, newWalletError(err, walletID)
+	}
+	defer unlock()
+
+	err = m.WithinTx(ctx, func(ops WalletOps) error {
+		transaction, err = ops.DebitAsDelegate(walletID, delegateID, amount, description, note, reference, data)
+		return err
+	})
+	if err != nil {
+		return nil, newWalletError(err, walletID)
+	}
+	return transaction, nil
+}
+
+// DebitAsDelegate removes points from a wallet on behalf of a delegate, spending its allowance on the wallet
+func (o *walletOps) DebitAsDelegate(walletID string, delegateID string, amount int64, description string, note string, reference string, data map[string]interface{}) (transaction *Transaction, err error) {
+	defer func() {
+		err = newWalletError(err, walletID)
+		attrs := []slog.Attr{
+			slog.String("wallet_id", walletID),
+			slog.String("delegate_id", delegateID),
+			slog.Int64("amount", amount),
+			slog.String("reference", reference),
+			o.m.log.note(note),
+			o.m.log.data(data),
+		}
+		if transaction != nil {
+			attrs = append(attrs, slog.String("transaction_id", transaction.ID))
+		}
+		o.m.log.operation(o.ctx, "debit_as_delegate", err, attrs...)
+	}()
+
+	if amount <= 0 {
+		return nil, ErrInvalidAmount
+	}
+	if delegateID == "" {
+		return nil, ErrInvalidDelegate
+	}
+
+	// Evaluate the risk rules once the store transaction is closed
+	defer func() { o.unit.attempt(walletID, TransactionTypeDebit, amount, err) }()
+
+	// debitTxn locks the wallet before the allowance is read
+	return o.m.debitTxn(o.txn, o.m.ids.NewID(), walletID, delegateID, amount, description, note, reference, data)
+}
+
+// TransferFrom transfers points from a wallet to another on behalf of a delegate, spending its
+// allowance on the source wallet
+func (m *DefaultWalletManager) TransferFrom(ctx context.Context, fromWalletID string, toWalletID string, delegateID string, amount int64, description string, note string, data map[string]interface{}) error {
+	unlock, err := m.lockWallets(ctx, fromWalletID, toWalletID)
+	if err != nil {
+		return newWalletError(err, fromWalletID)
+	}
+	defer unlock()
+
+	err = m.WithinTx(ctx, func(ops WalletOps) error {
+		return ops.TransferFrom(fromWalletID, toWalletID, delegateID, amount, description, note, data)
+	})
+	return newWalletError(err, fromWalletID)
+}
+
+// TransferFrom transfers points from a wallet to another on behalf of a delegate, spending its
+// allowance on the source wallet
+func (o *walletOps) TransferFrom(fromWalletID string, toWalletID string, delegateID string, amount int64, description string, note string, data map[string]interface{}) (err error) {
+	defer func() {
+		err = newWalletError(err, fromWalletID)
+		o.m.log.operation(o.ctx, "transfer_from", err,
+			slog.String("wallet_id", fromWalletID),
+			slog.String("to_wallet_id", toWalletID),
+			slog.String("delegate_id", delegateID),
+			slog.Int64("amount", amount),
+			o.m.log.note(note),
+			o.m.log.data(data),
+		)
+	}()
+
+	if amount <= 0 {
+		return ErrInvalidAmount
+	}
+	if delegateID == "" {
+		return ErrInvalidDelegate
+	}
+
+	// Evaluate the risk rules once the store transaction is closed
+	defer func() {
+		o.unit.attempt(fromWalletID, TransactionTypeDebit, amount, err)
+		if err == nil {
+			o.unit.attempt(toWalletID, TransactionTypeCredit, amount, nil)
+		}
+	}()
+
+	// transferTxn locks both wallets before the allowance is read
+	_, _, err = o.m.transferTxn(o.txn, o.m.ids.NewID(), o.m.ids.NewID(), o.m.ids.NewID(), fromWalletID, toWalletID, delegateID, amount, description, note, data)
+	return err
+}
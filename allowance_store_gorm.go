@@ -0,0 +1,141 @@
+package wallethub
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// AllowanceModel is the GORM model for Allowance entity
+type AllowanceModel struct {
+	TenantID   string    `gorm:"primaryKey;type:varchar(36);not null;default:''"`
+	WalletID   string    `gorm:"primaryKey;type:varchar(36)"`
+	DelegateID string    `gorm:"primaryKey;index;type:varchar(36)"`
+	Amount     int64     `gorm:"type:bigint;not null"`
+	CreatedAt  time.Time `gorm:"type:timestamp;not null;default:CURRENT_TIMESTAMP"`
+	UpdatedAt  time.Time `gorm:"type:timestamp;not null;default:CURRENT_TIMESTAMP"`
+}
+
+// ToAllowance converts an AllowanceModel to an Allowance entity
+func (m *AllowanceModel) ToAllowance() *Allowance {
+	return &Allowance{
+		TenantID:   m.TenantID,
+		WalletID:   m.WalletID,
+		DelegateID: m.DelegateID,
+		Amount:     m.Amount,
+		CreatedAt:  m.CreatedAt,
+		UpdatedAt:  m.UpdatedAt,
+	}
+}
+
+// FromAllowance initializes an AllowanceModel from an Allowance entity
+func (m *AllowanceModel) FromAllowance(allowance *Allowance) {
+	m.TenantID = allowance.TenantID
+	m.WalletID = allowance.WalletID
+	m.DelegateID = allowance.DelegateID
+	m.Amount = allowance.Amount
+	m.CreatedAt = allowance.CreatedAt
+	m.UpdatedAt = allowance.UpdatedAt
+}
+
+// WithStoreAllowanceTable sets the name of the allowance table, "wallet_allowances" by default
+func WithStoreAllowanceTable(table string) GormWalletStoreOption {
+	return func(s *GormWalletStore) {
+		if table != "" {
+			s.allowanceTable = table
+		}
+	}
+}
+
+// allowances returns a query on the allowances of the context's tenant
+func (s *GormWalletStore) allowances(ctx context.Context) *gorm.DB {
+	return s.db.WithContext(ctx).Table(s.allowanceTable).Where(s.allowanceTable+".tenant_id = ?", TenantFromContext(ctx))
+}
+
+// allowances returns a query on the allowances of the transaction's tenant
+func (t *GormTxn) allowances() *gorm.DB {
+	return t.tx.Table(t.allowanceTable).Where(t.allowanceTable+".tenant_id = ?", t.tenantID)
+}
+
+// SaveAllowance creates or replaces the allowance of a delegate on a wallet (transactional)
+func (t *GormTxn) SaveAllowance(allowance *Allowance) (err error) {
+	defer func() {
+		t.log.mutation(t.ctx, "SaveAllowance", true, err,
+			slog.String("wallet_id", allowance.WalletID),
+			slog.String("delegate_id", allowance.DelegateID),
+			slog.Int64("amount", allowance.Amount),
+		)
+	}()
+
+	now := t.clock.Now()
+	if allowance.CreatedAt.IsZero() {
+		allowance.CreatedAt = now
+	}
+	allowance.UpdatedAt = now
+	allowance.TenantID = t.tenantID
+
+	model := &AllowanceModel{}
+	model.FromAllowance(allowance)
+
+	return t.tx.Table(t.allowanceTable).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "tenant_id"}, {Name: "wallet_id"}, {Name: "delegate_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"amount", "updated_at"}),
+	}).Create(model).Error
+}
+
+// FindAllowance finds the allowance of a delegate on a wallet (transactional)
+func (t *GormTxn) FindAllowance(walletID string, delegateID string) (*Allowance, error) {
+	var model AllowanceModel
+	result := t.allowances().Where("wallet_id = ? AND delegate_id = ?", walletID, delegateID).First(&model)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+	return model.ToAllowance(), nil
+}
+
+// DeleteAllowance deletes the allowance of a delegate on a wallet (transactional)
+func (t *GormTxn) DeleteAllowance(walletID string, delegateID string) (err error) {
+	defer func() {
+		t.log.mutation(t.ctx, "DeleteAllowance", true, err,
+			slog.String("wallet_id", walletID),
+			slog.String("delegate_id", delegateID),
+		)
+	}()
+
+	return t.allowances().Where("wallet_id = ? AND delegate_id = ?", walletID, delegateID).Delete(&AllowanceModel{}).Error
+}
+
+// FindAllowance finds the allowance of a delegate on a wallet (non-transactional)
+func (s *GormWalletStore) FindAllowance(ctx context.Context, walletID string, delegateID string) (*Allowance, error) {
+	var model AllowanceModel
+	result := s.allowances(ctx).Where("wallet_id = ? AND delegate_id = ?", walletID, delegateID).First(&model)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+	return model.ToAllowance(), nil
+}
+
+// FindAllowancesByWalletID finds all allowances on a wallet, ordered by delegate (non-transactional)
+func (s *GormWalletStore) FindAllowancesByWalletID(ctx context.Context, walletID string) ([]Allowance, error) {
+	var models []AllowanceModel
+	result := s.allowances(ctx).Where("wallet_id = ?", walletID).Order("delegate_id").Find(&models)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	allowances := make([]Allowance, len(models))
+	for i, model := range models {
+		allowances[i] = *model.ToAllowance()
+	}
+	return allowances, nil
+}
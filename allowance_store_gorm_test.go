@@ -0,0 +1,58 @@
+package wallethub
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestGormTxn_Allowances tests saving, replacing, finding and deleting allowances
+func TestGormTxn_Allowances(t *testing.T) {
+	store := setupTestGormWalletStore(t)
+	ctx := context.Background()
+
+	txn := store.Begin(ctx)
+	require.NoError(t, txn.SaveAllowance(&Allowance{WalletID: "wallet-1", DelegateID: "delegate-1", Amount: 100}))
+	require.NoError(t, txn.SaveAllowance(&Allowance{WalletID: "wallet-1", DelegateID: "delegate-2", Amount: 50}))
+
+	// Saving again replaces the amount of the allowance
+	allowance, err := txn.FindAllowance("wallet-1", "delegate-1")
+	require.NoError(t, err)
+	require.NotNil(t, allowance)
+	createdAt := allowance.CreatedAt
+	allowance.Amount = 70
+	require.NoError(t, txn.SaveAllowance(allowance))
+	require.NoError(t, txn.Commit())
+
+	allowance, err = store.FindAllowance(ctx, "wallet-1", "delegate-1")
+	require.NoError(t, err)
+	require.NotNil(t, allowance)
+	assert.Equal(t, int64(70), allowance.Amount)
+	assert.True(t, allowance.CreatedAt.Equal(createdAt))
+
+	allowances, err := store.FindAllowancesByWalletID(ctx, "wallet-1")
+	require.NoError(t, err)
+	require.Len(t, allowances, 2)
+	assert.Equal(t, "delegate-2", allowances[1].DelegateID)
+
+	// Allowances of other tenants are invisible
+	other := WithTenant(ctx, "tenant-b")
+	allowance, err = store.FindAllowance(other, "wallet-1", "delegate-1")
+	require.NoError(t, err)
+	assert.Nil(t, allowance)
+
+	txn = store.Begin(other)
+	require.NoError(t, txn.DeleteAllowance("wallet-1", "delegate-1"))
+	require.NoError(t, txn.Commit())
+
+	txn = store.Begin(ctx)
+	require.NoError(t, txn.DeleteAllowance("wallet-1", "delegate-1"))
+	require.NoError(t, txn.Commit())
+
+	allowances, err = store.FindAllowancesByWalletID(ctx, "wallet-1")
+	require.NoError(t, err)
+	require.Len(t, allowances, 1)
+	assert.Equal(t, "delegate-2", allowances[0].DelegateID)
+}
@@ -0,0 +1,207 @@
+package wallethub
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// TestAllowances tests granting, spending and revoking allowances
+func TestAllowances(t *testing.T) {
+	manager := NewWalletManager(WithStore(setupTestGormWalletStore(t)))
+	ctx := context.Background()
+
+	wallet, err := manager.CreateWallet(ctx, "parent", "Family", "", "")
+	require.NoError(t, err)
+	_, err = manager.Credit(ctx, wallet.ID, 1000, "Deposit", "", "", nil)
+	require.NoError(t, err)
+
+	allowance, err := manager.GrantAllowance(ctx, wallet.ID, "child", 300)
+	require.NoError(t, err)
+	assert.Equal(t, int64(300), allowance.Amount)
+
+	// The delegate spends from the wallet up to its allowance
+	transaction, err := manager.DebitAsDelegate(ctx, wallet.ID, "child", 200, "Book", "", "order-1", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "child", transaction.DelegateID)
+	assert.Equal(t, int64(800), transaction.Balance)
+
+	allowance, err = manager.GetAllowance(ctx, wallet.ID, "child")
+	require.NoError(t, err)
+	assert.Equal(t, int64(100), allowance.Amount)
+
+	_, err = manager.DebitAsDelegate(ctx, wallet.ID, "child", 150, "Game", "", "", nil)
+	assert.ErrorIs(t, err, ErrAllowanceExceeded)
+
+	wallet, err = manager.GetWallet(ctx, wallet.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(800), wallet.Balance)
+
+	// Users without an allowance cannot spend
+	_, err = manager.DebitAsDelegate(ctx, wallet.ID, "stranger", 10, "Game", "", "", nil)
+	assert.ErrorIs(t, err, ErrAllowanceNotFound)
+
+	// Granting again replaces the allowance
+	_, err = manager.GrantAllowance(ctx, wallet.ID, "child", 500)
+	require.NoError(t, err)
+	_, err = manager.GrantAllowance(ctx, wallet.ID, "partner", 50)
+	require.NoError(t, err)
+
+	allowances, err := manager.ListAllowances(ctx, wallet.ID)
+	require.NoError(t, err)
+	require.Len(t, allowances, 2)
+	assert.Equal(t, "child", allowances[0].DelegateID)
+	assert.Equal(t, int64(500), allowances[0].Amount)
+
+	// A revoked allowance can no longer be spent
+	require.NoError(t, manager.RevokeAllowance(ctx, wallet.ID, "child"))
+	_, err = manager.DebitAsDelegate(ctx, wallet.ID, "child", 10, "Game", "", "", nil)
+	assert.ErrorIs(t, err, ErrAllowanceNotFound)
+	assert.ErrorIs(t, manager.RevokeAllowance(ctx, wallet.ID, "child"), ErrAllowanceNotFound)
+
+	allowance, err = manager.GetAllowance(ctx, wallet.ID, "child")
+	require.NoError(t, err)
+	assert.Nil(t, allowance)
+
+	// Allowances need a positive amount, an existing wallet and a delegate other than the owner
+	_, err = manager.GrantAllowance(ctx, wallet.ID, "child", 0)
+	assert.ErrorIs(t, err, ErrInvalidAmount)
+	_, err = manager.GrantAllowance(ctx, "missing", "child", 100)
+	assert.ErrorIs(t, err, ErrWalletNotFound)
+	_, err = manager.GrantAllowance(ctx, wallet.ID, "parent", 100)
+	assert.ErrorIs(t, err, ErrInvalidDelegate)
+}
+
+// TestTransferFrom tests that delegated transfers spend the allowance on the source wallet
+func TestTransferFrom(t *testing.T) {
+	manager := NewWalletManager(WithStore(setupTestGormWalletStore(t)))
+	ctx := context.Background()
+
+	company, err := manager.CreateWallet(ctx, "company", "Company", "", "")
+	require.NoError(t, err)
+	vendor, err := manager.CreateWallet(ctx, "vendor", "Vendor", "", "")
+	require.NoError(t, err)
+	_, err = manager.Credit(ctx, company.ID, 500, "Deposit", "", "", nil)
+	require.NoError(t, err)
+	_, err = manager.GrantAllowance(ctx, company.ID, "admin", 1000)
+	require.NoError(t, err)
+
+	require.NoError(t, manager.TransferFrom(ctx, company.ID, vendor.ID, "admin", 300, "Invoice", "", nil))
+
+	transactions, err := manager.ListTransactions(ctx, vendor.ID, 10, 0)
+	require.NoError(t, err)
+	require.Len(t, transactions, 1)
+	assert.Equal(t, "admin", transactions[0].DelegateID)
+
+	// A failed transfer leaves the allowance untouched
+	err = manager.TransferFrom(ctx, company.ID, vendor.ID, "admin", 300, "Invoice", "", nil)
+	assert.ErrorIs(t, err, ErrInsufficientBalance)
+
+	allowance, err := manager.GetAllowance(ctx, company.ID, "admin")
+	require.NoError(t, err)
+	assert.Equal(t, int64(700), allowance.Amount)
+
+	// Delegated transactions are covered by the hash chain
+	verification, err := manager.VerifyHashChain(ctx, company.ID)
+	require.NoError(t, err)
+	assert.True(t, verification.Valid())
+}
+
+// TestAllowanceAuthorization tests that only delegates spend under their allowance
+func TestAllowanceAuthorization(t *testing.T) {
+	base := NewWalletManager(WithStore(setupTestGormWalletStore(t)))
+	manager := NewAuthorizingWalletManager(base, nil)
+	admin := WithPrincipal(context.Background(), &Principal{UserID: "admin", Roles: []string{RoleAdmin}})
+	parent := WithPrincipal(context.Background(), &Principal{UserID: "parent"})
+	child := WithPrincipal(context.Background(), &Principal{UserID: "child"})
+	stranger := WithPrincipal(context.Background(), &Principal{UserID: "stranger"})
+
+	wallet, err := manager.CreateWallet(admin, "parent", "Family", "", "")
+	require.NoError(t, err)
+	_, err = manager.Credit(admin, wallet.ID, 1000, "Deposit", "", "", nil)
+	require.NoError(t, err)
+
+	// Only the owner grants allowances
+	_, err = manager.GrantAllowance(child, wallet.ID, "child", 100)
+	assert.ErrorIs(t, err, ErrPermissionDenied)
+	_, err = manager.GrantAllowance(parent, wallet.ID, "child", 100)
+	require.NoError(t, err)
+
+	// Only the delegate spends its allowance, not even the owner on its behalf
+	_, err = manager.DebitAsDelegate(parent, wallet.ID, "child", 10, "Book", "", "", nil)
+	assert.ErrorIs(t, err, ErrPermissionDenied)
+	_, err = manager.DebitAsDelegate(stranger, wallet.ID, "child", 10, "Book", "", "", nil)
+	assert.ErrorIs(t, err, ErrPermissionDenied)
+	_, err = manager.DebitAsDelegate(child, wallet.ID, "child", 10, "Book", "", "", nil)
+	require.NoError(t, err)
+
+	// The owner and the delegate read the allowance, while only the owner lists all allowances
+	_, err = manager.GetAllowance(parent, wallet.ID, "child")
+	require.NoError(t, err)
+	allowance, err := manager.GetAllowance(child, wallet.ID, "child")
+	require.NoError(t, err)
+	assert.Equal(t, int64(90), allowance.Amount)
+	_, err = manager.GetAllowance(stranger, wallet.ID, "child")
+	assert.ErrorIs(t, err, ErrPermissionDenied)
+	_, err = manager.ListAllowances(child, wallet.ID)
+	assert.ErrorIs(t, err, ErrPermissionDenied)
+
+	// The delegate cannot debit the wallet directly
+	_, err = manager.Debit(child, wallet.ID, 10, "Book", "", "", nil)
+	assert.ErrorIs(t, err, ErrPermissionDenied)
+}
+
+// TestConcurrentDelegatedDebits tests that concurrent delegated debits never overspend an allowance
+func TestConcurrentDelegatedDebits(t *testing.T) {
+	dsn := filepath.Join(t.TempDir(), "wallets.db") + "?_txlock=immediate&_busy_timeout=30000&_journal_mode=WAL"
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Discard})
+	require.NoError(t, err)
+	store := NewGormWalletStore(db, "", "")
+	require.NoError(t, store.AutoMigrate(context.Background()))
+
+	manager := NewWalletManager(WithStore(store))
+	ctx := context.Background()
+
+	wallet, err := manager.CreateWallet(ctx, "parent", "Family", "", "")
+	require.NoError(t, err)
+	_, err = manager.Credit(ctx, wallet.ID, 10000, "Deposit", "", "", nil)
+	require.NoError(t, err)
+	_, err = manager.GrantAllowance(ctx, wallet.ID, "child", 100)
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	spent := 0
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := manager.DebitAsDelegate(ctx, wallet.ID, "child", 10, "Snack", "", "", nil)
+			if err == nil {
+				mu.Lock()
+				spent++
+				mu.Unlock()
+				return
+			}
+			assert.ErrorIs(t, err, ErrAllowanceExceeded)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 10, spent)
+
+	allowance, err := manager.GetAllowance(ctx, wallet.ID, "child")
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), allowance.Amount)
+
+	wallet, err = manager.GetWallet(ctx, wallet.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(9900), wallet.Balance)
+}
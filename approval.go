@@ -0,0 +1,546 @@
+package wallethub
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Approval error definitions
+var (
+	ErrApprovalNotFound     = errors.New("approval not found")
+	ErrApprovalNotPending   = errors.New("approval is not pending")
+	ErrApprovalNotExecuting = errors.New("approval is not executing")
+	ErrSelfApproval         = errors.New("approval must be decided by a principal other than the requester")
+	ErrInvalidApproval      = errors.New("invalid approval operation")
+)
+
+// approvalNamespace is the UUID namespace used to derive the transaction IDs of approved credits
+var approvalNamespace = uuid.MustParse("8f4e2a61-0c7b-4b5e-9d36-71a2e5c9b804")
+
+// ApprovalOperation defines the wallet operation awaiting approval
+type ApprovalOperation string
+
+const (
+	ApprovalOperationCredit              ApprovalOperation = "credit"
+	ApprovalOperationDebit               ApprovalOperation = "debit"
+	ApprovalOperationTransfer            ApprovalOperation = "transfer"
+	ApprovalOperationUnfreezeWallet      ApprovalOperation = "unfreeze_wallet"
+	ApprovalOperationClearWalletRiskFlag ApprovalOperation = "clear_wallet_risk_flag"
+)
+
+// ApprovalStatus defines the possible statuses of an approval
+type ApprovalStatus string
+
+const (
+	ApprovalStatusPending   ApprovalStatus = "pending"
+	ApprovalStatusExecuting ApprovalStatus = "executing" // Approved, the operation is being executed
+	ApprovalStatusApproved  ApprovalStatus = "approved"  // Approved and executed
+	ApprovalStatusRejected  ApprovalStatus = "rejected"
+	ApprovalStatusFailed    ApprovalStatus = "failed" // Approved, but the operation failed
+)
+
+// ApprovalEventType defines the entries of an approval's audit trail
+type ApprovalEventType string
+
+const (
+	ApprovalEventRequested ApprovalEventType = "requested"
+	ApprovalEventApproved  ApprovalEventType = "approved"
+	ApprovalEventRejected  ApprovalEventType = "rejected"
+	ApprovalEventExecuted  ApprovalEventType = "executed"
+	ApprovalEventFailed    ApprovalEventType = "failed"
+)
+
+// Approval represents a wallet operation held until a second principal approves it
+type Approval struct {
+	ID            string                 `json:"id"`
+	TenantID      string                 `json:"tenant_id,omitempty"` // Set by the store from the context
+	Operation     ApprovalOperation      `json:"operation"`
+	WalletID      string                 `json:"wallet_id"`
+	ToWalletID    string                 `json:"to_wallet_id,omitempty"` // Destination of transfers
+	Amount        int64                  `json:"amount,omitempty"`
+	Description   string                 `json:"description,omitempty"`
+	Note          string                 `json:"note,omitempty"`
+	Reference     string                 `json:"reference,omitempty"`
+	Data          map[string]interface{} `json:"data,omitempty"`
+	Reason        string                 `json:"reason,omitempty"` // Why the operation was requested
+	Status        ApprovalStatus         `json:"status"`
+	RequestedBy   string                 `json:"requested_by"`
+	DecidedBy     string                 `json:"decided_by,omitempty"`
+	DecidedAt     time.Time              `json:"decided_at,omitempty"`
+	TransactionID string                 `json:"transaction_id,omitempty"` // Resulting transaction (the debit for transfers)
+	LastError     string                 `json:"last_error,omitempty"`
+	CreatedAt     time.Time              `json:"created_at"`
+	UpdatedAt     time.Time              `json:"updated_at"`
+}
+
+// ApprovalEvent is an entry in the audit trail of an approval
+type ApprovalEvent struct {
+	ID          string            `json:"id"`
+	TenantID    string            `json:"tenant_id,omitempty"` // Set by the store from the context
+	ApprovalID  string            `json:"approval_id"`
+	Type        ApprovalEventType `json:"type"`
+	PrincipalID string            `json:"principal_id"`
+	Comment     string            `json:"comment,omitempty"`
+	CreatedAt   time.Time         `json:"created_at"`
+}
+
+// ApprovalStore defines the data access layer interface for approvals
+type ApprovalStore interface {
+	// Approval operations
+	SaveApproval(ctx context.Context, approval *Approval) error
+	FindApproval(ctx context.Context, approvalID string) (*Approval, error)
+	FindApprovalsByStatus(ctx context.Context, status ApprovalStatus, limit int, offset int) ([]Approval, error)
+	UpdateApproval(ctx context.Context, approval *Approval) error
+	TransitionApproval(ctx context.Context, approval *Approval, from ApprovalStatus) (bool, error) // Updates only if the stored status is still from
+
+	// Audit trail operations
+	SaveApprovalEvent(ctx context.Context, event *ApprovalEvent) error
+	FindApprovalEvents(ctx context.Context, approvalID string) ([]ApprovalEvent, error)
+}
+
+// ApprovalManager enforces two-person approval of sensitive wallet operations. Credits above a
+// threshold, unfreezing risk-flagged wallets, clearing risk flags and outgoing amounts refused with
+// ErrRiskApprovalRequired are stored as pending approvals and executed only once a principal other
+// than the requester approves them. Requests and decisions are authorized against a policy.
+type ApprovalManager struct {
+	manager         *DefaultWalletManager
+	store           ApprovalStore
+	policy          Policy
+	creditThreshold int64
+}
+
+// ApprovalOption defines a functional option pattern for configuring the approval manager
+type ApprovalOption func(*ApprovalManager)
+
+// WithCreditApprovalThreshold sets the amount above which credits require approval (0 by default,
+// so every credit requires approval)
+func WithCreditApprovalThreshold(threshold int64) ApprovalOption {
+	return func(a *ApprovalManager) {
+		a.creditThreshold = threshold
+	}
+}
+
+// WithApprovalPolicy sets the policy authorizing requests and decisions (DefaultPolicy by default).
+// Requests are authorized with the action of their operation, and decisions with ActionDecideApproval.
+func WithApprovalPolicy(policy Policy) ApprovalOption {
+	return func(a *ApprovalManager) {
+		a.policy = policy
+	}
+}
+
+// NewApprovalManager creates a new approval manager executing operations through the given wallet manager
+func NewApprovalManager(manager *DefaultWalletManager, store ApprovalStore, options ...ApprovalOption) *ApprovalManager {
+	approvals := &ApprovalManager{
+		manager: manager,
+		store:   store,
+		policy:  DefaultPolicy{},
+	}
+
+	for _, option := range options {
+		option(approvals)
+	}
+
+	return approvals
+}
+
+// approvedTransactionID returns the deterministic ID of a transaction created by an approval
+func approvedTransactionID(approvalID string, leg TransactionType) string {
+	return uuid.NewSHA1(approvalNamespace, []byte(approvalID+"\x00"+string(leg))).String()
+}
+
+// Credit adds points to a wallet. Credits above the threshold are not applied but held for approval,
+// in which case the pending approval is returned instead of a transaction.
+func (a *ApprovalManager) Credit(ctx context.Context, walletID string, amount int64, description string, note string, reference string, data map[string]interface{}) (*Transaction, *Approval, error) {
+	if err := a.authorize(ctx, ActionCredit, walletID, amount); err != nil {
+		return nil, nil, err
+	}
+
+	if amount <= a.creditThreshold {
+		transaction, err := a.manager.Credit(ctx, walletID, amount, description, note, reference, data)
+		return transaction, nil, err
+	}
+
+	approval, err := a.request(ctx, &Approval{
+		Operation:   ApprovalOperationCredit,
+		WalletID:    walletID,
+		Amount:      amount,
+		Description: description,
+		Note:        note,
+		Reference:   reference,
+		Data:        data,
+	})
+	return nil, approval, err
+}
+
+// Debit removes points from a wallet. Debits the risk policy refuses with ErrRiskApprovalRequired
+// are held for approval, in which case the pending approval is returned instead of a transaction.
+func (a *ApprovalManager) Debit(ctx context.Context, walletID string, amount int64, description string, note string, reference string, data map[string]interface{}) (*Transaction, *Approval, error) {
+	if err := a.authorize(ctx, ActionDebit, walletID, amount); err != nil {
+		return nil, nil, err
+	}
+
+	transaction, err := a.manager.Debit(ctx, walletID, amount, description, note, reference, data)
+	if !errors.Is(err, ErrRiskApprovalRequired) {
+		return transaction, nil, err
+	}
+
+	approval, err := a.request(ctx, &Approval{
+		Operation:   ApprovalOperationDebit,
+		WalletID:    walletID,
+		Amount:      amount,
+		Description: description,
+		Note:        note,
+		Reference:   reference,
+		Data:        data,
+	})
+	return nil, approval, err
+}
+
+// Transfer transfers points from one wallet to another. Transfers the risk policy refuses with
+// ErrRiskApprovalRequired are held for approval, in which case the pending approval is returned.
+func (a *ApprovalManager) Transfer(ctx context.Context, fromWalletID string, toWalletID string, amount int64, description string, note string, data map[string]interface{}) (*Approval, error) {
+	if err := a.authorize(ctx, ActionTransfer, fromWalletID, amount); err != nil {
+		return nil, err
+	}
+
+	err := a.manager.Transfer(ctx, fromWalletID, toWalletID, amount, description, note, data)
+	if !errors.Is(err, ErrRiskApprovalRequired) {
+		return nil, err
+	}
+
+	return a.request(ctx, &Approval{
+		Operation:   ApprovalOperationTransfer,
+		WalletID:    fromWalletID,
+		ToWalletID:  toWalletID,
+		Amount:      amount,
+		Description: description,
+		Note:        note,
+		Data:        data,
+	})
+}
+
+// UnfreezeWallet unfreezes a wallet. Unfreezing a risk-flagged wallet is held for approval, in which
+// case the pending approval is returned.
+func (a *ApprovalManager) UnfreezeWallet(ctx context.Context, walletID string, reason string) (*Approval, error) {
+	if err := a.authorize(ctx, ActionUnfreezeWallet, walletID, 0); err != nil {
+		return nil, err
+	}
+
+	wallet, err := a.manager.GetWallet(ctx, walletID)
+	if err != nil {
+		return nil, err
+	}
+	if wallet == nil {
+		return nil, ErrWalletNotFound
+	}
+
+	if !wallet.RiskFlagged {
+		return nil, a.manager.UnfreezeWallet(ctx, walletID)
+	}
+
+	return a.request(ctx, &Approval{
+		Operation: ApprovalOperationUnfreezeWallet,
+		WalletID:  walletID,
+		Reason:    reason,
+	})
+}
+
+// ClearWalletRiskFlag requests clearing the risk flag of a wallet, which always requires approval
+func (a *ApprovalManager) ClearWalletRiskFlag(ctx context.Context, walletID string, reason string) (*Approval, error) {
+	if err := a.authorize(ctx, ActionClearWalletRiskFlag, walletID, 0); err != nil {
+		return nil, err
+	}
+
+	wallet, err := a.manager.GetWallet(ctx, walletID)
+	if err != nil {
+		return nil, err
+	}
+	if wallet == nil {
+		return nil, ErrWalletNotFound
+	}
+
+	return a.request(ctx, &Approval{
+		Operation: ApprovalOperationClearWalletRiskFlag,
+		WalletID:  walletID,
+		Reason:    reason,
+	})
+}
+
+// request stores a pending approval on behalf of the principal of the context
+func (a *ApprovalManager) request(ctx context.Context, approval *Approval) (*Approval, error) {
+	principal := PrincipalFromContext(ctx)
+	if principal == nil {
+		return nil, ErrUnauthenticated
+	}
+
+	now := a.manager.clock.Now()
+	approval.ID = a.manager.ids.NewID()
+	approval.Status = ApprovalStatusPending
+	approval.RequestedBy = principal.UserID
+	approval.CreatedAt = now
+	approval.UpdatedAt = now
+
+	if err := a.store.SaveApproval(ctx, approval); err != nil {
+		return nil, err
+	}
+	if err := a.record(ctx, approval, ApprovalEventRequested, principal.UserID, approval.Reason); err != nil {
+		return nil, err
+	}
+
+	return approval, nil
+}
+
+// Approve approves a pending approval and executes its operation. The approval is decided even if
+// the operation fails, in which case it ends up failed with the error recorded. Of concurrent
+// decisions on the same approval, only the first succeeds and the others fail with ErrApprovalNotPending.
+func (a *ApprovalManager) Approve(ctx context.Context, approvalID string, comment string) (*Approval, error) {
+	approval, principal, err := a.decide(ctx, approvalID)
+	if err != nil {
+		return nil, err
+	}
+
+	approval.DecidedBy = principal.UserID
+	approval.DecidedAt = a.manager.clock.Now()
+	if err := a.claim(ctx, approval, ApprovalStatusExecuting); err != nil {
+		return nil, err
+	}
+	if err := a.record(ctx, approval, ApprovalEventApproved, principal.UserID, comment); err != nil {
+		return nil, err
+	}
+
+	return a.finish(ctx, approval, principal.UserID)
+}
+
+// RecoverApproval executes the operation of an approval left executing, for example because the
+// process approving it stopped before the outcome was recorded, and records the outcome. Operations
+// already applied are detected by their deterministic transaction IDs and not applied again.
+func (a *ApprovalManager) RecoverApproval(ctx context.Context, approvalID string) (*Approval, error) {
+	principal := PrincipalFromContext(ctx)
+	if principal == nil {
+		return nil, ErrUnauthenticated
+	}
+
+	approval, err := a.store.FindApproval(ctx, approvalID)
+	if err != nil {
+		return nil, err
+	}
+	if approval == nil {
+		return nil, ErrApprovalNotFound
+	}
+	if err := a.authorize(ctx, ActionDecideApproval, approval.WalletID, approval.Amount); err != nil {
+		return nil, err
+	}
+	if approval.Status != ApprovalStatusExecuting {
+		return nil, ErrApprovalNotExecuting
+	}
+
+	return a.finish(ctx, approval, principal.UserID)
+}
+
+// finish executes the operation of an executing approval and records its outcome. If the outcome was
+// recorded concurrently, the stored approval is returned instead.
+func (a *ApprovalManager) finish(ctx context.Context, approval *Approval, principalID string) (*Approval, error) {
+	eventType, comment := ApprovalEventExecuted, ""
+	if err := a.execute(ctx, approval); err != nil {
+		approval.Status = ApprovalStatusFailed
+		approval.LastError = err.Error()
+		eventType, comment = ApprovalEventFailed, err.Error()
+	} else {
+		approval.Status = ApprovalStatusApproved
+		approval.LastError = ""
+	}
+
+	updated, err := a.store.TransitionApproval(ctx, approval, ApprovalStatusExecuting)
+	if err != nil {
+		return nil, err
+	}
+	if !updated {
+		return a.store.FindApproval(ctx, approval.ID)
+	}
+	if err := a.record(ctx, approval, eventType, principalID, comment); err != nil {
+		return nil, err
+	}
+	return approval, nil
+}
+
+// Reject rejects a pending approval without executing its operation
+func (a *ApprovalManager) Reject(ctx context.Context, approvalID string, comment string) (*Approval, error) {
+	approval, principal, err := a.decide(ctx, approvalID)
+	if err != nil {
+		return nil, err
+	}
+
+	approval.DecidedBy = principal.UserID
+	approval.DecidedAt = a.manager.clock.Now()
+	if err := a.claim(ctx, approval, ApprovalStatusRejected); err != nil {
+		return nil, err
+	}
+	if err := a.record(ctx, approval, ApprovalEventRejected, principal.UserID, comment); err != nil {
+		return nil, err
+	}
+	return approval, nil
+}
+
+// claim moves a pending approval to the given status, failing with ErrApprovalNotPending if it was
+// decided since it was loaded
+func (a *ApprovalManager) claim(ctx context.Context, approval *Approval, status ApprovalStatus) error {
+	approval.Status = status
+	updated, err := a.store.TransitionApproval(ctx, approval, ApprovalStatusPending)
+	if err != nil {
+		return err
+	}
+	if !updated {
+		return ErrApprovalNotPending
+	}
+	return nil
+}
+
+// decide loads a pending approval and checks that the principal of the context may decide it
+func (a *ApprovalManager) decide(ctx context.Context, approvalID string) (*Approval, *Principal, error) {
+	principal := PrincipalFromContext(ctx)
+	if principal == nil {
+		return nil, nil, ErrUnauthenticated
+	}
+
+	approval, err := a.store.FindApproval(ctx, approvalID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if approval == nil {
+		return nil, nil, ErrApprovalNotFound
+	}
+	if approval.Status != ApprovalStatusPending {
+		return nil, nil, ErrApprovalNotPending
+	}
+	if approval.RequestedBy == principal.UserID {
+		return nil, nil, ErrSelfApproval
+	}
+	if err := a.authorize(ctx, ActionDecideApproval, approval.WalletID, approval.Amount); err != nil {
+		return nil, nil, err
+	}
+
+	return approval, principal, nil
+}
+
+// execute performs the operation of an approval
+func (a *ApprovalManager) execute(ctx context.Context, approval *Approval) error {
+	switch approval.Operation {
+	case ApprovalOperationCredit, ApprovalOperationDebit, ApprovalOperationTransfer:
+		transactionID, err := a.apply(ctx, approval)
+		if err != nil {
+			return err
+		}
+		approval.TransactionID = transactionID
+		return nil
+	case ApprovalOperationUnfreezeWallet:
+		return a.manager.UnfreezeWallet(ctx, approval.WalletID)
+	case ApprovalOperationClearWalletRiskFlag:
+		return a.manager.ClearWalletRiskFlag(ctx, approval.WalletID)
+	default:
+		return ErrInvalidApproval
+	}
+}
+
+// apply performs the balance operation of an approval, lifting the approval threshold of the risk
+// policy. The resulting transactions use IDs derived from the approval ID, so an approval that was
+// already applied is detected instead of being applied again.
+func (a *ApprovalManager) apply(ctx context.Context, approval *Approval) (string, error) {
+	manager := a.manager.withRiskApproval()
+	transactionID := approvedTransactionID(approval.ID, TransactionTypeDebit)
+	if approval.Operation == ApprovalOperationCredit {
+		transactionID = approvedTransactionID(approval.ID, TransactionTypeCredit)
+	}
+
+	walletIDs := []string{approval.WalletID}
+	if approval.Operation == ApprovalOperationTransfer {
+		walletIDs = append(walletIDs, approval.ToWalletID)
+	}
+	unlock, err := manager.lockWallets(ctx, walletIDs...)
+	if err != nil {
+		return "", err
+	}
+	defer unlock()
+
+	err = manager.withinTx(ctx, func(o *walletOps) error {
+		// Check whether an earlier attempt already applied this approval
+		existing, err := o.txn.FindTransaction(transactionID)
+		if err != nil {
+			return err
+		}
+		if existing != nil {
+			return nil
+		}
+
+		switch approval.Operation {
+		case ApprovalOperationCredit:
+			_, err = o.creditBucket(transactionID, approval.WalletID, DefaultBucket, approval.Amount, approval.Description, approval.Note, approval.Reference, approval.Data)
+		case ApprovalOperationDebit:
+			_, err = o.debit(transactionID, approval.WalletID, approval.Amount, approval.Description, approval.Note, approval.Reference, approval.Data)
+		case ApprovalOperationTransfer:
+			creditID := approvedTransactionID(approval.ID, TransactionTypeCredit)
+			err = o.transfer(transactionID, creditID, approval.ID, approval.WalletID, approval.ToWalletID, approval.Amount, approval.Description, approval.Note, approval.Data)
+		}
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return transactionID, nil
+}
+
+// authorize checks an operation on a wallet against the policy, looking up the owner of the wallet
+func (a *ApprovalManager) authorize(ctx context.Context, action Action, walletID string, amount int64) error {
+	wallet, err := a.manager.GetWallet(ctx, walletID)
+	if err != nil {
+		return err
+	}
+
+	ownerID := ""
+	if wallet != nil {
+		ownerID = wallet.UserID
+	}
+	return a.policy.Authorize(ctx, &AuthorizationRequest{
+		Principal: PrincipalFromContext(ctx),
+		Action:    action,
+		OwnerID:   ownerID,
+		WalletID:  walletID,
+		Amount:    amount,
+	})
+}
+
+// record appends an entry to the audit trail of an approval
+func (a *ApprovalManager) record(ctx context.Context, approval *Approval, eventType ApprovalEventType, principalID string, comment string) error {
+	return a.store.SaveApprovalEvent(ctx, &ApprovalEvent{
+		ID:          a.manager.ids.NewID(),
+		ApprovalID:  approval.ID,
+		Type:        eventType,
+		PrincipalID: principalID,
+		Comment:     comment,
+		CreatedAt:   a.manager.clock.Now(),
+	})
+}
+
+// GetApproval gets an approval by ID
+func (a *ApprovalManager) GetApproval(ctx context.Context, approvalID string) (*Approval, error) {
+	return a.store.FindApproval(ctx, approvalID)
+}
+
+// ListPendingApprovals lists the approvals awaiting a decision with pagination
+func (a *ApprovalManager) ListPendingApprovals(ctx context.Context, limit int, offset int) ([]Approval, error) {
+	return a.store.FindApprovalsByStatus(ctx, ApprovalStatusPending, limit, offset)
+}
+
+// ListExecutingApprovals lists the approved approvals whose outcome is not recorded yet with pagination,
+// which includes those to be recovered with RecoverApproval
+func (a *ApprovalManager) ListExecutingApprovals(ctx context.Context, limit int, offset int) ([]Approval, error) {
+	return a.store.FindApprovalsByStatus(ctx, ApprovalStatusExecuting, limit, offset)
+}
+
+// ListApprovalEvents lists the audit trail of an approval in chronological order
+func (a *ApprovalManager) ListApprovalEvents(ctx context.Context, approvalID string) ([]ApprovalEvent, error) {
+	return a.store.FindApprovalEvents(ctx, approvalID)
+}
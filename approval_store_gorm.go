@@ -0,0 +1,327 @@
+package wallethub
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// ApprovalModel is the GORM model for Approval entity
+type ApprovalModel struct {
+	ID            string            `gorm:"primaryKey;type:varchar(36)"`
+	TenantID      string            `gorm:"index;type:varchar(36);not null;default:''"`
+	Operation     ApprovalOperation `gorm:"type:varchar(30);not null"`
+	WalletID      string            `gorm:"index;type:varchar(36)"`
+	ToWalletID    string            `gorm:"type:varchar(36)"`
+	Amount        int64             `gorm:"type:bigint"`
+	Description   string            `gorm:"type:varchar(255)"`
+	Note          string            `gorm:"type:text"`
+	Reference     string            `gorm:"type:varchar(100)"`
+	Data          datatypes.JSON    `gorm:"type:json"`
+	Reason        string            `gorm:"type:text"`
+	Status        ApprovalStatus    `gorm:"index;type:varchar(20);not null"`
+	RequestedBy   string            `gorm:"type:varchar(100);not null"`
+	DecidedBy     string            `gorm:"type:varchar(100)"`
+	DecidedAt     time.Time         `gorm:"type:timestamp"`
+	TransactionID string            `gorm:"type:varchar(36)"`
+	LastError     string            `gorm:"type:text"`
+	CreatedAt     time.Time         `gorm:"type:timestamp;not null;default:CURRENT_TIMESTAMP"`
+	UpdatedAt     time.Time         `gorm:"type:timestamp;not null;default:CURRENT_TIMESTAMP"`
+}
+
+// ApprovalEventModel is the GORM model for ApprovalEvent entity
+type ApprovalEventModel struct {
+	ID          string            `gorm:"primaryKey;type:varchar(36)"`
+	TenantID    string            `gorm:"index;type:varchar(36);not null;default:''"`
+	ApprovalID  string            `gorm:"index;type:varchar(36)"`
+	Type        ApprovalEventType `gorm:"type:varchar(20);not null"`
+	PrincipalID string            `gorm:"type:varchar(100);not null"`
+	Comment     string            `gorm:"type:text"`
+	CreatedAt   time.Time         `gorm:"type:timestamp;not null;default:CURRENT_TIMESTAMP"`
+}
+
+// ToApproval converts an ApprovalModel to an Approval entity
+func (m *ApprovalModel) ToApproval() *Approval {
+	data := make(map[string]interface{})
+	if len(m.Data) > 0 {
+		// Unmarshal the JSON data into the map
+		if err := json.Unmarshal(m.Data, &data); err != nil {
+			// If there's an error, just return an empty map
+			data = make(map[string]interface{})
+		}
+	}
+
+	return &Approval{
+		ID:            m.ID,
+		TenantID:      m.TenantID,
+		Operation:     m.Operation,
+		WalletID:      m.WalletID,
+		ToWalletID:    m.ToWalletID,
+		Amount:        m.Amount,
+		Description:   m.Description,
+		Note:          m.Note,
+		Reference:     m.Reference,
+		Data:          data,
+		Reason:        m.Reason,
+		Status:        m.Status,
+		RequestedBy:   m.RequestedBy,
+		DecidedBy:     m.DecidedBy,
+		DecidedAt:     m.DecidedAt,
+		TransactionID: m.TransactionID,
+		LastError:     m.LastError,
+		CreatedAt:     m.CreatedAt,
+		UpdatedAt:     m.UpdatedAt,
+	}
+}
+
+// FromApproval initializes an ApprovalModel from an Approval entity
+func (m *ApprovalModel) FromApproval(approval *Approval) error {
+	if approval.Data != nil {
+		// Convert the map to JSON bytes
+		jsonBytes, err := json.Marshal(approval.Data)
+		if err != nil {
+			return err
+		}
+		// Set the JSON data
+		err = m.Data.UnmarshalJSON(jsonBytes)
+		if err != nil {
+			return err
+		}
+	}
+
+	m.ID = approval.ID
+	m.TenantID = approval.TenantID
+	m.Operation = approval.Operation
+	m.WalletID = approval.WalletID
+	m.ToWalletID = approval.ToWalletID
+	m.Amount = approval.Amount
+	m.Description = approval.Description
+	m.Note = approval.Note
+	m.Reference = approval.Reference
+	m.Reason = approval.Reason
+	m.Status = approval.Status
+	m.RequestedBy = approval.RequestedBy
+	m.DecidedBy = approval.DecidedBy
+	m.DecidedAt = approval.DecidedAt
+	m.TransactionID = approval.TransactionID
+	m.LastError = approval.LastError
+	m.CreatedAt = approval.CreatedAt
+	m.UpdatedAt = approval.UpdatedAt
+
+	return nil
+}
+
+// ToApprovalEvent converts an ApprovalEventModel to an ApprovalEvent entity
+func (m *ApprovalEventModel) ToApprovalEvent() *ApprovalEvent {
+	return &ApprovalEvent{
+		ID:          m.ID,
+		TenantID:    m.TenantID,
+		ApprovalID:  m.ApprovalID,
+		Type:        m.Type,
+		PrincipalID: m.PrincipalID,
+		Comment:     m.Comment,
+		CreatedAt:   m.CreatedAt,
+	}
+}
+
+// FromApprovalEvent initializes an ApprovalEventModel from an ApprovalEvent entity
+func (m *ApprovalEventModel) FromApprovalEvent(event *ApprovalEvent) {
+	m.ID = event.ID
+	m.TenantID = event.TenantID
+	m.ApprovalID = event.ApprovalID
+	m.Type = event.Type
+	m.PrincipalID = event.PrincipalID
+	m.Comment = event.Comment
+	m.CreatedAt = event.CreatedAt
+}
+
+// GormApprovalStore implements ApprovalStore interface using GORM
+type GormApprovalStore struct {
+	db                 *gorm.DB
+	approvalTable      string
+	approvalEventTable string
+	clock              Clock
+}
+
+// GormApprovalStoreOption defines a function type for configuring GormApprovalStore
+type GormApprovalStoreOption func(*GormApprovalStore)
+
+// WithApprovalStoreClock sets the clock timestamping saved and updated approvals and approval events,
+// SystemClock by default. GORM's automatic timestamps use the clock too.
+func WithApprovalStoreClock(clock Clock) GormApprovalStoreOption {
+	return func(s *GormApprovalStore) {
+		s.clock = clock
+		s.db = s.db.Session(&gorm.Session{NowFunc: clock.Now})
+	}
+}
+
+// NewGormApprovalStore creates a new instance of GormApprovalStore with custom table names
+func NewGormApprovalStore(db *gorm.DB, approvalTable, approvalEventTable string, options ...GormApprovalStoreOption) *GormApprovalStore {
+	if approvalTable == "" {
+		approvalTable = "wallet_approvals"
+	}
+	if approvalEventTable == "" {
+		approvalEventTable = "wallet_approval_events"
+	}
+
+	store := &GormApprovalStore{
+		db:                 db,
+		approvalTable:      approvalTable,
+		approvalEventTable: approvalEventTable,
+		clock:              SystemClock,
+	}
+
+	// Apply all options
+	for _, option := range options {
+		option(store)
+	}
+
+	return store
+}
+
+// approvals returns a query on the approvals of the context's tenant
+func (s *GormApprovalStore) approvals(ctx context.Context) *gorm.DB {
+	return s.db.WithContext(ctx).Table(s.approvalTable).Where("tenant_id = ?", TenantFromContext(ctx))
+}
+
+// events returns a query on the approval events of the context's tenant
+func (s *GormApprovalStore) events(ctx context.Context) *gorm.DB {
+	return s.db.WithContext(ctx).Table(s.approvalEventTable).Where("tenant_id = ?", TenantFromContext(ctx))
+}
+
+// AutoMigrate creates or updates the necessary database tables
+func (s *GormApprovalStore) AutoMigrate(ctx context.Context) error {
+	// Use context with DB
+	db := s.db.WithContext(ctx)
+
+	// Create or update the approval table
+	if err := db.Table(s.approvalTable).AutoMigrate(&ApprovalModel{}); err != nil {
+		return err
+	}
+
+	// Create or update the approval event table
+	if err := db.Table(s.approvalEventTable).AutoMigrate(&ApprovalEventModel{}); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// SaveApproval saves an approval to the database
+func (s *GormApprovalStore) SaveApproval(ctx context.Context, approval *Approval) error {
+	if approval.CreatedAt.IsZero() {
+		approval.CreatedAt = s.clock.Now()
+	}
+	approval.UpdatedAt = s.clock.Now()
+	approval.TenantID = TenantFromContext(ctx)
+
+	model := &ApprovalModel{}
+	if err := model.FromApproval(approval); err != nil {
+		return err
+	}
+
+	return s.approvals(ctx).Create(model).Error
+}
+
+// FindApproval finds an approval by ID
+func (s *GormApprovalStore) FindApproval(ctx context.Context, approvalID string) (*Approval, error) {
+	var model ApprovalModel
+	result := s.approvals(ctx).Where("id = ?", approvalID).First(&model)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+	return model.ToApproval(), nil
+}
+
+// FindApprovalsByStatus finds approvals with the given status, oldest first, with pagination
+func (s *GormApprovalStore) FindApprovalsByStatus(ctx context.Context, status ApprovalStatus, limit int, offset int) ([]Approval, error) {
+	var models []ApprovalModel
+	result := s.approvals(ctx).
+		Where("status = ?", status).
+		Order("created_at ASC").
+		Limit(limit).
+		Offset(offset).
+		Find(&models)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	approvals := make([]Approval, len(models))
+	for i, model := range models {
+		approval := model.ToApproval()
+		approvals[i] = *approval
+	}
+	return approvals, nil
+}
+
+// UpdateApproval updates an existing approval
+func (s *GormApprovalStore) UpdateApproval(ctx context.Context, approval *Approval) error {
+	approval.UpdatedAt = s.clock.Now()
+	approval.TenantID = TenantFromContext(ctx)
+
+	model := &ApprovalModel{}
+	if err := model.FromApproval(approval); err != nil {
+		return err
+	}
+
+	// Update all columns of the row only if it belongs to the tenant
+	return s.approvals(ctx).Select("*").Updates(model).Error
+}
+
+// TransitionApproval updates an existing approval only if its stored status is still from, and
+// reports whether it did. The check and the update are one statement, so of concurrent transitions
+// from the same status only one succeeds.
+func (s *GormApprovalStore) TransitionApproval(ctx context.Context, approval *Approval, from ApprovalStatus) (bool, error) {
+	approval.UpdatedAt = s.clock.Now()
+	approval.TenantID = TenantFromContext(ctx)
+
+	model := &ApprovalModel{}
+	if err := model.FromApproval(approval); err != nil {
+		return false, err
+	}
+
+	result := s.approvals(ctx).Where("status = ?", from).Select("*").Updates(model)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// SaveApprovalEvent appends an event to the audit trail
+func (s *GormApprovalStore) SaveApprovalEvent(ctx context.Context, event *ApprovalEvent) error {
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = s.clock.Now()
+	}
+	event.TenantID = TenantFromContext(ctx)
+
+	model := &ApprovalEventModel{}
+	model.FromApprovalEvent(event)
+
+	return s.events(ctx).Create(model).Error
+}
+
+// FindApprovalEvents finds the audit trail of an approval in chronological order
+func (s *GormApprovalStore) FindApprovalEvents(ctx context.Context, approvalID string) ([]ApprovalEvent, error) {
+	var models []ApprovalEventModel
+	result := s.events(ctx).
+		Where("approval_id = ?", approvalID).
+		Order("created_at ASC").
+		Find(&models)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	events := make([]ApprovalEvent, len(models))
+	for i, model := range models {
+		event := model.ToApprovalEvent()
+		events[i] = *event
+	}
+	return events, nil
+}
@@ -0,0 +1,182 @@
+package wallethub
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// setupTestGormApprovalStore creates a new GormApprovalStore with an in-memory SQLite database for testing
+func setupTestGormApprovalStore(t *testing.T) *GormApprovalStore {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+
+	store := NewGormApprovalStore(db, "", "")
+
+	// Migrate tables using store's method
+	ctx := context.Background()
+	err = store.AutoMigrate(ctx)
+	require.NoError(t, err)
+
+	return store
+}
+
+// TestGormApprovalStore_Approvals tests saving, finding and updating approvals
+func TestGormApprovalStore_Approvals(t *testing.T) {
+	store := setupTestGormApprovalStore(t)
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+
+	approval := &Approval{
+		ID:          "test-approval-id",
+		Operation:   ApprovalOperationCredit,
+		WalletID:    "test-wallet-id",
+		Amount:      5000,
+		Description: "Large bonus",
+		Data:        map[string]interface{}{"campaign": "spring"},
+		Status:      ApprovalStatusPending,
+		RequestedBy: "maker",
+		CreatedAt:   now,
+	}
+	err := store.SaveApproval(ctx, approval)
+	assert.NoError(t, err)
+
+	found, err := store.FindApproval(ctx, approval.ID)
+	assert.NoError(t, err)
+	require.NotNil(t, found)
+	assert.Equal(t, ApprovalOperationCredit, found.Operation)
+	assert.Equal(t, int64(5000), found.Amount)
+	assert.Equal(t, "spring", found.Data["campaign"])
+	assert.Equal(t, "maker", found.RequestedBy)
+
+	pending, err := store.FindApprovalsByStatus(ctx, ApprovalStatusPending, 10, 0)
+	assert.NoError(t, err)
+	assert.Len(t, pending, 1)
+
+	found.Status = ApprovalStatusApproved
+	found.DecidedBy = "checker"
+	found.DecidedAt = now
+	err = store.UpdateApproval(ctx, found)
+	assert.NoError(t, err)
+
+	found, err = store.FindApproval(ctx, approval.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, ApprovalStatusApproved, found.Status)
+	assert.Equal(t, "checker", found.DecidedBy)
+
+	pending, err = store.FindApprovalsByStatus(ctx, ApprovalStatusPending, 10, 0)
+	assert.NoError(t, err)
+	assert.Empty(t, pending)
+
+	// Test finding a non-existent approval
+	found, err = store.FindApproval(ctx, "non-existent-id")
+	assert.NoError(t, err)
+	assert.Nil(t, found)
+
+	// Test the approval is invisible to other tenants
+	found, err = store.FindApproval(WithTenant(ctx, "other-tenant"), approval.ID)
+	assert.NoError(t, err)
+	assert.Nil(t, found)
+}
+
+// TestGormApprovalStore_TransitionApproval tests that approvals are only updated from the expected status
+func TestGormApprovalStore_TransitionApproval(t *testing.T) {
+	store := setupTestGormApprovalStore(t)
+	ctx := context.Background()
+
+	approval := &Approval{
+		ID:          "test-approval-id",
+		Operation:   ApprovalOperationCredit,
+		WalletID:    "test-wallet-id",
+		Amount:      5000,
+		Status:      ApprovalStatusPending,
+		RequestedBy: "maker",
+	}
+	require.NoError(t, store.SaveApproval(ctx, approval))
+
+	first := *approval
+	first.Status = ApprovalStatusExecuting
+	first.DecidedBy = "checker-1"
+	updated, err := store.TransitionApproval(ctx, &first, ApprovalStatusPending)
+	assert.NoError(t, err)
+	assert.True(t, updated)
+
+	// A transition from a stale status leaves the approval untouched
+	second := *approval
+	second.Status = ApprovalStatusRejected
+	second.DecidedBy = "checker-2"
+	updated, err = store.TransitionApproval(ctx, &second, ApprovalStatusPending)
+	assert.NoError(t, err)
+	assert.False(t, updated)
+
+	found, err := store.FindApproval(ctx, approval.ID)
+	assert.NoError(t, err)
+	require.NotNil(t, found)
+	assert.Equal(t, ApprovalStatusExecuting, found.Status)
+	assert.Equal(t, "checker-1", found.DecidedBy)
+
+	// Approvals of other tenants are not transitioned
+	updated, err = store.TransitionApproval(WithTenant(ctx, "other-tenant"), &first, ApprovalStatusExecuting)
+	assert.NoError(t, err)
+	assert.False(t, updated)
+}
+
+// TestGormApprovalStore_Events tests the audit trail of approvals
+func TestGormApprovalStore_Events(t *testing.T) {
+	store := setupTestGormApprovalStore(t)
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+
+	events := []*ApprovalEvent{
+		{ID: "event-2", ApprovalID: "test-approval-id", Type: ApprovalEventApproved, PrincipalID: "checker", Comment: "Looks good", CreatedAt: now.Add(time.Minute)},
+		{ID: "event-1", ApprovalID: "test-approval-id", Type: ApprovalEventRequested, PrincipalID: "maker", CreatedAt: now},
+		{ID: "event-3", ApprovalID: "other-approval-id", Type: ApprovalEventRequested, PrincipalID: "maker", CreatedAt: now},
+	}
+	for _, event := range events {
+		err := store.SaveApprovalEvent(ctx, event)
+		assert.NoError(t, err)
+	}
+
+	found, err := store.FindApprovalEvents(ctx, "test-approval-id")
+	assert.NoError(t, err)
+	require.Len(t, found, 2)
+	assert.Equal(t, ApprovalEventRequested, found[0].Type)
+	assert.Equal(t, ApprovalEventApproved, found[1].Type)
+	assert.Equal(t, "Looks good", found[1].Comment)
+}
+
+// TestGormApprovalStoreClock tests that approvals and their events are timestamped by the store clock
+func TestGormApprovalStoreClock(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	store := NewGormApprovalStore(db, "", "", WithApprovalStoreClock(ClockFunc(func() time.Time { return now })))
+	ctx := context.Background()
+	require.NoError(t, store.AutoMigrate(ctx))
+
+	approval := &Approval{ID: "approval-id", Operation: ApprovalOperationCredit, WalletID: "wallet-id", Amount: 100, Status: ApprovalStatusPending}
+	require.NoError(t, store.SaveApproval(ctx, approval))
+	assert.True(t, now.Equal(approval.CreatedAt))
+	assert.True(t, now.Equal(approval.UpdatedAt))
+
+	now = now.Add(time.Hour)
+	approval.Status = ApprovalStatusRejected
+	updated, err := store.TransitionApproval(ctx, approval, ApprovalStatusPending)
+	require.NoError(t, err)
+	require.True(t, updated)
+
+	found, err := store.FindApproval(ctx, approval.ID)
+	require.NoError(t, err)
+	assert.True(t, now.Add(-time.Hour).Equal(found.CreatedAt))
+	assert.True(t, now.Equal(found.UpdatedAt))
+
+	event := &ApprovalEvent{ID: "event-id", ApprovalID: approval.ID, Type: ApprovalEventRejected}
+	require.NoError(t, store.SaveApprovalEvent(ctx, event))
+	assert.True(t, now.Equal(event.CreatedAt))
+}
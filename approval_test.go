@@ -0,0 +1,370 @@
+package wallethub
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupTestApprovals creates an approval manager requiring approval for credits above 1000
+func setupTestApprovals(t *testing.T) (*DefaultWalletManager, *ApprovalManager, *Wallet) {
+	manager := NewWalletManager(WithStore(setupTestGormWalletStore(t)))
+	approvals := NewApprovalManager(manager, setupTestGormApprovalStore(t), WithCreditApprovalThreshold(1000))
+
+	wallet, err := manager.CreateWallet(context.Background(), "test-user", "Test Wallet", "", "")
+	require.NoError(t, err)
+
+	return manager, approvals, wallet
+}
+
+// TestApprovalCredit tests that large credits are held until approved by another principal
+func TestApprovalCredit(t *testing.T) {
+	manager, approvals, wallet := setupTestApprovals(t)
+	makerCtx := WithPrincipal(context.Background(), &Principal{UserID: "maker", Roles: []string{RoleAdmin}})
+	checkerCtx := WithPrincipal(context.Background(), &Principal{UserID: "checker", Roles: []string{RoleAdmin}})
+
+	// Credits up to the threshold are applied directly
+	transaction, approval, err := approvals.Credit(makerCtx, wallet.ID, 1000, "Bonus", "", "", nil)
+	assert.NoError(t, err)
+	assert.NotNil(t, transaction)
+	assert.Nil(t, approval)
+
+	// Larger credits are held for approval
+	transaction, approval, err = approvals.Credit(makerCtx, wallet.ID, 5000, "Large bonus", "", "bonus-1", nil)
+	assert.NoError(t, err)
+	assert.Nil(t, transaction)
+	require.NotNil(t, approval)
+	assert.Equal(t, ApprovalStatusPending, approval.Status)
+	assert.Equal(t, "maker", approval.RequestedBy)
+
+	updatedWallet, err := manager.GetWallet(context.Background(), wallet.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1000), updatedWallet.Balance)
+
+	pending, err := approvals.ListPendingApprovals(context.Background(), 10, 0)
+	assert.NoError(t, err)
+	assert.Len(t, pending, 1)
+
+	// The requester cannot approve their own request
+	_, err = approvals.Approve(makerCtx, approval.ID, "")
+	assert.ErrorIs(t, err, ErrSelfApproval)
+
+	_, err = approvals.Approve(context.Background(), approval.ID, "")
+	assert.ErrorIs(t, err, ErrUnauthenticated)
+
+	// Another principal approves and the credit is applied
+	approval, err = approvals.Approve(checkerCtx, approval.ID, "Verified with finance")
+	assert.NoError(t, err)
+	assert.Equal(t, ApprovalStatusApproved, approval.Status)
+	assert.Equal(t, "checker", approval.DecidedBy)
+	require.NotEmpty(t, approval.TransactionID)
+
+	transaction, err = manager.GetTransaction(context.Background(), approval.TransactionID)
+	assert.NoError(t, err)
+	require.NotNil(t, transaction)
+	assert.Equal(t, int64(5000), transaction.Amount)
+	assert.Equal(t, "bonus-1", transaction.Reference)
+
+	updatedWallet, err = manager.GetWallet(context.Background(), wallet.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(6000), updatedWallet.Balance)
+
+	// Decided approvals cannot be decided again
+	_, err = approvals.Approve(checkerCtx, approval.ID, "")
+	assert.ErrorIs(t, err, ErrApprovalNotPending)
+
+	_, err = approvals.Reject(checkerCtx, approval.ID, "")
+	assert.ErrorIs(t, err, ErrApprovalNotPending)
+
+	// The audit trail records every step
+	events, err := approvals.ListApprovalEvents(context.Background(), approval.ID)
+	assert.NoError(t, err)
+	require.Len(t, events, 3)
+	assert.Equal(t, ApprovalEventRequested, events[0].Type)
+	assert.Equal(t, "maker", events[0].PrincipalID)
+	assert.Equal(t, ApprovalEventApproved, events[1].Type)
+	assert.Equal(t, "checker", events[1].PrincipalID)
+	assert.Equal(t, "Verified with finance", events[1].Comment)
+	assert.Equal(t, ApprovalEventExecuted, events[2].Type)
+}
+
+// TestApprovalReject tests that rejected requests are never executed
+func TestApprovalReject(t *testing.T) {
+	manager, approvals, wallet := setupTestApprovals(t)
+	makerCtx := WithPrincipal(context.Background(), &Principal{UserID: "maker", Roles: []string{RoleAdmin}})
+	checkerCtx := WithPrincipal(context.Background(), &Principal{UserID: "checker", Roles: []string{RoleAdmin}})
+
+	_, approval, err := approvals.Credit(makerCtx, wallet.ID, 5000, "Large bonus", "", "", nil)
+	require.NoError(t, err)
+
+	approval, err = approvals.Reject(checkerCtx, approval.ID, "Not budgeted")
+	assert.NoError(t, err)
+	assert.Equal(t, ApprovalStatusRejected, approval.Status)
+
+	_, err = approvals.Approve(checkerCtx, approval.ID, "")
+	assert.ErrorIs(t, err, ErrApprovalNotPending)
+
+	updatedWallet, err := manager.GetWallet(context.Background(), wallet.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), updatedWallet.Balance)
+
+	events, err := approvals.ListApprovalEvents(context.Background(), approval.ID)
+	assert.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, ApprovalEventRejected, events[1].Type)
+	assert.Equal(t, "Not budgeted", events[1].Comment)
+
+	_, err = approvals.Reject(checkerCtx, "non-existent-id", "")
+	assert.ErrorIs(t, err, ErrApprovalNotFound)
+}
+
+// TestApprovalRiskFlaggedWallet tests approvals for unfreezing and clearing the risk flag of a wallet
+func TestApprovalRiskFlaggedWallet(t *testing.T) {
+	manager, approvals, wallet := setupTestApprovals(t)
+	ctx := context.Background()
+	makerCtx := WithPrincipal(ctx, &Principal{UserID: "maker", Roles: []string{RoleAdmin}})
+	checkerCtx := WithPrincipal(ctx, &Principal{UserID: "checker", Roles: []string{RoleAdmin}})
+
+	// Wallets without a risk flag are unfrozen directly
+	require.NoError(t, manager.FreezeWallet(ctx, wallet.ID, "Lost card"))
+	approval, err := approvals.UnfreezeWallet(makerCtx, wallet.ID, "Card found")
+	assert.NoError(t, err)
+	assert.Nil(t, approval)
+
+	updatedWallet, err := manager.GetWallet(ctx, wallet.ID)
+	require.NoError(t, err)
+	assert.False(t, updatedWallet.Frozen)
+
+	// Unfreezing a risk-flagged wallet requires approval
+	require.NoError(t, manager.FreezeWallet(ctx, wallet.ID, "Suspicious activity"))
+	require.NoError(t, manager.FlagWalletRisk(ctx, wallet.ID, "Suspicious activity"))
+
+	unfreeze, err := approvals.UnfreezeWallet(makerCtx, wallet.ID, "Customer verified")
+	assert.NoError(t, err)
+	require.NotNil(t, unfreeze)
+	assert.Equal(t, ApprovalOperationUnfreezeWallet, unfreeze.Operation)
+
+	clear, err := approvals.ClearWalletRiskFlag(makerCtx, wallet.ID, "Customer verified")
+	assert.NoError(t, err)
+	require.NotNil(t, clear)
+
+	updatedWallet, err = manager.GetWallet(ctx, wallet.ID)
+	require.NoError(t, err)
+	assert.True(t, updatedWallet.Frozen)
+	assert.True(t, updatedWallet.RiskFlagged)
+
+	_, err = approvals.Approve(checkerCtx, unfreeze.ID, "")
+	assert.NoError(t, err)
+	_, err = approvals.Approve(checkerCtx, clear.ID, "")
+	assert.NoError(t, err)
+
+	updatedWallet, err = manager.GetWallet(ctx, wallet.ID)
+	require.NoError(t, err)
+	assert.False(t, updatedWallet.Frozen)
+	assert.False(t, updatedWallet.RiskFlagged)
+
+	_, err = approvals.ClearWalletRiskFlag(makerCtx, "non-existent-id", "")
+	assert.ErrorIs(t, err, ErrWalletNotFound)
+}
+
+// TestApprovalFailedExecution tests that an approved operation that fails is recorded as failed
+func TestApprovalFailedExecution(t *testing.T) {
+	manager, approvals, wallet := setupTestApprovals(t)
+	makerCtx := WithPrincipal(context.Background(), &Principal{UserID: "maker", Roles: []string{RoleAdmin}})
+	checkerCtx := WithPrincipal(context.Background(), &Principal{UserID: "checker", Roles: []string{RoleAdmin}})
+
+	_, approval, err := approvals.Credit(makerCtx, wallet.ID, 5000, "Large bonus", "", "", nil)
+	require.NoError(t, err)
+
+	require.NoError(t, manager.FreezeWallet(context.Background(), wallet.ID, "Investigation"))
+
+	approval, err = approvals.Approve(checkerCtx, approval.ID, "")
+	assert.NoError(t, err)
+	assert.Equal(t, ApprovalStatusFailed, approval.Status)
+	assert.Equal(t, "wallet "+wallet.ID+": wallet is frozen", approval.LastError)
+
+	events, err := approvals.ListApprovalEvents(context.Background(), approval.ID)
+	assert.NoError(t, err)
+	require.Len(t, events, 3)
+	assert.Equal(t, ApprovalEventFailed, events[2].Type)
+}
+
+// TestApprovalRiskRules tests that approved operations are evaluated by the risk rules
+func TestApprovalRiskRules(t *testing.T) {
+	manager := NewWalletManager(
+		WithStore(setupTestGormWalletStore(t)),
+		WithRiskRules(UnusualAmountRule{Type: TransactionTypeCredit, Threshold: 5000}),
+	)
+	approvals := NewApprovalManager(manager, setupTestGormApprovalStore(t), WithCreditApprovalThreshold(1000))
+	makerCtx := WithPrincipal(context.Background(), &Principal{UserID: "maker", Roles: []string{RoleAdmin}})
+	checkerCtx := WithPrincipal(context.Background(), &Principal{UserID: "checker", Roles: []string{RoleAdmin}})
+
+	wallet, err := manager.CreateWallet(context.Background(), "test-user", "Test Wallet", "", "")
+	require.NoError(t, err)
+
+	_, approval, err := approvals.Credit(makerCtx, wallet.ID, 5000, "Large bonus", "", "", nil)
+	require.NoError(t, err)
+
+	approval, err = approvals.Approve(checkerCtx, approval.ID, "")
+	require.NoError(t, err)
+	assert.Equal(t, ApprovalStatusApproved, approval.Status)
+
+	updatedWallet, err := manager.GetWallet(context.Background(), wallet.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(5000), updatedWallet.Balance)
+	assert.True(t, updatedWallet.RiskFlagged)
+}
+
+// staleApprovalStore returns approvals as they were when first read, like a decision racing another one
+type staleApprovalStore struct {
+	ApprovalStore
+	read map[string]Approval
+}
+
+// FindApproval returns the first read copy of an approval
+func (s *staleApprovalStore) FindApproval(ctx context.Context, approvalID string) (*Approval, error) {
+	if approval, ok := s.read[approvalID]; ok {
+		return &approval, nil
+	}
+
+	approval, err := s.ApprovalStore.FindApproval(ctx, approvalID)
+	if err != nil || approval == nil {
+		return approval, err
+	}
+	s.read[approvalID] = *approval
+	return approval, nil
+}
+
+// TestApprovalConcurrentDecisions tests that a decision based on a stale pending approval is refused
+func TestApprovalConcurrentDecisions(t *testing.T) {
+	manager := NewWalletManager(WithStore(setupTestGormWalletStore(t)))
+	store := setupTestGormApprovalStore(t)
+	approvals := NewApprovalManager(manager, store, WithCreditApprovalThreshold(1000))
+	makerCtx := WithPrincipal(context.Background(), &Principal{UserID: "maker", Roles: []string{RoleAdmin}})
+	checkerCtx := WithPrincipal(context.Background(), &Principal{UserID: "checker", Roles: []string{RoleAdmin}})
+
+	wallet, err := manager.CreateWallet(context.Background(), "test-user", "Test Wallet", "", "")
+	require.NoError(t, err)
+	_, approval, err := approvals.Credit(makerCtx, wallet.ID, 5000, "Large bonus", "", "", nil)
+	require.NoError(t, err)
+
+	stale := NewApprovalManager(manager, &staleApprovalStore{ApprovalStore: store, read: make(map[string]Approval)})
+	_, err = stale.GetApproval(context.Background(), approval.ID)
+	require.NoError(t, err)
+
+	_, err = approvals.Approve(checkerCtx, approval.ID, "")
+	require.NoError(t, err)
+
+	// The stale copy is still pending, but the decision is refused by the store
+	_, err = stale.Approve(checkerCtx, approval.ID, "")
+	assert.ErrorIs(t, err, ErrApprovalNotPending)
+	_, err = stale.Reject(checkerCtx, approval.ID, "")
+	assert.ErrorIs(t, err, ErrApprovalNotPending)
+
+	// The operation was executed and recorded once
+	events, err := approvals.ListApprovalEvents(context.Background(), approval.ID)
+	require.NoError(t, err)
+	assert.Len(t, events, 3)
+
+	updatedWallet, err := manager.GetWallet(context.Background(), wallet.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(5000), updatedWallet.Balance)
+}
+
+// TestApprovalAuthorization tests that requests and decisions are authorized against the policy
+func TestApprovalAuthorization(t *testing.T) {
+	manager, approvals, wallet := setupTestApprovals(t)
+	ctx := context.Background()
+	ownerCtx := WithPrincipal(ctx, &Principal{UserID: wallet.UserID})
+	userCtx := WithPrincipal(ctx, &Principal{UserID: "other-user"})
+	adminCtx := WithPrincipal(ctx, &Principal{UserID: "admin", Roles: []string{RoleAdmin}})
+
+	require.NoError(t, manager.FreezeWallet(ctx, wallet.ID, "Suspicious activity"))
+	require.NoError(t, manager.FlagWalletRisk(ctx, wallet.ID, "Suspicious activity"))
+
+	// Only admins may request unfreezing a wallet or clearing its risk flag
+	_, err := approvals.UnfreezeWallet(ownerCtx, wallet.ID, "")
+	assert.ErrorIs(t, err, ErrPermissionDenied)
+	_, err = approvals.ClearWalletRiskFlag(userCtx, wallet.ID, "")
+	assert.ErrorIs(t, err, ErrPermissionDenied)
+	_, _, err = approvals.Credit(ownerCtx, wallet.ID, 5000, "Large bonus", "", "", nil)
+	assert.ErrorIs(t, err, ErrPermissionDenied)
+	_, err = approvals.ClearWalletRiskFlag(ctx, wallet.ID, "")
+	assert.ErrorIs(t, err, ErrUnauthenticated)
+
+	pending, err := approvals.ListPendingApprovals(ctx, 10, 0)
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+
+	// Only admins may decide approvals
+	approval, err := approvals.ClearWalletRiskFlag(adminCtx, wallet.ID, "Customer verified")
+	require.NoError(t, err)
+
+	_, err = approvals.Approve(ownerCtx, approval.ID, "")
+	assert.ErrorIs(t, err, ErrPermissionDenied)
+	_, err = approvals.Reject(userCtx, approval.ID, "")
+	assert.ErrorIs(t, err, ErrPermissionDenied)
+
+	updatedWallet, err := manager.GetWallet(ctx, wallet.ID)
+	require.NoError(t, err)
+	assert.True(t, updatedWallet.RiskFlagged)
+
+	// A custom policy can allow others to decide
+	approvals = NewApprovalManager(manager, approvals.store, WithApprovalPolicy(PolicyFunc(func(ctx context.Context, request *AuthorizationRequest) error {
+		if request.Principal == nil {
+			return ErrUnauthenticated
+		}
+		return nil
+	})))
+	approval, err = approvals.Approve(userCtx, approval.ID, "")
+	require.NoError(t, err)
+	assert.Equal(t, ApprovalStatusApproved, approval.Status)
+}
+
+// TestApprovalRecover tests that approvals left executing are executed once when recovered
+func TestApprovalRecover(t *testing.T) {
+	manager, approvals, wallet := setupTestApprovals(t)
+	ctx := context.Background()
+	makerCtx := WithPrincipal(ctx, &Principal{UserID: "maker", Roles: []string{RoleAdmin}})
+	checkerCtx := WithPrincipal(ctx, &Principal{UserID: "checker", Roles: []string{RoleAdmin}})
+
+	_, approval, err := approvals.Credit(makerCtx, wallet.ID, 5000, "Large bonus", "", "", nil)
+	require.NoError(t, err)
+
+	// Pending approvals cannot be recovered
+	_, err = approvals.RecoverApproval(checkerCtx, approval.ID)
+	assert.ErrorIs(t, err, ErrApprovalNotExecuting)
+
+	// The approving process stops after applying the credit, before recording the outcome
+	approval.DecidedBy = "checker"
+	require.NoError(t, approvals.claim(ctx, approval, ApprovalStatusExecuting))
+	_, err = approvals.apply(ctx, approval)
+	require.NoError(t, err)
+
+	executing, err := approvals.ListExecutingApprovals(ctx, 10, 0)
+	require.NoError(t, err)
+	require.Len(t, executing, 1)
+
+	_, err = approvals.RecoverApproval(ctx, approval.ID)
+	assert.ErrorIs(t, err, ErrUnauthenticated)
+
+	approval, err = approvals.RecoverApproval(checkerCtx, approval.ID)
+	require.NoError(t, err)
+	assert.Equal(t, ApprovalStatusApproved, approval.Status)
+	assert.Equal(t, approvedTransactionID(approval.ID, TransactionTypeCredit), approval.TransactionID)
+
+	// The credit was applied only once
+	updatedWallet, err := manager.GetWallet(ctx, wallet.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(5000), updatedWallet.Balance)
+
+	_, err = approvals.RecoverApproval(checkerCtx, approval.ID)
+	assert.ErrorIs(t, err, ErrApprovalNotExecuting)
+
+	events, err := approvals.ListApprovalEvents(ctx, approval.ID)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, ApprovalEventExecuted, events[1].Type)
+}
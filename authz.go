@@ -0,0 +1,529 @@
+package wallethub
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// Authorization error definitions
+var (
+	ErrUnauthenticated  = errors.New("no principal in context")
+	ErrPermissionDenied = errors.New("permission denied")
+)
+
+// RoleAdmin is the role allowed every action by DefaultPolicy
+const RoleAdmin = "admin"
+
+// Principal identifies the caller of a wallet operation
+type Principal struct {
+	UserID string   `json:"user_id"`
+	Roles  []string `json:"roles"`
+}
+
+// HasRole reports whether the principal has the given role
+func (p *Principal) HasRole(role string) bool {
+	return slices.Contains(p.Roles, role)
+}
+
+// principalContextKey is the context key under which the principal is stored
+type principalContextKey struct{}
+
+// WithPrincipal returns a context carrying the caller of wallet operations
+func WithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, principalContextKey{}, principal)
+}
+
+// PrincipalFromContext returns the principal carried by a context, or nil if there is none
+func PrincipalFromContext(ctx context.Context) *Principal {
+	principal, _ := ctx.Value(principalContextKey{}).(*Principal)
+	return principal
+}
+
+// Action identifies a wallet operation subject to authorization
+type Action string
+
+const (
+	ActionCreateWallet        Action = "create_wallet"
+	ActionReadWallet          Action = "read_wallet"
+	ActionUpdateWallet        Action = "update_wallet"
+	ActionUpdateWalletActive  Action = "update_wallet_active"
+	ActionCredit              Action = "credit"
+	ActionDebit               Action = "debit"
+	ActionTransfer            Action = "transfer"
+	ActionReadTransactions    Action = "read_transactions"
+	ActionCancelTransaction   Action = "cancel_transaction"
+	ActionCompleteTransaction Action = "complete_transaction"
+	ActionFreezeWallet        Action = "freeze_wallet"
+	ActionUnfreezeWallet      Action = "unfreeze_wallet"
+	ActionFlagWalletRisk      Action = "flag_wallet_risk"
+	ActionClearWalletRiskFlag Action = "clear_wallet_risk_flag"
+	ActionGrantAllowance      Action = "grant_allowance"
+	ActionRevokeAllowance     Action = "revoke_allowance"
+	ActionReadAllowances      Action = "read_allowances"
+	ActionDebitAsDelegate     Action = "debit_as_delegate"
+	ActionTransferFrom        Action = "transfer_from"
+	ActionDecideApproval      Action = "decide_approval" // Approving, rejecting or recovering an approval
+)
+
+// AuthorizationRequest describes an operation to be authorized
+type AuthorizationRequest struct {
+	Principal  *Principal // Nil if the context carries no principal
+	Action     Action
+	OwnerID    string // User owning the wallets acted on, empty if the wallet does not exist
+	WalletID   string // Wallet acted on, empty for operations on all wallets of a user
+	DelegateID string // Delegate spending from or reading its allowance on the wallet, if any
+	Amount     int64  // Amount of credits, debits and transfers
+}
+
+// Policy decides whether an operation is allowed. It returns nil to allow the operation, or an error
+// to deny it, preferably one wrapping ErrUnauthenticated or ErrPermissionDenied.
+type Policy interface {
+	Authorize(ctx context.Context, request *AuthorizationRequest) error
+}
+
+// PolicyFunc adapts a function to the Policy interface
+type PolicyFunc func(ctx context.Context, request *AuthorizationRequest) error
+
+// Authorize calls f(ctx, request)
+func (f PolicyFunc) Authorize(ctx context.Context, request *AuthorizationRequest) error {
+	return f(ctx, request)
+}
+
+// AllPolicies returns a policy allowing an operation only if every given policy allows it
+func AllPolicies(policies ...Policy) Policy {
+	return PolicyFunc(func(ctx context.Context, request *AuthorizationRequest) error {
+		for _, policy := range policies {
+			if err := policy.Authorize(ctx, request); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// DenialError is returned when a policy denies an operation
+type DenialError struct {
+	UserID string
+	Action Action
+	Reason string
+}
+
+// Error implements the error interface
+func (e *DenialError) Error() string {
+	return fmt.Sprintf("permission denied: user %q may not %s: %s", e.UserID, e.Action, e.Reason)
+}
+
+// Unwrap makes errors.Is(err, ErrPermissionDenied) hold for denials
+func (e *DenialError) Unwrap() error {
+	return ErrPermissionDenied
+}
+
+// adminActions are the actions DefaultPolicy reserves for admins
+var adminActions = []Action{
+	ActionCredit,
+	ActionUpdateWalletActive,
+	ActionCancelTransaction,
+	ActionCompleteTransaction,
+	ActionFreezeWallet,
+	ActionUnfreezeWallet,
+	ActionFlagWalletRisk,
+	ActionClearWalletRiskFlag,
+	ActionDecideApproval,
+}
+
+// delegateActions are the actions DefaultPolicy allows only the delegate of an allowance
+var delegateActions = []Action{
+	ActionDebitAsDelegate,
+	ActionTransferFrom,
+}
+
+// DefaultPolicy allows admins everything. Other users may read, update, debit and transfer from their
+// own wallets only, while credits, activation, transaction lifecycle changes, freezing, risk
+// flagging and deciding approvals are reserved for admins. Only delegates may spend under their allowance on a wallet, which
+// they and the owner may read.
+type DefaultPolicy struct{}
+
+// Authorize implements the Policy interface
+func (DefaultPolicy) Authorize(ctx context.Context, request *AuthorizationRequest) error {
+	principal := request.Principal
+	if principal == nil {
+		return ErrUnauthenticated
+	}
+	if principal.HasRole(RoleAdmin) {
+		return nil
+	}
+
+	if slices.Contains(adminActions, request.Action) {
+		return &DenialError{UserID: principal.UserID, Action: request.Action, Reason: "admin role required"}
+	}
+	if slices.Contains(delegateActions, request.Action) {
+		if request.DelegateID == "" || request.DelegateID != principal.UserID {
+			return &DenialError{UserID: principal.UserID, Action: request.Action, Reason: "not the delegate"}
+		}
+		return nil
+	}
+	if request.Action == ActionReadAllowances && request.DelegateID != "" && request.DelegateID == principal.UserID {
+		return nil
+	}
+	if request.OwnerID == "" || request.OwnerID != principal.UserID {
+		return &DenialError{UserID: principal.UserID, Action: request.Action, Reason: "not the wallet owner"}
+	}
+	return nil
+}
+
+// AuthorizingWalletManager is a WalletManager that authorizes every operation against a policy before
+// passing it on to the wrapped manager. The caller is read from the context with PrincipalFromContext.
+type AuthorizingWalletManager struct {
+	next   WalletManager
+	policy Policy
+}
+
+// NewAuthorizingWalletManager wraps a wallet manager with authorization. A nil policy uses DefaultPolicy.
+func NewAuthorizingWalletManager(next WalletManager, policy Policy) *AuthorizingWalletManager {
+	if policy == nil {
+		policy = DefaultPolicy{}
+	}
+
+	return &AuthorizingWalletManager{
+		next:   next,
+		policy: policy,
+	}
+}
+
+// authorize checks an operation on the wallets of a user
+func (m *AuthorizingWalletManager) authorize(ctx context.Context, action Action, ownerID string, walletID string, amount int64) error {
+	return m.policy.Authorize(ctx, &AuthorizationRequest{
+		Principal: PrincipalFromContext(ctx),
+		Action:    action,
+		OwnerID:   ownerID,
+		WalletID:  walletID,
+		Amount:    amount,
+	})
+}
+
+// authorizeWallet checks an operation on a wallet, looking up its owner
+func (m *AuthorizingWalletManager) authorizeWallet(ctx context.Context, action Action, walletID string, amount int64) error {
+	wallet, err := m.next.GetWallet(ctx, walletID)
+	if err != nil {
+		return err
+	}
+
+	ownerID := ""
+	if wallet != nil {
+		ownerID = wallet.UserID
+	}
+	return m.authorize(ctx, action, ownerID, walletID, amount)
+}
+
+// authorizeDelegate checks an operation of a delegate on a wallet, looking up its owner
+func (m *AuthorizingWalletManager) authorizeDelegate(ctx context.Context, action Action, walletID string, delegateID string, amount int64) error {
+	wallet, err := m.next.GetWallet(ctx, walletID)
+	if err != nil {
+		return err
+	}
+
+	ownerID := ""
+	if wallet != nil {
+		ownerID = wallet.UserID
+	}
+	return m.policy.Authorize(ctx, &AuthorizationRequest{
+		Principal:  PrincipalFromContext(ctx),
+		Action:     action,
+		OwnerID:    ownerID,
+		WalletID:   walletID,
+		DelegateID: delegateID,
+		Amount:     amount,
+	})
+}
+
+// authorizeTransaction checks an operation on a transaction, looking up the owner of its wallet
+func (m *AuthorizingWalletManager) authorizeTransaction(ctx context.Context, action Action, transactionID string) error {
+	transaction, err := m.next.GetTransaction(ctx, transactionID)
+	if err != nil {
+		return err
+	}
+
+	walletID := ""
+	if transaction != nil {
+		walletID = transaction.WalletID
+	}
+	return m.authorizeWallet(ctx, action, walletID, 0)
+}
+
+// CreateWallet creates a new wallet for a user
+func (m *AuthorizingWalletManager) CreateWallet(ctx context.Context, userID string, name string, description string, reference string) (*Wallet, error) {
+	if err := m.authorize(ctx, ActionCreateWallet, userID, "", 0); err != nil {
+		return nil, err
+	}
+	return m.next.CreateWallet(ctx, userID, name, description, reference)
+}
+
+// GetWallet gets a wallet by ID. Missing wallets are authorized like wallets without an owner, so
+// their absence is only revealed to principals the policy allows to read any wallet.
+func (m *AuthorizingWalletManager) GetWallet(ctx context.Context, walletID string) (*Wallet, error) {
+	wallet, err := m.next.GetWallet(ctx, walletID)
+	if err != nil {
+		return nil, err
+	}
+
+	ownerID := ""
+	if wallet != nil {
+		ownerID = wallet.UserID
+	}
+	if err := m.authorize(ctx, ActionReadWallet, ownerID, walletID, 0); err != nil {
+		return nil, err
+	}
+	return wallet, nil
+}
+
+// GetWalletsByUserID gets all wallets for a user
+func (m *AuthorizingWalletManager) GetWalletsByUserID(ctx context.Context, userID string) ([]Wallet, error) {
+	if err := m.authorize(ctx, ActionReadWallet, userID, "", 0); err != nil {
+		return nil, err
+	}
+	return m.next.GetWalletsByUserID(ctx, userID)
+}
+
+// GetWalletByUserIDAndReference gets a wallet by user ID and reference
+func (m *AuthorizingWalletManager) GetWalletByUserIDAndReference(ctx context.Context, userID string, reference string) (*Wallet, error) {
+	if err := m.authorize(ctx, ActionReadWallet, userID, "", 0); err != nil {
+		return nil, err
+	}
+	return m.next.GetWalletByUserIDAndReference(ctx, userID, reference)
+}
+
+// GetPrimaryWallet gets the primary wallet for a user
+func (m *AuthorizingWalletManager) GetPrimaryWallet(ctx context.Context, userID string) (*Wallet, error) {
+	if err := m.authorize(ctx, ActionReadWallet, userID, "", 0); err != nil {
+		return nil, err
+	}
+	return m.next.GetPrimaryWallet(ctx, userID)
+}
+
+// SetPrimaryWallet sets a wallet as the primary wallet for its user
+func (m *AuthorizingWalletManager) SetPrimaryWallet(ctx context.Context, walletID string) error {
+	if err := m.authorizeWallet(ctx, ActionUpdateWallet, walletID, 0); err != nil {
+		return err
+	}
+	return m.next.SetPrimaryWallet(ctx, walletID)
+}
+
+// UpdateWalletActive updates the active status of a wallet
+func (m *AuthorizingWalletManager) UpdateWalletActive(ctx context.Context, walletID string, active bool) error {
+	if err := m.authorizeWallet(ctx, ActionUpdateWalletActive, walletID, 0); err != nil {
+		return err
+	}
+	return m.next.UpdateWalletActive(ctx, walletID, active)
+}
+
+// UpdateWalletName updates the name of a wallet
+func (m *AuthorizingWalletManager) UpdateWalletName(ctx context.Context, walletID string, name string) error {
+	if err := m.authorizeWallet(ctx, ActionUpdateWallet, walletID, 0); err != nil {
+		return err
+	}
+	return m.next.UpdateWalletName(ctx, walletID, name)
+}
+
+// UpdateWalletDescription updates the description of a wallet
+func (m *AuthorizingWalletManager) UpdateWalletDescription(ctx context.Context, walletID string, description string) error {
+	if err := m.authorizeWallet(ctx, ActionUpdateWallet, walletID, 0); err != nil {
+		return err
+	}
+	return m.next.UpdateWalletDescription(ctx, walletID, description)
+}
+
+// UpdateWalletReference updates the reference of a wallet
+func (m *AuthorizingWalletManager) UpdateWalletReference(ctx context.Context, walletID string, reference string) error {
+	if err := m.authorizeWallet(ctx, ActionUpdateWallet, walletID, 0); err != nil {
+		return err
+	}
+	return m.next.UpdateWalletReference(ctx, walletID, reference)
+}
+
+// Credit adds points to a wallet
+func (m *AuthorizingWalletManager) Credit(ctx context.Context, walletID string, amount int64, description string, note string, reference string, data map[string]interface{}) (*Transaction, error) {
+	if err := m.authorizeWallet(ctx, ActionCredit, walletID, amount); err != nil {
+		return nil, err
+	}
+	return m.next.Credit(ctx, walletID, amount, description, note, reference, data)
+}
+
+// CreditBucket adds points to a balance bucket of a wallet
+func (m *AuthorizingWalletManager) CreditBucket(ctx context.Context, walletID string, bucket string, amount int64, description string, note string, reference string, data map[string]interface{}) (*Transaction, error) {
+	if err := m.authorizeWallet(ctx, ActionCredit, walletID, amount); err != nil {
+		return nil, err
+	}
+	return m.next.CreditBucket(ctx, walletID, bucket, amount, description, note, reference, data)
+}
+
+// Debit subtracts points from a wallet
+func (m *AuthorizingWalletManager) Debit(ctx context.Context, walletID string, amount int64, description string, note string, reference string, data map[string]interface{}) (*Transaction, error) {
+	if err := m.authorizeWallet(ctx, ActionDebit, walletID, amount); err != nil {
+		return nil, err
+	}
+	return m.next.Debit(ctx, walletID, amount, description, note, reference, data)
+}
+
+// GetTransaction gets a transaction by ID
+func (m *AuthorizingWalletManager) GetTransaction(ctx context.Context, transactionID string) (*Transaction, error) {
+	transaction, err := m.next.GetTransaction(ctx, transactionID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Authorize unknown transactions too, so only principals allowed to read them learn they do not exist
+	walletID := ""
+	if transaction != nil {
+		walletID = transaction.WalletID
+	}
+	if err := m.authorizeWallet(ctx, ActionReadTransactions, walletID, 0); err != nil {
+		return nil, err
+	}
+	return transaction, nil
+}
+
+// ListTransactions lists the transactions of a wallet
+func (m *AuthorizingWalletManager) ListTransactions(ctx context.Context, walletID string, limit int, offset int) ([]Transaction, error) {
+	if err := m.authorizeWallet(ctx, ActionReadTransactions, walletID, 0); err != nil {
+		return nil, err
+	}
+	return m.next.ListTransactions(ctx, walletID, limit, offset)
+}
+
+// ListUserTransactions lists the transactions of all wallets of a user
+func (m *AuthorizingWalletManager) ListUserTransactions(ctx context.Context, userID string, limit int, offset int) ([]Transaction, error) {
+	if err := m.authorize(ctx, ActionReadTransactions, userID, "", 0); err != nil {
+		return nil, err
+	}
+	return m.next.ListUserTransactions(ctx, userID, limit, offset)
+}
+
+// Transfer transfers points from one wallet to another. Only the source wallet is authorized.
+func (m *AuthorizingWalletManager) Transfer(ctx context.Context, fromWalletID string, toWalletID string, amount int64, description string, note string, data map[string]interface{}) error {
+	if err := m.authorizeWallet(ctx, ActionTransfer, fromWalletID, amount); err != nil {
+		return err
+	}
+	return m.next.Transfer(ctx, fromWalletID, toWalletID, amount, description, note, data)
+}
+
+// FreezeWallet freezes a wallet
+func (m *AuthorizingWalletManager) FreezeWallet(ctx context.Context, walletID string, reason string) error {
+	if err := m.authorizeWallet(ctx, ActionFreezeWallet, walletID, 0); err != nil {
+		return err
+	}
+	return m.next.FreezeWallet(ctx, walletID, reason)
+}
+
+// FreezeWalletWithMode freezes a wallet in the given mode
+func (m *AuthorizingWalletManager) FreezeWalletWithMode(ctx context.Context, walletID string, mode FreezeMode, reason string) error {
+	if err := m.authorizeWallet(ctx, ActionFreezeWallet, walletID, 0); err != nil {
+		return err
+	}
+	return m.next.FreezeWalletWithMode(ctx, walletID, mode, reason)
+}
+
+// UnfreezeWallet unfreezes a wallet
+func (m *AuthorizingWalletManager) UnfreezeWallet(ctx context.Context, walletID string) error {
+	if err := m.authorizeWallet(ctx, ActionUnfreezeWallet, walletID, 0); err != nil {
+		return err
+	}
+	return m.next.UnfreezeWallet(ctx, walletID)
+}
+
+// SetFrozenAmount freezes part of a wallet's balance
+func (m *AuthorizingWalletManager) SetFrozenAmount(ctx context.Context, walletID string, amount int64, reason string) error {
+	if err := m.authorizeWallet(ctx, ActionFreezeWallet, walletID, amount); err != nil {
+		return err
+	}
+	return m.next.SetFrozenAmount(ctx, walletID, amount, reason)
+}
+
+// CancelTransaction cancels a pending transaction
+func (m *AuthorizingWalletManager) CancelTransaction(ctx context.Context, transactionID string, reason string) error {
+	if err := m.authorizeTransaction(ctx, ActionCancelTransaction, transactionID); err != nil {
+		return err
+	}
+	return m.next.CancelTransaction(ctx, transactionID, reason)
+}
+
+// CompleteTransaction completes a pending transaction
+func (m *AuthorizingWalletManager) CompleteTransaction(ctx context.Context, transactionID string) error {
+	if err := m.authorizeTransaction(ctx, ActionCompleteTransaction, transactionID); err != nil {
+		return err
+	}
+	return m.next.CompleteTransaction(ctx, transactionID)
+}
+
+// GetUserWalletSummary returns the total balance of all wallets of a user
+func (m *AuthorizingWalletManager) GetUserWalletSummary(ctx context.Context, userID string) (int64, error) {
+	if err := m.authorize(ctx, ActionReadWallet, userID, "", 0); err != nil {
+		return 0, err
+	}
+	return m.next.GetUserWalletSummary(ctx, userID)
+}
+
+// FlagWalletRisk flags a wallet for risk control
+func (m *AuthorizingWalletManager) FlagWalletRisk(ctx context.Context, walletID string, reason string) error {
+	if err := m.authorizeWallet(ctx, ActionFlagWalletRisk, walletID, 0); err != nil {
+		return err
+	}
+	return m.next.FlagWalletRisk(ctx, walletID, reason)
+}
+
+// ClearWalletRiskFlag clears the risk flag of a wallet
+func (m *AuthorizingWalletManager) ClearWalletRiskFlag(ctx context.Context, walletID string) error {
+	if err := m.authorizeWallet(ctx, ActionClearWalletRiskFlag, walletID, 0); err != nil {
+		return err
+	}
+	return m.next.ClearWalletRiskFlag(ctx, walletID)
+}
+
+// GrantAllowance lets a delegate spend up to an amount from a wallet
+func (m *AuthorizingWalletManager) GrantAllowance(ctx context.Context, walletID string, delegateID string, amount int64) (*Allowance, error) {
+	if err := m.authorizeWallet(ctx, ActionGrantAllowance, walletID, amount); err != nil {
+		return nil, err
+	}
+	return m.next.GrantAllowance(ctx, walletID, delegateID, amount)
+}
+
+// RevokeAllowance withdraws the allowance of a delegate on a wallet
+func (m *AuthorizingWalletManager) RevokeAllowance(ctx context.Context, walletID string, delegateID string) error {
+	if err := m.authorizeWallet(ctx, ActionRevokeAllowance, walletID, 0); err != nil {
+		return err
+	}
+	return m.next.RevokeAllowance(ctx, walletID, delegateID)
+}
+
+// GetAllowance gets the allowance of a delegate on a wallet, which the owner and the delegate may read
+func (m *AuthorizingWalletManager) GetAllowance(ctx context.Context, walletID string, delegateID string) (*Allowance, error) {
+	if err := m.authorizeDelegate(ctx, ActionReadAllowances, walletID, delegateID, 0); err != nil {
+		return nil, err
+	}
+	return m.next.GetAllowance(ctx, walletID, delegateID)
+}
+
+// ListAllowances lists the allowances on a wallet
+func (m *AuthorizingWalletManager) ListAllowances(ctx context.Context, walletID string) ([]Allowance, error) {
+	if err := m.authorizeWallet(ctx, ActionReadAllowances, walletID, 0); err != nil {
+		return nil, err
+	}
+	return m.next.ListAllowances(ctx, walletID)
+}
+
+// DebitAsDelegate removes points from a wallet on behalf of a delegate
+func (m *AuthorizingWalletManager) DebitAsDelegate(ctx context.Context, walletID string, delegateID string, amount int64, description string, note string, reference string, data map[string]interface{}) (*Transaction, error) {
+	if err := m.authorizeDelegate(ctx, ActionDebitAsDelegate, walletID, delegateID, amount); err != nil {
+		return nil, err
+	}
+	return m.next.DebitAsDelegate(ctx, walletID, delegateID, amount, description, note, reference, data)
+}
+
+// TransferFrom transfers points from a wallet to another on behalf of a delegate
+func (m *AuthorizingWalletManager) TransferFrom(ctx context.Context, fromWalletID string, toWalletID string, delegateID string, amount int64, description string, note string, data map[string]interface{}) error {
+	if err := m.authorizeDelegate(ctx, ActionTransferFrom, fromWalletID, delegateID, amount); err != nil {
+		return err
+	}
+	return m.next.TransferFrom(ctx, fromWalletID, toWalletID, delegateID, amount, description, note, data)
+}
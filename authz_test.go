@@ -0,0 +1,169 @@
+package wallethub
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupTestAuthorization creates an authorizing manager with a funded wallet for each of two users
+func setupTestAuthorization(t *testing.T, policy Policy) (WalletManager, *Wallet, *Wallet) {
+	manager := NewWalletManager(WithStore(setupTestGormWalletStore(t)))
+	ctx := context.Background()
+
+	wallet1, err := manager.CreateWallet(ctx, "user-1", "Wallet 1", "", "")
+	require.NoError(t, err)
+	wallet2, err := manager.CreateWallet(ctx, "user-2", "Wallet 2", "", "")
+	require.NoError(t, err)
+
+	_, err = manager.Credit(ctx, wallet1.ID, 1000, "Deposit", "", "", nil)
+	require.NoError(t, err)
+	_, err = manager.Credit(ctx, wallet2.ID, 1000, "Deposit", "", "", nil)
+	require.NoError(t, err)
+
+	return NewAuthorizingWalletManager(manager, policy), wallet1, wallet2
+}
+
+// TestAuthorizingWalletManagerOwner tests that users may only act on their own wallets
+func TestAuthorizingWalletManagerOwner(t *testing.T) {
+	manager, wallet1, wallet2 := setupTestAuthorization(t, nil)
+	ctx := WithPrincipal(context.Background(), &Principal{UserID: "user-1"})
+
+	// Own wallets
+	wallet, err := manager.GetWallet(ctx, wallet1.ID)
+	assert.NoError(t, err)
+	assert.NotNil(t, wallet)
+
+	_, err = manager.Debit(ctx, wallet1.ID, 100, "Purchase", "", "", nil)
+	assert.NoError(t, err)
+
+	err = manager.Transfer(ctx, wallet1.ID, wallet2.ID, 100, "Gift", "", nil)
+	assert.NoError(t, err)
+
+	err = manager.UpdateWalletName(ctx, wallet1.ID, "Renamed")
+	assert.NoError(t, err)
+
+	transactions, err := manager.ListUserTransactions(ctx, "user-1", 10, 0)
+	assert.NoError(t, err)
+	assert.Len(t, transactions, 3)
+
+	// Wallets of other users
+	_, err = manager.GetWallet(ctx, wallet2.ID)
+	assert.ErrorIs(t, err, ErrPermissionDenied)
+
+	_, err = manager.Debit(ctx, wallet2.ID, 100, "Theft", "", "", nil)
+	assert.ErrorIs(t, err, ErrPermissionDenied)
+
+	err = manager.Transfer(ctx, wallet2.ID, wallet1.ID, 100, "Theft", "", nil)
+	assert.ErrorIs(t, err, ErrPermissionDenied)
+
+	_, err = manager.GetWalletsByUserID(ctx, "user-2")
+	assert.ErrorIs(t, err, ErrPermissionDenied)
+
+	_, err = manager.ListTransactions(ctx, wallet2.ID, 10, 0)
+	assert.ErrorIs(t, err, ErrPermissionDenied)
+
+	// Missing wallets are denied rather than revealed
+	_, err = manager.GetWallet(ctx, "missing-wallet")
+	assert.ErrorIs(t, err, ErrPermissionDenied)
+
+	_, err = manager.Debit(ctx, "missing-wallet", 100, "Purchase", "", "", nil)
+	assert.ErrorIs(t, err, ErrPermissionDenied)
+
+	var denial *DenialError
+	require.True(t, errors.As(err, &denial))
+	assert.Equal(t, "user-1", denial.UserID)
+	assert.Equal(t, ActionDebit, denial.Action)
+
+	_, err = manager.GetTransaction(ctx, "missing-transaction")
+	assert.ErrorIs(t, err, ErrPermissionDenied)
+
+	balance, err := manager.GetUserWalletSummary(WithPrincipal(context.Background(), &Principal{UserID: "user-2"}), "user-2")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1100), balance)
+}
+
+// TestAuthorizingWalletManagerAdmin tests the actions reserved for admins
+func TestAuthorizingWalletManagerAdmin(t *testing.T) {
+	manager, wallet1, _ := setupTestAuthorization(t, nil)
+	userCtx := WithPrincipal(context.Background(), &Principal{UserID: "user-1"})
+	adminCtx := WithPrincipal(context.Background(), &Principal{UserID: "operator", Roles: []string{RoleAdmin}})
+
+	// Users may not perform admin actions, not even on their own wallets
+	_, err := manager.Credit(userCtx, wallet1.ID, 100, "Free money", "", "", nil)
+	assert.ErrorIs(t, err, ErrPermissionDenied)
+
+	err = manager.FreezeWallet(userCtx, wallet1.ID, "Freeze")
+	assert.ErrorIs(t, err, ErrPermissionDenied)
+
+	err = manager.FlagWalletRisk(userCtx, wallet1.ID, "Flag")
+	assert.ErrorIs(t, err, ErrPermissionDenied)
+
+	// Admins may act on any wallet
+	_, err = manager.Credit(adminCtx, wallet1.ID, 100, "Bonus", "", "", nil)
+	assert.NoError(t, err)
+
+	err = manager.FreezeWallet(adminCtx, wallet1.ID, "Investigation")
+	assert.NoError(t, err)
+
+	err = manager.FlagWalletRisk(adminCtx, wallet1.ID, "Investigation")
+	assert.NoError(t, err)
+
+	wallet, err := manager.GetWallet(adminCtx, wallet1.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1100), wallet.Balance)
+	assert.True(t, wallet.Frozen)
+	assert.True(t, wallet.RiskFlagged)
+
+	// Admins learn that a wallet is missing
+	wallet, err = manager.GetWallet(adminCtx, "missing-wallet")
+	assert.NoError(t, err)
+	assert.Nil(t, wallet)
+
+	// Contexts without a principal are rejected, whether the wallet exists or not
+	_, err = manager.GetWallet(context.Background(), wallet1.ID)
+	assert.ErrorIs(t, err, ErrUnauthenticated)
+
+	_, err = manager.GetWallet(context.Background(), "missing-wallet")
+	assert.ErrorIs(t, err, ErrUnauthenticated)
+
+	// The same holds for transactions
+	transactions, err := manager.ListTransactions(adminCtx, wallet1.ID, 1, 0)
+	require.NoError(t, err)
+	require.Len(t, transactions, 1)
+
+	_, err = manager.GetTransaction(context.Background(), transactions[0].ID)
+	assert.ErrorIs(t, err, ErrUnauthenticated)
+
+	_, err = manager.GetTransaction(context.Background(), "missing-transaction")
+	assert.ErrorIs(t, err, ErrUnauthenticated)
+
+	missing, err := manager.GetTransaction(adminCtx, "missing-transaction")
+	assert.NoError(t, err)
+	assert.Nil(t, missing)
+}
+
+// TestAuthorizingWalletManagerCustomPolicy tests combining a custom policy with the default one
+func TestAuthorizingWalletManagerCustomPolicy(t *testing.T) {
+	limit := PolicyFunc(func(ctx context.Context, request *AuthorizationRequest) error {
+		if request.Action == ActionDebit && request.Amount > 500 {
+			return &DenialError{UserID: request.Principal.UserID, Action: request.Action, Reason: "amount exceeds limit"}
+		}
+		return nil
+	})
+	manager, wallet1, wallet2 := setupTestAuthorization(t, AllPolicies(DefaultPolicy{}, limit))
+	ctx := WithPrincipal(context.Background(), &Principal{UserID: "user-1"})
+
+	_, err := manager.Debit(ctx, wallet1.ID, 500, "Purchase", "", "", nil)
+	assert.NoError(t, err)
+
+	_, err = manager.Debit(ctx, wallet1.ID, 501, "Purchase", "", "", nil)
+	assert.ErrorIs(t, err, ErrPermissionDenied)
+	assert.EqualError(t, err, `permission denied: user "user-1" may not debit: amount exceeds limit`)
+
+	_, err = manager.Debit(ctx, wallet2.ID, 100, "Purchase", "", "", nil)
+	assert.ErrorIs(t, err, ErrPermissionDenied)
+}
@@ -0,0 +1,438 @@
+package wallethub
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// StatementFormatCamt053 renders the statement as an ISO 20022 camt.053 bank-to-customer statement
+	StatementFormatCamt053 StatementFormat = "camt.053"
+	// StatementFormatOFX renders the statement as an OFX 2.2 bank statement response
+	StatementFormatOFX StatementFormat = "ofx"
+)
+
+// camt053Namespace is the XML namespace of the camt.053 message version written by WriteStatementCamt053
+const camt053Namespace = "urn:iso:std:iso:20022:tech:xsd:camt.053.001.02"
+
+// statementNamespace is the UUID namespace used to derive deterministic statement message IDs
+var statementNamespace = uuid.MustParse("6a0e4f7d-2c1b-4b9a-9e3d-5f8c7a1b2d40")
+
+// BankStatementOptions configures how wallet amounts appear in bank statement formats
+type BankStatementOptions struct {
+	Currency string // ISO 4217 currency code, defaults to "XXX" (no currency) for points
+	Scale    int    // Number of decimal places amounts are stored in, e.g. 2 when amounts are cents
+	BankID   string // Routing identifier reported in OFX, defaults to "WALLETHUB"
+}
+
+// withDefaults fills in unset options
+func (o BankStatementOptions) withDefaults() BankStatementOptions {
+	if o.Currency == "" {
+		o.Currency = "XXX"
+	}
+	if o.BankID == "" {
+		o.BankID = "WALLETHUB"
+	}
+	return o
+}
+
+// StatementMessageID returns the deterministic message ID of a statement, so exporting the same
+// period twice yields the same identifiers
+func StatementMessageID(statement *Statement) string {
+	name := statement.WalletID + "\x00" + statement.From.UTC().Format(time.RFC3339Nano) + "\x00" + statement.To.UTC().Format(time.RFC3339Nano)
+	return compactID(uuid.NewSHA1(statementNamespace, []byte(name)).String())
+}
+
+// WriteStatementCamt053 renders a statement as an ISO 20022 camt.053.001.02 message. Credits and
+// debits map to CRDT and DBIT entries, the transaction ID to the entry and account servicer
+// references, Reference to the end-to-end ID and Description to the unstructured remittance
+// information. References and texts are cut to the lengths the schema allows, the account ID never is.
+func WriteStatementCamt053(ctx context.Context, w io.Writer, statement *Statement, options BankStatementOptions) error {
+	options = options.withDefaults()
+	accountID, err := statementAccountID(statement.WalletID, 34)
+	if err != nil {
+		return err
+	}
+	x := newXMLWriter(w)
+	currency := xml.Attr{Name: xml.Name{Local: "Ccy"}, Value: options.Currency}
+	messageID := StatementMessageID(statement)
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	x.start("Document", xml.Attr{Name: xml.Name{Local: "xmlns"}, Value: camt053Namespace})
+	x.start("BkToCstmrStmt")
+
+	x.start("GrpHdr")
+	x.element("MsgId", messageID)
+	x.element("CreDtTm", formatCamtTime(statement.GeneratedAt))
+	x.end("GrpHdr")
+
+	x.start("Stmt")
+	x.element("Id", messageID)
+	x.element("CreDtTm", formatCamtTime(statement.GeneratedAt))
+	x.start("FrToDt")
+	x.element("FrDtTm", formatCamtTime(statement.From))
+	x.element("ToDtTm", formatCamtTime(statement.To))
+	x.end("FrToDt")
+
+	x.start("Acct")
+	x.start("Id")
+	x.start("Othr")
+	x.element("Id", accountID)
+	x.end("Othr")
+	x.end("Id")
+	x.element("Ccy", options.Currency)
+	x.element("Nm", truncate(statement.WalletName, 70))
+	x.start("Ownr")
+	x.start("Id")
+	x.start("PrvtId")
+	x.start("Othr")
+	x.element("Id", truncate(statement.UserID, 35))
+	x.end("Othr")
+	x.end("PrvtId")
+	x.end("Id")
+	x.end("Ownr")
+	x.end("Acct")
+
+	for _, balance := range []struct {
+		code   string
+		amount int64
+		at     time.Time
+	}{
+		{"OPBD", statement.OpeningBalance, statement.From},
+		{"CLBD", statement.ClosingBalance, statement.To},
+	} {
+		x.start("Bal")
+		x.start("Tp")
+		x.start("CdOrPrtry")
+		x.element("Cd", balance.code)
+		x.end("CdOrPrtry")
+		x.end("Tp")
+		x.element("Amt", formatDecimal(abs(balance.amount), options.Scale), currency)
+		x.element("CdtDbtInd", camtIndicator(balance.amount >= 0))
+		x.start("Dt")
+		x.element("DtTm", formatCamtTime(balance.at))
+		x.end("Dt")
+		x.end("Bal")
+	}
+
+	net := statement.TotalCredits - statement.TotalDebits
+	x.start("TxsSummry")
+	x.start("TtlNtries")
+	x.element("Sum", formatDecimal(statement.TotalCredits+statement.TotalDebits, options.Scale))
+	x.element("TtlNetNtryAmt", formatDecimal(abs(net), options.Scale))
+	x.element("CdtDbtInd", camtIndicator(net >= 0))
+	x.end("TtlNtries")
+	x.start("TtlCdtNtries")
+	x.element("Sum", formatDecimal(statement.TotalCredits, options.Scale))
+	x.end("TtlCdtNtries")
+	x.start("TtlDbtNtries")
+	x.element("Sum", formatDecimal(statement.TotalDebits, options.Scale))
+	x.end("TtlDbtNtries")
+	x.end("TxsSummry")
+
+	for line, err := range statement.Lines(ctx) {
+		if err != nil {
+			return err
+		}
+
+		credit := line.Type != TransactionTypeDebit
+		reference := truncate(compactID(line.TransactionID), 35)
+
+		x.start("Ntry")
+		x.element("NtryRef", reference)
+		x.element("Amt", formatDecimal(line.Amount, options.Scale), currency)
+		x.element("CdtDbtInd", camtIndicator(credit))
+		x.element("Sts", "BOOK")
+		x.start("BookgDt")
+		x.element("DtTm", formatCamtTime(line.CompletedAt))
+		x.end("BookgDt")
+		x.start("ValDt")
+		x.element("DtTm", formatCamtTime(line.CompletedAt))
+		x.end("ValDt")
+		x.element("AcctSvcrRef", reference)
+		x.start("BkTxCd")
+		x.start("Prtry")
+		x.element("Cd", strings.ToUpper(string(line.Type)))
+		x.end("Prtry")
+		x.end("BkTxCd")
+		x.start("NtryDtls")
+		x.start("TxDtls")
+		x.start("Refs")
+		x.element("AcctSvcrRef", reference)
+		x.element("EndToEndId", camtEndToEndID(line.Reference))
+		x.end("Refs")
+		if line.Description != "" {
+			x.start("RmtInf")
+			x.element("Ustrd", truncate(line.Description, 140))
+			x.end("RmtInf")
+		}
+		x.end("TxDtls")
+		x.end("NtryDtls")
+		x.end("Ntry")
+
+		if x.err != nil {
+			return x.err
+		}
+	}
+
+	x.end("Stmt")
+	x.end("BkToCstmrStmt")
+	x.end("Document")
+	return x.close()
+}
+
+// WriteStatementOFX renders a statement as an OFX 2.2 bank statement response. Credits and debits
+// map to CREDIT and DEBIT transactions with signed amounts, the transaction ID to FITID, Reference to
+// REFNUM and Description to NAME and MEMO. The closing balance is reported as the ledger balance.
+// UUID and ULID wallet IDs are too long for ACCTID and are written as their 128 bits in base62.
+func WriteStatementOFX(ctx context.Context, w io.Writer, statement *Statement, options BankStatementOptions) error {
+	options = options.withDefaults()
+	accountID, err := statementAccountID(statement.WalletID, 22)
+	if err != nil {
+		return err
+	}
+	x := newXMLWriter(w)
+
+	header := xml.Header + `<?OFX OFXHEADER="200" VERSION="220" SECURITY="NONE" OLDFILEUID="NONE" NEWFILEUID="NONE"?>` + "\n"
+	if _, err := io.WriteString(w, header); err != nil {
+		return err
+	}
+
+	x.start("OFX")
+	x.start("SIGNONMSGSRSV1")
+	x.start("SONRS")
+	writeOFXStatus(x)
+	x.element("DTSERVER", formatOFXTime(statement.GeneratedAt))
+	x.element("LANGUAGE", "ENG")
+	x.end("SONRS")
+	x.end("SIGNONMSGSRSV1")
+
+	x.start("BANKMSGSRSV1")
+	x.start("STMTTRNRS")
+	x.element("TRNUID", StatementMessageID(statement))
+	writeOFXStatus(x)
+	x.start("STMTRS")
+	x.element("CURDEF", options.Currency)
+	x.start("BANKACCTFROM")
+	x.element("BANKID", options.BankID)
+	x.element("ACCTID", accountID)
+	x.element("ACCTTYPE", "CHECKING")
+	x.end("BANKACCTFROM")
+
+	x.start("BANKTRANLIST")
+	x.element("DTSTART", formatOFXTime(statement.From))
+	x.element("DTEND", formatOFXTime(statement.To))
+
+	for line, err := range statement.Lines(ctx) {
+		if err != nil {
+			return err
+		}
+
+		amount := formatDecimal(line.Amount, options.Scale)
+		transactionType := "CREDIT"
+		if line.Type == TransactionTypeDebit {
+			amount = "-" + amount
+			transactionType = "DEBIT"
+		}
+
+		x.start("STMTTRN")
+		x.element("TRNTYPE", transactionType)
+		x.element("DTPOSTED", formatOFXTime(line.CompletedAt))
+		x.element("TRNAMT", amount)
+		x.element("FITID", line.TransactionID)
+		if line.Reference != "" {
+			x.element("REFNUM", truncate(line.Reference, 32))
+		}
+		if line.Description != "" {
+			x.element("NAME", truncate(line.Description, 32))
+			x.element("MEMO", truncate(line.Description, 255))
+		}
+		x.end("STMTTRN")
+
+		if x.err != nil {
+			return x.err
+		}
+	}
+
+	x.end("BANKTRANLIST")
+	x.start("LEDGERBAL")
+	x.element("BALAMT", formatSignedDecimal(statement.ClosingBalance, options.Scale))
+	x.element("DTASOF", formatOFXTime(statement.To))
+	x.end("LEDGERBAL")
+	x.end("STMTRS")
+	x.end("STMTTRNRS")
+	x.end("BANKMSGSRSV1")
+	x.end("OFX")
+	return x.close()
+}
+
+// writeOFXStatus writes a successful OFX status aggregate
+func writeOFXStatus(x *xmlWriter) {
+	x.start("STATUS")
+	x.element("CODE", "0")
+	x.element("SEVERITY", "INFO")
+	x.end("STATUS")
+}
+
+// camtIndicator returns the camt.053 credit/debit indicator
+func camtIndicator(credit bool) string {
+	if credit {
+		return "CRDT"
+	}
+	return "DBIT"
+}
+
+// camtEndToEndID returns the end-to-end ID for a reference, which camt.053 requires to be present
+func camtEndToEndID(reference string) string {
+	if reference == "" {
+		return "NOTPROVIDED"
+	}
+	return truncate(reference, 35)
+}
+
+// formatCamtTime formats a time as an ISO 8601 date time in UTC
+func formatCamtTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02T15:04:05Z")
+}
+
+// formatOFXTime formats a time as an OFX date time in UTC
+func formatOFXTime(t time.Time) string {
+	return t.UTC().Format("20060102150405.000") + "[0:GMT]"
+}
+
+// formatDecimal formats a non-negative amount stored with the given number of decimal places
+func formatDecimal(amount int64, scale int) string {
+	digits := strconv.FormatInt(amount, 10)
+	if scale <= 0 {
+		return digits
+	}
+	if len(digits) <= scale {
+		digits = strings.Repeat("0", scale-len(digits)+1) + digits
+	}
+	return digits[:len(digits)-scale] + "." + digits[len(digits)-scale:]
+}
+
+// formatSignedDecimal formats an amount stored with the given number of decimal places
+func formatSignedDecimal(amount int64, scale int) string {
+	if amount < 0 {
+		return "-" + formatDecimal(-amount, scale)
+	}
+	return formatDecimal(amount, scale)
+}
+
+// abs returns the absolute value of an amount
+func abs(amount int64) int64 {
+	if amount < 0 {
+		return -amount
+	}
+	return amount
+}
+
+// compactID removes the hyphens of a UUID so it fits 35 character identifier fields, or fewer when truncated
+func compactID(id string) string {
+	return strings.ReplaceAll(id, "-", "")
+}
+
+// statementAccountID returns an account identifier of at most length characters that maps each wallet to
+// a distinct account: the wallet ID without hyphens if it fits, otherwise the 128 bits of a UUID or ULID
+// wallet ID as 22 base62 digits. Other wallet IDs that do not fit return ErrAccountIDTooLong.
+func statementAccountID(walletID string, length int) (string, error) {
+	compact := compactID(walletID)
+	if utf8.RuneCountInString(compact) <= length {
+		return compact, nil
+	}
+
+	if value, ok := parseID128(walletID); ok && length >= 22 {
+		encoded := value.Text(62)
+		return strings.Repeat("0", 22-len(encoded)) + encoded, nil
+	}
+	return "", fmt.Errorf("%w: %s does not fit %d characters", ErrAccountIDTooLong, walletID, length)
+}
+
+// parseID128 returns the 128-bit value of a UUID or ULID
+func parseID128(id string) (*big.Int, bool) {
+	if parsed, err := uuid.Parse(id); err == nil {
+		return new(big.Int).SetBytes(parsed[:]), true
+	}
+
+	// The first character of a ULID only carries 3 of its 130 bits
+	if len(id) != 26 || id[0] > '7' {
+		return nil, false
+	}
+	value := new(big.Int)
+	for _, char := range strings.ToUpper(id) {
+		digit := strings.IndexRune(crockfordAlphabet, char)
+		if digit < 0 {
+			return nil, false
+		}
+		value.Lsh(value, 5).Or(value, big.NewInt(int64(digit)))
+	}
+	return value, true
+}
+
+// truncate cuts text to at most the given number of characters
+func truncate(text string, length int) string {
+	runes := []rune(text)
+	if len(runes) <= length {
+		return text
+	}
+	return string(runes[:length])
+}
+
+// xmlWriter streams indented XML elements and keeps the first error
+type xmlWriter struct {
+	w       io.Writer
+	encoder *xml.Encoder
+	err     error
+}
+
+// newXMLWriter creates an xmlWriter with two space indentation
+func newXMLWriter(w io.Writer) *xmlWriter {
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	return &xmlWriter{w: w, encoder: encoder}
+}
+
+func (x *xmlWriter) start(name string, attrs ...xml.Attr) {
+	if x.err == nil {
+		x.err = x.encoder.EncodeToken(xml.StartElement{Name: xml.Name{Local: name}, Attr: attrs})
+	}
+}
+
+func (x *xmlWriter) end(name string) {
+	if x.err == nil {
+		x.err = x.encoder.EncodeToken(xml.EndElement{Name: xml.Name{Local: name}})
+	}
+}
+
+func (x *xmlWriter) element(name string, value string, attrs ...xml.Attr) {
+	x.start(name, attrs...)
+	if x.err == nil {
+		x.err = x.encoder.EncodeToken(xml.CharData(value))
+	}
+	x.end(name)
+}
+
+// close flushes the output and writes the final newline
+func (x *xmlWriter) close() error {
+	if x.err != nil {
+		return x.err
+	}
+	if err := x.encoder.Close(); err != nil {
+		return err
+	}
+	_, err := io.WriteString(x.w, "\n")
+	return err
+}
@@ -0,0 +1,157 @@
+package wallethub
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// update rewrites the golden files with the current output
+var update = flag.Bool("update", false, "update golden files")
+
+// setupTestBankStatement creates a statement with fixed IDs and times so its output is reproducible
+func setupTestBankStatement(t *testing.T) *Statement {
+	store := setupTestGormWalletStore(t)
+	manager := NewWalletManager(WithStore(store))
+	ctx := context.Background()
+
+	created := time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC)
+	err := store.SaveWallet(ctx, &Wallet{
+		ID:        "2f1c0d9e-8b7a-4c6d-9e5f-4a3b2c1d0e9f",
+		UserID:    "user-1",
+		Name:      "Main Wallet",
+		Balance:   101050,
+		Active:    true,
+		CreatedAt: created,
+	})
+	require.NoError(t, err)
+
+	for _, transaction := range []Transaction{
+		{ID: "0b6a1c52-0000-4000-8000-000000000001", Type: TransactionTypeCredit, Amount: 100000, Balance: 100000, Description: "Opening deposit", Reference: "DEP-1", CompletedAt: time.Date(2024, 12, 20, 9, 0, 0, 0, time.UTC)},
+		{ID: "0b6a1c52-0000-4000-8000-000000000002", Type: TransactionTypeCredit, Amount: 2550, Balance: 102550, Description: "Cashback & rewards <December>", Reference: "CB-2024-12", CompletedAt: time.Date(2025, 1, 5, 10, 30, 0, 0, time.UTC)},
+		{ID: "0b6a1c52-0000-4000-8000-000000000003", Type: TransactionTypeDebit, Amount: 1999, Balance: 100551, Description: "Purchase at a store with a rather long name", Reference: "ORDER-123456789012345678901234567890", CompletedAt: time.Date(2025, 1, 15, 18, 45, 0, 0, time.UTC)},
+		{ID: "0b6a1c52-0000-4000-8000-000000000004", Type: TransactionTypeCredit, Amount: 499, Balance: 101050, CompletedAt: time.Date(2025, 1, 31, 23, 59, 59, 0, time.UTC)},
+	} {
+		transaction.WalletID = "2f1c0d9e-8b7a-4c6d-9e5f-4a3b2c1d0e9f"
+		transaction.Status = TransactionStatusCompleted
+		transaction.CreatedAt = transaction.CompletedAt
+		require.NoError(t, store.SaveTransaction(ctx, &transaction))
+	}
+
+	statement, err := manager.GenerateStatement(ctx, "2f1c0d9e-8b7a-4c6d-9e5f-4a3b2c1d0e9f", time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	statement.GeneratedAt = time.Date(2025, 2, 1, 6, 0, 0, 0, time.UTC)
+
+	return statement
+}
+
+// assertGolden compares output with a golden file in testdata, rewriting it with -update
+func assertGolden(t *testing.T, name string, actual []byte) {
+	path := filepath.Join("testdata", name)
+	if *update {
+		require.NoError(t, os.WriteFile(path, actual, 0o644))
+	}
+
+	expected, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, string(expected), string(actual))
+}
+
+// TestWriteStatementCamt053 tests camt.053 output against a golden file
+func TestWriteStatementCamt053(t *testing.T) {
+	statement := setupTestBankStatement(t)
+
+	var buf bytes.Buffer
+	err := WriteStatementCamt053(context.Background(), &buf, statement, BankStatementOptions{Currency: "EUR", Scale: 2})
+	require.NoError(t, err)
+
+	assertGolden(t, "statement.camt053.xml", buf.Bytes())
+}
+
+// TestWriteStatementOFX tests OFX output against a golden file
+func TestWriteStatementOFX(t *testing.T) {
+	statement := setupTestBankStatement(t)
+
+	var buf bytes.Buffer
+	err := WriteStatementOFX(context.Background(), &buf, statement, BankStatementOptions{Currency: "EUR", Scale: 2})
+	require.NoError(t, err)
+
+	assertGolden(t, "statement.ofx", buf.Bytes())
+
+	// UUIDs are written in base62 to fit the 22 characters of account IDs
+	assert.Contains(t, buf.String(), "<ACCTID>1qTsv9o598L7Sv6c48O5xt</ACCTID>")
+}
+
+// TestStatementAccountID tests that wallet IDs map to distinct account IDs that fit the format
+func TestStatementAccountID(t *testing.T) {
+	// Short IDs are written as they are, without hyphens
+	id, err := statementAccountID("wallet-1", 22)
+	require.NoError(t, err)
+	assert.Equal(t, "wallet1", id)
+
+	id, err = statementAccountID("2f1c0d9e-8b7a-4c6d-9e5f-4a3b2c1d0e9f", 34)
+	require.NoError(t, err)
+	assert.Equal(t, "2f1c0d9e8b7a4c6d9e5f4a3b2c1d0e9f", id)
+
+	// UUIDs sharing the first 22 characters remain distinct
+	first, err := statementAccountID("2f1c0d9e-8b7a-4c6d-9e5f-4a3b2c1d0e9f", 22)
+	require.NoError(t, err)
+	second, err := statementAccountID("2f1c0d9e-8b7a-4c6d-9e5f-4a0000000000", 22)
+	require.NoError(t, err)
+	assert.Len(t, first, 22)
+	assert.Len(t, second, 22)
+	assert.NotEqual(t, first, second)
+
+	// Small values are padded, ULIDs are encoded like UUIDs of the same value
+	id, err = statementAccountID("00000000-0000-0000-0000-000000000001", 22)
+	require.NoError(t, err)
+	assert.Equal(t, "0000000000000000000001", id)
+	ulid, err := statementAccountID("00000000000000000000000001", 22)
+	require.NoError(t, err)
+	assert.Equal(t, id, ulid)
+	ulid, err = statementAccountID("01ARZ3NDEKTSV4RRFFQ69G5FAV", 22)
+	require.NoError(t, err)
+	assert.Len(t, ulid, 22)
+
+	// Other IDs are not cut
+	_, err = statementAccountID("a-wallet-id-much-longer-than-an-ofx-account", 22)
+	assert.ErrorIs(t, err, ErrAccountIDTooLong)
+
+	var buf bytes.Buffer
+	err = WriteStatementOFX(context.Background(), &buf, &Statement{WalletID: "a-wallet-id-much-longer-than-an-ofx-account"}, BankStatementOptions{})
+	assert.ErrorIs(t, err, ErrAccountIDTooLong)
+	assert.Empty(t, buf.String())
+}
+
+// TestWriteStatementBankFormats tests the bank statement formats with the default options
+func TestWriteStatementBankFormats(t *testing.T) {
+	statement := setupTestBankStatement(t)
+	ctx := context.Background()
+
+	var buf bytes.Buffer
+	err := WriteStatement(ctx, &buf, statement, StatementFormatCamt053)
+	require.NoError(t, err)
+	assert.Contains(t, buf.String(), `<Amt Ccy="XXX">2550</Amt>`)
+
+	buf.Reset()
+	err = WriteStatement(ctx, &buf, statement, StatementFormatOFX)
+	require.NoError(t, err)
+	assert.Contains(t, buf.String(), "<TRNAMT>-1999</TRNAMT>")
+	assert.Contains(t, buf.String(), "<BANKID>WALLETHUB</BANKID>")
+}
+
+// TestFormatDecimal tests formatting amounts with decimal places
+func TestFormatDecimal(t *testing.T) {
+	assert.Equal(t, "123", formatDecimal(123, 0))
+	assert.Equal(t, "1.23", formatDecimal(123, 2))
+	assert.Equal(t, "0.05", formatDecimal(5, 2))
+	assert.Equal(t, "0.000", formatDecimal(0, 3))
+	assert.Equal(t, "-12.50", formatSignedDecimal(-1250, 2))
+}
@@ -0,0 +1,195 @@
+package wallethub
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"maps"
+	"slices"
+)
+
+// DefaultBucket is the balance bucket of points credited without naming a bucket
+const DefaultBucket = "default"
+
+// ErrInvalidBucket is returned for empty bucket names
+var ErrInvalidBucket = errors.New("invalid bucket")
+
+// BucketPolicy configures how debits and transfers draw on the balance buckets of a wallet
+type BucketPolicy struct {
+	Priority        []string // Order in which buckets are spent; unlisted buckets follow by name, the default bucket last
+	NonWithdrawable []string // Buckets that debits can spend but transfers cannot move out of the wallet
+}
+
+// WithBucketPolicy sets the order in which debits spend the balance buckets of wallets and which
+// buckets cannot be transferred out. By default, named buckets are spent by name before the default bucket.
+func WithBucketPolicy(policy BucketPolicy) Option {
+	return func(m *DefaultWalletManager) {
+		m.bucketPolicy = policy
+	}
+}
+
+// BucketBalance returns the balance of a bucket of the wallet. Points outside named buckets are in the default bucket.
+func (w *Wallet) BucketBalance(bucket string) int64 {
+	if bucket != DefaultBucket {
+		return w.Buckets[bucket]
+	}
+
+	balance := w.Balance
+	for _, amount := range w.Buckets {
+		balance -= amount
+	}
+	return balance
+}
+
+// deposit adds points to a bucket of the wallet and returns the split recorded on the transaction
+func (w *Wallet) deposit(bucket string, amount int64) map[string]int64 {
+	w.Balance += amount
+	if bucket != DefaultBucket {
+		if w.Buckets == nil {
+			w.Buckets = make(map[string]int64)
+		}
+		w.Buckets[bucket] += amount
+	}
+	return map[string]int64{bucket: amount}
+}
+
+// depositSplit adds the points of a pending credit to the buckets recorded on it. A split that does not
+// add up to the amount is ignored, and the amount goes to the first of its buckets by name instead.
+func (w *Wallet) depositSplit(split map[string]int64, amount int64) map[string]int64 {
+	names := slices.Sorted(maps.Keys(split))
+	if len(names) == 0 {
+		return w.deposit(DefaultBucket, amount)
+	}
+
+	var total int64
+	for _, name := range names {
+		if split[name] <= 0 {
+			return w.deposit(names[0], amount)
+		}
+		total += split[name]
+	}
+	if total != amount {
+		return w.deposit(names[0], amount)
+	}
+
+	for _, name := range names {
+		w.deposit(name, split[name])
+	}
+	return maps.Clone(split)
+}
+
+// withdraw removes points from the buckets of the wallet in the given order and returns the split
+// recorded on the transaction. Buckets missing from the order are not touched.
+func (w *Wallet) withdraw(order []string, amount int64) (map[string]int64, error) {
+	split := make(map[string]int64)
+	remaining := amount
+	for _, bucket := range order {
+		taken := min(w.BucketBalance(bucket), remaining)
+		if taken <= 0 {
+			continue
+		}
+		split[bucket] = taken
+		remaining -= taken
+		if remaining == 0 {
+			break
+		}
+	}
+	if remaining > 0 {
+		return nil, ErrInsufficientBalance
+	}
+
+	w.Balance -= amount
+	for bucket, taken := range split {
+		if bucket == DefaultBucket {
+			continue
+		}
+		w.Buckets[bucket] -= taken
+		if w.Buckets[bucket] == 0 {
+			delete(w.Buckets, bucket)
+		}
+	}
+	return split, nil
+}
+
+// spendOrder returns the order in which a debit spends the buckets of a wallet. Transfers, which move
+// points out of the wallet, skip the non-withdrawable buckets.
+func (m *DefaultWalletManager) spendOrder(wallet *Wallet, transfer bool) []string {
+	buckets := make([]string, 0, len(wallet.Buckets)+1)
+	for bucket := range wallet.Buckets {
+		buckets = append(buckets, bucket)
+	}
+	slices.Sort(buckets)
+	buckets = append(buckets, DefaultBucket)
+
+	// Listed buckets come first, in the order of the policy
+	rank := func(bucket string) int {
+		if i := slices.Index(m.bucketPolicy.Priority, bucket); i >= 0 {
+			return i
+		}
+		return len(m.bucketPolicy.Priority)
+	}
+	slices.SortStableFunc(buckets, func(a, b string) int {
+		return rank(a) - rank(b)
+	})
+
+	if transfer {
+		buckets = slices.DeleteFunc(buckets, func(bucket string) bool {
+			return slices.Contains(m.bucketPolicy.NonWithdrawable, bucket)
+		})
+	}
+	return buckets
+}
+
+// CreditBucket adds points to a balance bucket of a wallet
+func (m *DefaultWalletManager) CreditBucket(ctx context.Context, walletID string, bucket string, amount int64, description string, note string, reference string, data map[string]interface{}) (transaction *Transaction, err error) {
+	unlock, err := m.lockWallets(ctx, walletID)
+	if err != nil {
+		return nil, newWalletError(err, walletID)
+	}
+	defer unlock()
+
+	err = m.WithinTx(ctx, func(ops WalletOps) error {
+		transaction, err = ops.CreditBucket(walletID, bucket, amount, description, note, reference, data)
+		return err
+	})
+	if err != nil {
+		return nil, newWalletError(err, walletID)
+	}
+	return transaction, nil
+}
+
+// CreditBucket adds points to a balance bucket of a wallet
+func (o *walletOps) CreditBucket(walletID string, bucket string, amount int64, description string, note string, reference string, data map[string]interface{}) (*Transaction, error) {
+	return o.creditBucket(o.m.ids.NewID(), walletID, bucket, amount, description, note, reference, data)
+}
+
+// creditBucket adds points to a bucket of a wallet, recording a transaction with the given ID
+func (o *walletOps) creditBucket(transactionID string, walletID string, bucket string, amount int64, description string, note string, reference string, data map[string]interface{}) (transaction *Transaction, err error) {
+	defer func() {
+		err = newWalletError(err, walletID)
+		attrs := []slog.Attr{
+			slog.String("wallet_id", walletID),
+			slog.String("bucket", bucket),
+			slog.Int64("amount", amount),
+			slog.String("reference", reference),
+			o.m.log.note(note),
+			o.m.log.data(data),
+		}
+		if transaction != nil {
+			attrs = append(attrs, slog.String("transaction_id", transaction.ID))
+		}
+		o.m.log.operation(o.ctx, "credit", err, attrs...)
+	}()
+
+	if amount <= 0 {
+		return nil, ErrInvalidAmount
+	}
+	if bucket == "" {
+		return nil, ErrInvalidBucket
+	}
+
+	// Evaluate the risk rules once the store transaction is closed
+	defer func() { o.unit.attempt(walletID, TransactionTypeCredit, amount, err) }()
+
+	return o.m.creditTxn(o.txn, transactionID, walletID, bucket, amount, description, note, reference, data)
+}
@@ -0,0 +1,172 @@
+package wallethub
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestBuckets tests that debits spend balance buckets by priority and record their splits
+func TestBuckets(t *testing.T) {
+	manager := NewWalletManager(WithStore(setupTestGormWalletStore(t)), WithBucketPolicy(BucketPolicy{
+		Priority:        []string{"promotional"},
+		NonWithdrawable: []string{"promotional"},
+	}))
+	ctx := context.Background()
+
+	wallet, err := manager.CreateWallet(ctx, "user-1", "Wallet", "", "")
+	require.NoError(t, err)
+
+	transaction, err := manager.CreditBucket(ctx, wallet.ID, "promotional", 300, "Promotion", "", "", nil)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int64{"promotional": 300}, transaction.Buckets)
+	_, err = manager.Credit(ctx, wallet.ID, 500, "Purchase", "", "", nil)
+	require.NoError(t, err)
+
+	wallet, err = manager.GetWallet(ctx, wallet.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(800), wallet.Balance)
+	assert.Equal(t, int64(300), wallet.BucketBalance("promotional"))
+	assert.Equal(t, int64(500), wallet.BucketBalance(DefaultBucket))
+
+	// Promotional points are spent first
+	transaction, err = manager.Debit(ctx, wallet.ID, 400, "Order", "", "", nil)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int64{"promotional": 300, DefaultBucket: 100}, transaction.Buckets)
+
+	stored, err := manager.GetTransaction(ctx, transaction.ID)
+	require.NoError(t, err)
+	assert.Equal(t, transaction.Buckets, stored.Buckets)
+
+	wallet, err = manager.GetWallet(ctx, wallet.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(400), wallet.Balance)
+	assert.Empty(t, wallet.Buckets)
+
+	// Bucket splits are covered by the hash chain
+	verification, err := manager.VerifyHashChain(ctx, wallet.ID)
+	require.NoError(t, err)
+	assert.True(t, verification.Valid())
+
+	// Empty bucket names are rejected
+	_, err = manager.CreditBucket(ctx, wallet.ID, "", 100, "Promotion", "", "", nil)
+	assert.ErrorIs(t, err, ErrInvalidBucket)
+}
+
+// TestBucketsTransfer tests that transfers leave non-withdrawable buckets in the wallet
+func TestBucketsTransfer(t *testing.T) {
+	manager := NewWalletManager(WithStore(setupTestGormWalletStore(t)), WithBucketPolicy(BucketPolicy{
+		NonWithdrawable: []string{"promotional"},
+	}))
+	ctx := context.Background()
+
+	wallet1, err := manager.CreateWallet(ctx, "user-1", "Wallet 1", "", "")
+	require.NoError(t, err)
+	wallet2, err := manager.CreateWallet(ctx, "user-2", "Wallet 2", "", "")
+	require.NoError(t, err)
+
+	_, err = manager.CreditBucket(ctx, wallet1.ID, "promotional", 200, "Promotion", "", "", nil)
+	require.NoError(t, err)
+	_, err = manager.Credit(ctx, wallet1.ID, 100, "Purchase", "", "", nil)
+	require.NoError(t, err)
+
+	// Only the default bucket can be transferred
+	err = manager.Transfer(ctx, wallet1.ID, wallet2.ID, 150, "Transfer", "", nil)
+	assert.ErrorIs(t, err, ErrInsufficientBalance)
+
+	err = manager.Transfer(ctx, wallet1.ID, wallet2.ID, 100, "Transfer", "", nil)
+	require.NoError(t, err)
+
+	wallet1, err = manager.GetWallet(ctx, wallet1.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(200), wallet1.Balance)
+	assert.Equal(t, int64(200), wallet1.BucketBalance("promotional"))
+	assert.Equal(t, int64(0), wallet1.BucketBalance(DefaultBucket))
+
+	// Transferred points arrive in the default bucket
+	wallet2, err = manager.GetWallet(ctx, wallet2.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(100), wallet2.BucketBalance(DefaultBucket))
+
+	// Debits can still spend promotional points
+	transaction, err := manager.Debit(ctx, wallet1.ID, 200, "Order", "", "", nil)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int64{"promotional": 200}, transaction.Buckets)
+}
+
+// TestBucketsDefaultOrder tests that without a policy named buckets are spent by name before the default bucket
+func TestBucketsDefaultOrder(t *testing.T) {
+	manager := NewWalletManager()
+	wallet := &Wallet{}
+	wallet.deposit(DefaultBucket, 100)
+	wallet.deposit("b", 100)
+	wallet.deposit("a", 100)
+
+	order := manager.spendOrder(wallet, false)
+	assert.Equal(t, []string{"a", "b", DefaultBucket}, order)
+
+	split, err := wallet.withdraw(order, 250)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int64{"a": 100, "b": 100, DefaultBucket: 50}, split)
+	assert.Equal(t, int64(50), wallet.Balance)
+
+	_, err = wallet.withdraw(order, 100)
+	assert.ErrorIs(t, err, ErrInsufficientBalance)
+	assert.Equal(t, int64(50), wallet.Balance)
+}
+
+// TestBucketsCompletePending tests that completing pending credits fills the buckets recorded on them
+func TestBucketsCompletePending(t *testing.T) {
+	store := setupTestGormWalletStore(t)
+	manager := NewWalletManager(WithStore(store))
+	ctx := context.Background()
+
+	wallet, err := manager.CreateWallet(ctx, "user-1", "Wallet", "", "")
+	require.NoError(t, err)
+
+	pending := func(amount int64, buckets map[string]int64) string {
+		transaction := &Transaction{
+			ID:        GenerateID(),
+			WalletID:  wallet.ID,
+			Type:      TransactionTypeCredit,
+			Amount:    amount,
+			Buckets:   buckets,
+			Status:    TransactionStatusPending,
+			CreatedAt: time.Now(),
+		}
+		require.NoError(t, store.SaveTransaction(ctx, transaction))
+		return transaction.ID
+	}
+
+	// A split over two buckets is kept
+	split := pending(500, map[string]int64{"purchased": 200, "promotional": 300})
+	require.NoError(t, manager.CompleteTransaction(ctx, split))
+	transaction, err := manager.GetTransaction(ctx, split)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int64{"purchased": 200, "promotional": 300}, transaction.Buckets)
+
+	// A split not adding up to the amount goes to the first bucket by name
+	unbalanced := pending(100, map[string]int64{"purchased": 1, "promotional": 1})
+	require.NoError(t, manager.CompleteTransaction(ctx, unbalanced))
+	transaction, err = manager.GetTransaction(ctx, unbalanced)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int64{"promotional": 100}, transaction.Buckets)
+
+	// Credits without buckets go to the default bucket
+	plain := pending(50, nil)
+	require.NoError(t, manager.CompleteTransaction(ctx, plain))
+
+	wallet, err = manager.GetWallet(ctx, wallet.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(650), wallet.Balance)
+	assert.Equal(t, int64(400), wallet.BucketBalance("promotional"))
+	assert.Equal(t, int64(200), wallet.BucketBalance("purchased"))
+	assert.Equal(t, int64(50), wallet.BucketBalance(DefaultBucket))
+
+	verification, err := manager.VerifyHashChain(ctx, wallet.ID)
+	require.NoError(t, err)
+	assert.True(t, verification.Valid())
+}
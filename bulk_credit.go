@@ -0,0 +1,302 @@
+package wallethub
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"slices"
+
+	"github.com/google/uuid"
+)
+
+// DefaultBulkChunkSize is the number of items credited per database transaction by BulkCredit
+const DefaultBulkChunkSize = 500
+
+// ErrReferenceRequired is returned for bulk items without a reference, which is used as their idempotency key
+var ErrReferenceRequired = errors.New("reference is required")
+
+// bulkCreditNamespace is the UUID namespace used to derive deterministic bulk credit transaction IDs
+var bulkCreditNamespace = uuid.MustParse("f821657d-f753-4373-a80b-3562b94c35be")
+
+// BulkCreditItem describes a single credit to be applied by BulkCredit
+type BulkCreditItem struct {
+	WalletID    string                 `json:"wallet_id"`
+	Amount      int64                  `json:"amount"`
+	Description string                 `json:"description"`
+	Note        string                 `json:"note"`
+	Reference   string                 `json:"reference"` // Must be unique per wallet within a batch
+	Data        map[string]interface{} `json:"data"`
+}
+
+// BulkCreditStatus defines the outcome of a single bulk credit item
+type BulkCreditStatus string
+
+const (
+	BulkCreditStatusCredited BulkCreditStatus = "credited"
+	BulkCreditStatusSkipped  BulkCreditStatus = "skipped" // Already credited by this or a previous run of the batch
+	BulkCreditStatusFailed   BulkCreditStatus = "failed"
+)
+
+// BulkCreditResult reports the outcome of a single bulk credit item
+type BulkCreditResult struct {
+	Index         int              `json:"index"` // Position of the item in the input stream
+	Item          BulkCreditItem   `json:"item"`
+	Status        BulkCreditStatus `json:"status"`
+	TransactionID string           `json:"transaction_id,omitempty"`
+	Err           error            `json:"-"`
+}
+
+// BulkCreditReport summarizes a bulk credit run. Only failed items are kept, so the report stays small
+// however long the input stream is; every result is passed to the result function of BulkCredit.
+type BulkCreditReport struct {
+	BatchID  string             `json:"batch_id"`
+	Credited int                `json:"credited"`
+	Skipped  int                `json:"skipped"`
+	Failed   int                `json:"failed"`
+	Failures []BulkCreditResult `json:"failures"`
+}
+
+// WithBulkChunkSize sets the number of items credited per database transaction by BulkCredit
+func WithBulkChunkSize(size int) Option {
+	return func(m *DefaultWalletManager) {
+		m.bulkChunkSize = size
+	}
+}
+
+// BulkCreditTransactionID returns the deterministic transaction ID used for a bulk credit item.
+// Running the same batch again yields the same IDs, which is what makes BulkCredit resumable.
+func BulkCreditTransactionID(batchID string, walletID string, reference string) string {
+	return uuid.NewSHA1(bulkCreditNamespace, []byte(batchID+"\x00"+walletID+"\x00"+reference)).String()
+}
+
+// BulkCredit credits many wallets in chunked database transactions.
+//
+// Each item is identified by the batch ID, its wallet ID and its reference, so a batch that was
+// interrupted can be run again with the same batch ID and input: items that were already credited
+// are reported as skipped instead of being credited twice. Items that fail validation are reported
+// as failed without affecting the rest of their chunk. If a chunk cannot be committed, BulkCredit
+// stops and returns the report gathered so far together with the error.
+//
+// If onResult is not nil, it is called with the result of every item in input order once its chunk is
+// done, so callers can record which transaction each item created without the report growing.
+func (m *DefaultWalletManager) BulkCredit(ctx context.Context, batchID string, items iter.Seq[BulkCreditItem], onResult func(result BulkCreditResult)) (*BulkCreditReport, error) {
+	chunkSize := m.bulkChunkSize
+	if chunkSize <= 0 {
+		chunkSize = DefaultBulkChunkSize
+	}
+
+	report := &BulkCreditReport{
+		BatchID:  batchID,
+		Failures: make([]BulkCreditResult, 0),
+	}
+
+	chunk := make([]BulkCreditResult, 0, chunkSize)
+	index := 0
+	for item := range items {
+		chunk = append(chunk, BulkCreditResult{Index: index, Item: item})
+		index++
+
+		if len(chunk) < chunkSize {
+			continue
+		}
+
+		err := m.bulkCreditChunk(ctx, batchID, chunk)
+		report.add(chunk, onResult)
+		if err != nil {
+			return report, err
+		}
+		chunk = make([]BulkCreditResult, 0, chunkSize)
+	}
+
+	if len(chunk) > 0 {
+		err := m.bulkCreditChunk(ctx, batchID, chunk)
+		report.add(chunk, onResult)
+		if err != nil {
+			return report, err
+		}
+	}
+
+	return report, nil
+}
+
+// add updates the counters of the report, keeps the failed items of a chunk and passes every result to onResult
+func (r *BulkCreditReport) add(results []BulkCreditResult, onResult func(result BulkCreditResult)) {
+	for _, result := range results {
+		if onResult != nil {
+			onResult(result)
+		}
+		switch result.Status {
+		case BulkCreditStatusCredited:
+			r.Credited++
+		case BulkCreditStatusSkipped:
+			r.Skipped++
+		case BulkCreditStatusFailed:
+			r.Failed++
+			r.Failures = append(r.Failures, result)
+		}
+	}
+}
+
+// bulkCreditChunk credits a single chunk of items within one database transaction. The wallets are
+// locked within the process first, and the transaction is retried under the retry policy.
+func (m *DefaultWalletManager) bulkCreditChunk(ctx context.Context, batchID string, chunk []BulkCreditResult) error {
+	if err := ctx.Err(); err != nil {
+		markBulkCreditFailed(chunk, err)
+		return err
+	}
+
+	// Validate items and derive their transaction IDs
+	seen := make(map[string]bool)
+	walletIDs := make([]string, 0, len(chunk))
+	transactionIDs := make([]string, 0, len(chunk))
+	valid := make([]int, 0, len(chunk))
+	for i := range chunk {
+		result := &chunk[i]
+		if result.Item.Amount <= 0 {
+			result.fail(ErrInvalidAmount)
+			continue
+		}
+		if result.Item.Reference == "" {
+			result.fail(ErrReferenceRequired)
+			continue
+		}
+
+		result.TransactionID = BulkCreditTransactionID(batchID, result.Item.WalletID, result.Item.Reference)
+		if seen[result.TransactionID] {
+			// Duplicate of an earlier item in the same chunk
+			result.Status = BulkCreditStatusSkipped
+			continue
+		}
+		seen[result.TransactionID] = true
+
+		walletIDs = append(walletIDs, result.Item.WalletID)
+		transactionIDs = append(transactionIDs, result.TransactionID)
+		valid = append(valid, i)
+	}
+	slices.Sort(walletIDs)
+	walletIDs = slices.Compact(walletIDs)
+
+	unlock, err := m.lockWallets(ctx, walletIDs...)
+	if err != nil {
+		markBulkCreditFailed(chunk, err)
+		return err
+	}
+	defer unlock()
+
+	err = m.withinTx(ctx, func(o *walletOps) error {
+		// Start over from the validated items, as the transaction may be retried
+		for j, i := range valid {
+			chunk[i].Status = ""
+			chunk[i].Err = nil
+			chunk[i].TransactionID = transactionIDs[j]
+		}
+		return m.creditChunkTxn(o.txn, chunk, transactionIDs, walletIDs)
+	})
+	if err != nil {
+		markBulkCreditFailed(chunk, err)
+		return err
+	}
+
+	return nil
+}
+
+// creditChunkTxn credits the validated items of a chunk within an open store transaction
+func (m *DefaultWalletManager) creditChunkTxn(txn Txn, chunk []BulkCreditResult, transactionIDs []string, walletIDs []string) error {
+	// Skip items credited by a previous run
+	existing, err := txn.FindTransactionsByIDs(transactionIDs)
+	if err != nil {
+		return err
+	}
+	credited := make(map[string]bool, len(existing))
+	for _, transaction := range existing {
+		credited[transaction.ID] = true
+	}
+
+	// Load and lock the target wallets in ascending ID order, so concurrent chunks cannot deadlock
+	found, err := txn.LockWallets(walletIDs)
+	if err != nil {
+		return err
+	}
+	wallets := make(map[string]*Wallet, len(found))
+	for i := range found {
+		wallets[found[i].ID] = &found[i]
+	}
+
+	// Apply credits in input order so running balances are recorded correctly
+	now := m.clock.Now()
+	touched := make(map[string]bool)
+	updated := make([]*Wallet, 0)
+	transactions := make([]Transaction, 0, len(chunk))
+	for i := range chunk {
+		result := &chunk[i]
+		if result.Status != "" {
+			continue
+		}
+		if credited[result.TransactionID] {
+			result.Status = BulkCreditStatusSkipped
+			continue
+		}
+
+		wallet := wallets[result.Item.WalletID]
+		if wallet == nil {
+			result.fail(ErrWalletNotFound)
+			continue
+		}
+		if !wallet.Active {
+			result.fail(ErrWalletInactive)
+			continue
+		}
+		if wallet.CreditBlocked() {
+			result.fail(ErrWalletFrozen)
+			continue
+		}
+
+		if !touched[wallet.ID] {
+			touched[wallet.ID] = true
+			updated = append(updated, wallet)
+		}
+		buckets := wallet.deposit(DefaultBucket, result.Item.Amount)
+
+		transactions = append(transactions, Transaction{
+			ID:          result.TransactionID,
+			WalletID:    wallet.ID,
+			Type:        TransactionTypeCredit,
+			Amount:      result.Item.Amount,
+			Balance:     wallet.Balance,
+			Buckets:     buckets,
+			Description: result.Item.Description,
+			Note:        result.Item.Note,
+			Reference:   result.Item.Reference,
+			Status:      TransactionStatusCompleted,
+			Data:        result.Item.Data,
+			CreatedAt:   now,
+			CompletedAt: now,
+		})
+		result.Status = BulkCreditStatusCredited
+	}
+
+	// Persist wallet balances and transactions
+	for _, wallet := range updated {
+		if err := txn.UpdateWallet(wallet); err != nil {
+			return err
+		}
+	}
+
+	return txn.SaveTransactions(transactions)
+}
+
+// markBulkCreditFailed marks every item of a chunk that would have been credited as failed
+func markBulkCreditFailed(chunk []BulkCreditResult, err error) {
+	for i := range chunk {
+		if chunk[i].Status == "" || chunk[i].Status == BulkCreditStatusCredited {
+			chunk[i].fail(err)
+		}
+	}
+}
+
+// fail marks a single item as failed
+func (r *BulkCreditResult) fail(err error) {
+	r.Status = BulkCreditStatusFailed
+	r.TransactionID = ""
+	r.Err = err
+}
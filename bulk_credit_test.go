@@ -0,0 +1,184 @@
+package wallethub
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestBulkCredit tests crediting many wallets in chunks with failed items reported
+func TestBulkCredit(t *testing.T) {
+	store := setupTestGormWalletStore(t)
+	manager := NewWalletManager(WithStore(store), WithBulkChunkSize(2))
+	ctx := context.Background()
+
+	// Create wallets
+	wallet1, err := manager.CreateWallet(ctx, "user-1", "Wallet 1", "Description 1", "ref-1")
+	require.NoError(t, err)
+
+	wallet2, err := manager.CreateWallet(ctx, "user-2", "Wallet 2", "Description 2", "ref-2")
+	require.NoError(t, err)
+
+	frozenWallet, err := manager.CreateWallet(ctx, "user-3", "Wallet 3", "Description 3", "ref-3")
+	require.NoError(t, err)
+	err = manager.FreezeWallet(ctx, frozenWallet.ID, "Test freeze")
+	require.NoError(t, err)
+
+	items := []BulkCreditItem{
+		{WalletID: wallet1.ID, Amount: 100, Description: "Campaign", Reference: "campaign-1"},
+		{WalletID: wallet2.ID, Amount: 200, Description: "Campaign", Reference: "campaign-1"},
+		{WalletID: "non-existent-id", Amount: 300, Description: "Campaign", Reference: "campaign-1"},
+		{WalletID: frozenWallet.ID, Amount: 400, Description: "Campaign", Reference: "campaign-1"},
+		{WalletID: wallet1.ID, Amount: 0, Description: "Campaign", Reference: "campaign-2"},
+		{WalletID: wallet1.ID, Amount: 50, Description: "Campaign", Reference: ""},
+		{WalletID: wallet1.ID, Amount: 500, Description: "Campaign", Reference: "campaign-3", Data: map[string]interface{}{"tier": "gold"}},
+	}
+
+	var results []BulkCreditResult
+	report, err := manager.BulkCredit(ctx, "batch-1", slices.Values(items), func(result BulkCreditResult) {
+		results = append(results, result)
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "batch-1", report.BatchID)
+	assert.Equal(t, 3, report.Credited)
+	assert.Equal(t, 0, report.Skipped)
+	assert.Equal(t, 4, report.Failed)
+	require.Len(t, report.Failures, 4)
+
+	// Verify the failed items, which are the only ones kept in the report
+	assert.Equal(t, 2, report.Failures[0].Index)
+	assert.Equal(t, ErrWalletNotFound, report.Failures[0].Err)
+	assert.Equal(t, ErrWalletFrozen, report.Failures[1].Err)
+	assert.Equal(t, ErrInvalidAmount, report.Failures[2].Err)
+	assert.Equal(t, 5, report.Failures[3].Index)
+	assert.Equal(t, ErrReferenceRequired, report.Failures[3].Err)
+	assert.Empty(t, report.Failures[0].TransactionID)
+
+	// Every result is passed on in input order, with the transaction of each credited item
+	require.Len(t, results, len(items))
+	for i, result := range results {
+		assert.Equal(t, i, result.Index)
+	}
+	assert.Equal(t, BulkCreditStatusCredited, results[0].Status)
+	assert.Equal(t, BulkCreditTransactionID("batch-1", wallet1.ID, "campaign-1"), results[0].TransactionID)
+	assert.Equal(t, BulkCreditStatusCredited, results[1].Status)
+	assert.Equal(t, BulkCreditTransactionID("batch-1", wallet2.ID, "campaign-1"), results[1].TransactionID)
+	assert.Equal(t, BulkCreditStatusFailed, results[2].Status)
+
+	// Verify balances
+	updatedWallet1, err := manager.GetWallet(ctx, wallet1.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(600), updatedWallet1.Balance)
+
+	updatedWallet2, err := manager.GetWallet(ctx, wallet2.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(200), updatedWallet2.Balance)
+
+	// Verify the recorded transaction and its running balance
+	tx, err := manager.GetTransaction(ctx, BulkCreditTransactionID("batch-1", wallet1.ID, "campaign-3"))
+	assert.NoError(t, err)
+	require.NotNil(t, tx)
+	assert.Equal(t, wallet1.ID, tx.WalletID)
+	assert.Equal(t, TransactionTypeCredit, tx.Type)
+	assert.Equal(t, int64(500), tx.Amount)
+	assert.Equal(t, int64(600), tx.Balance)
+	assert.Equal(t, "campaign-3", tx.Reference)
+	assert.Equal(t, "gold", tx.Data["tier"])
+}
+
+// TestBulkCreditResume tests that running a batch again does not credit twice
+func TestBulkCreditResume(t *testing.T) {
+	store := setupTestGormWalletStore(t)
+	manager := NewWalletManager(WithStore(store), WithBulkChunkSize(2))
+	ctx := context.Background()
+
+	wallet, err := manager.CreateWallet(ctx, "test-user", "Test Wallet", "Test Description", "test-ref")
+	require.NoError(t, err)
+
+	items := []BulkCreditItem{
+		{WalletID: wallet.ID, Amount: 100, Reference: "item-1"},
+		{WalletID: wallet.ID, Amount: 100, Reference: "item-2"},
+		{WalletID: wallet.ID, Amount: 100, Reference: "item-3"},
+	}
+
+	// Simulate a crash after the first chunk
+	report, err := manager.BulkCredit(ctx, "batch-1", slices.Values(items[:2]), nil)
+	require.NoError(t, err)
+	assert.Equal(t, 2, report.Credited)
+
+	// Resume with the full input
+	report, err = manager.BulkCredit(ctx, "batch-1", slices.Values(items), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, report.Credited)
+	assert.Equal(t, 2, report.Skipped)
+	assert.Empty(t, report.Failures)
+
+	updatedWallet, err := manager.GetWallet(ctx, wallet.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(300), updatedWallet.Balance)
+
+	// Duplicates within the same run are skipped as well
+	report, err = manager.BulkCredit(ctx, "batch-2", slices.Values([]BulkCreditItem{items[0], items[0]}), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, report.Credited)
+	assert.Equal(t, 1, report.Skipped)
+
+	// A different batch credits again
+	updatedWallet, err = manager.GetWallet(ctx, wallet.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(400), updatedWallet.Balance)
+
+	txs, err := manager.ListTransactions(ctx, wallet.ID, 10, 0)
+	assert.NoError(t, err)
+	assert.Len(t, txs, 4)
+}
+
+// TestBulkCreditCancelled tests that a cancelled context stops the run
+func TestBulkCreditCancelled(t *testing.T) {
+	store := setupTestGormWalletStore(t)
+	manager := NewWalletManager(WithStore(store))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	wallet, err := manager.CreateWallet(ctx, "test-user", "Test Wallet", "Test Description", "test-ref")
+	require.NoError(t, err)
+	cancel()
+
+	report, err := manager.BulkCredit(ctx, "batch-1", slices.Values([]BulkCreditItem{
+		{WalletID: wallet.ID, Amount: 100, Reference: "item-1"},
+	}), nil)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 1, report.Failed)
+}
+
+// TestBulkCreditRetry tests that chunks are retried after retryable database errors
+func TestBulkCreditRetry(t *testing.T) {
+	store := &conflictingStore{WalletStore: setupTestGormWalletStore(t), err: &sqlStateError{"40001"}}
+	manager := NewWalletManager(WithStore(store), WithWalletLocks(16), WithRetryPolicy(RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   time.Millisecond,
+		MaxDelay:    5 * time.Millisecond,
+	}))
+	ctx := context.Background()
+
+	wallet, err := manager.CreateWallet(ctx, "user-1", "Wallet", "", "")
+	require.NoError(t, err)
+
+	store.conflicts = 2
+	store.begins = 0
+	report, err := manager.BulkCredit(ctx, "batch-1", slices.Values([]BulkCreditItem{
+		{WalletID: wallet.ID, Amount: 100, Reference: "item-1"},
+		{WalletID: wallet.ID, Amount: 200, Reference: "item-2"},
+	}), nil)
+	require.NoError(t, err)
+	assert.Equal(t, 3, store.begins)
+	assert.Equal(t, 2, report.Credited)
+	assert.Equal(t, 0, report.Failed)
+
+	updated, err := manager.GetWallet(ctx, wallet.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(300), updated.Balance)
+}
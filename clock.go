@@ -0,0 +1,29 @@
+package wallethub
+
+import "time"
+
+// Clock provides the current time to the wallet manager and stores
+type Clock interface {
+	Now() time.Time
+}
+
+// ClockFunc adapts a function to the Clock interface
+type ClockFunc func() time.Time
+
+// Now returns the result of calling the function
+func (f ClockFunc) Now() time.Time {
+	return f()
+}
+
+// SystemClock is the clock reading the system time, used by default
+var SystemClock Clock = ClockFunc(time.Now)
+
+// WithClock sets the clock timestamping wallets, transactions, snapshots, statements, approvals and
+// schedules, SystemClock by default. Approval managers and schedulers use the clock of their wallet manager.
+// The GORM stores take their own clock, set with WithStoreClock, WithSnapshotStoreClock,
+// WithApprovalStoreClock and WithScheduleStoreClock.
+func WithClock(clock Clock) Option {
+	return func(m *DefaultWalletManager) {
+		m.clock = clock
+	}
+}
@@ -0,0 +1,61 @@
+package wallethub
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestWalletManagerClock tests that records are timestamped and identified by the injected clock and ID generator
+func TestWalletManagerClock(t *testing.T) {
+	now := time.Date(2030, time.January, 1, 12, 0, 0, 0, time.UTC)
+	clock := ClockFunc(func() time.Time { return now })
+
+	next := 0
+	ids := IDGeneratorFunc(func() string {
+		next++
+		return fmt.Sprintf("id-%d", next)
+	})
+
+	store := setupTestGormWalletStore(t)
+	WithStoreClock(clock)(store)
+	manager := NewWalletManager(WithStore(store), WithClock(clock), WithIDGenerator(ids))
+	ctx := context.Background()
+
+	wallet, err := manager.CreateWallet(ctx, "user-1", "Wallet", "", "")
+	require.NoError(t, err)
+	assert.Equal(t, "id-1", wallet.ID)
+
+	// Time travel a day ahead
+	now = now.Add(24 * time.Hour)
+	transaction, err := manager.Credit(ctx, wallet.ID, 100, "Deposit", "", "", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "id-2", transaction.ID)
+
+	transaction, err = manager.GetTransaction(ctx, transaction.ID)
+	require.NoError(t, err)
+	assert.True(t, now.Equal(transaction.CreatedAt))
+	assert.True(t, now.Equal(transaction.CompletedAt))
+
+	wallet, err = manager.GetWallet(ctx, wallet.ID)
+	require.NoError(t, err)
+	assert.True(t, now.Add(-24*time.Hour).Equal(wallet.CreatedAt))
+	assert.True(t, now.Equal(wallet.UpdatedAt))
+
+	// Schedulers use the clock and IDs of their manager
+	scheduler := NewScheduler(manager, setupTestGormScheduleStore(t))
+	schedule, err := scheduler.CreateSchedule(ctx, &Schedule{
+		Operation: ScheduleOperationCredit,
+		WalletID:  wallet.ID,
+		Amount:    100,
+		Cron:      "0 0 * * *",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "id-3", schedule.ID)
+	assert.True(t, now.Equal(schedule.CreatedAt))
+	assert.True(t, time.Date(2030, time.January, 3, 0, 0, 0, 0, time.UTC).Equal(schedule.NextRunAt))
+}
@@ -0,0 +1,172 @@
+package wallethub
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Default batching limits of a CreditBatcher
+const (
+	DefaultCreditBatchSize  = 100
+	DefaultCreditBatchDelay = 5 * time.Millisecond
+)
+
+// CreditBatcher coalesces concurrent credits to the same wallet into a single store transaction.
+// The first credit to a wallet opens a batch and waits until the batch is full or its delay has
+// passed, then applies every credit of the batch and commits them together. Each credit is applied
+// within a savepoint, so a credit that fails does not fail the others.
+type CreditBatcher struct {
+	manager  *DefaultWalletManager
+	maxSize  int
+	maxDelay time.Duration
+	mu       sync.Mutex
+	pending  map[creditBatchKey]*creditBatch
+}
+
+// creditBatchKey identifies the wallet of a batch. Wallets of different tenants never share a batch.
+type creditBatchKey struct {
+	tenantID string
+	walletID string
+}
+
+// creditBatch is a batch of credits to one wallet
+type creditBatch struct {
+	requests []*creditRequest
+	full     chan struct{}
+}
+
+// creditRequest is a credit waiting in a batch and, once the batch is done, its outcome
+type creditRequest struct {
+	amount      int64
+	description string
+	note        string
+	reference   string
+	data        map[string]interface{}
+	transaction *Transaction
+	err         error
+	done        chan struct{}
+}
+
+// CreditBatcherOption defines a function type for configuring CreditBatcher
+type CreditBatcherOption func(*CreditBatcher)
+
+// WithCreditBatchSize sets the number of credits after which a batch is applied without waiting
+// further, DefaultCreditBatchSize by default
+func WithCreditBatchSize(size int) CreditBatcherOption {
+	return func(b *CreditBatcher) {
+		if size > 0 {
+			b.maxSize = size
+		}
+	}
+}
+
+// WithCreditBatchDelay sets how long a batch waits for more credits, DefaultCreditBatchDelay by default
+func WithCreditBatchDelay(delay time.Duration) CreditBatcherOption {
+	return func(b *CreditBatcher) {
+		b.maxDelay = delay
+	}
+}
+
+// NewCreditBatcher creates a credit batcher applying credits through the given wallet manager
+func NewCreditBatcher(manager *DefaultWalletManager, options ...CreditBatcherOption) *CreditBatcher {
+	batcher := &CreditBatcher{
+		manager:  manager,
+		maxSize:  DefaultCreditBatchSize,
+		maxDelay: DefaultCreditBatchDelay,
+		pending:  make(map[creditBatchKey]*creditBatch),
+	}
+
+	for _, option := range options {
+		option(batcher)
+	}
+
+	return batcher
+}
+
+// Credit adds points to a wallet as part of a batch and returns once the batch is committed. The
+// batch runs in the context of its first credit, so cancelling that context fails the whole batch.
+func (b *CreditBatcher) Credit(ctx context.Context, walletID string, amount int64, description string, note string, reference string, data map[string]interface{}) (*Transaction, error) {
+	if amount <= 0 {
+		return nil, newWalletError(ErrInvalidAmount, walletID)
+	}
+
+	request := &creditRequest{
+		amount:      amount,
+		description: description,
+		note:        note,
+		reference:   reference,
+		data:        data,
+		done:        make(chan struct{}),
+	}
+
+	// Join the open batch of the wallet or open a new one
+	key := creditBatchKey{tenantID: TenantFromContext(ctx), walletID: walletID}
+	b.mu.Lock()
+	batch := b.pending[key]
+	leader := batch == nil
+	if leader {
+		batch = &creditBatch{full: make(chan struct{})}
+		b.pending[key] = batch
+	}
+	batch.requests = append(batch.requests, request)
+	if len(batch.requests) >= b.maxSize {
+		delete(b.pending, key)
+		close(batch.full)
+	}
+	b.mu.Unlock()
+
+	if leader {
+		b.run(ctx, key, batch)
+	}
+
+	<-request.done
+	return request.transaction, request.err
+}
+
+// run waits for the batch to fill up, then applies and commits its credits
+func (b *CreditBatcher) run(ctx context.Context, key creditBatchKey, batch *creditBatch) {
+	timer := time.NewTimer(b.maxDelay)
+	select {
+	case <-batch.full:
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+	timer.Stop()
+
+	// Close the batch to further credits
+	b.mu.Lock()
+	if b.pending[key] == batch {
+		delete(b.pending, key)
+	}
+	b.mu.Unlock()
+
+	err := b.apply(ctx, key.walletID, batch.requests)
+	for _, request := range batch.requests {
+		if err != nil {
+			request.transaction, request.err = nil, err
+		}
+		close(request.done)
+	}
+}
+
+// apply credits every request of a batch within one store transaction
+func (b *CreditBatcher) apply(ctx context.Context, walletID string, requests []*creditRequest) error {
+	unlock, err := b.manager.lockWallets(ctx, walletID)
+	if err != nil {
+		return newWalletError(err, walletID)
+	}
+	defer unlock()
+
+	err = b.manager.WithinTx(ctx, func(ops WalletOps) error {
+		for _, request := range requests {
+			request.err = ops.WithinTx(func(ops WalletOps) error {
+				var err error
+				request.transaction, err = ops.Credit(walletID, request.amount, request.description, request.note, request.reference, request.data)
+				return err
+			})
+		}
+		return nil
+	})
+	return newWalletError(err, walletID)
+}
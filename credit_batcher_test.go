@@ -0,0 +1,132 @@
+package wallethub
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingStore is a store counting the transactions it commits and rejecting transactions
+// with a description
+type countingStore struct {
+	WalletStore
+	mu      sync.Mutex
+	commits int
+	reject  string
+}
+
+// countingTxn is a transaction of a countingStore
+type countingTxn struct {
+	Txn
+	store *countingStore
+}
+
+func (s *countingStore) Begin(ctx context.Context) Txn {
+	return &countingTxn{Txn: s.WalletStore.Begin(ctx), store: s}
+}
+
+func (t *countingTxn) Commit() error {
+	t.store.mu.Lock()
+	t.store.commits++
+	t.store.mu.Unlock()
+	return t.Txn.Commit()
+}
+
+func (t *countingTxn) SaveTransaction(transaction *Transaction) error {
+	if t.store.reject != "" && transaction.Description == t.store.reject {
+		return errors.New("transaction rejected")
+	}
+	return t.Txn.SaveTransaction(transaction)
+}
+
+// TestCreditBatcher tests that concurrent credits to a wallet are committed together
+func TestCreditBatcher(t *testing.T) {
+	store := &countingStore{WalletStore: setupTestGormWalletStore(t)}
+	manager := NewWalletManager(WithStore(store), WithWalletLocks(16))
+	batcher := NewCreditBatcher(manager, WithCreditBatchSize(10), WithCreditBatchDelay(time.Second))
+	ctx := context.Background()
+
+	wallet, err := manager.CreateWallet(ctx, "user-1", "Wallet", "", "")
+	require.NoError(t, err)
+	store.commits = 0
+
+	// A full batch is applied without waiting for its delay
+	var wg sync.WaitGroup
+	transactions := make([]*Transaction, 10)
+	start := time.Now()
+	for i := range transactions {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var err error
+			transactions[i], err = batcher.Credit(ctx, wallet.ID, 10, "Reward", "", "", nil)
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Equal(t, 1, store.commits)
+
+	for _, transaction := range transactions {
+		require.NotNil(t, transaction)
+		assert.Equal(t, int64(10), transaction.Amount)
+		assert.Equal(t, TransactionTypeCredit, transaction.Type)
+	}
+
+	wallet, err = manager.GetWallet(ctx, wallet.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(100), wallet.Balance)
+
+	list, err := manager.ListTransactions(ctx, wallet.ID, 20, 0)
+	require.NoError(t, err)
+	assert.Len(t, list, 10)
+}
+
+// TestCreditBatcherFailures tests that a failing credit does not fail the other credits of its batch
+func TestCreditBatcherFailures(t *testing.T) {
+	store := &countingStore{WalletStore: setupTestGormWalletStore(t), reject: "Rejected"}
+	manager := NewWalletManager(WithStore(store))
+	batcher := NewCreditBatcher(manager, WithCreditBatchSize(2), WithCreditBatchDelay(time.Second))
+	ctx := context.Background()
+
+	wallet, err := manager.CreateWallet(ctx, "user-1", "Wallet", "", "")
+	require.NoError(t, err)
+	_, err = manager.Credit(ctx, wallet.ID, 100, "Deposit", "", "", nil)
+	require.NoError(t, err)
+
+	// A credit failing after updating the balance is rolled back alone
+	store.commits = 0
+	var wg sync.WaitGroup
+	var errReward, errRejected error
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		_, errReward = batcher.Credit(ctx, wallet.ID, 50, "Reward", "", "", nil)
+	}()
+	go func() {
+		defer wg.Done()
+		_, errRejected = batcher.Credit(ctx, wallet.ID, 1000, "Rejected", "", "", nil)
+	}()
+	wg.Wait()
+
+	assert.NoError(t, errReward)
+	assert.Error(t, errRejected)
+	assert.Equal(t, 1, store.commits)
+
+	wallet, err = manager.GetWallet(ctx, wallet.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(150), wallet.Balance)
+
+	// Invalid amounts are rejected before batching
+	_, err = batcher.Credit(ctx, wallet.ID, 0, "Reward", "", "", nil)
+	assert.ErrorIs(t, err, ErrInvalidAmount)
+
+	// Credits to unknown wallets fail
+	_, err = batcher.Credit(ctx, "missing", 10, "Reward", "", "", nil)
+	assert.Equal(t, CodeWalletNotFound, ErrorCodeOf(err))
+}
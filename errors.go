@@ -0,0 +1,225 @@
+package wallethub
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+// ErrorCode is a stable, machine-readable identifier of the cause of an error
+type ErrorCode string
+
+const (
+	CodeInternal                   ErrorCode = "internal"
+	CodeCanceled                   ErrorCode = "canceled"
+	CodeDeadlineExceeded           ErrorCode = "deadline_exceeded"
+	CodeUnauthenticated            ErrorCode = "unauthenticated"
+	CodePermissionDenied           ErrorCode = "permission_denied"
+	CodeWalletNotFound             ErrorCode = "wallet_not_found"
+	CodeWalletInactive             ErrorCode = "wallet_inactive"
+	CodeWalletFrozen               ErrorCode = "wallet_frozen"
+	CodeInsufficientBalance        ErrorCode = "insufficient_balance"
+	CodeTransactionNotFound        ErrorCode = "transaction_not_found"
+	CodeInvalidAmount              ErrorCode = "invalid_amount"
+	CodeNotPending                 ErrorCode = "not_pending"
+	CodeInvalidFreezeMode          ErrorCode = "invalid_freeze_mode"
+	CodeRiskBlocked                ErrorCode = "risk_blocked"
+	CodeRiskApprovalRequired       ErrorCode = "risk_approval_required"
+	CodeRiskVelocityExceeded       ErrorCode = "risk_velocity_exceeded"
+	CodeReferenceRequired          ErrorCode = "reference_required"
+	CodeApprovalNotFound           ErrorCode = "approval_not_found"
+	CodeApprovalNotPending         ErrorCode = "approval_not_pending"
+	CodeApprovalNotExecuting       ErrorCode = "approval_not_executing"
+	CodeSelfApproval               ErrorCode = "self_approval"
+	CodeInvalidApproval            ErrorCode = "invalid_approval"
+	CodeScheduleNotFound           ErrorCode = "schedule_not_found"
+	CodeInvalidSchedule            ErrorCode = "invalid_schedule"
+	CodeScheduleNotActive          ErrorCode = "schedule_not_active"
+	CodeScheduleNotPaused          ErrorCode = "schedule_not_paused"
+	CodeInvalidCronExpression      ErrorCode = "invalid_cron_expression"
+	CodeUnsupportedDataFormat      ErrorCode = "unsupported_data_format"
+	CodeInvalidImportRecord        ErrorCode = "invalid_import_record"
+	CodeImportInconsistent         ErrorCode = "import_inconsistent"
+	CodeInvalidReportInterval      ErrorCode = "invalid_report_interval"
+	CodeInvalidStatementPeriod     ErrorCode = "invalid_statement_period"
+	CodeUnsupportedStatementFormat ErrorCode = "unsupported_statement_format"
+	CodeAccountIDTooLong           ErrorCode = "account_id_too_long"
+	CodeSnapshotStoreRequired      ErrorCode = "snapshot_store_required"
+	CodeSnapshotInFuture           ErrorCode = "snapshot_in_future"
+	CodeAllowanceNotFound          ErrorCode = "allowance_not_found"
+	CodeAllowanceExceeded          ErrorCode = "allowance_exceeded"
+	CodeInvalidDelegate            ErrorCode = "invalid_delegate"
+)
+
+// gRPC status codes, numerically equal to those of google.golang.org/grpc/codes
+const (
+	grpcCanceled           uint32 = 1
+	grpcInvalidArgument    uint32 = 3
+	grpcDeadlineExceeded   uint32 = 4
+	grpcNotFound           uint32 = 5
+	grpcPermissionDenied   uint32 = 7
+	grpcResourceExhausted  uint32 = 8
+	grpcFailedPrecondition uint32 = 9
+	grpcInternal           uint32 = 13
+	grpcUnauthenticated    uint32 = 16
+)
+
+// errorCode describes how an error code maps to transport statuses
+type errorCode struct {
+	err        error
+	code       ErrorCode
+	httpStatus int
+	grpcCode   uint32
+}
+
+// errorCodes maps the errors of this package to their codes. Errors not listed are internal errors.
+var errorCodes = []errorCode{
+	{context.Canceled, CodeCanceled, 499, grpcCanceled},
+	{context.DeadlineExceeded, CodeDeadlineExceeded, http.StatusGatewayTimeout, grpcDeadlineExceeded},
+	{ErrUnauthenticated, CodeUnauthenticated, http.StatusUnauthorized, grpcUnauthenticated},
+	{ErrPermissionDenied, CodePermissionDenied, http.StatusForbidden, grpcPermissionDenied},
+	{ErrWalletNotFound, CodeWalletNotFound, http.StatusNotFound, grpcNotFound},
+	{ErrWalletInactive, CodeWalletInactive, http.StatusConflict, grpcFailedPrecondition},
+	{ErrWalletFrozen, CodeWalletFrozen, http.StatusConflict, grpcFailedPrecondition},
+	{ErrInsufficientBalance, CodeInsufficientBalance, http.StatusConflict, grpcFailedPrecondition},
+	{ErrTransactionNotFound, CodeTransactionNotFound, http.StatusNotFound, grpcNotFound},
+	{ErrInvalidAmount, CodeInvalidAmount, http.StatusBadRequest, grpcInvalidArgument},
+	{ErrPendingTransactionOnly, CodeNotPending, http.StatusConflict, grpcFailedPrecondition},
+	{ErrInvalidFreezeMode, CodeInvalidFreezeMode, http.StatusBadRequest, grpcInvalidArgument},
+	{ErrRiskBlocked, CodeRiskBlocked, http.StatusForbidden, grpcPermissionDenied},
+	{ErrRiskApprovalRequired, CodeRiskApprovalRequired, http.StatusConflict, grpcFailedPrecondition},
+	{ErrRiskVelocityExceeded, CodeRiskVelocityExceeded, http.StatusTooManyRequests, grpcResourceExhausted},
+	{ErrReferenceRequired, CodeReferenceRequired, http.StatusBadRequest, grpcInvalidArgument},
+	{ErrApprovalNotFound, CodeApprovalNotFound, http.StatusNotFound, grpcNotFound},
+	{ErrApprovalNotPending, CodeApprovalNotPending, http.StatusConflict, grpcFailedPrecondition},
+	{ErrApprovalNotExecuting, CodeApprovalNotExecuting, http.StatusConflict, grpcFailedPrecondition},
+	{ErrSelfApproval, CodeSelfApproval, http.StatusForbidden, grpcPermissionDenied},
+	{ErrInvalidApproval, CodeInvalidApproval, http.StatusBadRequest, grpcInvalidArgument},
+	{ErrScheduleNotFound, CodeScheduleNotFound, http.StatusNotFound, grpcNotFound},
+	{ErrInvalidSchedule, CodeInvalidSchedule, http.StatusBadRequest, grpcInvalidArgument},
+	{ErrScheduleNotActive, CodeScheduleNotActive, http.StatusConflict, grpcFailedPrecondition},
+	{ErrScheduleNotPaused, CodeScheduleNotPaused, http.StatusConflict, grpcFailedPrecondition},
+	{ErrInvalidCronExpression, CodeInvalidCronExpression, http.StatusBadRequest, grpcInvalidArgument},
+	{ErrUnsupportedDataFormat, CodeUnsupportedDataFormat, http.StatusBadRequest, grpcInvalidArgument},
+	{ErrInvalidImportRecord, CodeInvalidImportRecord, http.StatusBadRequest, grpcInvalidArgument},
+	{ErrImportInconsistent, CodeImportInconsistent, http.StatusConflict, grpcFailedPrecondition},
+	{ErrInvalidReportInterval, CodeInvalidReportInterval, http.StatusBadRequest, grpcInvalidArgument},
+	{ErrInvalidStatementPeriod, CodeInvalidStatementPeriod, http.StatusBadRequest, grpcInvalidArgument},
+	{ErrUnsupportedStatementFormat, CodeUnsupportedStatementFormat, http.StatusBadRequest, grpcInvalidArgument},
+	{ErrAccountIDTooLong, CodeAccountIDTooLong, http.StatusUnprocessableEntity, grpcFailedPrecondition},
+	{ErrSnapshotStoreRequired, CodeSnapshotStoreRequired, http.StatusInternalServerError, grpcFailedPrecondition},
+	{ErrSnapshotInFuture, CodeSnapshotInFuture, http.StatusBadRequest, grpcInvalidArgument},
+	{ErrAllowanceNotFound, CodeAllowanceNotFound, http.StatusNotFound, grpcNotFound},
+	{ErrAllowanceExceeded, CodeAllowanceExceeded, http.StatusConflict, grpcFailedPrecondition},
+	{ErrInvalidDelegate, CodeInvalidDelegate, http.StatusBadRequest, grpcInvalidArgument},
+}
+
+// internalErrorCode describes errors not listed in errorCodes
+var internalErrorCode = errorCode{code: CodeInternal, httpStatus: http.StatusInternalServerError, grpcCode: grpcInternal}
+
+// lookupErrorCode returns the description of the code of an error
+func lookupErrorCode(err error) errorCode {
+	var walletErr *WalletError
+	if errors.As(err, &walletErr) {
+		err = walletErr.Err
+	}
+
+	for _, known := range errorCodes {
+		if errors.Is(err, known.err) {
+			return known
+		}
+	}
+	return internalErrorCode
+}
+
+// WalletError is the error returned by wallet operations. It identifies the cause with a stable code
+// and names the wallet or transaction concerned. errors.Is matches it against the sentinel errors of
+// this package, and errors.As exposes underlying store errors.
+type WalletError struct {
+	Code          ErrorCode
+	WalletID      string // Wallet the error concerns, if any
+	TransactionID string // Transaction the error concerns, if any
+	Err           error  // Sentinel error of this package or underlying store error
+}
+
+// newWalletError wraps an error of an operation on a wallet. Nil errors and errors that are already
+// wallet errors are returned unchanged.
+func newWalletError(err error, walletID string) error {
+	return newTransactionError(err, "", walletID)
+}
+
+// newTransactionError wraps an error of an operation on a transaction. Nil errors and errors that are
+// already wallet errors are returned unchanged.
+func newTransactionError(err error, transactionID string, walletID string) error {
+	if err == nil {
+		return nil
+	}
+
+	var walletErr *WalletError
+	if errors.As(err, &walletErr) {
+		return err
+	}
+
+	return &WalletError{
+		Code:          lookupErrorCode(err).code,
+		WalletID:      walletID,
+		TransactionID: transactionID,
+		Err:           err,
+	}
+}
+
+// Error returns the message of the underlying error prefixed with the wallet or transaction concerned
+func (e *WalletError) Error() string {
+	switch {
+	case e.TransactionID != "":
+		return "transaction " + e.TransactionID + ": " + e.Err.Error()
+	case e.WalletID != "":
+		return "wallet " + e.WalletID + ": " + e.Err.Error()
+	default:
+		return e.Err.Error()
+	}
+}
+
+// Unwrap returns the underlying error
+func (e *WalletError) Unwrap() error {
+	return e.Err
+}
+
+// HTTPStatus returns the HTTP status code matching the error code
+func (e *WalletError) HTTPStatus() int {
+	return lookupErrorCode(e).httpStatus
+}
+
+// GRPCCode returns the gRPC status code matching the error code, convertible with codes.Code
+func (e *WalletError) GRPCCode() uint32 {
+	return lookupErrorCode(e).grpcCode
+}
+
+// ErrorCodeOf returns the code of an error, CodeInternal for unknown errors and an empty code for nil
+func ErrorCodeOf(err error) ErrorCode {
+	if err == nil {
+		return ""
+	}
+
+	var walletErr *WalletError
+	if errors.As(err, &walletErr) {
+		return walletErr.Code
+	}
+	return lookupErrorCode(err).code
+}
+
+// HTTPStatus returns the HTTP status code for an error, http.StatusOK for nil
+func HTTPStatus(err error) int {
+	if err == nil {
+		return http.StatusOK
+	}
+	return lookupErrorCode(err).httpStatus
+}
+
+// GRPCCode returns the gRPC status code for an error, 0 (OK) for nil
+func GRPCCode(err error) uint32 {
+	if err == nil {
+		return 0
+	}
+	return lookupErrorCode(err).grpcCode
+}
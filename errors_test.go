@@ -0,0 +1,108 @@
+package wallethub
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestWalletError tests the codes, messages and statuses of wallet errors
+func TestWalletError(t *testing.T) {
+	err := newWalletError(ErrWalletFrozen, "wallet-1")
+	assert.Equal(t, "wallet wallet-1: wallet is frozen", err.Error())
+	assert.ErrorIs(t, err, ErrWalletFrozen)
+	assert.Equal(t, CodeWalletFrozen, ErrorCodeOf(err))
+	assert.Equal(t, http.StatusConflict, HTTPStatus(err))
+	assert.Equal(t, uint32(9), GRPCCode(err))
+
+	var walletErr *WalletError
+	require.ErrorAs(t, err, &walletErr)
+	assert.Equal(t, "wallet-1", walletErr.WalletID)
+	assert.Equal(t, http.StatusConflict, walletErr.HTTPStatus())
+	assert.Equal(t, uint32(9), walletErr.GRPCCode())
+
+	// Transaction errors name the transaction
+	err = newTransactionError(ErrPendingTransactionOnly, "txn-1", "wallet-1")
+	assert.Equal(t, "transaction txn-1: only pending transactions can be modified", err.Error())
+	require.ErrorAs(t, err, &walletErr)
+	assert.Equal(t, "wallet-1", walletErr.WalletID)
+	assert.Equal(t, CodeNotPending, walletErr.Code)
+
+	// Wallet errors are not wrapped again
+	assert.Same(t, err, newWalletError(err, "wallet-2"))
+	wrapped := fmt.Errorf("retry: %w", err)
+	assert.Equal(t, wrapped, newWalletError(wrapped, "wallet-2"))
+	assert.NoError(t, newWalletError(nil, "wallet-1"))
+
+	// Unknown errors are internal errors and remain reachable
+	storeErr := errors.New("database is locked")
+	err = newWalletError(storeErr, "wallet-1")
+	assert.ErrorIs(t, err, storeErr)
+	assert.Equal(t, CodeInternal, ErrorCodeOf(err))
+	assert.Equal(t, http.StatusInternalServerError, HTTPStatus(err))
+	assert.Equal(t, uint32(13), GRPCCode(err))
+
+	// Context errors keep their own codes
+	err = newWalletError(fmt.Errorf("query: %w", context.DeadlineExceeded), "wallet-1")
+	assert.Equal(t, CodeDeadlineExceeded, ErrorCodeOf(err))
+	assert.Equal(t, http.StatusGatewayTimeout, HTTPStatus(err))
+
+	assert.Equal(t, ErrorCode(""), ErrorCodeOf(nil))
+	assert.Equal(t, http.StatusOK, HTTPStatus(nil))
+	assert.Equal(t, uint32(0), GRPCCode(nil))
+}
+
+// TestWalletManagerErrors tests that manager operations return wallet errors naming the wallet concerned
+func TestWalletManagerErrors(t *testing.T) {
+	store := setupTestGormWalletStore(t)
+	manager := NewWalletManager(WithStore(store))
+	ctx := context.Background()
+
+	wallet1, err := manager.CreateWallet(ctx, "user-1", "Wallet 1", "", "")
+	require.NoError(t, err)
+	wallet2, err := manager.CreateWallet(ctx, "user-2", "Wallet 2", "", "")
+	require.NoError(t, err)
+	_, err = manager.Credit(ctx, wallet1.ID, 1000, "Deposit", "", "", nil)
+	require.NoError(t, err)
+
+	var walletErr *WalletError
+
+	_, err = manager.Debit(ctx, wallet2.ID, 100, "Purchase", "", "", nil)
+	require.ErrorAs(t, err, &walletErr)
+	assert.Equal(t, CodeInsufficientBalance, walletErr.Code)
+	assert.Equal(t, wallet2.ID, walletErr.WalletID)
+
+	// Transfers name the end of the transfer that failed
+	require.NoError(t, manager.FreezeWallet(ctx, wallet2.ID, "Investigation"))
+	err = manager.Transfer(ctx, wallet1.ID, wallet2.ID, 100, "Transfer", "", nil)
+	require.ErrorAs(t, err, &walletErr)
+	assert.Equal(t, CodeWalletFrozen, walletErr.Code)
+	assert.Equal(t, wallet2.ID, walletErr.WalletID)
+
+	err = manager.Transfer(ctx, wallet1.ID, wallet2.ID, 0, "Transfer", "", nil)
+	require.ErrorAs(t, err, &walletErr)
+	assert.Equal(t, CodeInvalidAmount, walletErr.Code)
+	assert.Equal(t, http.StatusBadRequest, HTTPStatus(err))
+
+	// Transaction operations name the transaction and its wallet
+	transactions, err := manager.ListTransactions(ctx, wallet1.ID, 10, 0)
+	require.NoError(t, err)
+	require.NotEmpty(t, transactions)
+
+	err = manager.CancelTransaction(ctx, transactions[0].ID, "Duplicate")
+	require.ErrorAs(t, err, &walletErr)
+	assert.Equal(t, CodeNotPending, walletErr.Code)
+	assert.Equal(t, transactions[0].ID, walletErr.TransactionID)
+	assert.Equal(t, wallet1.ID, walletErr.WalletID)
+
+	err = manager.CompleteTransaction(ctx, "non-existent-id")
+	require.ErrorAs(t, err, &walletErr)
+	assert.Equal(t, CodeTransactionNotFound, walletErr.Code)
+	assert.Equal(t, http.StatusNotFound, walletErr.HTTPStatus())
+	assert.Equal(t, uint32(5), walletErr.GRPCCode())
+}
@@ -0,0 +1,626 @@
+package wallethub
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"strconv"
+	"time"
+)
+
+// Export and import error definitions
+var (
+	ErrUnsupportedDataFormat = errors.New("unsupported data format")
+	ErrInvalidImportRecord   = errors.New("invalid import record")
+	ErrImportInconsistent    = errors.New("imported history is inconsistent with the wallet balance")
+)
+
+// DataFormat defines the formats wallets and transactions can be exported and imported in
+type DataFormat string
+
+const (
+	// DataFormatNDJSON writes one JSON object per line
+	DataFormatNDJSON DataFormat = "ndjson"
+	// DataFormatCSV writes a header row followed by one row per record
+	DataFormatCSV DataFormat = "csv"
+)
+
+// walletCSVHeader lists the columns of exported wallets
+var walletCSVHeader = []string{
+	"id", "user_id", "name", "description", "reference", "balance", "primary", "active", "frozen",
+	"freeze_mode", "freeze_reason", "frozen_amount", "frozen_amount_reason", "risk_flagged", "risk_reason", "closed_at", "created_at", "updated_at", "buckets",
+}
+
+// transactionCSVHeader lists the columns of exported transactions
+var transactionCSVHeader = []string{
+	"id", "wallet_id", "type", "amount", "balance", "description", "note", "reference", "status", "data",
+	"created_at", "completed_at", "failed_reason", "chain_sequence", "prev_hash", "hash", "buckets", "delegate_id",
+}
+
+// ExportWallets writes every wallet in the given format, reading them page by page from the last wallet
+// read, and returns the number of wallets written.
+func (m *DefaultWalletManager) ExportWallets(ctx context.Context, w io.Writer, format DataFormat) (int, error) {
+	write, flush, err := newRecordWriter(w, format, walletCSVHeader)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	afterID := ""
+	for {
+		wallets, err := m.store.FindWalletsAfter(ctx, afterID, ledgerPageSize)
+		if err != nil {
+			return count, err
+		}
+
+		for i := range wallets {
+			if err := write(&wallets[i], walletToCSV(&wallets[i])); err != nil {
+				return count, err
+			}
+			count++
+		}
+
+		if len(wallets) < ledgerPageSize {
+			break
+		}
+		afterID = wallets[len(wallets)-1].ID
+	}
+
+	return count, flush()
+}
+
+// ExportTransactions writes every transaction in the given format, grouped by wallet and each wallet's
+// in the order they were applied, and returns the number of transactions written. The output can be
+// fed to ImportTransactions as is.
+func (m *DefaultWalletManager) ExportTransactions(ctx context.Context, w io.Writer, format DataFormat) (int, error) {
+	write, flush, err := newRecordWriter(w, format, transactionCSVHeader)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	var after *Transaction
+	for {
+		transactions, err := m.store.FindTransactionsAfter(ctx, after, ledgerPageSize)
+		if err != nil {
+			return count, err
+		}
+
+		for i := range transactions {
+			row, err := transactionToCSV(&transactions[i])
+			if err != nil {
+				return count, err
+			}
+			if err := write(&transactions[i], row); err != nil {
+				return count, err
+			}
+			count++
+		}
+
+		if len(transactions) < ledgerPageSize {
+			break
+		}
+		after = &transactions[len(transactions)-1]
+	}
+
+	return count, flush()
+}
+
+// Import reads wallets and their transactions in the given format and saves them with their IDs,
+// balances, timestamps and hash chain fields preserved. It returns the number of wallets and
+// transactions imported. Wallets that already exist are rejected.
+//
+// Both inputs must be ordered by wallet ID, and transactions must be in the order they were applied
+// within each wallet, as written by ExportWallets and ExportTransactions. Every wallet is saved together
+// with its transactions in a single store transaction and is rejected with ErrImportInconsistent unless
+// the running balance recorded on each completed transaction follows from the history and the final
+// balance matches the wallet balance, so a wallet without transactions must have a zero balance.
+// Wallets imported before a rejected one are kept, each consistent with its history.
+func (m *DefaultWalletManager) Import(ctx context.Context, wallets io.Reader, transactions io.Reader, format DataFormat) (walletCount int, transactionCount int, err error) {
+	walletRecords, err := readRecords(wallets, format, walletCSVHeader, csvToWallet)
+	if err != nil {
+		return 0, 0, err
+	}
+	transactionRecords, err := readRecords(transactions, format, transactionCSVHeader, csvToTransaction)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	next, stop := iter.Pull2(transactionRecords)
+	defer stop()
+
+	// read returns the next transaction, or nil at the end of the input
+	read := func() (*Transaction, error) {
+		transaction, err, ok := next()
+		if !ok {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		return &transaction, nil
+	}
+
+	current, err := read()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	previousID := ""
+	for wallet, err := range walletRecords {
+		if err != nil {
+			return walletCount, transactionCount, err
+		}
+		if walletCount > 0 && wallet.ID <= previousID {
+			return walletCount, transactionCount, fmt.Errorf("%w: wallet %s is not ordered by ID", ErrInvalidImportRecord, wallet.ID)
+		}
+		previousID = wallet.ID
+
+		// Transactions of wallets before this one have no wallet
+		if current != nil && current.WalletID < wallet.ID {
+			return walletCount, transactionCount, fmt.Errorf("%w: transaction %s belongs to unknown wallet %s", ErrInvalidImportRecord, current.ID, current.WalletID)
+		}
+
+		var imported int
+		imported, current, err = m.importWallet(ctx, &wallet, current, read)
+		if err != nil {
+			return walletCount, transactionCount, err
+		}
+		walletCount++
+		transactionCount += imported
+	}
+
+	if current != nil {
+		return walletCount, transactionCount, fmt.Errorf("%w: transaction %s belongs to unknown wallet %s", ErrInvalidImportRecord, current.ID, current.WalletID)
+	}
+
+	return walletCount, transactionCount, nil
+}
+
+// importWallet saves a wallet together with its transactions, which start at current if it belongs to
+// the wallet, and returns the number of transactions saved and the first transaction of the next wallet
+func (m *DefaultWalletManager) importWallet(ctx context.Context, wallet *Wallet, current *Transaction, read func() (*Transaction, error)) (int, *Transaction, error) {
+	walletID := wallet.ID
+
+	txn := m.store.Begin(ctx)
+	defer txn.Rollback()
+
+	if err := txn.SaveWallet(wallet); err != nil {
+		return 0, nil, err
+	}
+
+	count := 0
+	batch := make([]Transaction, 0, transactionInsertBatchSize)
+	saveBatch := func() error {
+		if err := txn.SaveTransactions(batch); err != nil {
+			return err
+		}
+		count += len(batch)
+		batch = batch[:0]
+		return nil
+	}
+
+	// Feed the wallet's completed transactions to the ledger walk while saving all of them
+	var following *Transaction
+	fetch := func(limit int, offset int) ([]Transaction, error) {
+		page := make([]Transaction, 0, limit)
+		for current != nil && len(page) < limit {
+			if current.WalletID != walletID {
+				following, current = current, nil
+				break
+			}
+
+			batch = append(batch, *current)
+			if len(batch) == transactionInsertBatchSize {
+				if err := saveBatch(); err != nil {
+					return nil, err
+				}
+			}
+			if current.Status == TransactionStatusCompleted {
+				page = append(page, *current)
+			}
+
+			var err error
+			if current, err = read(); err != nil {
+				return nil, err
+			}
+		}
+		return page, nil
+	}
+
+	balance, err := walkLedger(0, fetch, func(transaction *Transaction, balance int64) error {
+		if transaction.Balance != balance {
+			return fmt.Errorf("%w: transaction %s of wallet %s records balance %d, expected %d", ErrImportInconsistent, transaction.ID, walletID, transaction.Balance, balance)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if balance != wallet.Balance {
+		return 0, nil, fmt.Errorf("%w: wallet %s has balance %d, its history sums up to %d", ErrImportInconsistent, walletID, wallet.Balance, balance)
+	}
+
+	if len(batch) > 0 {
+		if err := saveBatch(); err != nil {
+			return 0, nil, err
+		}
+	}
+
+	if err := txn.Commit(); err != nil {
+		return 0, nil, err
+	}
+
+	return count, following, nil
+}
+
+// newRecordWriter returns a function writing one record in the given format and a function flushing
+// buffered output. NDJSON writes value, CSV writes row after the header.
+func newRecordWriter(w io.Writer, format DataFormat, header []string) (func(value any, row []string) error, func() error, error) {
+	switch format {
+	case DataFormatNDJSON:
+		encoder := json.NewEncoder(w)
+		write := func(value any, row []string) error {
+			return encoder.Encode(value)
+		}
+		return write, func() error { return nil }, nil
+
+	case DataFormatCSV:
+		writer := csv.NewWriter(w)
+		if err := writer.Write(header); err != nil {
+			return nil, nil, err
+		}
+		write := func(value any, row []string) error {
+			return writer.Write(row)
+		}
+		flush := func() error {
+			writer.Flush()
+			return writer.Error()
+		}
+		return write, flush, nil
+
+	default:
+		return nil, nil, ErrUnsupportedDataFormat
+	}
+}
+
+// importRecord is implemented by the record types that can be imported
+type importRecord interface {
+	Wallet | Transaction
+}
+
+// readRecords returns the records of the input in the given format, validated and one at a time
+func readRecords[T importRecord](r io.Reader, format DataFormat, header []string, fromCSV func(row []string) (T, error)) (iter.Seq2[T, error], error) {
+	switch format {
+	case DataFormatNDJSON:
+		return readNDJSON[T](r), nil
+	case DataFormatCSV:
+		return readCSV(r, header, fromCSV), nil
+	default:
+		return nil, ErrUnsupportedDataFormat
+	}
+}
+
+// readNDJSON decodes one record per non-empty line. Numbers in the Data map are kept as json.Number
+// so large integers survive the round trip.
+func readNDJSON[T importRecord](r io.Reader) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		reader := bufio.NewReader(r)
+		for line := 1; ; line++ {
+			content, err := reader.ReadBytes('\n')
+			if err != nil && err != io.EOF {
+				var zero T
+				yield(zero, err)
+				return
+			}
+
+			if content = bytes.TrimSpace(content); len(content) > 0 {
+				var record T
+				decoder := json.NewDecoder(bytes.NewReader(content))
+				decoder.UseNumber()
+				decoder.DisallowUnknownFields()
+
+				decodeErr := decoder.Decode(&record)
+				if decodeErr != nil {
+					decodeErr = fmt.Errorf("%w: line %d: %v", ErrInvalidImportRecord, line, decodeErr)
+				} else {
+					decodeErr = validateImportRecord(&record, line)
+				}
+				if !yield(record, decodeErr) || decodeErr != nil {
+					return
+				}
+			}
+
+			if err == io.EOF {
+				return
+			}
+		}
+	}
+}
+
+// readCSV decodes one record per row after verifying the header row
+func readCSV[T importRecord](r io.Reader, header []string, fromCSV func(row []string) (T, error)) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+		reader := csv.NewReader(r)
+		reader.FieldsPerRecord = len(header)
+
+		columns, err := reader.Read()
+		if err == io.EOF {
+			return
+		}
+		if err != nil {
+			yield(zero, fmt.Errorf("%w: %v", ErrInvalidImportRecord, err))
+			return
+		}
+		for i := range header {
+			if columns[i] != header[i] {
+				yield(zero, fmt.Errorf("%w: unexpected column %q, expected %q", ErrInvalidImportRecord, columns[i], header[i]))
+				return
+			}
+		}
+
+		for line := 2; ; line++ {
+			row, err := reader.Read()
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				yield(zero, fmt.Errorf("%w: %v", ErrInvalidImportRecord, err))
+				return
+			}
+
+			record, err := fromCSV(row)
+			if err != nil {
+				err = fmt.Errorf("%w: line %d: %v", ErrInvalidImportRecord, line, err)
+			} else {
+				err = validateImportRecord(&record, line)
+			}
+			if !yield(record, err) || err != nil {
+				return
+			}
+		}
+	}
+}
+
+// validateImportRecord checks the fields every imported record needs
+func validateImportRecord[T importRecord](record *T, line int) error {
+	var problem string
+
+	switch record := any(record).(type) {
+	case *Wallet:
+		switch {
+		case record.ID == "":
+			problem = "wallet ID is required"
+		case record.UserID == "":
+			problem = "user ID is required"
+		case record.CreatedAt.IsZero():
+			problem = "creation time is required"
+		}
+
+	case *Transaction:
+		switch {
+		case record.ID == "":
+			problem = "transaction ID is required"
+		case record.WalletID == "":
+			problem = "wallet ID is required"
+		case record.Type != TransactionTypeCredit && record.Type != TransactionTypeDebit:
+			problem = fmt.Sprintf("unknown transaction type %q", record.Type)
+		case record.Amount <= 0:
+			problem = "amount must be positive"
+		case record.Status != TransactionStatusPending && record.Status != TransactionStatusCompleted &&
+			record.Status != TransactionStatusFailed && record.Status != TransactionStatusCancelled:
+			problem = fmt.Sprintf("unknown transaction status %q", record.Status)
+		case record.CreatedAt.IsZero():
+			problem = "creation time is required"
+		case record.Status == TransactionStatusCompleted && record.CompletedAt.IsZero():
+			problem = "completion time is required for completed transactions"
+		}
+	}
+
+	if problem != "" {
+		return fmt.Errorf("%w: line %d: %s", ErrInvalidImportRecord, line, problem)
+	}
+	return nil
+}
+
+// walletToCSV converts a wallet to a CSV row
+func walletToCSV(wallet *Wallet) []string {
+	return []string{
+		wallet.ID,
+		wallet.UserID,
+		wallet.Name,
+		wallet.Description,
+		wallet.Reference,
+		strconv.FormatInt(wallet.Balance, 10),
+		strconv.FormatBool(wallet.Primary),
+		strconv.FormatBool(wallet.Active),
+		strconv.FormatBool(wallet.Frozen),
+		string(wallet.FreezeMode),
+		wallet.FreezeReason,
+		strconv.FormatInt(wallet.FrozenAmount, 10),
+		wallet.FrozenAmountReason,
+		strconv.FormatBool(wallet.RiskFlagged),
+		wallet.RiskReason,
+		formatCSVTime(wallet.ClosedAt),
+		formatCSVTime(wallet.CreatedAt),
+		formatCSVTime(wallet.UpdatedAt),
+		formatCSVBuckets(wallet.Buckets),
+	}
+}
+
+// csvToWallet converts a CSV row to a wallet
+func csvToWallet(row []string) (Wallet, error) {
+	p := csvParser{}
+	wallet := Wallet{
+		ID:                 row[0],
+		UserID:             row[1],
+		Name:               row[2],
+		Description:        row[3],
+		Reference:          row[4],
+		Balance:            p.int(row[5]),
+		Primary:            p.bool(row[6]),
+		Active:             p.bool(row[7]),
+		Frozen:             p.bool(row[8]),
+		FreezeMode:         FreezeMode(row[9]),
+		FreezeReason:       row[10],
+		FrozenAmount:       p.int(row[11]),
+		FrozenAmountReason: row[12],
+		RiskFlagged:        p.bool(row[13]),
+		RiskReason:         row[14],
+		ClosedAt:           p.time(row[15]),
+		CreatedAt:          p.time(row[16]),
+		UpdatedAt:          p.time(row[17]),
+		Buckets:            p.buckets(row[18]),
+	}
+	return wallet, p.err
+}
+
+// transactionToCSV converts a transaction to a CSV row, with the Data map as a JSON object
+func transactionToCSV(transaction *Transaction) ([]string, error) {
+	var data []byte
+	if transaction.Data != nil {
+		var err error
+		if data, err = json.Marshal(transaction.Data); err != nil {
+			return nil, err
+		}
+	}
+
+	var sequence string
+	if transaction.ChainSequence > 0 {
+		sequence = strconv.FormatInt(transaction.ChainSequence, 10)
+	}
+
+	return []string{
+		transaction.ID,
+		transaction.WalletID,
+		string(transaction.Type),
+		strconv.FormatInt(transaction.Amount, 10),
+		strconv.FormatInt(transaction.Balance, 10),
+		transaction.Description,
+		transaction.Note,
+		transaction.Reference,
+		string(transaction.Status),
+		string(data),
+		formatCSVTime(transaction.CreatedAt),
+		formatCSVTime(transaction.CompletedAt),
+		transaction.FailedReason,
+		sequence,
+		transaction.PrevHash,
+		transaction.Hash,
+		formatCSVBuckets(transaction.Buckets),
+		transaction.DelegateID,
+	}, nil
+}
+
+// csvToTransaction converts a CSV row to a transaction
+func csvToTransaction(row []string) (Transaction, error) {
+	p := csvParser{}
+	transaction := Transaction{
+		ID:            row[0],
+		WalletID:      row[1],
+		Type:          TransactionType(row[2]),
+		Amount:        p.int(row[3]),
+		Balance:       p.int(row[4]),
+		Description:   row[5],
+		Note:          row[6],
+		Reference:     row[7],
+		Status:        TransactionStatus(row[8]),
+		Data:          p.data(row[9]),
+		CreatedAt:     p.time(row[10]),
+		CompletedAt:   p.time(row[11]),
+		FailedReason:  row[12],
+		ChainSequence: p.int(row[13]),
+		PrevHash:      row[14],
+		Hash:          row[15],
+		Buckets:       p.buckets(row[16]),
+		DelegateID:    row[17],
+	}
+	return transaction, p.err
+}
+
+// formatCSVTime formats a time losslessly, leaving zero times empty
+func formatCSVTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339Nano)
+}
+
+// formatCSVBuckets formats bucket amounts as a JSON object, leaving no buckets empty
+func formatCSVBuckets(buckets map[string]int64) string {
+	if len(buckets) == 0 {
+		return ""
+	}
+	encoded, _ := json.Marshal(buckets) // A map of integers always encodes
+	return string(encoded)
+}
+
+// csvParser parses CSV fields and keeps the first error
+type csvParser struct {
+	err error
+}
+
+func (p *csvParser) int(value string) int64 {
+	if value == "" || p.err != nil {
+		return 0
+	}
+	parsed, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		p.err = err
+	}
+	return parsed
+}
+
+func (p *csvParser) bool(value string) bool {
+	if p.err != nil {
+		return false
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		p.err = err
+	}
+	return parsed
+}
+
+func (p *csvParser) time(value string) time.Time {
+	if value == "" || p.err != nil {
+		return time.Time{}
+	}
+	parsed, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		p.err = err
+	}
+	return parsed
+}
+
+func (p *csvParser) data(value string) map[string]interface{} {
+	if value == "" || p.err != nil {
+		return nil
+	}
+	var data map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader([]byte(value)))
+	decoder.UseNumber()
+	if err := decoder.Decode(&data); err != nil {
+		p.err = err
+	}
+	return data
+}
+
+func (p *csvParser) buckets(value string) map[string]int64 {
+	if value == "" || p.err != nil {
+		return nil
+	}
+	var buckets map[string]int64
+	if err := json.Unmarshal([]byte(value), &buckets); err != nil {
+		p.err = err
+	}
+	return buckets
+}
@@ -0,0 +1,202 @@
+package wallethub
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupTestExport creates wallets with completed, pending and failed transactions to export
+func setupTestExport(t *testing.T) *DefaultWalletManager {
+	store := setupTestGormWalletStore(t)
+	manager := NewWalletManager(WithStore(store))
+	ctx := context.Background()
+
+	wallet1, err := manager.CreateWallet(ctx, "user-1", "Wallet, \"One\"", "Line one\nline two", "ref-1")
+	require.NoError(t, err)
+	wallet2, err := manager.CreateWallet(ctx, "user-2", "Wallet 2", "Description 2", "ref-2")
+	require.NoError(t, err)
+	_, err = manager.CreateWallet(ctx, "user-3", "Empty Wallet", "Description 3", "ref-3")
+	require.NoError(t, err)
+
+	_, err = manager.Credit(ctx, wallet1.ID, 1000, "Credit", "Note", "credit-ref", map[string]interface{}{"order": "A-1", "big": int64(9007199254740993), "nested": map[string]interface{}{"rate": 1.5}})
+	require.NoError(t, err)
+	_, err = manager.Debit(ctx, wallet1.ID, 300, "Debit", "Note", "debit-ref", nil)
+	require.NoError(t, err)
+	err = manager.Transfer(ctx, wallet1.ID, wallet2.ID, 200, "Transfer", "Note", nil)
+	require.NoError(t, err)
+
+	pending := &Transaction{
+		ID:        GenerateID(),
+		WalletID:  wallet2.ID,
+		Type:      TransactionTypeCredit,
+		Amount:    50,
+		Status:    TransactionStatusPending,
+		CreatedAt: time.Now(),
+	}
+	require.NoError(t, store.SaveTransaction(ctx, pending))
+
+	failed := &Transaction{
+		ID:           GenerateID(),
+		WalletID:     wallet2.ID,
+		Type:         TransactionTypeDebit,
+		Amount:       70,
+		Status:       TransactionStatusFailed,
+		FailedReason: "declined",
+		CreatedAt:    time.Now(),
+	}
+	require.NoError(t, store.SaveTransaction(ctx, failed))
+
+	return manager
+}
+
+// TestExportImportRoundTrip tests that exporting and importing preserves all data in every format
+func TestExportImportRoundTrip(t *testing.T) {
+	for _, format := range []DataFormat{DataFormatNDJSON, DataFormatCSV} {
+		t.Run(string(format), func(t *testing.T) {
+			source := setupTestExport(t)
+			target := NewWalletManager(WithStore(setupTestGormWalletStore(t)), WithBulkChunkSize(2))
+			ctx := context.Background()
+
+			var wallets, transactions bytes.Buffer
+			count, err := source.ExportWallets(ctx, &wallets, format)
+			require.NoError(t, err)
+			assert.Equal(t, 3, count)
+
+			count, err = source.ExportTransactions(ctx, &transactions, format)
+			require.NoError(t, err)
+			assert.Equal(t, 6, count)
+
+			walletCount, transactionCount, err := target.Import(ctx, &wallets, &transactions, format)
+			require.NoError(t, err)
+			assert.Equal(t, 3, walletCount)
+			assert.Equal(t, 6, transactionCount)
+
+			sourceWallets, err := source.store.FindWallets(ctx, 10, 0)
+			require.NoError(t, err)
+			targetWallets, err := target.store.FindWallets(ctx, 10, 0)
+			require.NoError(t, err)
+			require.Len(t, targetWallets, len(sourceWallets))
+			for i := range sourceWallets {
+				assert.Equal(t, sourceWallets[i].ID, targetWallets[i].ID)
+				assert.Equal(t, sourceWallets[i].Name, targetWallets[i].Name)
+				assert.Equal(t, sourceWallets[i].Description, targetWallets[i].Description)
+				assert.Equal(t, sourceWallets[i].Balance, targetWallets[i].Balance)
+				assert.True(t, sourceWallets[i].CreatedAt.Equal(targetWallets[i].CreatedAt))
+				assert.True(t, sourceWallets[i].UpdatedAt.Equal(targetWallets[i].UpdatedAt))
+			}
+
+			sourceTransactions, err := source.store.FindTransactionsAfter(ctx, nil, 10)
+			require.NoError(t, err)
+			targetTransactions, err := target.store.FindTransactionsAfter(ctx, nil, 10)
+			require.NoError(t, err)
+			require.Len(t, targetTransactions, len(sourceTransactions))
+			for i := range sourceTransactions {
+				expected, actual := sourceTransactions[i], targetTransactions[i]
+				assert.Equal(t, expected.ID, actual.ID)
+				assert.Equal(t, expected.Status, actual.Status)
+				assert.Equal(t, expected.Balance, actual.Balance)
+				assert.Equal(t, expected.FailedReason, actual.FailedReason)
+				assert.Equal(t, expected.Hash, actual.Hash)
+				assert.Equal(t, expected.ChainSequence, actual.ChainSequence)
+				assert.True(t, expected.CreatedAt.Equal(actual.CreatedAt))
+				assert.True(t, expected.CompletedAt.Equal(actual.CompletedAt))
+				assert.Equal(t, expected.Data, actual.Data)
+			}
+
+			// The imported history is intact
+			report, err := target.Reconcile(ctx, false)
+			require.NoError(t, err)
+			assert.True(t, report.Consistent())
+
+			for _, wallet := range targetWallets {
+				verification, err := target.VerifyHashChain(ctx, wallet.ID)
+				require.NoError(t, err)
+				assert.True(t, verification.Valid())
+			}
+		})
+	}
+}
+
+// TestImportInconsistent tests that wallets are rejected together with their history if the two disagree
+func TestImportInconsistent(t *testing.T) {
+	source := setupTestExport(t)
+	ctx := context.Background()
+
+	var wallets, transactions bytes.Buffer
+	_, err := source.ExportWallets(ctx, &wallets, DataFormatNDJSON)
+	require.NoError(t, err)
+	_, err = source.ExportTransactions(ctx, &transactions, DataFormatNDJSON)
+	require.NoError(t, err)
+
+	// Drop the debit of the first wallet, so its history no longer adds up
+	var kept []string
+	for _, line := range strings.Split(strings.TrimSpace(transactions.String()), "\n") {
+		if !strings.Contains(line, `"reference":"debit-ref"`) {
+			kept = append(kept, line)
+		}
+	}
+	require.Len(t, kept, 5)
+
+	target := NewWalletManager(WithStore(setupTestGormWalletStore(t)))
+	_, _, err = target.Import(ctx, bytes.NewReader(wallets.Bytes()), strings.NewReader(strings.Join(kept, "\n")), DataFormatNDJSON)
+	assert.True(t, errors.Is(err, ErrImportInconsistent))
+
+	// Neither the rejected wallet nor its history was saved
+	wallet, err := source.store.FindWalletByUserIDAndReference(ctx, "user-1", "ref-1")
+	require.NoError(t, err)
+	imported, err := target.store.FindWallet(ctx, wallet.ID)
+	require.NoError(t, err)
+	assert.Nil(t, imported)
+	history, err := target.store.FindTransactionsByWalletID(ctx, wallet.ID, 10, 0)
+	require.NoError(t, err)
+	assert.Empty(t, history)
+
+	// Wallets without history must have a zero balance
+	target = NewWalletManager(WithStore(setupTestGormWalletStore(t)))
+	_, _, err = target.Import(ctx, strings.NewReader(`{"id":"wallet-1","user_id":"user-1","balance":100,"created_at":"2025-01-01T00:00:00Z"}`), strings.NewReader(""), DataFormatNDJSON)
+	assert.True(t, errors.Is(err, ErrImportInconsistent))
+	imported, err = target.store.FindWallet(ctx, "wallet-1")
+	require.NoError(t, err)
+	assert.Nil(t, imported)
+
+	// Transactions of unknown wallets are rejected
+	target = NewWalletManager(WithStore(setupTestGormWalletStore(t)))
+	_, _, err = target.Import(ctx, strings.NewReader(""), strings.NewReader(kept[0]), DataFormatNDJSON)
+	assert.True(t, errors.Is(err, ErrInvalidImportRecord))
+
+	// Wallets must be ordered by ID
+	target = NewWalletManager(WithStore(setupTestGormWalletStore(t)))
+	_, _, err = target.Import(ctx, strings.NewReader(`{"id":"wallet-2","user_id":"user-1","created_at":"2025-01-01T00:00:00Z"}`+"\n"+`{"id":"wallet-1","user_id":"user-1","created_at":"2025-01-01T00:00:00Z"}`), strings.NewReader(""), DataFormatNDJSON)
+	assert.True(t, errors.Is(err, ErrInvalidImportRecord))
+}
+
+// TestImportInvalidRecords tests validation of malformed input
+func TestImportInvalidRecords(t *testing.T) {
+	manager := NewWalletManager(WithStore(setupTestGormWalletStore(t)))
+	ctx := context.Background()
+
+	_, _, err := manager.Import(ctx, strings.NewReader(`{"id":"wallet-1","user_id":"user-1","created_at":"2025-01-01T00:00:00Z"}`+"\n"+`{"id":"wallet-2"}`), strings.NewReader(""), DataFormatNDJSON)
+	assert.True(t, errors.Is(err, ErrInvalidImportRecord))
+	assert.Contains(t, err.Error(), "line 2")
+
+	_, _, err = manager.Import(ctx, strings.NewReader(`{"id":"wallet-1","unknown":true}`), strings.NewReader(""), DataFormatNDJSON)
+	assert.True(t, errors.Is(err, ErrInvalidImportRecord))
+
+	_, _, err = manager.Import(ctx, strings.NewReader("id,name\nwallet-1,Wallet\n"), strings.NewReader(strings.Join(transactionCSVHeader, ",")+"\n"), DataFormatCSV)
+	assert.True(t, errors.Is(err, ErrInvalidImportRecord))
+
+	header := strings.Join(transactionCSVHeader, ",")
+	_, _, err = manager.Import(ctx, strings.NewReader(strings.Join(walletCSVHeader, ",")+"\n"), strings.NewReader(header+"\ntx-1,wallet-1,refund,100,100,,,,completed,,2025-01-01T00:00:00Z,2025-01-01T00:00:00Z,,,,,,\n"), DataFormatCSV)
+	assert.True(t, errors.Is(err, ErrInvalidImportRecord))
+	assert.Contains(t, err.Error(), "unknown transaction type")
+
+	_, _, err = manager.Import(ctx, strings.NewReader(""), strings.NewReader(""), DataFormat("xml"))
+	assert.ErrorIs(t, err, ErrUnsupportedDataFormat)
+}
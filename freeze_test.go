@@ -0,0 +1,196 @@
+package wallethub
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupTestFreeze creates a manager with two funded wallets
+func setupTestFreeze(t *testing.T) (*DefaultWalletManager, *GormWalletStore, *Wallet, *Wallet) {
+	store := setupTestGormWalletStore(t)
+	manager := NewWalletManager(WithStore(store))
+	ctx := context.Background()
+
+	wallet1, err := manager.CreateWallet(ctx, "user-1", "Wallet 1", "", "")
+	require.NoError(t, err)
+	wallet2, err := manager.CreateWallet(ctx, "user-2", "Wallet 2", "", "")
+	require.NoError(t, err)
+
+	_, err = manager.Credit(ctx, wallet1.ID, 1000, "Deposit", "", "", nil)
+	require.NoError(t, err)
+	_, err = manager.Credit(ctx, wallet2.ID, 1000, "Deposit", "", "", nil)
+	require.NoError(t, err)
+
+	return manager, store, wallet1, wallet2
+}
+
+// TestFreezeModes tests that each freeze mode blocks only its direction
+func TestFreezeModes(t *testing.T) {
+	manager, _, wallet1, wallet2 := setupTestFreeze(t)
+	ctx := context.Background()
+
+	// A debit freeze blocks outflows but allows inflows
+	err := manager.FreezeWalletWithMode(ctx, wallet1.ID, FreezeModeDebit, "Legal hold")
+	require.NoError(t, err)
+
+	wallet, err := manager.GetWallet(ctx, wallet1.ID)
+	require.NoError(t, err)
+	assert.True(t, wallet.Frozen)
+	assert.Equal(t, FreezeModeDebit, wallet.FreezeMode)
+	assert.Equal(t, "Legal hold", wallet.FreezeReason)
+
+	_, err = manager.Credit(ctx, wallet1.ID, 100, "Refund", "", "", nil)
+	assert.NoError(t, err)
+
+	err = manager.Transfer(ctx, wallet2.ID, wallet1.ID, 100, "Transfer", "", nil)
+	assert.NoError(t, err)
+
+	_, err = manager.Debit(ctx, wallet1.ID, 100, "Purchase", "", "", nil)
+	assert.ErrorIs(t, err, ErrWalletFrozen)
+
+	err = manager.Transfer(ctx, wallet1.ID, wallet2.ID, 100, "Transfer", "", nil)
+	assert.ErrorIs(t, err, ErrWalletFrozen)
+
+	// A credit freeze blocks inflows but allows outflows
+	err = manager.FreezeWalletWithMode(ctx, wallet1.ID, FreezeModeCredit, "Closing")
+	require.NoError(t, err)
+
+	_, err = manager.Credit(ctx, wallet1.ID, 100, "Refund", "", "", nil)
+	assert.ErrorIs(t, err, ErrWalletFrozen)
+
+	err = manager.Transfer(ctx, wallet2.ID, wallet1.ID, 100, "Transfer", "", nil)
+	assert.ErrorIs(t, err, ErrWalletFrozen)
+
+	_, err = manager.Debit(ctx, wallet1.ID, 100, "Purchase", "", "", nil)
+	assert.NoError(t, err)
+
+	// A full freeze blocks both
+	err = manager.FreezeWallet(ctx, wallet1.ID, "Investigation")
+	require.NoError(t, err)
+
+	_, err = manager.Credit(ctx, wallet1.ID, 100, "Refund", "", "", nil)
+	assert.ErrorIs(t, err, ErrWalletFrozen)
+
+	_, err = manager.Debit(ctx, wallet1.ID, 100, "Purchase", "", "", nil)
+	assert.ErrorIs(t, err, ErrWalletFrozen)
+
+	// Unfreezing lifts the mode
+	err = manager.UnfreezeWallet(ctx, wallet1.ID)
+	require.NoError(t, err)
+
+	wallet, err = manager.GetWallet(ctx, wallet1.ID)
+	require.NoError(t, err)
+	assert.False(t, wallet.Frozen)
+	assert.Equal(t, FreezeModeNone, wallet.FreezeMode)
+	assert.Empty(t, wallet.FreezeReason)
+	assert.Equal(t, int64(1100), wallet.Balance)
+
+	err = manager.FreezeWalletWithMode(ctx, wallet1.ID, FreezeMode("partial"), "")
+	assert.ErrorIs(t, err, ErrInvalidFreezeMode)
+
+	err = manager.FreezeWalletWithMode(ctx, "non-existent-id", FreezeModeDebit, "")
+	assert.ErrorIs(t, err, ErrWalletNotFound)
+}
+
+// TestFreezeLegacyWallet tests that wallets frozen without a mode are treated as fully frozen
+func TestFreezeLegacyWallet(t *testing.T) {
+	wallet := &Wallet{Frozen: true}
+	assert.True(t, wallet.CreditBlocked())
+	assert.True(t, wallet.DebitBlocked())
+
+	wallet = &Wallet{}
+	assert.False(t, wallet.CreditBlocked())
+	assert.False(t, wallet.DebitBlocked())
+}
+
+// TestFrozenAmount tests that a frozen amount of the balance cannot be debited
+func TestFrozenAmount(t *testing.T) {
+	manager, store, wallet1, wallet2 := setupTestFreeze(t)
+	ctx := context.Background()
+
+	err := manager.SetFrozenAmount(ctx, wallet1.ID, 700, "Court order")
+	require.NoError(t, err)
+
+	wallet, err := manager.GetWallet(ctx, wallet1.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(700), wallet.FrozenAmount)
+	assert.Equal(t, "Court order", wallet.FrozenAmountReason)
+	assert.Equal(t, int64(300), wallet.AvailableBalance())
+	assert.False(t, wallet.Frozen)
+
+	// Only the available balance can be debited
+	_, err = manager.Debit(ctx, wallet1.ID, 301, "Purchase", "", "", nil)
+	assert.ErrorIs(t, err, ErrInsufficientBalance)
+
+	err = manager.Transfer(ctx, wallet1.ID, wallet2.ID, 301, "Transfer", "", nil)
+	assert.ErrorIs(t, err, ErrInsufficientBalance)
+
+	_, err = manager.Debit(ctx, wallet1.ID, 200, "Purchase", "", "", nil)
+	assert.NoError(t, err)
+
+	// Pending debits are completed only within the available balance
+	pending := &Transaction{
+		ID:       GenerateID(),
+		WalletID: wallet1.ID,
+		Type:     TransactionTypeDebit,
+		Amount:   200,
+		Status:   TransactionStatusPending,
+	}
+	require.NoError(t, store.SaveTransaction(ctx, pending))
+
+	err = manager.CompleteTransaction(ctx, pending.ID)
+	assert.ErrorIs(t, err, ErrInsufficientBalance)
+
+	// Credits are not affected and raise the available balance
+	_, err = manager.Credit(ctx, wallet1.ID, 100, "Refund", "", "", nil)
+	assert.NoError(t, err)
+
+	err = manager.CompleteTransaction(ctx, pending.ID)
+	assert.NoError(t, err)
+
+	wallet, err = manager.GetWallet(ctx, wallet1.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(700), wallet.Balance)
+	assert.Equal(t, int64(0), wallet.AvailableBalance())
+
+	// Releasing the amount makes the whole balance available again
+	err = manager.SetFrozenAmount(ctx, wallet1.ID, 0, "")
+	require.NoError(t, err)
+
+	_, err = manager.Debit(ctx, wallet1.ID, 700, "Purchase", "", "", nil)
+	assert.NoError(t, err)
+
+	err = manager.SetFrozenAmount(ctx, wallet1.ID, -1, "")
+	assert.ErrorIs(t, err, ErrInvalidAmount)
+}
+
+// TestFreezeCompleteTransaction tests that freeze modes apply when completing pending transactions
+func TestFreezeCompleteTransaction(t *testing.T) {
+	manager, store, wallet1, _ := setupTestFreeze(t)
+	ctx := context.Background()
+
+	credit := &Transaction{ID: GenerateID(), WalletID: wallet1.ID, Type: TransactionTypeCredit, Amount: 100, Status: TransactionStatusPending}
+	debit := &Transaction{ID: GenerateID(), WalletID: wallet1.ID, Type: TransactionTypeDebit, Amount: 100, Status: TransactionStatusPending}
+	require.NoError(t, store.SaveTransaction(ctx, credit))
+	require.NoError(t, store.SaveTransaction(ctx, debit))
+
+	require.NoError(t, manager.FreezeWalletWithMode(ctx, wallet1.ID, FreezeModeDebit, "Legal hold"))
+
+	err := manager.CompleteTransaction(ctx, debit.ID)
+	assert.ErrorIs(t, err, ErrWalletFrozen)
+
+	err = manager.CompleteTransaction(ctx, credit.ID)
+	assert.NoError(t, err)
+
+	require.NoError(t, manager.FreezeWalletWithMode(ctx, wallet1.ID, FreezeModeCredit, "Closing"))
+
+	err = manager.CompleteTransaction(ctx, debit.ID)
+	assert.NoError(t, err)
+
+	wallet, err := manager.GetWallet(ctx, wallet1.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1000), wallet.Balance)
+}
@@ -0,0 +1,175 @@
+package wallethub
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+)
+
+// hashChainVersion identifies the canonical encoding used by TransactionHash
+const hashChainVersion = 1
+
+// ChainBreakReason defines why a link of a hash chain failed verification
+type ChainBreakReason string
+
+const (
+	// ChainBreakHashMismatch means the transaction content no longer matches its stored hash
+	ChainBreakHashMismatch ChainBreakReason = "hash_mismatch"
+	// ChainBreakPrevHashMismatch means the transaction does not point to the hash of its predecessor
+	ChainBreakPrevHashMismatch ChainBreakReason = "prev_hash_mismatch"
+	// ChainBreakSequenceGap means a transaction is missing from the chain
+	ChainBreakSequenceGap ChainBreakReason = "sequence_gap"
+)
+
+// ChainBreak pinpoints the first broken link of a wallet's hash chain
+type ChainBreak struct {
+	TransactionID string           `json:"transaction_id"`
+	ChainSequence int64            `json:"chain_sequence"`
+	Reason        ChainBreakReason `json:"reason"`
+	Expected      string           `json:"expected"` // Expected hash, previous hash or sequence
+	Actual        string           `json:"actual"`   // Stored hash, previous hash or sequence
+}
+
+// ChainVerification is the result of verifying a wallet's hash chain
+type ChainVerification struct {
+	WalletID string      `json:"wallet_id"`
+	Verified int64       `json:"verified"` // Number of links verified before the first break, or in total
+	Head     string      `json:"head"`     // Hash of the last verified link
+	Break    *ChainBreak `json:"break,omitempty"`
+}
+
+// Valid reports whether the whole chain verified successfully
+func (v *ChainVerification) Valid() bool {
+	return v.Break == nil
+}
+
+// TransactionHash computes the hash of a transaction's canonical content together with its chain
+// sequence and the hash of its predecessor. Timestamps are hashed at second precision and the Data
+// map in normalized JSON, so the hash survives a round trip through any supported database.
+func TransactionHash(transaction *Transaction) (string, error) {
+	data, err := canonicalData(transaction.Data)
+	if err != nil {
+		return "", err
+	}
+
+	// The tenant is hashed only when set, right after the version where it cannot be mistaken for the
+	// chain sequence, so hashes of transactions of the default tenant stay valid
+	fields := []interface{}{hashChainVersion}
+	if transaction.TenantID != "" {
+		fields = append(fields, transaction.TenantID)
+	}
+	fields = append(fields,
+		transaction.ChainSequence,
+		transaction.PrevHash,
+		transaction.ID,
+		transaction.WalletID,
+		transaction.Type,
+		transaction.Amount,
+		transaction.Balance,
+		transaction.Description,
+		transaction.Note,
+		transaction.Reference,
+		transaction.Status,
+		data,
+		transaction.CreatedAt.Unix(),
+		transaction.CompletedAt.Unix(),
+		transaction.FailedReason,
+	)
+
+	// Bucket splits and delegates are hashed only when present, so hashes of earlier transactions stay valid
+	if len(transaction.Buckets) > 0 {
+		fields = append(fields, transaction.Buckets)
+	}
+	if transaction.DelegateID != "" {
+		fields = append(fields, transaction.DelegateID)
+	}
+
+	content, err := json.Marshal(fields)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// canonicalData encodes a data map the same way before storage and after loading it back
+func canonicalData(data map[string]interface{}) (json.RawMessage, error) {
+	if len(data) == 0 {
+		return json.RawMessage("{}"), nil
+	}
+
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+
+	// Decode and encode again so numbers take the same form as when read from the database
+	var decoded interface{}
+	if err := json.Unmarshal(encoded, &decoded); err != nil {
+		return nil, err
+	}
+	return json.Marshal(decoded)
+}
+
+// VerifyHashChain walks a wallet's hash chain from the start and reports the first broken link,
+// if any. A broken link means a transaction was edited, removed or inserted outside the store.
+func (m *DefaultWalletManager) VerifyHashChain(ctx context.Context, walletID string) (*ChainVerification, error) {
+	verification := &ChainVerification{WalletID: walletID}
+
+	for offset := 0; ; offset += ledgerPageSize {
+		transactions, err := m.store.FindChainedTransactionsByWalletID(ctx, walletID, ledgerPageSize, offset)
+		if err != nil {
+			return nil, err
+		}
+
+		for i := range transactions {
+			if chainBreak := verifyChainLink(&transactions[i], verification); chainBreak != nil {
+				verification.Break = chainBreak
+				return verification, nil
+			}
+			verification.Verified++
+			verification.Head = transactions[i].Hash
+		}
+
+		if len(transactions) < ledgerPageSize {
+			break
+		}
+	}
+
+	return verification, nil
+}
+
+// verifyChainLink checks a single transaction against the chain verified so far
+func verifyChainLink(transaction *Transaction, verification *ChainVerification) *ChainBreak {
+	chainBreak := &ChainBreak{
+		TransactionID: transaction.ID,
+		ChainSequence: transaction.ChainSequence,
+	}
+
+	if expected := verification.Verified + 1; transaction.ChainSequence != expected {
+		chainBreak.Reason = ChainBreakSequenceGap
+		chainBreak.Expected = strconv.FormatInt(expected, 10)
+		chainBreak.Actual = strconv.FormatInt(transaction.ChainSequence, 10)
+		return chainBreak
+	}
+
+	if transaction.PrevHash != verification.Head {
+		chainBreak.Reason = ChainBreakPrevHashMismatch
+		chainBreak.Expected = verification.Head
+		chainBreak.Actual = transaction.PrevHash
+		return chainBreak
+	}
+
+	hash, err := TransactionHash(transaction)
+	if err != nil || hash != transaction.Hash {
+		chainBreak.Reason = ChainBreakHashMismatch
+		chainBreak.Expected = hash
+		chainBreak.Actual = transaction.Hash
+		return chainBreak
+	}
+
+	return nil
+}
@@ -0,0 +1,156 @@
+package wallethub
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestHashChainValid tests that every write path extends the wallet's hash chain
+func TestHashChainValid(t *testing.T) {
+	store := setupTestGormWalletStore(t)
+	manager := NewWalletManager(WithStore(store))
+	ctx := context.Background()
+
+	wallet1, err := manager.CreateWallet(ctx, "test-user", "Wallet 1", "Description 1", "ref-1")
+	require.NoError(t, err)
+	wallet2, err := manager.CreateWallet(ctx, "test-user", "Wallet 2", "Description 2", "ref-2")
+	require.NoError(t, err)
+
+	creditTx, err := manager.Credit(ctx, wallet1.ID, 1000, "Credit", "Note", "credit-ref", map[string]interface{}{"amount": 12.5, "count": 3, "nested": map[string]interface{}{"b": 1, "a": "x"}})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), creditTx.ChainSequence)
+	assert.Empty(t, creditTx.PrevHash)
+	assert.Len(t, creditTx.Hash, 64)
+
+	debitTx, err := manager.Debit(ctx, wallet1.ID, 300, "Debit", "Note", "debit-ref", nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), debitTx.ChainSequence)
+	assert.Equal(t, creditTx.Hash, debitTx.PrevHash)
+
+	err = manager.Transfer(ctx, wallet1.ID, wallet2.ID, 200, "Transfer", "Note", nil)
+	require.NoError(t, err)
+
+	_, err = manager.BulkCredit(ctx, "batch-1", slices.Values([]BulkCreditItem{
+		{WalletID: wallet1.ID, Amount: 10, Reference: "item-1"},
+		{WalletID: wallet1.ID, Amount: 20, Reference: "item-2"},
+	}), nil)
+	require.NoError(t, err)
+
+	// Pending transactions join the chain once they are completed or cancelled
+	pendingTx := &Transaction{
+		ID:        GenerateID(),
+		WalletID:  wallet1.ID,
+		Type:      TransactionTypeCredit,
+		Amount:    50,
+		Status:    TransactionStatusPending,
+		CreatedAt: time.Now(),
+	}
+	err = store.SaveTransaction(ctx, pendingTx)
+	require.NoError(t, err)
+	assert.Zero(t, pendingTx.ChainSequence)
+
+	err = manager.CompleteTransaction(ctx, pendingTx.ID)
+	require.NoError(t, err)
+
+	completedTx, err := manager.GetTransaction(ctx, pendingTx.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(6), completedTx.ChainSequence)
+
+	verification, err := manager.VerifyHashChain(ctx, wallet1.ID)
+	assert.NoError(t, err)
+	assert.True(t, verification.Valid())
+	assert.Equal(t, int64(6), verification.Verified)
+	assert.Equal(t, completedTx.Hash, verification.Head)
+
+	verification, err = manager.VerifyHashChain(ctx, wallet2.ID)
+	assert.NoError(t, err)
+	assert.True(t, verification.Valid())
+	assert.Equal(t, int64(1), verification.Verified)
+}
+
+// TestHashChainTampering tests that edits, removals and relinking are pinpointed
+func TestHashChainTampering(t *testing.T) {
+	store := setupTestGormWalletStore(t)
+	manager := NewWalletManager(WithStore(store))
+	ctx := context.Background()
+
+	wallet, err := manager.CreateWallet(ctx, "test-user", "Test Wallet", "Test Description", "test-ref")
+	require.NoError(t, err)
+
+	transactions := make([]*Transaction, 4)
+	for i := range transactions {
+		transactions[i], err = manager.Credit(ctx, wallet.ID, 100, "Credit", "Note", "credit-ref", nil)
+		require.NoError(t, err)
+	}
+
+	// Edit the amount of the second transaction directly in the database
+	err = store.db.Table("transactions").Where("id = ?", transactions[1].ID).Update("amount", 1000).Error
+	require.NoError(t, err)
+
+	verification, err := manager.VerifyHashChain(ctx, wallet.ID)
+	assert.NoError(t, err)
+	assert.False(t, verification.Valid())
+	assert.Equal(t, int64(1), verification.Verified)
+	require.NotNil(t, verification.Break)
+	assert.Equal(t, transactions[1].ID, verification.Break.TransactionID)
+	assert.Equal(t, ChainBreakHashMismatch, verification.Break.Reason)
+
+	// Restore it and relink the third transaction to a forged predecessor
+	err = store.db.Table("transactions").Where("id = ?", transactions[1].ID).Update("amount", 100).Error
+	require.NoError(t, err)
+	err = store.db.Table("transactions").Where("id = ?", transactions[2].ID).Update("prev_hash", transactions[0].Hash).Error
+	require.NoError(t, err)
+
+	verification, err = manager.VerifyHashChain(ctx, wallet.ID)
+	assert.NoError(t, err)
+	require.NotNil(t, verification.Break)
+	assert.Equal(t, transactions[2].ID, verification.Break.TransactionID)
+	assert.Equal(t, ChainBreakPrevHashMismatch, verification.Break.Reason)
+
+	// Remove the third transaction entirely
+	err = store.db.Table("transactions").Where("id = ?", transactions[2].ID).Delete(&TransactionModel{}).Error
+	require.NoError(t, err)
+
+	verification, err = manager.VerifyHashChain(ctx, wallet.ID)
+	assert.NoError(t, err)
+	require.NotNil(t, verification.Break)
+	assert.Equal(t, transactions[3].ID, verification.Break.TransactionID)
+	assert.Equal(t, ChainBreakSequenceGap, verification.Break.Reason)
+	assert.Equal(t, "3", verification.Break.Expected)
+	assert.Equal(t, "4", verification.Break.Actual)
+}
+
+// TestHashChainTenant tests that moving a wallet's history to another tenant breaks its chain
+func TestHashChainTenant(t *testing.T) {
+	store := setupTestGormWalletStore(t)
+	manager := NewWalletManager(WithStore(store))
+	ctx := WithTenant(context.Background(), "tenant-a")
+
+	wallet, err := manager.CreateWallet(ctx, "test-user", "Test Wallet", "Test Description", "test-ref")
+	require.NoError(t, err)
+	first, err := manager.Credit(ctx, wallet.ID, 100, "Credit", "Note", "credit-ref", nil)
+	require.NoError(t, err)
+	_, err = manager.Credit(ctx, wallet.ID, 100, "Credit", "Note", "credit-ref", nil)
+	require.NoError(t, err)
+
+	verification, err := manager.VerifyHashChain(ctx, wallet.ID)
+	assert.NoError(t, err)
+	assert.True(t, verification.Valid())
+
+	// Move the wallet and its history to another tenant directly in the database
+	err = store.db.Table("wallets").Where("id = ?", wallet.ID).Update("tenant_id", "tenant-b").Error
+	require.NoError(t, err)
+	err = store.db.Table("transactions").Where("wallet_id = ?", wallet.ID).Update("tenant_id", "tenant-b").Error
+	require.NoError(t, err)
+
+	verification, err = manager.VerifyHashChain(WithTenant(context.Background(), "tenant-b"), wallet.ID)
+	assert.NoError(t, err)
+	require.NotNil(t, verification.Break)
+	assert.Equal(t, first.ID, verification.Break.TransactionID)
+	assert.Equal(t, ChainBreakHashMismatch, verification.Break.Reason)
+}
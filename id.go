@@ -0,0 +1,133 @@
+package wallethub
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// IDGenerator generates the IDs of new wallets, transactions, approvals and schedules
+type IDGenerator interface {
+	NewID() string
+}
+
+// IDGeneratorFunc adapts a function to the IDGenerator interface
+type IDGeneratorFunc func() string
+
+// NewID returns the result of calling the function
+func (f IDGeneratorFunc) NewID() string {
+	return f()
+}
+
+// UUIDv4Generator generates random UUIDs, used by default
+var UUIDv4Generator IDGenerator = IDGeneratorFunc(GenerateID)
+
+// WithIDGenerator sets the generator of the IDs of new records, UUIDv4Generator by default.
+// Approval managers and schedulers use the ID generator of their wallet manager.
+func WithIDGenerator(ids IDGenerator) Option {
+	return func(m *DefaultWalletManager) {
+		m.ids = ids
+	}
+}
+
+// crockfordAlphabet is the base32 alphabet of ULIDs
+const crockfordAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// timeOrderedIDs produces the millisecond timestamp and 80 bits of entropy of time-ordered IDs.
+// IDs within the same millisecond, or while the clock goes back, keep the last timestamp and increment
+// the entropy, so IDs from one generator always sort in the order they were generated.
+type timeOrderedIDs struct {
+	clock   Clock
+	mask    byte // Mask of the first entropy byte on a new millisecond, leaving room to increment
+	mu      sync.Mutex
+	ms      int64
+	entropy [10]byte
+}
+
+// newTimeOrderedIDs creates time-ordered IDs read from the clock, SystemClock if nil
+func newTimeOrderedIDs(clock Clock, mask byte) *timeOrderedIDs {
+	if clock == nil {
+		clock = SystemClock
+	}
+	return &timeOrderedIDs{clock: clock, mask: mask, ms: -1}
+}
+
+// next returns the timestamp and entropy of the next ID
+func (g *timeOrderedIDs) next() (int64, [10]byte) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	ms := g.clock.Now().UnixMilli()
+	if ms > g.ms {
+		g.ms = ms
+		rand.Read(g.entropy[:])
+		g.entropy[0] &= g.mask
+		return g.ms, g.entropy
+	}
+
+	for i := len(g.entropy) - 1; i >= 0; i-- {
+		g.entropy[i]++
+		if g.entropy[i] != 0 {
+			break
+		}
+	}
+	return g.ms, g.entropy
+}
+
+// ULIDGenerator generates ULIDs, 26 character IDs that sort lexically by creation time
+type ULIDGenerator struct {
+	ids *timeOrderedIDs
+}
+
+// NewULIDGenerator creates a ULID generator timestamping IDs with the clock, SystemClock if nil
+func NewULIDGenerator(clock Clock) *ULIDGenerator {
+	return &ULIDGenerator{ids: newTimeOrderedIDs(clock, 0x7f)}
+}
+
+// NewID generates a ULID
+func (g *ULIDGenerator) NewID() string {
+	ms, entropy := g.ids.next()
+
+	// 48 bits of timestamp followed by 80 bits of entropy, encoded 5 bits per character
+	hi := uint64(ms)<<16 | uint64(entropy[0])<<8 | uint64(entropy[1])
+	lo := binary.BigEndian.Uint64(entropy[2:])
+
+	var id [26]byte
+	for i := len(id) - 1; i >= 0; i-- {
+		id[i] = crockfordAlphabet[lo&0x1f]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(id[:])
+}
+
+// UUIDv7Generator generates version 7 UUIDs, which sort by creation time
+type UUIDv7Generator struct {
+	ids *timeOrderedIDs
+}
+
+// NewUUIDv7Generator creates a UUIDv7 generator timestamping IDs with the clock, SystemClock if nil
+func NewUUIDv7Generator(clock Clock) *UUIDv7Generator {
+	// Only the low 74 bits of entropy are used
+	return &UUIDv7Generator{ids: newTimeOrderedIDs(clock, 0x01)}
+}
+
+// NewID generates a UUIDv7
+func (g *UUIDv7Generator) NewID() string {
+	ms, entropy := g.ids.next()
+
+	// 12 bits of rand_a and 62 bits of rand_b around the version and variant bits
+	hi := uint64(entropy[0])<<8 | uint64(entropy[1])
+	lo := binary.BigEndian.Uint64(entropy[2:])
+	randA := (hi&0x3ff)<<2 | lo>>62
+	randB := lo & (1<<62 - 1)
+
+	var id uuid.UUID
+	binary.BigEndian.PutUint64(id[0:], uint64(ms)<<16)
+	id[6] = 0x70 | byte(randA>>8)
+	id[7] = byte(randA)
+	binary.BigEndian.PutUint64(id[8:], 0x8000000000000000|randB)
+	return id.String()
+}
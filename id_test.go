@@ -0,0 +1,62 @@
+package wallethub
+
+import (
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestULIDGenerator tests that ULIDs encode their timestamp and sort by creation time
+func TestULIDGenerator(t *testing.T) {
+	now := time.UnixMilli(1469918176385)
+	generator := NewULIDGenerator(ClockFunc(func() time.Time { return now }))
+
+	id := generator.NewID()
+	assert.Len(t, id, 26)
+	assert.Equal(t, "01ARYZ6S41", id[:10])
+
+	// IDs within the same millisecond, and while the clock goes back, still sort
+	ids := []string{id}
+	for i := 0; i < 100; i++ {
+		switch i {
+		case 50:
+			now = now.Add(-time.Second)
+		case 75:
+			now = now.Add(time.Hour)
+		}
+		ids = append(ids, generator.NewID())
+	}
+	assert.True(t, sort.StringsAreSorted(ids))
+	assert.Equal(t, ids[0][:10], ids[75][:10])
+	assert.NotEqual(t, ids[0][:10], ids[76][:10])
+
+	assert.Len(t, NewULIDGenerator(nil).NewID(), 26)
+}
+
+// TestUUIDv7Generator tests that UUIDv7s are valid version 7 UUIDs that sort by creation time
+func TestUUIDv7Generator(t *testing.T) {
+	now := time.UnixMilli(1645557742000)
+	generator := NewUUIDv7Generator(ClockFunc(func() time.Time { return now }))
+
+	ids := make([]string, 0, 101)
+	for i := 0; i <= 100; i++ {
+		if i == 50 {
+			now = now.Add(time.Millisecond)
+		}
+		ids = append(ids, generator.NewID())
+	}
+	assert.True(t, sort.StringsAreSorted(ids))
+
+	for _, id := range ids {
+		parsed, err := uuid.Parse(id)
+		require.NoError(t, err)
+		assert.Equal(t, uuid.Version(7), parsed.Version())
+		assert.Equal(t, uuid.RFC4122, parsed.Variant())
+	}
+	assert.Equal(t, "017f22e2-79b0-7", ids[0][:15])
+	assert.Equal(t, "017f22e2-79b1-7", ids[100][:15])
+}
@@ -0,0 +1,72 @@
+package wallethub
+
+// ledgerPageSize is the number of transactions read per query when walking a wallet's history
+const ledgerPageSize = 500
+
+// signedAmount returns the change in wallet balance caused by a completed transaction
+func signedAmount(transaction *Transaction) int64 {
+	if transaction.Type == TransactionTypeDebit {
+		return -transaction.Amount
+	}
+	return transaction.Amount
+}
+
+// walkLedger calls fn for every completed transaction of a wallet in the order they were applied,
+// together with the balance recomputed from the opening balance and the history up to and including
+// that transaction, and returns the final recomputed balance. Pages are read through fetch, so memory use is bounded by
+// the page size.
+//
+// Transactions completed at the same instant, such as those of a single bulk credit chunk, cannot be
+// ordered by timestamp alone; within such a group the transaction whose recorded running balance
+// follows from the current balance is taken first.
+func walkLedger(opening int64, fetch func(limit int, offset int) ([]Transaction, error), fn func(transaction *Transaction, balance int64) error) (int64, error) {
+	balance := opening
+	var group []Transaction
+
+	flush := func() error {
+		for len(group) > 0 {
+			next := 0
+			for i := range group {
+				if group[i].Balance == balance+signedAmount(&group[i]) {
+					next = i
+					break
+				}
+			}
+
+			transaction := group[next]
+			group = append(group[:next], group[next+1:]...)
+
+			balance += signedAmount(&transaction)
+			if err := fn(&transaction, balance); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for offset := 0; ; offset += ledgerPageSize {
+		page, err := fetch(ledgerPageSize, offset)
+		if err != nil {
+			return 0, err
+		}
+
+		for _, transaction := range page {
+			if len(group) > 0 && !transaction.CompletedAt.Equal(group[0].CompletedAt) {
+				if err := flush(); err != nil {
+					return 0, err
+				}
+			}
+			group = append(group, transaction)
+		}
+
+		if len(page) < ledgerPageSize {
+			break
+		}
+	}
+
+	if err := flush(); err != nil {
+		return 0, err
+	}
+
+	return balance, nil
+}
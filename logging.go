@@ -0,0 +1,118 @@
+package wallethub
+
+import (
+	"context"
+	"log/slog"
+)
+
+// RedactedValue replaces redacted fields in log records
+const RedactedValue = "[REDACTED]"
+
+// LogRedaction selects the free-form fields replaced by RedactedValue in log records
+type LogRedaction struct {
+	Note bool
+	Data bool
+}
+
+// DefaultLogRedaction redacts both notes and data, which may hold personal information
+var DefaultLogRedaction = LogRedaction{Note: true, Data: true}
+
+// WithLogger sets the logger receiving a record for every mutation and its outcome. Nothing is logged
+// without a logger.
+func WithLogger(logger *slog.Logger) Option {
+	return func(m *DefaultWalletManager) {
+		m.log.logger = logger
+	}
+}
+
+// WithLogRedaction sets the fields redacted in log records, DefaultLogRedaction by default
+func WithLogRedaction(redaction LogRedaction) Option {
+	return func(m *DefaultWalletManager) {
+		m.log.redaction = redaction
+	}
+}
+
+// walletLogger writes structured log records with redaction
+type walletLogger struct {
+	logger    *slog.Logger
+	redaction LogRedaction
+}
+
+// newWalletLogger creates a logger that logs nothing until a logger is set
+func newWalletLogger() walletLogger {
+	return walletLogger{redaction: DefaultLogRedaction}
+}
+
+// log writes a record at the given level on success. Errors are logged at warning level if a known
+// error rejected the operation and at error level otherwise.
+func (l walletLogger) log(ctx context.Context, level slog.Level, msg string, err error, attrs ...slog.Attr) {
+	if l.logger == nil {
+		return
+	}
+
+	if err != nil {
+		code := ErrorCodeOf(err)
+		level = slog.LevelWarn
+		if code == CodeInternal {
+			level = slog.LevelError
+		}
+		attrs = append(attrs, slog.String("error", err.Error()), slog.String("error_code", string(code)))
+	}
+	if tenantID := TenantFromContext(ctx); tenantID != "" {
+		attrs = append(attrs, slog.String("tenant_id", tenantID))
+	}
+
+	l.logger.LogAttrs(ctx, level, msg, attrs...)
+}
+
+// note returns the attribute of a note, redacted if configured
+func (l walletLogger) note(note string) slog.Attr {
+	if l.redaction.Note && note != "" {
+		return slog.String("note", RedactedValue)
+	}
+	return slog.String("note", note)
+}
+
+// data returns the attribute of transaction data, redacted if configured
+func (l walletLogger) data(data map[string]interface{}) slog.Attr {
+	if l.redaction.Data && len(data) > 0 {
+		return slog.String("data", RedactedValue)
+	}
+	return slog.Any("data", data)
+}
+
+// walletAttrs returns the attributes describing a wallet
+func (l walletLogger) walletAttrs(wallet *Wallet) []slog.Attr {
+	return []slog.Attr{
+		slog.String("wallet_id", wallet.ID),
+		slog.String("user_id", wallet.UserID),
+		slog.Int64("balance", wallet.Balance),
+	}
+}
+
+// transactionAttrs returns the attributes describing a transaction
+func (l walletLogger) transactionAttrs(transaction *Transaction) []slog.Attr {
+	return []slog.Attr{
+		slog.String("transaction_id", transaction.ID),
+		slog.String("wallet_id", transaction.WalletID),
+		slog.String("type", string(transaction.Type)),
+		slog.Int64("amount", transaction.Amount),
+		slog.String("status", string(transaction.Status)),
+		slog.String("reference", transaction.Reference),
+		l.note(transaction.Note),
+		l.data(transaction.Data),
+	}
+}
+
+// operation logs the outcome of a wallet manager mutation
+func (l walletLogger) operation(ctx context.Context, operation string, err error, attrs ...slog.Attr) {
+	l.log(ctx, slog.LevelInfo, "wallet operation", err, append([]slog.Attr{slog.String("operation", operation)}, attrs...)...)
+}
+
+// mutation logs the outcome of a store write
+func (l walletLogger) mutation(ctx context.Context, operation string, transactional bool, err error, attrs ...slog.Attr) {
+	l.log(ctx, slog.LevelDebug, "store mutation", err, append([]slog.Attr{
+		slog.String("operation", operation),
+		slog.Bool("transactional", transactional),
+	}, attrs...)...)
+}
@@ -0,0 +1,153 @@
+package wallethub
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupTestLogging creates a logger writing JSON records into a buffer
+func setupTestLogging(t *testing.T) (*slog.Logger, *bytes.Buffer) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	return logger, &buf
+}
+
+// logRecords parses the JSON records written into a buffer and resets it
+func logRecords(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	var records []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		record := make(map[string]interface{})
+		require.NoError(t, json.Unmarshal([]byte(line), &record))
+		records = append(records, record)
+	}
+	buf.Reset()
+	return records
+}
+
+// findLogRecord returns the first record with the given message and operation
+func findLogRecord(records []map[string]interface{}, msg string, operation string) map[string]interface{} {
+	for _, record := range records {
+		if record["msg"] == msg && record["operation"] == operation {
+			return record
+		}
+	}
+	return nil
+}
+
+// TestWalletManagerLogging tests the records of wallet manager mutations
+func TestWalletManagerLogging(t *testing.T) {
+	logger, buf := setupTestLogging(t)
+	manager := NewWalletManager(WithStore(setupTestGormWalletStore(t)), WithLogger(logger))
+	ctx := WithTenant(context.Background(), "tenant-1")
+
+	wallet, err := manager.CreateWallet(ctx, "user-1", "Wallet", "", "main")
+	require.NoError(t, err)
+
+	records := logRecords(t, buf)
+	record := findLogRecord(records, "wallet operation", "create_wallet")
+	require.NotNil(t, record)
+	assert.Equal(t, "INFO", record["level"])
+	assert.Equal(t, wallet.ID, record["wallet_id"])
+	assert.Equal(t, "tenant-1", record["tenant_id"])
+
+	// Notes and data are redacted by default
+	transaction, err := manager.Credit(ctx, wallet.ID, 1000, "Deposit", "Card 4111", "order-1", map[string]interface{}{"email": "user@example.com"})
+	require.NoError(t, err)
+
+	records = logRecords(t, buf)
+	record = findLogRecord(records, "wallet operation", "credit")
+	require.NotNil(t, record)
+	assert.Equal(t, "INFO", record["level"])
+	assert.Equal(t, transaction.ID, record["transaction_id"])
+	assert.Equal(t, float64(1000), record["amount"])
+	assert.Equal(t, "order-1", record["reference"])
+	assert.Equal(t, RedactedValue, record["note"])
+	assert.Equal(t, RedactedValue, record["data"])
+
+	// Rejected operations are logged as warnings
+	_, err = manager.Debit(ctx, wallet.ID, 5000, "Purchase", "", "", nil)
+	assert.ErrorIs(t, err, ErrInsufficientBalance)
+
+	record = findLogRecord(logRecords(t, buf), "wallet operation", "debit")
+	require.NotNil(t, record)
+	assert.Equal(t, "WARN", record["level"])
+	assert.Equal(t, err.Error(), record["error"])
+	assert.Equal(t, "insufficient_balance", record["error_code"])
+
+	err = manager.FreezeWalletWithMode(ctx, wallet.ID, FreezeModeDebit, "Legal hold")
+	require.NoError(t, err)
+
+	record = findLogRecord(logRecords(t, buf), "wallet operation", "freeze_wallet")
+	require.NotNil(t, record)
+	assert.Equal(t, "debit", record["mode"])
+	assert.Equal(t, "Legal hold", record["reason"])
+}
+
+// TestWalletManagerLoggingRedaction tests disabling the redaction of notes and data
+func TestWalletManagerLoggingRedaction(t *testing.T) {
+	logger, buf := setupTestLogging(t)
+	manager := NewWalletManager(
+		WithStore(setupTestGormWalletStore(t)),
+		WithLogger(logger),
+		WithLogRedaction(LogRedaction{Data: true}),
+	)
+	ctx := context.Background()
+
+	wallet, err := manager.CreateWallet(ctx, "user-1", "Wallet", "", "")
+	require.NoError(t, err)
+	_, err = manager.Credit(ctx, wallet.ID, 1000, "Deposit", "Welcome bonus", "", map[string]interface{}{"campaign": "spring"})
+	require.NoError(t, err)
+
+	record := findLogRecord(logRecords(t, buf), "wallet operation", "credit")
+	require.NotNil(t, record)
+	assert.Equal(t, "Welcome bonus", record["note"])
+	assert.Equal(t, RedactedValue, record["data"])
+	_, ok := record["tenant_id"]
+	assert.False(t, ok)
+}
+
+// TestGormWalletStoreLogging tests the records of store writes and commits
+func TestGormWalletStoreLogging(t *testing.T) {
+	logger, buf := setupTestLogging(t)
+	store := setupTestGormWalletStore(t)
+	store = NewGormWalletStore(store.db, "", "", WithStoreLogger(logger), WithStoreLogRedaction(LogRedaction{}))
+	manager := NewWalletManager(WithStore(store))
+	ctx := context.Background()
+
+	wallet, err := manager.CreateWallet(ctx, "user-1", "Wallet", "", "")
+	require.NoError(t, err)
+	_, err = manager.Credit(ctx, wallet.ID, 1000, "Deposit", "Welcome bonus", "", nil)
+	require.NoError(t, err)
+
+	records := logRecords(t, buf)
+	for _, operation := range []string{"SaveWallet", "UpdateWallet", "SaveTransaction", "Commit"} {
+		record := findLogRecord(records, "store mutation", operation)
+		require.NotNil(t, record, operation)
+		assert.Equal(t, "DEBUG", record["level"])
+		assert.Equal(t, true, record["transactional"])
+	}
+
+	record := findLogRecord(records, "store mutation", "SaveTransaction")
+	assert.Equal(t, wallet.ID, record["wallet_id"])
+	assert.Equal(t, "Welcome bonus", record["note"])
+
+	// Failed writes are logged as errors
+	err = store.SaveWallet(ctx, wallet)
+	assert.Error(t, err)
+
+	record = findLogRecord(logRecords(t, buf), "store mutation", "SaveWallet")
+	require.NotNil(t, record)
+	assert.Equal(t, "ERROR", record["level"])
+	assert.Equal(t, false, record["transactional"])
+	assert.Equal(t, "internal", record["error_code"])
+}
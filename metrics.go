@@ -0,0 +1,529 @@
+package wallethub
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Default histogram buckets of MetricsWalletManager
+var (
+	DefaultLatencyBuckets = []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+	DefaultAmountBuckets  = []float64{10, 100, 1000, 10000, 100000, 1000000, 10000000}
+)
+
+// histogram is a Prometheus histogram with fixed upper bounds
+type histogram struct {
+	counts []uint64 // Observations per bucket, with the last bucket for values above all bounds
+	sum    float64
+	count  uint64
+}
+
+// observe adds a value to the histogram
+func (h *histogram) observe(bounds []float64, value float64) {
+	i := sort.SearchFloat64s(bounds, value)
+	h.counts[i]++
+	h.sum += value
+	h.count++
+}
+
+// MetricsOption defines a function type for configuring MetricsWalletManager
+type MetricsOption func(*MetricsWalletManager)
+
+// WithMetricsStore sets the store queried for the pending transaction gauge when metrics are collected
+func WithMetricsStore(store WalletStore) MetricsOption {
+	return func(m *MetricsWalletManager) {
+		m.store = store
+	}
+}
+
+// WithLatencyBuckets sets the upper bounds, in seconds, of the latency histogram buckets
+func WithLatencyBuckets(bounds ...float64) MetricsOption {
+	return func(m *MetricsWalletManager) {
+		m.latencyBuckets = bounds
+	}
+}
+
+// WithAmountBuckets sets the upper bounds of the amount histogram buckets
+func WithAmountBuckets(bounds ...float64) MetricsOption {
+	return func(m *MetricsWalletManager) {
+		m.amountBuckets = bounds
+	}
+}
+
+// MetricsWalletManager is a WalletManager that records the count, errors and latency of every operation
+// and the amounts of successful credits, debits and transfers before passing them on to the wrapped
+// manager. Metrics are kept in memory and written in Prometheus text exposition format.
+type MetricsWalletManager struct {
+	next           WalletManager
+	store          WalletStore
+	latencyBuckets []float64
+	amountBuckets  []float64
+
+	mu         sync.Mutex
+	operations map[[2]string]uint64 // Keyed by operation and result
+	errors     map[[2]string]uint64 // Keyed by operation and error code
+	latencies  map[string]*histogram
+	amounts    map[string]*histogram
+}
+
+// NewMetricsWalletManager wraps a wallet manager with metrics
+func NewMetricsWalletManager(next WalletManager, options ...MetricsOption) *MetricsWalletManager {
+	m := &MetricsWalletManager{
+		next:           next,
+		latencyBuckets: DefaultLatencyBuckets,
+		amountBuckets:  DefaultAmountBuckets,
+		operations:     make(map[[2]string]uint64),
+		errors:         make(map[[2]string]uint64),
+		latencies:      make(map[string]*histogram),
+		amounts:        make(map[string]*histogram),
+	}
+
+	// Apply all options
+	for _, option := range options {
+		option(m)
+	}
+
+	m.latencyBuckets = slices.Sorted(slices.Values(m.latencyBuckets))
+	m.amountBuckets = slices.Sorted(slices.Values(m.amountBuckets))
+
+	return m
+}
+
+// observe records the outcome and latency of an operation
+func (m *MetricsWalletManager) observe(operation string, start time.Time, err error) {
+	elapsed := time.Since(start).Seconds()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	result := "success"
+	if err != nil {
+		result = "error"
+		m.errors[[2]string{operation, string(ErrorCodeOf(err))}]++
+	}
+	m.operations[[2]string{operation, result}]++
+
+	latency, ok := m.latencies[operation]
+	if !ok {
+		latency = &histogram{counts: make([]uint64, len(m.latencyBuckets)+1)}
+		m.latencies[operation] = latency
+	}
+	latency.observe(m.latencyBuckets, elapsed)
+}
+
+// observeAmount records the amount of a successful operation
+func (m *MetricsWalletManager) observeAmount(operation string, amount int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	h, ok := m.amounts[operation]
+	if !ok {
+		h = &histogram{counts: make([]uint64, len(m.amountBuckets)+1)}
+		m.amounts[operation] = h
+	}
+	h.observe(m.amountBuckets, float64(amount))
+}
+
+// WriteMetrics writes all metrics in Prometheus text exposition format. The pending transaction gauge
+// counts the transactions of the context's tenant and is only written if a store is configured.
+func (m *MetricsWalletManager) WriteMetrics(ctx context.Context, w io.Writer) error {
+	var pending int64
+	if m.store != nil {
+		count, err := m.store.CountTransactionsByStatus(ctx, TransactionStatusPending)
+		if err != nil {
+			return err
+		}
+		pending = count
+	}
+
+	var buf bytes.Buffer
+
+	m.mu.Lock()
+
+	writeMetricHeader(&buf, "wallethub_operations_total", "counter", "Wallet operations by result.")
+	for _, key := range sortedKeys(m.operations) {
+		writeMetric(&buf, "wallethub_operations_total", []string{"operation", key[0], "result", key[1]}, float64(m.operations[key]))
+	}
+
+	writeMetricHeader(&buf, "wallethub_operation_errors_total", "counter", "Failed wallet operations by error code.")
+	for _, key := range sortedKeys(m.errors) {
+		writeMetric(&buf, "wallethub_operation_errors_total", []string{"operation", key[0], "code", key[1]}, float64(m.errors[key]))
+	}
+
+	writeHistogram(&buf, "wallethub_operation_duration_seconds", "Latency of wallet operations in seconds.", m.latencyBuckets, m.latencies)
+	writeHistogram(&buf, "wallethub_transaction_amount", "Amounts of successful credits, debits and transfers.", m.amountBuckets, m.amounts)
+
+	m.mu.Unlock()
+
+	if m.store != nil {
+		writeMetricHeader(&buf, "wallethub_pending_transactions", "gauge", "Transactions awaiting completion or cancellation.")
+		writeMetric(&buf, "wallethub_pending_transactions", nil, float64(pending))
+	}
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// Handler returns an http.Handler serving the metrics in Prometheus text exposition format
+func (m *MetricsWalletManager) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var buf bytes.Buffer
+		if err := m.WriteMetrics(r.Context(), &buf); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		w.Write(buf.Bytes())
+	})
+}
+
+// sortedKeys returns the keys of a counter map in label order
+func sortedKeys(counters map[[2]string]uint64) [][2]string {
+	keys := make([][2]string, 0, len(counters))
+	for key := range counters {
+		keys = append(keys, key)
+	}
+	slices.SortFunc(keys, func(a, b [2]string) int {
+		if c := strings.Compare(a[0], b[0]); c != 0 {
+			return c
+		}
+		return strings.Compare(a[1], b[1])
+	})
+	return keys
+}
+
+// writeMetricHeader writes the HELP and TYPE lines of a metric
+func writeMetricHeader(buf *bytes.Buffer, name string, kind string, help string) {
+	fmt.Fprintf(buf, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+// writeMetric writes a sample with labels given as name and value pairs
+func writeMetric(buf *bytes.Buffer, name string, labels []string, value float64) {
+	buf.WriteString(name)
+	if len(labels) > 0 {
+		buf.WriteByte('{')
+		for i := 0; i < len(labels); i += 2 {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			fmt.Fprintf(buf, "%s=\"%s\"", labels[i], escapeLabelValue(labels[i+1]))
+		}
+		buf.WriteByte('}')
+	}
+	buf.WriteByte(' ')
+	buf.WriteString(strconv.FormatFloat(value, 'g', -1, 64))
+	buf.WriteByte('\n')
+}
+
+// writeHistogram writes the cumulative buckets, sum and count of histograms keyed by operation
+func writeHistogram(buf *bytes.Buffer, name string, help string, bounds []float64, histograms map[string]*histogram) {
+	writeMetricHeader(buf, name, "histogram", help)
+
+	operations := make([]string, 0, len(histograms))
+	for operation := range histograms {
+		operations = append(operations, operation)
+	}
+	slices.Sort(operations)
+
+	for _, operation := range operations {
+		h := histograms[operation]
+
+		var cumulative uint64
+		for i, bound := range bounds {
+			cumulative += h.counts[i]
+			writeMetric(buf, name+"_bucket", []string{"operation", operation, "le", strconv.FormatFloat(bound, 'g', -1, 64)}, float64(cumulative))
+		}
+		writeMetric(buf, name+"_bucket", []string{"operation", operation, "le", "+Inf"}, float64(h.count))
+		writeMetric(buf, name+"_sum", []string{"operation", operation}, h.sum)
+		writeMetric(buf, name+"_count", []string{"operation", operation}, float64(h.count))
+	}
+}
+
+// escapeLabelValue escapes backslashes, double quotes and line feeds in a label value
+func escapeLabelValue(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+// CreateWallet creates a new wallet for a user
+func (m *MetricsWalletManager) CreateWallet(ctx context.Context, userID string, name string, description string, reference string) (*Wallet, error) {
+	start := time.Now()
+	wallet, err := m.next.CreateWallet(ctx, userID, name, description, reference)
+	m.observe("create_wallet", start, err)
+	return wallet, err
+}
+
+// GetWallet retrieves a wallet by ID
+func (m *MetricsWalletManager) GetWallet(ctx context.Context, walletID string) (*Wallet, error) {
+	start := time.Now()
+	wallet, err := m.next.GetWallet(ctx, walletID)
+	m.observe("get_wallet", start, err)
+	return wallet, err
+}
+
+// GetWalletsByUserID retrieves all wallets for a user
+func (m *MetricsWalletManager) GetWalletsByUserID(ctx context.Context, userID string) ([]Wallet, error) {
+	start := time.Now()
+	wallets, err := m.next.GetWalletsByUserID(ctx, userID)
+	m.observe("get_wallets_by_user_id", start, err)
+	return wallets, err
+}
+
+// GetWalletByUserIDAndReference retrieves a wallet by user ID and reference
+func (m *MetricsWalletManager) GetWalletByUserIDAndReference(ctx context.Context, userID string, reference string) (*Wallet, error) {
+	start := time.Now()
+	wallet, err := m.next.GetWalletByUserIDAndReference(ctx, userID, reference)
+	m.observe("get_wallet_by_user_id_and_reference", start, err)
+	return wallet, err
+}
+
+// GetPrimaryWallet retrieves the primary wallet of a user
+func (m *MetricsWalletManager) GetPrimaryWallet(ctx context.Context, userID string) (*Wallet, error) {
+	start := time.Now()
+	wallet, err := m.next.GetPrimaryWallet(ctx, userID)
+	m.observe("get_primary_wallet", start, err)
+	return wallet, err
+}
+
+// SetPrimaryWallet sets a wallet as the primary wallet of its user
+func (m *MetricsWalletManager) SetPrimaryWallet(ctx context.Context, walletID string) error {
+	start := time.Now()
+	err := m.next.SetPrimaryWallet(ctx, walletID)
+	m.observe("set_primary_wallet", start, err)
+	return err
+}
+
+// UpdateWalletActive updates the active status of a wallet
+func (m *MetricsWalletManager) UpdateWalletActive(ctx context.Context, walletID string, active bool) error {
+	start := time.Now()
+	err := m.next.UpdateWalletActive(ctx, walletID, active)
+	m.observe("update_wallet_active", start, err)
+	return err
+}
+
+// UpdateWalletName updates the name of a wallet
+func (m *MetricsWalletManager) UpdateWalletName(ctx context.Context, walletID string, name string) error {
+	start := time.Now()
+	err := m.next.UpdateWalletName(ctx, walletID, name)
+	m.observe("update_wallet_name", start, err)
+	return err
+}
+
+// UpdateWalletDescription updates the description of a wallet
+func (m *MetricsWalletManager) UpdateWalletDescription(ctx context.Context, walletID string, description string) error {
+	start := time.Now()
+	err := m.next.UpdateWalletDescription(ctx, walletID, description)
+	m.observe("update_wallet_description", start, err)
+	return err
+}
+
+// UpdateWalletReference updates the reference of a wallet
+func (m *MetricsWalletManager) UpdateWalletReference(ctx context.Context, walletID string, reference string) error {
+	start := time.Now()
+	err := m.next.UpdateWalletReference(ctx, walletID, reference)
+	m.observe("update_wallet_reference", start, err)
+	return err
+}
+
+// Credit adds funds to a wallet
+func (m *MetricsWalletManager) Credit(ctx context.Context, walletID string, amount int64, description string, note string, reference string, data map[string]interface{}) (*Transaction, error) {
+	start := time.Now()
+	transaction, err := m.next.Credit(ctx, walletID, amount, description, note, reference, data)
+	m.observe("credit", start, err)
+	if err == nil {
+		m.observeAmount("credit", amount)
+	}
+	return transaction, err
+}
+
+// CreditBucket adds funds to a balance bucket of a wallet
+func (m *MetricsWalletManager) CreditBucket(ctx context.Context, walletID string, bucket string, amount int64, description string, note string, reference string, data map[string]interface{}) (*Transaction, error) {
+	start := time.Now()
+	transaction, err := m.next.CreditBucket(ctx, walletID, bucket, amount, description, note, reference, data)
+	m.observe("credit_bucket", start, err)
+	if err == nil {
+		m.observeAmount("credit", amount)
+	}
+	return transaction, err
+}
+
+// Debit removes funds from a wallet
+func (m *MetricsWalletManager) Debit(ctx context.Context, walletID string, amount int64, description string, note string, reference string, data map[string]interface{}) (*Transaction, error) {
+	start := time.Now()
+	transaction, err := m.next.Debit(ctx, walletID, amount, description, note, reference, data)
+	m.observe("debit", start, err)
+	if err == nil {
+		m.observeAmount("debit", amount)
+	}
+	return transaction, err
+}
+
+// GetTransaction retrieves a transaction by ID
+func (m *MetricsWalletManager) GetTransaction(ctx context.Context, transactionID string) (*Transaction, error) {
+	start := time.Now()
+	transaction, err := m.next.GetTransaction(ctx, transactionID)
+	m.observe("get_transaction", start, err)
+	return transaction, err
+}
+
+// ListTransactions retrieves transactions for a wallet with pagination
+func (m *MetricsWalletManager) ListTransactions(ctx context.Context, walletID string, limit int, offset int) ([]Transaction, error) {
+	start := time.Now()
+	transactions, err := m.next.ListTransactions(ctx, walletID, limit, offset)
+	m.observe("list_transactions", start, err)
+	return transactions, err
+}
+
+// ListUserTransactions retrieves transactions for all wallets of a user with pagination
+func (m *MetricsWalletManager) ListUserTransactions(ctx context.Context, userID string, limit int, offset int) ([]Transaction, error) {
+	start := time.Now()
+	transactions, err := m.next.ListUserTransactions(ctx, userID, limit, offset)
+	m.observe("list_user_transactions", start, err)
+	return transactions, err
+}
+
+// Transfer moves funds from one wallet to another
+func (m *MetricsWalletManager) Transfer(ctx context.Context, fromWalletID string, toWalletID string, amount int64, description string, note string, data map[string]interface{}) error {
+	start := time.Now()
+	err := m.next.Transfer(ctx, fromWalletID, toWalletID, amount, description, note, data)
+	m.observe("transfer", start, err)
+	if err == nil {
+		m.observeAmount("transfer", amount)
+	}
+	return err
+}
+
+// FreezeWallet fully freezes a wallet
+func (m *MetricsWalletManager) FreezeWallet(ctx context.Context, walletID string, reason string) error {
+	start := time.Now()
+	err := m.next.FreezeWallet(ctx, walletID, reason)
+	m.observe("freeze_wallet", start, err)
+	return err
+}
+
+// FreezeWalletWithMode freezes a wallet in the given mode
+func (m *MetricsWalletManager) FreezeWalletWithMode(ctx context.Context, walletID string, mode FreezeMode, reason string) error {
+	start := time.Now()
+	err := m.next.FreezeWalletWithMode(ctx, walletID, mode, reason)
+	m.observe("freeze_wallet_with_mode", start, err)
+	return err
+}
+
+// UnfreezeWallet lifts the freeze mode of a wallet
+func (m *MetricsWalletManager) UnfreezeWallet(ctx context.Context, walletID string) error {
+	start := time.Now()
+	err := m.next.UnfreezeWallet(ctx, walletID)
+	m.observe("unfreeze_wallet", start, err)
+	return err
+}
+
+// SetFrozenAmount freezes part of a wallet's balance
+func (m *MetricsWalletManager) SetFrozenAmount(ctx context.Context, walletID string, amount int64, reason string) error {
+	start := time.Now()
+	err := m.next.SetFrozenAmount(ctx, walletID, amount, reason)
+	m.observe("set_frozen_amount", start, err)
+	return err
+}
+
+// CancelTransaction cancels a pending transaction
+func (m *MetricsWalletManager) CancelTransaction(ctx context.Context, transactionID string, reason string) error {
+	start := time.Now()
+	err := m.next.CancelTransaction(ctx, transactionID, reason)
+	m.observe("cancel_transaction", start, err)
+	return err
+}
+
+// CompleteTransaction completes a pending transaction
+func (m *MetricsWalletManager) CompleteTransaction(ctx context.Context, transactionID string) error {
+	start := time.Now()
+	err := m.next.CompleteTransaction(ctx, transactionID)
+	m.observe("complete_transaction", start, err)
+	return err
+}
+
+// GetUserWalletSummary returns the total balance of all wallets of a user
+func (m *MetricsWalletManager) GetUserWalletSummary(ctx context.Context, userID string) (int64, error) {
+	start := time.Now()
+	total, err := m.next.GetUserWalletSummary(ctx, userID)
+	m.observe("get_user_wallet_summary", start, err)
+	return total, err
+}
+
+// FlagWalletRisk flags a wallet as risky
+func (m *MetricsWalletManager) FlagWalletRisk(ctx context.Context, walletID string, reason string) error {
+	start := time.Now()
+	err := m.next.FlagWalletRisk(ctx, walletID, reason)
+	m.observe("flag_wallet_risk", start, err)
+	return err
+}
+
+// ClearWalletRiskFlag clears the risk flag of a wallet
+func (m *MetricsWalletManager) ClearWalletRiskFlag(ctx context.Context, walletID string) error {
+	start := time.Now()
+	err := m.next.ClearWalletRiskFlag(ctx, walletID)
+	m.observe("clear_wallet_risk_flag", start, err)
+	return err
+}
+
+// GrantAllowance lets a delegate spend up to an amount from a wallet
+func (m *MetricsWalletManager) GrantAllowance(ctx context.Context, walletID string, delegateID string, amount int64) (*Allowance, error) {
+	start := time.Now()
+	allowance, err := m.next.GrantAllowance(ctx, walletID, delegateID, amount)
+	m.observe("grant_allowance", start, err)
+	return allowance, err
+}
+
+// RevokeAllowance withdraws the allowance of a delegate on a wallet
+func (m *MetricsWalletManager) RevokeAllowance(ctx context.Context, walletID string, delegateID string) error {
+	start := time.Now()
+	err := m.next.RevokeAllowance(ctx, walletID, delegateID)
+	m.observe("revoke_allowance", start, err)
+	return err
+}
+
+// GetAllowance gets the allowance of a delegate on a wallet
+func (m *MetricsWalletManager) GetAllowance(ctx context.Context, walletID string, delegateID string) (*Allowance, error) {
+	start := time.Now()
+	allowance, err := m.next.GetAllowance(ctx, walletID, delegateID)
+	m.observe("get_allowance", start, err)
+	return allowance, err
+}
+
+// ListAllowances lists the allowances on a wallet
+func (m *MetricsWalletManager) ListAllowances(ctx context.Context, walletID string) ([]Allowance, error) {
+	start := time.Now()
+	allowances, err := m.next.ListAllowances(ctx, walletID)
+	m.observe("list_allowances", start, err)
+	return allowances, err
+}
+
+// DebitAsDelegate removes funds from a wallet on behalf of a delegate
+func (m *MetricsWalletManager) DebitAsDelegate(ctx context.Context, walletID string, delegateID string, amount int64, description string, note string, reference string, data map[string]interface{}) (*Transaction, error) {
+	start := time.Now()
+	transaction, err := m.next.DebitAsDelegate(ctx, walletID, delegateID, amount, description, note, reference, data)
+	m.observe("debit_as_delegate", start, err)
+	if err == nil {
+		m.observeAmount("debit", amount)
+	}
+	return transaction, err
+}
+
+// TransferFrom moves funds between wallets on behalf of a delegate
+func (m *MetricsWalletManager) TransferFrom(ctx context.Context, fromWalletID string, toWalletID string, delegateID string, amount int64, description string, note string, data map[string]interface{}) error {
+	start := time.Now()
+	err := m.next.TransferFrom(ctx, fromWalletID, toWalletID, delegateID, amount, description, note, data)
+	m.observe("transfer_from", start, err)
+	if err == nil {
+		m.observeAmount("transfer", amount)
+	}
+	return err
+}
@@ -0,0 +1,77 @@
+package wallethub
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestMetricsWalletManager tests the counters, histograms and gauges of the metrics decorator
+func TestMetricsWalletManager(t *testing.T) {
+	store := setupTestGormWalletStore(t)
+	var manager WalletManager = NewMetricsWalletManager(
+		NewWalletManager(WithStore(store)),
+		WithMetricsStore(store),
+		WithAmountBuckets(1000, 100),
+	)
+	metrics := manager.(*MetricsWalletManager)
+	ctx := context.Background()
+
+	wallet, err := manager.CreateWallet(ctx, "user-1", "Wallet", "", "")
+	require.NoError(t, err)
+
+	_, err = manager.Credit(ctx, wallet.ID, 500, "Deposit", "", "", nil)
+	require.NoError(t, err)
+	_, err = manager.Debit(ctx, wallet.ID, 50, "Purchase", "", "", nil)
+	require.NoError(t, err)
+	_, err = manager.Debit(ctx, wallet.ID, 5000, "Purchase", "", "", nil)
+	assert.ErrorIs(t, err, ErrInsufficientBalance)
+	_, err = manager.Credit(ctx, "non-existent-id", 100, "Deposit", "", "", nil)
+	assert.ErrorIs(t, err, ErrWalletNotFound)
+
+	require.NoError(t, store.SaveTransaction(ctx, &Transaction{
+		ID:       GenerateID(),
+		WalletID: wallet.ID,
+		Type:     TransactionTypeCredit,
+		Amount:   100,
+		Status:   TransactionStatusPending,
+	}))
+
+	recorder := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "text/plain; version=0.0.4; charset=utf-8", recorder.Header().Get("Content-Type"))
+
+	body := recorder.Body.String()
+	for _, line := range []string{
+		"# TYPE wallethub_operations_total counter",
+		`wallethub_operations_total{operation="create_wallet",result="success"} 1`,
+		`wallethub_operations_total{operation="credit",result="error"} 1`,
+		`wallethub_operations_total{operation="credit",result="success"} 1`,
+		`wallethub_operations_total{operation="debit",result="error"} 1`,
+		`wallethub_operations_total{operation="debit",result="success"} 1`,
+		`wallethub_operation_errors_total{operation="credit",code="wallet_not_found"} 1`,
+		`wallethub_operation_errors_total{operation="debit",code="insufficient_balance"} 1`,
+		"# TYPE wallethub_operation_duration_seconds histogram",
+		`wallethub_operation_duration_seconds_bucket{operation="debit",le="+Inf"} 2`,
+		`wallethub_operation_duration_seconds_count{operation="debit"} 2`,
+		`wallethub_transaction_amount_bucket{operation="credit",le="100"} 0`,
+		`wallethub_transaction_amount_bucket{operation="credit",le="1000"} 1`,
+		`wallethub_transaction_amount_bucket{operation="debit",le="100"} 1`,
+		`wallethub_transaction_amount_bucket{operation="debit",le="+Inf"} 1`,
+		`wallethub_transaction_amount_sum{operation="debit"} 50`,
+		"# TYPE wallethub_pending_transactions gauge",
+		"wallethub_pending_transactions 1",
+	} {
+		assert.Contains(t, body, line+"\n")
+	}
+}
+
+// TestEscapeLabelValue tests the escaping of label values
+func TestEscapeLabelValue(t *testing.T) {
+	assert.Equal(t, `a\\b\"c\nd`, escapeLabelValue("a\\b\"c\nd"))
+}
@@ -0,0 +1,181 @@
+package wallethub
+
+import (
+	"context"
+	"time"
+)
+
+// reconciliationPageSize is the number of wallets read per query by Reconcile
+const reconciliationPageSize = 100
+
+// DiscrepancyKind defines the kinds of inconsistencies found by reconciliation
+type DiscrepancyKind string
+
+const (
+	// DiscrepancyKindBalance means the wallet balance differs from the sum of its completed transactions
+	DiscrepancyKindBalance DiscrepancyKind = "balance_mismatch"
+	// DiscrepancyKindRunningBalance means a transaction's recorded balance differs from the recomputed running total
+	DiscrepancyKindRunningBalance DiscrepancyKind = "running_balance_mismatch"
+)
+
+// Discrepancy describes a single inconsistency between a wallet and its transaction history
+type Discrepancy struct {
+	Kind          DiscrepancyKind `json:"kind"`
+	WalletID      string          `json:"wallet_id"`
+	TransactionID string          `json:"transaction_id,omitempty"` // Set for running balance mismatches
+	Expected      int64           `json:"expected"`                 // Balance recomputed from the history
+	Actual        int64           `json:"actual"`                   // Balance stored in the database
+}
+
+// ReconciliationReport summarizes a reconciliation run
+type ReconciliationReport struct {
+	StartedAt      time.Time     `json:"started_at"`
+	FinishedAt     time.Time     `json:"finished_at"`
+	WalletsChecked int           `json:"wallets_checked"`
+	Discrepancies  []Discrepancy `json:"discrepancies"`
+	Adjustments    []Transaction `json:"adjustments"` // Adjustment transactions posted when repairing
+}
+
+// Consistent reports whether no discrepancies were found
+func (r *ReconciliationReport) Consistent() bool {
+	return len(r.Discrepancies) == 0
+}
+
+// Reconcile checks every wallet against its transaction history. See ReconcileWallet.
+func (m *DefaultWalletManager) Reconcile(ctx context.Context, repair bool) (*ReconciliationReport, error) {
+	report := m.newReconciliationReport()
+
+	for offset := 0; ; offset += reconciliationPageSize {
+		wallets, err := m.store.FindWallets(ctx, reconciliationPageSize, offset)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, wallet := range wallets {
+			if err := m.reconcileWallet(ctx, wallet.ID, repair, report); err != nil {
+				return nil, err
+			}
+		}
+
+		if len(wallets) < reconciliationPageSize {
+			break
+		}
+	}
+
+	report.FinishedAt = m.clock.Now()
+	return report, nil
+}
+
+// ReconcileWallet recomputes a wallet's balance from its completed transactions and verifies the
+// running balance recorded on each of them.
+//
+// When repair is set and the wallet balance differs from the history, an adjustment transaction for
+// the difference is posted so the history matches the wallet balance again. The wallet balance itself
+// is left untouched. Running balance mismatches are only reported, as they cannot be repaired without
+// editing historical transactions.
+func (m *DefaultWalletManager) ReconcileWallet(ctx context.Context, walletID string, repair bool) (*ReconciliationReport, error) {
+	report := m.newReconciliationReport()
+
+	if err := m.reconcileWallet(ctx, walletID, repair, report); err != nil {
+		return nil, err
+	}
+
+	report.FinishedAt = m.clock.Now()
+	return report, nil
+}
+
+// newReconciliationReport creates an empty report started now
+func (m *DefaultWalletManager) newReconciliationReport() *ReconciliationReport {
+	return &ReconciliationReport{
+		StartedAt:     m.clock.Now(),
+		Discrepancies: make([]Discrepancy, 0),
+		Adjustments:   make([]Transaction, 0),
+	}
+}
+
+// reconcileWallet checks a single wallet within one store transaction and adds the findings to the report
+func (m *DefaultWalletManager) reconcileWallet(ctx context.Context, walletID string, repair bool, report *ReconciliationReport) error {
+	txn := m.store.Begin(ctx)
+	defer txn.Rollback()
+
+	// Lock the wallet, so its balance and history cannot change while they are compared
+	wallet, err := lockWallet(txn, walletID)
+	if err != nil {
+		return newWalletError(err, walletID)
+	}
+
+	// Verify running balances while recomputing the balance
+	fetch := func(limit int, offset int) ([]Transaction, error) {
+		return txn.FindCompletedTransactionsByWalletID(walletID, limit, offset)
+	}
+	expected, err := walkLedger(0, fetch, func(transaction *Transaction, balance int64) error {
+		if transaction.Balance != balance {
+			report.Discrepancies = append(report.Discrepancies, Discrepancy{
+				Kind:          DiscrepancyKindRunningBalance,
+				WalletID:      walletID,
+				TransactionID: transaction.ID,
+				Expected:      balance,
+				Actual:        transaction.Balance,
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	report.WalletsChecked++
+	if wallet.Balance == expected {
+		return nil
+	}
+
+	report.Discrepancies = append(report.Discrepancies, Discrepancy{
+		Kind:     DiscrepancyKindBalance,
+		WalletID: walletID,
+		Expected: expected,
+		Actual:   wallet.Balance,
+	})
+
+	if !repair {
+		return nil
+	}
+
+	// Post an adjustment so the history sums up to the wallet balance
+	difference := wallet.Balance - expected
+	transactionType := TransactionTypeCredit
+	if difference < 0 {
+		transactionType = TransactionTypeDebit
+		difference = -difference
+	}
+
+	now := m.clock.Now()
+	adjustment := &Transaction{
+		ID:          m.ids.NewID(),
+		WalletID:    walletID,
+		Type:        transactionType,
+		Amount:      difference,
+		Balance:     wallet.Balance,
+		Description: "Reconciliation adjustment",
+		Reference:   "reconciliation",
+		Status:      TransactionStatusCompleted,
+		Data: map[string]interface{}{
+			"reconciliation":   true,
+			"expected_balance": expected,
+			"actual_balance":   wallet.Balance,
+		},
+		CreatedAt:   now,
+		CompletedAt: now,
+	}
+
+	if err := txn.SaveTransaction(adjustment); err != nil {
+		return err
+	}
+
+	// Commit the transaction
+	if err := txn.Commit(); err != nil {
+		return err
+	}
+
+	report.Adjustments = append(report.Adjustments, *adjustment)
+	return nil
+}
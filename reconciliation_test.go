@@ -0,0 +1,109 @@
+package wallethub
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestReconcileConsistent tests that a consistent ledger produces no discrepancies
+func TestReconcileConsistent(t *testing.T) {
+	store := setupTestGormWalletStore(t)
+	manager := NewWalletManager(WithStore(store))
+	ctx := context.Background()
+
+	wallet1, err := manager.CreateWallet(ctx, "test-user", "Wallet 1", "Description 1", "ref-1")
+	require.NoError(t, err)
+	wallet2, err := manager.CreateWallet(ctx, "test-user", "Wallet 2", "Description 2", "ref-2")
+	require.NoError(t, err)
+
+	_, err = manager.Credit(ctx, wallet1.ID, 1000, "Credit", "Note", "credit-ref", nil)
+	require.NoError(t, err)
+	_, err = manager.Debit(ctx, wallet1.ID, 300, "Debit", "Note", "debit-ref", nil)
+	require.NoError(t, err)
+	err = manager.Transfer(ctx, wallet1.ID, wallet2.ID, 200, "Transfer", "Note", nil)
+	require.NoError(t, err)
+
+	// Bulk credits complete at the same instant
+	_, err = manager.BulkCredit(ctx, "batch-1", slices.Values([]BulkCreditItem{
+		{WalletID: wallet2.ID, Amount: 10, Reference: "item-1"},
+		{WalletID: wallet2.ID, Amount: 20, Reference: "item-2"},
+		{WalletID: wallet2.ID, Amount: 30, Reference: "item-3"},
+	}), nil)
+	require.NoError(t, err)
+
+	report, err := manager.Reconcile(ctx, false)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, report.WalletsChecked)
+	assert.True(t, report.Consistent())
+	assert.Empty(t, report.Adjustments)
+	assert.False(t, report.FinishedAt.Before(report.StartedAt))
+}
+
+// TestReconcileDiscrepancies tests detecting and repairing drift between balances and history
+func TestReconcileDiscrepancies(t *testing.T) {
+	store := setupTestGormWalletStore(t)
+	manager := NewWalletManager(WithStore(store))
+	ctx := context.Background()
+
+	wallet, err := manager.CreateWallet(ctx, "test-user", "Test Wallet", "Test Description", "test-ref")
+	require.NoError(t, err)
+
+	_, err = manager.Credit(ctx, wallet.ID, 1000, "Credit", "Note", "credit-ref", nil)
+	require.NoError(t, err)
+	debitTx, err := manager.Debit(ctx, wallet.ID, 300, "Debit", "Note", "debit-ref", nil)
+	require.NoError(t, err)
+
+	// Tamper with the running balance of a transaction
+	debitTx.Balance = 650
+	err = store.UpdateTransaction(ctx, debitTx)
+	require.NoError(t, err)
+
+	// Let the wallet balance drift
+	drifted, err := manager.GetWallet(ctx, wallet.ID)
+	require.NoError(t, err)
+	drifted.Balance = 750
+	err = store.UpdateWallet(ctx, drifted)
+	require.NoError(t, err)
+
+	report, err := manager.ReconcileWallet(ctx, wallet.ID, false)
+	assert.NoError(t, err)
+	assert.False(t, report.Consistent())
+	require.Len(t, report.Discrepancies, 2)
+
+	assert.Equal(t, DiscrepancyKindRunningBalance, report.Discrepancies[0].Kind)
+	assert.Equal(t, debitTx.ID, report.Discrepancies[0].TransactionID)
+	assert.Equal(t, int64(700), report.Discrepancies[0].Expected)
+	assert.Equal(t, int64(650), report.Discrepancies[0].Actual)
+
+	assert.Equal(t, DiscrepancyKindBalance, report.Discrepancies[1].Kind)
+	assert.Equal(t, wallet.ID, report.Discrepancies[1].WalletID)
+	assert.Equal(t, int64(700), report.Discrepancies[1].Expected)
+	assert.Equal(t, int64(750), report.Discrepancies[1].Actual)
+
+	// Repair posts an adjustment without changing the wallet balance
+	report, err = manager.Reconcile(ctx, true)
+	assert.NoError(t, err)
+	require.Len(t, report.Adjustments, 1)
+	assert.Equal(t, TransactionTypeCredit, report.Adjustments[0].Type)
+	assert.Equal(t, int64(50), report.Adjustments[0].Amount)
+	assert.Equal(t, int64(750), report.Adjustments[0].Balance)
+
+	updatedWallet, err := manager.GetWallet(ctx, wallet.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(750), updatedWallet.Balance)
+
+	// Only the tampered running balance remains
+	report, err = manager.ReconcileWallet(ctx, wallet.ID, true)
+	assert.NoError(t, err)
+	require.Len(t, report.Discrepancies, 1)
+	assert.Equal(t, DiscrepancyKindRunningBalance, report.Discrepancies[0].Kind)
+	assert.Empty(t, report.Adjustments)
+
+	// Unknown wallets are reported as not found
+	_, err = manager.ReconcileWallet(ctx, "non-existent-id", false)
+	assert.ErrorIs(t, err, ErrWalletNotFound)
+}
@@ -0,0 +1,39 @@
+package wallethub
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrInvalidReportInterval is returned for report intervals other than hour, day and month
+var ErrInvalidReportInterval = errors.New("invalid report interval")
+
+// ReportInterval defines the size of the time buckets of a report
+type ReportInterval string
+
+const (
+	ReportIntervalHour  ReportInterval = "hour"
+	ReportIntervalDay   ReportInterval = "day"
+	ReportIntervalMonth ReportInterval = "month"
+)
+
+// ReportFilter narrows the transactions included in a report. Empty fields match everything, except
+// Statuses which only matches completed transactions when empty, so failed and pending attempts are
+// not reported as moved amounts.
+type ReportFilter struct {
+	WalletID string
+	UserID   string
+	Types    []TransactionType
+	Statuses []TransactionStatus
+}
+
+// TransactionAggregate holds the totals of the transactions created within one time bucket
+type TransactionAggregate struct {
+	Bucket           time.Time `json:"bucket"` // Start of the bucket
+	Credited         int64     `json:"credited"`
+	Debited          int64     `json:"debited"`
+	CreditCount      int64     `json:"credit_count"`
+	DebitCount       int64     `json:"debit_count"`
+	TransactionCount int64     `json:"transaction_count"`
+	ActiveWallets    int64     `json:"active_wallets"` // Number of distinct wallets with a transaction in the bucket
+}
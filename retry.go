@@ -0,0 +1,120 @@
+package wallethub
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math/rand/v2"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// RetryPolicy configures how transactional operations are re-run after retryable database errors.
+// The whole operation is re-run in a new store transaction, so no partial attempt is ever committed.
+type RetryPolicy struct {
+	MaxAttempts int                  // Total attempts including the first; 1 or less disables retries
+	BaseDelay   time.Duration        // Upper bound of the delay before the first retry, doubled per retry
+	MaxDelay    time.Duration        // Upper bound of any delay
+	Retryable   func(err error) bool // Classifies retryable errors, IsRetryableError if nil
+}
+
+// DefaultRetryPolicy retries an operation up to twice after retryable database errors
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   10 * time.Millisecond,
+	MaxDelay:    200 * time.Millisecond,
+}
+
+// WithRetryPolicy sets the policy re-running transactional operations that fail with retryable
+// database errors, such as deadlocks. Operations are not retried by default.
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(m *DefaultWalletManager) {
+		m.retryPolicy = policy
+	}
+}
+
+// retryableSQLStates are the SQLSTATE codes of serialization failures and deadlocks
+var retryableSQLStates = map[string]bool{
+	"40001": true, // serialization_failure
+	"40P01": true, // deadlock_detected (PostgreSQL)
+}
+
+// mysqlErrorPattern matches the messages of MySQL deadlock (1213) and lock wait timeout (1205) errors
+var mysqlErrorPattern = regexp.MustCompile(`^Error (1213|1205)\b`)
+
+// IsRetryableError reports whether an error is a transient database error after which the whole
+// store transaction can be re-run: a deadlock, a serialization failure, a lock wait timeout or a
+// busy or locked SQLite database. Errors are classified without depending on the database driver.
+func IsRetryableError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	// PostgreSQL drivers expose the SQLSTATE of errors
+	var stateErr interface{ SQLState() string }
+	if errors.As(err, &stateErr) && retryableSQLStates[stateErr.SQLState()] {
+		return true
+	}
+
+	// MySQL and SQLite errors are recognized by their messages
+	for e := err; e != nil; e = errors.Unwrap(e) {
+		msg := e.Error()
+		if mysqlErrorPattern.MatchString(msg) ||
+			strings.Contains(msg, "database is locked") ||
+			strings.Contains(msg, "database table is locked") ||
+			strings.Contains(msg, "SQLITE_BUSY") {
+			return true
+		}
+	}
+	return false
+}
+
+// retryable reports whether an error is retried under the policy
+func (p RetryPolicy) retryable(err error) bool {
+	if p.Retryable != nil {
+		return p.Retryable(err)
+	}
+	return IsRetryableError(err)
+}
+
+// delay returns the jittered delay before the given retry, counted from 1
+func (p RetryPolicy) delay(retry int) time.Duration {
+	limit := p.BaseDelay << (retry - 1)
+	if limit <= 0 || (p.MaxDelay > 0 && limit > p.MaxDelay) {
+		limit = p.MaxDelay
+	}
+	if limit <= 0 {
+		return 0
+	}
+	return rand.N(limit + 1)
+}
+
+// retry runs an attempt until it succeeds, fails with an error the policy does not retry, runs out of
+// attempts, or the context would end before the next attempt. The error of the last attempt is returned.
+func (m *DefaultWalletManager) retry(ctx context.Context, attempt func() error) error {
+	for retry := 1; ; retry++ {
+		err := attempt()
+		if err == nil || retry >= m.retryPolicy.MaxAttempts || !m.retryPolicy.retryable(err) {
+			return err
+		}
+
+		delay := m.retryPolicy.delay(retry)
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			return err
+		}
+		m.log.log(ctx, slog.LevelWarn, "transaction retry", nil,
+			slog.Int("attempt", retry),
+			slog.Duration("delay", delay),
+			slog.String("error", err.Error()),
+		)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
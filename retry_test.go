@@ -0,0 +1,147 @@
+package wallethub
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sqlStateError mimics the errors of PostgreSQL drivers
+type sqlStateError struct {
+	state string
+}
+
+func (e *sqlStateError) Error() string    { return "pq: error " + e.state }
+func (e *sqlStateError) SQLState() string { return e.state }
+
+// conflictingStore is a store whose commits fail with an error a number of times
+type conflictingStore struct {
+	WalletStore
+	conflicts int
+	err       error
+	begins    int
+}
+
+// conflictingTxn is a transaction of a conflictingStore
+type conflictingTxn struct {
+	Txn
+	store *conflictingStore
+}
+
+func (s *conflictingStore) Begin(ctx context.Context) Txn {
+	s.begins++
+	return &conflictingTxn{Txn: s.WalletStore.Begin(ctx), store: s}
+}
+
+func (t *conflictingTxn) Commit() error {
+	if t.store.conflicts > 0 {
+		t.store.conflicts--
+		return t.store.err
+	}
+	return t.Txn.Commit()
+}
+
+// TestIsRetryableError tests the classification of database errors
+func TestIsRetryableError(t *testing.T) {
+	assert.True(t, IsRetryableError(&sqlStateError{"40001"}))
+	assert.True(t, IsRetryableError(&sqlStateError{"40P01"}))
+	assert.False(t, IsRetryableError(&sqlStateError{"23505"}))
+
+	assert.True(t, IsRetryableError(errors.New("Error 1213 (40001): Deadlock found when trying to get lock; try restarting transaction")))
+	assert.True(t, IsRetryableError(errors.New("Error 1205 (HY000): Lock wait timeout exceeded; try restarting transaction")))
+	assert.False(t, IsRetryableError(errors.New("Error 1062 (23000): Duplicate entry '1' for key 'PRIMARY'")))
+
+	assert.True(t, IsRetryableError(errors.New("database is locked")))
+	assert.True(t, IsRetryableError(errors.New("database is locked (5) (SQLITE_BUSY)")))
+
+	// Wrapped errors are classified by their cause
+	assert.True(t, IsRetryableError(newWalletError(&sqlStateError{"40001"}, "wallet-1")))
+	assert.True(t, IsRetryableError(newWalletError(errors.New("database is locked"), "wallet-1")))
+
+	assert.False(t, IsRetryableError(newWalletError(ErrInsufficientBalance, "wallet-1")))
+	assert.False(t, IsRetryableError(nil))
+}
+
+// TestWalletManagerRetry tests that transactional operations are re-run after retryable errors
+func TestWalletManagerRetry(t *testing.T) {
+	store := &conflictingStore{WalletStore: setupTestGormWalletStore(t), err: &sqlStateError{"40P01"}}
+	manager := NewWalletManager(WithStore(store), WithRetryPolicy(RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   time.Millisecond,
+		MaxDelay:    5 * time.Millisecond,
+	}))
+	ctx := context.Background()
+
+	wallet1, err := manager.CreateWallet(ctx, "user-1", "Wallet 1", "", "")
+	require.NoError(t, err)
+	wallet2, err := manager.CreateWallet(ctx, "user-2", "Wallet 2", "", "")
+	require.NoError(t, err)
+	_, err = manager.Credit(ctx, wallet1.ID, 1000, "Deposit", "", "", nil)
+	require.NoError(t, err)
+
+	// A transfer that deadlocks twice succeeds on the third attempt and is applied once
+	store.conflicts = 2
+	store.begins = 0
+	err = manager.Transfer(ctx, wallet1.ID, wallet2.ID, 300, "Transfer", "", nil)
+	require.NoError(t, err)
+	assert.Equal(t, 3, store.begins)
+
+	wallet1, err = manager.GetWallet(ctx, wallet1.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(700), wallet1.Balance)
+
+	transactions, err := manager.ListTransactions(ctx, wallet2.ID, 10, 0)
+	require.NoError(t, err)
+	assert.Len(t, transactions, 1)
+
+	// The error of the last attempt is returned once attempts run out
+	store.conflicts = 3
+	store.begins = 0
+	err = manager.Transfer(ctx, wallet1.ID, wallet2.ID, 300, "Transfer", "", nil)
+	assert.True(t, IsRetryableError(err))
+	assert.Equal(t, CodeInternal, ErrorCodeOf(err))
+	assert.Equal(t, 3, store.begins)
+	store.conflicts = 0
+
+	// Other errors are not retried
+	store.begins = 0
+	err = manager.Transfer(ctx, wallet1.ID, wallet2.ID, 5000, "Transfer", "", nil)
+	assert.ErrorIs(t, err, ErrInsufficientBalance)
+	assert.Equal(t, 1, store.begins)
+}
+
+// TestWalletManagerRetryDeadline tests that retries stop when the context would end before the next attempt
+func TestWalletManagerRetryDeadline(t *testing.T) {
+	store := &conflictingStore{WalletStore: setupTestGormWalletStore(t), err: errors.New("database is locked")}
+	manager := NewWalletManager(WithStore(store), WithRetryPolicy(RetryPolicy{
+		MaxAttempts: 5,
+		BaseDelay:   1000 * time.Hour,
+		MaxDelay:    1000 * time.Hour,
+	}))
+
+	wallet, err := manager.CreateWallet(context.Background(), "user-1", "Wallet", "", "")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	store.conflicts = 1
+	store.begins = 0
+	start := time.Now()
+	_, err = manager.Credit(ctx, wallet.ID, 100, "Deposit", "", "", nil)
+	assert.True(t, IsRetryableError(err))
+	assert.Equal(t, 1, store.begins)
+	assert.Less(t, time.Since(start), time.Second)
+
+	// Without a retry policy, nothing is retried
+	manager = NewWalletManager(WithStore(store))
+	store.conflicts = 1
+	store.begins = 0
+	_, err = manager.Credit(context.Background(), wallet.ID, 100, "Deposit", "", "", nil)
+	assert.True(t, IsRetryableError(err))
+	assert.Equal(t, 1, store.begins)
+}
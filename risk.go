@@ -0,0 +1,203 @@
+package wallethub
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Risk error definitions
+var (
+	ErrRiskBlocked          = errors.New("outgoing transactions of risk-flagged wallet are blocked")
+	ErrRiskApprovalRequired = errors.New("amount requires approval for risk-flagged wallet")
+	ErrRiskVelocityExceeded = errors.New("outgoing velocity limit of risk-flagged wallet exceeded")
+)
+
+// DefaultRiskVelocityWindow is the velocity window used when RiskPolicy.VelocityWindow is zero
+const DefaultRiskVelocityWindow = 24 * time.Hour
+
+// RiskPolicy restricts the outgoing transactions, debits and the source side of transfers, of
+// risk-flagged wallets. The zero value places no restrictions.
+type RiskPolicy struct {
+	BlockOutgoing     bool          // Refuse all outgoing transactions
+	ApprovalThreshold int64         // Refuse outgoing amounts above this unless approved, 0 disables
+	VelocityLimit     int64         // Maximum total outgoing amount within VelocityWindow, 0 disables
+	VelocityWindow    time.Duration // Period over which VelocityLimit applies
+}
+
+// WithRiskPolicy sets the restrictions applied to risk-flagged wallets
+func WithRiskPolicy(policy RiskPolicy) Option {
+	return func(m *DefaultWalletManager) {
+		m.riskPolicy = policy
+	}
+}
+
+// RiskEvent describes an attempted credit or debit evaluated by risk rules. Transfers are evaluated
+// as a debit of the source wallet and, if successful, a credit of the destination wallet.
+type RiskEvent struct {
+	WalletID string
+	Type     TransactionType
+	Amount   int64
+	Err      error // Why the attempt failed, nil if it succeeded
+	At       time.Time
+}
+
+// RiskRule decides whether a wallet should be flagged after a credit or debit attempt. It returns
+// the reason to record with the flag, or an empty string to leave the wallet alone.
+type RiskRule interface {
+	Evaluate(ctx context.Context, event *RiskEvent) string
+}
+
+// RiskRuleFunc adapts a function to the RiskRule interface
+type RiskRuleFunc func(ctx context.Context, event *RiskEvent) string
+
+// Evaluate calls f(ctx, event)
+func (f RiskRuleFunc) Evaluate(ctx context.Context, event *RiskEvent) string {
+	return f(ctx, event)
+}
+
+// WithRiskRules sets the rules that automatically flag wallets through FlagWalletRisk
+func WithRiskRules(rules ...RiskRule) Option {
+	return func(m *DefaultWalletManager) {
+		m.riskRules = rules
+	}
+}
+
+// UnusualAmountRule flags wallets on attempts of at least Threshold. An empty Type matches both
+// credits and debits.
+type UnusualAmountRule struct {
+	Type      TransactionType
+	Threshold int64
+}
+
+// Evaluate implements the RiskRule interface
+func (r UnusualAmountRule) Evaluate(ctx context.Context, event *RiskEvent) string {
+	if r.Type != "" && r.Type != event.Type {
+		return ""
+	}
+	if event.Amount < r.Threshold {
+		return ""
+	}
+	return fmt.Sprintf("unusual %s amount %d", event.Type, event.Amount)
+}
+
+// FailedDebitsRule flags wallets with a number of failed debits within a period. Failures are
+// counted in memory, so each process counts only the attempts it handled itself.
+type FailedDebitsRule struct {
+	max      int
+	window   time.Duration
+	mu       sync.Mutex
+	failures map[string][]time.Time
+}
+
+// NewFailedDebitsRule creates a rule flagging wallets with max failed debits within window
+func NewFailedDebitsRule(max int, window time.Duration) *FailedDebitsRule {
+	return &FailedDebitsRule{
+		max:      max,
+		window:   window,
+		failures: make(map[string][]time.Time),
+	}
+}
+
+// Evaluate implements the RiskRule interface
+func (r *FailedDebitsRule) Evaluate(ctx context.Context, event *RiskEvent) string {
+	if event.Type != TransactionTypeDebit || event.Err == nil {
+		return ""
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Keep only the failures within the window
+	key := TenantFromContext(ctx) + "\x00" + event.WalletID
+	cutoff := event.At.Add(-r.window)
+	recent := []time.Time{event.At}
+	for _, at := range r.failures[key] {
+		if at.After(cutoff) {
+			recent = append(recent, at)
+		}
+	}
+
+	if len(recent) < r.max {
+		r.failures[key] = recent
+		return ""
+	}
+
+	delete(r.failures, key)
+	return fmt.Sprintf("%d failed debits within %s", len(recent), r.window)
+}
+
+// checkOutgoingRisk applies the risk policy to an outgoing amount of a wallet within an open store transaction
+func (m *DefaultWalletManager) checkOutgoingRisk(txn Txn, wallet *Wallet, amount int64) error {
+	if !wallet.RiskFlagged {
+		return nil
+	}
+
+	policy := m.riskPolicy
+	if policy.BlockOutgoing {
+		return ErrRiskBlocked
+	}
+	if policy.ApprovalThreshold > 0 && amount > policy.ApprovalThreshold {
+		return ErrRiskApprovalRequired
+	}
+
+	if policy.VelocityLimit > 0 {
+		window := policy.VelocityWindow
+		if window <= 0 {
+			window = DefaultRiskVelocityWindow
+		}
+
+		now := m.clock.Now()
+		_, debits, err := txn.SumTransactionTotalsByWalletID(wallet.ID, now.Add(-window), now)
+		if err != nil {
+			return err
+		}
+		if debits+amount > policy.VelocityLimit {
+			return ErrRiskVelocityExceeded
+		}
+	}
+
+	return nil
+}
+
+// withRiskApproval returns a copy of the manager that lets outgoing amounts of risk-flagged wallets
+// above the approval threshold pass, for executing operations that have been approved
+func (m *DefaultWalletManager) withRiskApproval() *DefaultWalletManager {
+	approved := *m
+	approved.riskPolicy.ApprovalThreshold = 0
+	return &approved
+}
+
+// evaluateRiskRules runs every risk rule on a credit or debit attempt and flags the wallet with the
+// reason of the first rule that fires. Flagging is best effort and never fails the attempt.
+func (m *DefaultWalletManager) evaluateRiskRules(ctx context.Context, walletID string, transactionType TransactionType, amount int64, attemptErr error) {
+	if len(m.riskRules) == 0 {
+		return
+	}
+
+	event := &RiskEvent{
+		WalletID: walletID,
+		Type:     transactionType,
+		Amount:   amount,
+		Err:      attemptErr,
+		At:       m.clock.Now(),
+	}
+	reason := ""
+	for _, rule := range m.riskRules {
+		// Every rule sees every attempt, so stateful rules keep counting
+		if ruleReason := rule.Evaluate(ctx, event); reason == "" {
+			reason = ruleReason
+		}
+	}
+	if reason == "" {
+		return
+	}
+
+	wallet, err := m.store.FindWallet(ctx, walletID)
+	if err != nil || wallet == nil || wallet.RiskFlagged {
+		return
+	}
+	_ = m.FlagWalletRisk(ctx, walletID, reason)
+}
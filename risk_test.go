@@ -0,0 +1,213 @@
+package wallethub
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupTestRisk creates a manager with the given options and a flagged and an unflagged funded wallet
+func setupTestRisk(t *testing.T, options ...Option) (*DefaultWalletManager, *Wallet, *Wallet) {
+	manager := NewWalletManager(append([]Option{WithStore(setupTestGormWalletStore(t))}, options...)...)
+	ctx := context.Background()
+
+	flagged, err := manager.CreateWallet(ctx, "user-1", "Flagged Wallet", "", "")
+	require.NoError(t, err)
+	other, err := manager.CreateWallet(ctx, "user-2", "Other Wallet", "", "")
+	require.NoError(t, err)
+
+	_, err = manager.Credit(ctx, flagged.ID, 10000, "Deposit", "", "", nil)
+	require.NoError(t, err)
+	_, err = manager.Credit(ctx, other.ID, 10000, "Deposit", "", "", nil)
+	require.NoError(t, err)
+	require.NoError(t, manager.FlagWalletRisk(ctx, flagged.ID, "Manual review"))
+
+	return manager, flagged, other
+}
+
+// TestRiskPolicyBlockOutgoing tests blocking outgoing transactions of flagged wallets
+func TestRiskPolicyBlockOutgoing(t *testing.T) {
+	manager, flagged, other := setupTestRisk(t, WithRiskPolicy(RiskPolicy{BlockOutgoing: true}))
+	ctx := context.Background()
+
+	_, err := manager.Debit(ctx, flagged.ID, 100, "Purchase", "", "", nil)
+	assert.ErrorIs(t, err, ErrRiskBlocked)
+
+	err = manager.Transfer(ctx, flagged.ID, other.ID, 100, "Transfer", "", nil)
+	assert.ErrorIs(t, err, ErrRiskBlocked)
+
+	// Incoming transactions and unflagged wallets are not affected
+	_, err = manager.Credit(ctx, flagged.ID, 100, "Refund", "", "", nil)
+	assert.NoError(t, err)
+
+	err = manager.Transfer(ctx, other.ID, flagged.ID, 100, "Transfer", "", nil)
+	assert.NoError(t, err)
+
+	_, err = manager.Debit(ctx, other.ID, 100, "Purchase", "", "", nil)
+	assert.NoError(t, err)
+
+	// Clearing the flag lifts the restriction
+	require.NoError(t, manager.ClearWalletRiskFlag(ctx, flagged.ID))
+	_, err = manager.Debit(ctx, flagged.ID, 100, "Purchase", "", "", nil)
+	assert.NoError(t, err)
+}
+
+// TestRiskPolicyVelocity tests limiting the outgoing velocity of flagged wallets
+func TestRiskPolicyVelocity(t *testing.T) {
+	manager, flagged, other := setupTestRisk(t, WithRiskPolicy(RiskPolicy{VelocityLimit: 1000, VelocityWindow: time.Hour}))
+	ctx := context.Background()
+
+	_, err := manager.Debit(ctx, flagged.ID, 600, "Purchase", "", "", nil)
+	assert.NoError(t, err)
+
+	err = manager.Transfer(ctx, flagged.ID, other.ID, 400, "Transfer", "", nil)
+	assert.NoError(t, err)
+
+	_, err = manager.Debit(ctx, flagged.ID, 1, "Purchase", "", "", nil)
+	assert.ErrorIs(t, err, ErrRiskVelocityExceeded)
+
+	wallet, err := manager.GetWallet(ctx, flagged.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(9000), wallet.Balance)
+}
+
+// TestRiskPolicyApproval tests that large outgoing amounts of flagged wallets are held for approval
+func TestRiskPolicyApproval(t *testing.T) {
+	manager, flagged, other := setupTestRisk(t, WithRiskPolicy(RiskPolicy{ApprovalThreshold: 1000}))
+	approvals := NewApprovalManager(manager, setupTestGormApprovalStore(t), WithCreditApprovalThreshold(1000000))
+	makerCtx := WithPrincipal(context.Background(), &Principal{UserID: "maker", Roles: []string{RoleAdmin}})
+	checkerCtx := WithPrincipal(context.Background(), &Principal{UserID: "checker", Roles: []string{RoleAdmin}})
+
+	_, err := manager.Debit(context.Background(), flagged.ID, 1001, "Purchase", "", "", nil)
+	assert.ErrorIs(t, err, ErrRiskApprovalRequired)
+
+	// Amounts up to the threshold pass
+	transaction, approval, err := approvals.Debit(makerCtx, flagged.ID, 1000, "Purchase", "", "", nil)
+	assert.NoError(t, err)
+	assert.NotNil(t, transaction)
+	assert.Nil(t, approval)
+
+	// Larger amounts are held for approval
+	transaction, debitApproval, err := approvals.Debit(makerCtx, flagged.ID, 2000, "Purchase", "", "order-1", nil)
+	assert.NoError(t, err)
+	assert.Nil(t, transaction)
+	require.NotNil(t, debitApproval)
+	assert.Equal(t, ApprovalOperationDebit, debitApproval.Operation)
+
+	transferApproval, err := approvals.Transfer(makerCtx, flagged.ID, other.ID, 3000, "Transfer", "", nil)
+	assert.NoError(t, err)
+	require.NotNil(t, transferApproval)
+	assert.Equal(t, other.ID, transferApproval.ToWalletID)
+
+	wallet, err := manager.GetWallet(context.Background(), flagged.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(9000), wallet.Balance)
+
+	// Approved operations pass the threshold
+	debitApproval, err = approvals.Approve(checkerCtx, debitApproval.ID, "")
+	assert.NoError(t, err)
+	assert.Equal(t, ApprovalStatusApproved, debitApproval.Status)
+
+	transferApproval, err = approvals.Approve(checkerCtx, transferApproval.ID, "")
+	assert.NoError(t, err)
+	assert.Equal(t, ApprovalStatusApproved, transferApproval.Status)
+
+	wallet, err = manager.GetWallet(context.Background(), flagged.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(4000), wallet.Balance)
+
+	wallet, err = manager.GetWallet(context.Background(), other.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(13000), wallet.Balance)
+
+	transaction, err = manager.GetTransaction(context.Background(), debitApproval.TransactionID)
+	assert.NoError(t, err)
+	require.NotNil(t, transaction)
+	assert.Equal(t, "order-1", transaction.Reference)
+
+	// The manager itself still enforces the threshold
+	_, err = manager.Debit(context.Background(), flagged.ID, 1001, "Purchase", "", "", nil)
+	assert.ErrorIs(t, err, ErrRiskApprovalRequired)
+}
+
+// TestRiskRules tests automatic flagging of wallets
+func TestRiskRules(t *testing.T) {
+	manager := NewWalletManager(
+		WithStore(setupTestGormWalletStore(t)),
+		WithRiskRules(
+			UnusualAmountRule{Type: TransactionTypeCredit, Threshold: 50000},
+			NewFailedDebitsRule(3, time.Hour),
+		),
+	)
+	ctx := context.Background()
+
+	wallet1, err := manager.CreateWallet(ctx, "user-1", "Wallet 1", "", "")
+	require.NoError(t, err)
+	wallet2, err := manager.CreateWallet(ctx, "user-2", "Wallet 2", "", "")
+	require.NoError(t, err)
+
+	// Unusual amounts
+	_, err = manager.Credit(ctx, wallet1.ID, 49999, "Deposit", "", "", nil)
+	require.NoError(t, err)
+
+	wallet, err := manager.GetWallet(ctx, wallet1.ID)
+	require.NoError(t, err)
+	assert.False(t, wallet.RiskFlagged)
+
+	_, err = manager.Credit(ctx, wallet1.ID, 50000, "Deposit", "", "", nil)
+	require.NoError(t, err)
+
+	wallet, err = manager.GetWallet(ctx, wallet1.ID)
+	require.NoError(t, err)
+	assert.True(t, wallet.RiskFlagged)
+	assert.Equal(t, "unusual credit amount 50000", wallet.RiskReason)
+
+	// Repeated failed debits
+	for i := 0; i < 2; i++ {
+		_, err = manager.Debit(ctx, wallet2.ID, 100, "Purchase", "", "", nil)
+		assert.ErrorIs(t, err, ErrInsufficientBalance)
+	}
+
+	wallet, err = manager.GetWallet(ctx, wallet2.ID)
+	require.NoError(t, err)
+	assert.False(t, wallet.RiskFlagged)
+
+	err = manager.Transfer(ctx, wallet2.ID, wallet1.ID, 100, "Transfer", "", nil)
+	assert.ErrorIs(t, err, ErrInsufficientBalance)
+
+	wallet, err = manager.GetWallet(ctx, wallet2.ID)
+	require.NoError(t, err)
+	assert.True(t, wallet.RiskFlagged)
+	assert.Equal(t, "3 failed debits within 1h0m0s", wallet.RiskReason)
+
+	// Clearing the flag clears the reason
+	require.NoError(t, manager.ClearWalletRiskFlag(ctx, wallet2.ID))
+	wallet, err = manager.GetWallet(ctx, wallet2.ID)
+	require.NoError(t, err)
+	assert.False(t, wallet.RiskFlagged)
+	assert.Empty(t, wallet.RiskReason)
+}
+
+// TestFailedDebitsRuleWindow tests that failures outside the window are not counted
+func TestFailedDebitsRuleWindow(t *testing.T) {
+	rule := NewFailedDebitsRule(2, time.Hour)
+	ctx := context.Background()
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	failure := func(walletID string, at time.Time) *RiskEvent {
+		return &RiskEvent{WalletID: walletID, Type: TransactionTypeDebit, Amount: 100, Err: ErrInsufficientBalance, At: at}
+	}
+
+	assert.Empty(t, rule.Evaluate(ctx, failure("wallet-1", start)))
+	assert.Empty(t, rule.Evaluate(ctx, failure("wallet-1", start.Add(2*time.Hour))))
+	assert.Empty(t, rule.Evaluate(ctx, failure("wallet-2", start.Add(2*time.Hour))))
+	assert.Empty(t, rule.Evaluate(ctx, &RiskEvent{WalletID: "wallet-1", Type: TransactionTypeDebit, Amount: 100, At: start.Add(2 * time.Hour)}))
+	assert.Equal(t, "2 failed debits within 1h0m0s", rule.Evaluate(ctx, failure("wallet-1", start.Add(150*time.Minute))))
+
+	// The count restarts after flagging and is kept per tenant
+	assert.Empty(t, rule.Evaluate(ctx, failure("wallet-1", start.Add(151*time.Minute))))
+	assert.Empty(t, rule.Evaluate(WithTenant(ctx, "other-tenant"), failure("wallet-2", start.Add(151*time.Minute))))
+}
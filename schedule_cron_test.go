@@ -0,0 +1,81 @@
+package wallethub
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestParseCron tests parsing valid and invalid cron expressions
+func TestParseCron(t *testing.T) {
+	valid := []string{
+		"* * * * *",
+		"*/15 * * * *",
+		"0 9-17 * * 1-5",
+		"0 0 1,15 * *",
+		"30 2 * * 7",
+		"5/10 * * * *",
+		"@monthly",
+		"@daily",
+	}
+	for _, expr := range valid {
+		_, err := ParseCron(expr)
+		assert.NoError(t, err, expr)
+	}
+
+	invalid := []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"a * * * *",
+		"@every",
+	}
+	for _, expr := range invalid {
+		_, err := ParseCron(expr)
+		assert.ErrorIs(t, err, ErrInvalidCronExpression, expr)
+	}
+}
+
+// TestCronScheduleNext tests computing the next matching time
+func TestCronScheduleNext(t *testing.T) {
+	base := time.Date(2025, 1, 31, 10, 30, 15, 0, time.UTC) // Friday
+
+	tests := []struct {
+		expr     string
+		expected time.Time
+	}{
+		{"* * * * *", time.Date(2025, 1, 31, 10, 31, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2025, 1, 31, 10, 45, 0, 0, time.UTC)},
+		{"0 * * * *", time.Date(2025, 1, 31, 11, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 31 * *", time.Date(2025, 3, 31, 0, 0, 0, 0, time.UTC)},
+		{"0 9 * * 1", time.Date(2025, 2, 3, 9, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 0 15 * 0", time.Date(2025, 2, 2, 0, 0, 0, 0, time.UTC)}, // Day of month OR day of week
+	}
+
+	for _, test := range tests {
+		cron, err := ParseCron(test.expr)
+		require.NoError(t, err, test.expr)
+		assert.Equal(t, test.expected, cron.Next(base), test.expr)
+	}
+
+	// Next is strictly after the given time
+	cron, err := ParseCron("30 10 * * *")
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2025, 2, 1, 10, 30, 0, 0, time.UTC), cron.Next(time.Date(2025, 1, 31, 10, 30, 0, 0, time.UTC)))
+
+	// Impossible dates never match
+	cron, err = ParseCron("0 0 31 2 *")
+	require.NoError(t, err)
+	assert.True(t, cron.Next(base).IsZero())
+}
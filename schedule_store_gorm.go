@@ -0,0 +1,408 @@
+package wallethub
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ScheduleModel is the GORM model for Schedule entity
+type ScheduleModel struct {
+	ID          string            `gorm:"primaryKey;type:varchar(36)"`
+	TenantID    string            `gorm:"index;type:varchar(36);not null;default:''"`
+	Operation   ScheduleOperation `gorm:"type:varchar(20);not null"`
+	WalletID    string            `gorm:"index;type:varchar(36)"`
+	ToWalletID  string            `gorm:"index;type:varchar(36)"`
+	Amount      int64             `gorm:"type:bigint;not null"`
+	Description string            `gorm:"type:varchar(255)"`
+	Note        string            `gorm:"type:text"`
+	Reference   string            `gorm:"type:varchar(100)"`
+	Data        datatypes.JSON    `gorm:"type:json"`
+	RunAt       time.Time         `gorm:"type:timestamp"`
+	Cron        string            `gorm:"type:varchar(100)"`
+	Status      ScheduleStatus    `gorm:"index;type:varchar(20);not null"`
+	NextRunAt   time.Time         `gorm:"index;type:timestamp"`
+	LastRunAt   time.Time         `gorm:"type:timestamp"`
+	CreatedAt   time.Time         `gorm:"type:timestamp;not null;default:CURRENT_TIMESTAMP"`
+	UpdatedAt   time.Time         `gorm:"type:timestamp;not null;default:CURRENT_TIMESTAMP"`
+}
+
+// ScheduleRunModel is the GORM model for ScheduleRun entity
+type ScheduleRunModel struct {
+	ID            string            `gorm:"primaryKey;type:varchar(36)"`
+	TenantID      string            `gorm:"index;type:varchar(36);not null;default:''"`
+	ScheduleID    string            `gorm:"index;type:varchar(36)"`
+	OccurrenceAt  time.Time         `gorm:"type:timestamp;not null"`
+	Status        ScheduleRunStatus `gorm:"index;type:varchar(20);not null"`
+	Attempts      int               `gorm:"not null;default:0"`
+	LastError     string            `gorm:"type:text"`
+	TransactionID string            `gorm:"type:varchar(36)"`
+	NextAttemptAt time.Time         `gorm:"index;type:timestamp"`
+	CompletedAt   time.Time         `gorm:"type:timestamp"`
+	CreatedAt     time.Time         `gorm:"type:timestamp;not null;default:CURRENT_TIMESTAMP"`
+	UpdatedAt     time.Time         `gorm:"type:timestamp;not null;default:CURRENT_TIMESTAMP"`
+}
+
+// ToSchedule converts a ScheduleModel to a Schedule entity
+func (m *ScheduleModel) ToSchedule() *Schedule {
+	data := make(map[string]interface{})
+	if len(m.Data) > 0 {
+		// Unmarshal the JSON data into the map
+		if err := json.Unmarshal(m.Data, &data); err != nil {
+			// If there's an error, just return an empty map
+			data = make(map[string]interface{})
+		}
+	}
+
+	return &Schedule{
+		ID:          m.ID,
+		TenantID:    m.TenantID,
+		Operation:   m.Operation,
+		WalletID:    m.WalletID,
+		ToWalletID:  m.ToWalletID,
+		Amount:      m.Amount,
+		Description: m.Description,
+		Note:        m.Note,
+		Reference:   m.Reference,
+		Data:        data,
+		RunAt:       m.RunAt,
+		Cron:        m.Cron,
+		Status:      m.Status,
+		NextRunAt:   m.NextRunAt,
+		LastRunAt:   m.LastRunAt,
+		CreatedAt:   m.CreatedAt,
+		UpdatedAt:   m.UpdatedAt,
+	}
+}
+
+// FromSchedule initializes a ScheduleModel from a Schedule entity
+func (m *ScheduleModel) FromSchedule(schedule *Schedule) error {
+	if schedule.Data != nil {
+		// Convert the map to JSON bytes
+		jsonBytes, err := json.Marshal(schedule.Data)
+		if err != nil {
+			return err
+		}
+		// Set the JSON data
+		err = m.Data.UnmarshalJSON(jsonBytes)
+		if err != nil {
+			return err
+		}
+	}
+
+	m.ID = schedule.ID
+	m.TenantID = schedule.TenantID
+	m.Operation = schedule.Operation
+	m.WalletID = schedule.WalletID
+	m.ToWalletID = schedule.ToWalletID
+	m.Amount = schedule.Amount
+	m.Description = schedule.Description
+	m.Note = schedule.Note
+	m.Reference = schedule.Reference
+	m.RunAt = schedule.RunAt
+	m.Cron = schedule.Cron
+	m.Status = schedule.Status
+	m.NextRunAt = schedule.NextRunAt
+	m.LastRunAt = schedule.LastRunAt
+	m.CreatedAt = schedule.CreatedAt
+	m.UpdatedAt = schedule.UpdatedAt
+
+	return nil
+}
+
+// ToScheduleRun converts a ScheduleRunModel to a ScheduleRun entity
+func (m *ScheduleRunModel) ToScheduleRun() *ScheduleRun {
+	return &ScheduleRun{
+		ID:            m.ID,
+		TenantID:      m.TenantID,
+		ScheduleID:    m.ScheduleID,
+		OccurrenceAt:  m.OccurrenceAt,
+		Status:        m.Status,
+		Attempts:      m.Attempts,
+		LastError:     m.LastError,
+		TransactionID: m.TransactionID,
+		NextAttemptAt: m.NextAttemptAt,
+		CompletedAt:   m.CompletedAt,
+		CreatedAt:     m.CreatedAt,
+		UpdatedAt:     m.UpdatedAt,
+	}
+}
+
+// FromScheduleRun initializes a ScheduleRunModel from a ScheduleRun entity
+func (m *ScheduleRunModel) FromScheduleRun(run *ScheduleRun) {
+	m.ID = run.ID
+	m.TenantID = run.TenantID
+	m.ScheduleID = run.ScheduleID
+	m.OccurrenceAt = run.OccurrenceAt
+	m.Status = run.Status
+	m.Attempts = run.Attempts
+	m.LastError = run.LastError
+	m.TransactionID = run.TransactionID
+	m.NextAttemptAt = run.NextAttemptAt
+	m.CompletedAt = run.CompletedAt
+	m.CreatedAt = run.CreatedAt
+	m.UpdatedAt = run.UpdatedAt
+}
+
+// GormScheduleStore implements ScheduleStore interface using GORM
+type GormScheduleStore struct {
+	db               *gorm.DB
+	scheduleTable    string
+	scheduleRunTable string
+	clock            Clock
+}
+
+// GormScheduleStoreOption defines a function type for configuring GormScheduleStore
+type GormScheduleStoreOption func(*GormScheduleStore)
+
+// WithScheduleStoreClock sets the clock timestamping saved and updated schedules and schedule runs,
+// SystemClock by default. GORM's automatic timestamps use the clock too.
+func WithScheduleStoreClock(clock Clock) GormScheduleStoreOption {
+	return func(s *GormScheduleStore) {
+		s.clock = clock
+		s.db = s.db.Session(&gorm.Session{NowFunc: clock.Now})
+	}
+}
+
+// NewGormScheduleStore creates a new instance of GormScheduleStore with custom table names
+func NewGormScheduleStore(db *gorm.DB, scheduleTable, scheduleRunTable string, options ...GormScheduleStoreOption) *GormScheduleStore {
+	if scheduleTable == "" {
+		scheduleTable = "wallet_schedules"
+	}
+	if scheduleRunTable == "" {
+		scheduleRunTable = "wallet_schedule_runs"
+	}
+
+	store := &GormScheduleStore{
+		db:               db,
+		scheduleTable:    scheduleTable,
+		scheduleRunTable: scheduleRunTable,
+		clock:            SystemClock,
+	}
+
+	// Apply all options
+	for _, option := range options {
+		option(store)
+	}
+
+	return store
+}
+
+// schedules returns a query on the schedules of the context's tenant
+func (s *GormScheduleStore) schedules(ctx context.Context) *gorm.DB {
+	return s.db.WithContext(ctx).Table(s.scheduleTable).Where("tenant_id = ?", TenantFromContext(ctx))
+}
+
+// runs returns a query on the schedule runs of the context's tenant
+func (s *GormScheduleStore) runs(ctx context.Context) *gorm.DB {
+	return s.db.WithContext(ctx).Table(s.scheduleRunTable).Where("tenant_id = ?", TenantFromContext(ctx))
+}
+
+// AutoMigrate creates or updates the necessary database tables
+func (s *GormScheduleStore) AutoMigrate(ctx context.Context) error {
+	// Use context with DB
+	db := s.db.WithContext(ctx)
+
+	// Create or update the schedule table
+	if err := db.Table(s.scheduleTable).AutoMigrate(&ScheduleModel{}); err != nil {
+		return err
+	}
+
+	// Create or update the schedule run table
+	if err := db.Table(s.scheduleRunTable).AutoMigrate(&ScheduleRunModel{}); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// SaveSchedule saves a schedule to the database
+func (s *GormScheduleStore) SaveSchedule(ctx context.Context, schedule *Schedule) error {
+	if schedule.CreatedAt.IsZero() {
+		schedule.CreatedAt = s.clock.Now()
+	}
+	schedule.UpdatedAt = s.clock.Now()
+	schedule.TenantID = TenantFromContext(ctx)
+
+	model := &ScheduleModel{}
+	if err := model.FromSchedule(schedule); err != nil {
+		return err
+	}
+
+	return s.schedules(ctx).Create(model).Error
+}
+
+// FindSchedule finds a schedule by ID
+func (s *GormScheduleStore) FindSchedule(ctx context.Context, scheduleID string) (*Schedule, error) {
+	var model ScheduleModel
+	result := s.schedules(ctx).Where("id = ?", scheduleID).First(&model)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+	return model.ToSchedule(), nil
+}
+
+// FindSchedulesByWalletID finds schedules debiting or crediting a wallet with pagination
+func (s *GormScheduleStore) FindSchedulesByWalletID(ctx context.Context, walletID string, limit int, offset int) ([]Schedule, error) {
+	var models []ScheduleModel
+	result := s.schedules(ctx).
+		Where("(wallet_id = ? OR to_wallet_id = ?)", walletID, walletID).
+		Order("created_at DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&models)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	schedules := make([]Schedule, len(models))
+	for i, model := range models {
+		schedule := model.ToSchedule()
+		schedules[i] = *schedule
+	}
+	return schedules, nil
+}
+
+// FindDueSchedules finds active schedules of all tenants whose next occurrence is at or before the given time
+func (s *GormScheduleStore) FindDueSchedules(ctx context.Context, now time.Time, limit int) ([]Schedule, error) {
+	var models []ScheduleModel
+	result := s.db.WithContext(ctx).Table(s.scheduleTable).
+		Where("status = ? AND next_run_at <= ?", ScheduleStatusActive, now).
+		Order("next_run_at ASC").
+		Limit(limit).
+		Find(&models)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	schedules := make([]Schedule, len(models))
+	for i, model := range models {
+		schedule := model.ToSchedule()
+		schedules[i] = *schedule
+	}
+	return schedules, nil
+}
+
+// UpdateSchedule updates an existing schedule
+func (s *GormScheduleStore) UpdateSchedule(ctx context.Context, schedule *Schedule) error {
+	schedule.UpdatedAt = s.clock.Now()
+	schedule.TenantID = TenantFromContext(ctx)
+
+	model := &ScheduleModel{}
+	if err := model.FromSchedule(schedule); err != nil {
+		return err
+	}
+
+	// Update all columns of the row only if it belongs to the tenant
+	return s.schedules(ctx).Select("*").Updates(model).Error
+}
+
+// TransitionSchedule updates the status and the occurrence times of an existing schedule only if its
+// stored status is still from, and reports whether it did. The check and the update are one statement,
+// so a concurrent pause or cancellation is never overwritten.
+func (s *GormScheduleStore) TransitionSchedule(ctx context.Context, schedule *Schedule, from ScheduleStatus) (bool, error) {
+	schedule.UpdatedAt = s.clock.Now()
+	schedule.TenantID = TenantFromContext(ctx)
+
+	model := &ScheduleModel{}
+	if err := model.FromSchedule(schedule); err != nil {
+		return false, err
+	}
+
+	result := s.schedules(ctx).
+		Where("status = ?", from).
+		Select("status", "next_run_at", "last_run_at", "updated_at").
+		Updates(model)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// SaveScheduleRun saves a schedule run to the database, ignoring runs that already exist
+func (s *GormScheduleStore) SaveScheduleRun(ctx context.Context, run *ScheduleRun) error {
+	if run.CreatedAt.IsZero() {
+		run.CreatedAt = s.clock.Now()
+	}
+	run.UpdatedAt = s.clock.Now()
+	run.TenantID = TenantFromContext(ctx)
+
+	model := &ScheduleRunModel{}
+	model.FromScheduleRun(run)
+
+	return s.runs(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(model).Error
+}
+
+// FindScheduleRun finds a schedule run by ID
+func (s *GormScheduleStore) FindScheduleRun(ctx context.Context, runID string) (*ScheduleRun, error) {
+	var model ScheduleRunModel
+	result := s.runs(ctx).Where("id = ?", runID).First(&model)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+	return model.ToScheduleRun(), nil
+}
+
+// FindScheduleRunsByScheduleID finds the runs of a schedule with pagination
+func (s *GormScheduleStore) FindScheduleRunsByScheduleID(ctx context.Context, scheduleID string, limit int, offset int) ([]ScheduleRun, error) {
+	var models []ScheduleRunModel
+	result := s.runs(ctx).
+		Where("schedule_id = ?", scheduleID).
+		Order("occurrence_at DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&models)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	runs := make([]ScheduleRun, len(models))
+	for i, model := range models {
+		run := model.ToScheduleRun()
+		runs[i] = *run
+	}
+	return runs, nil
+}
+
+// FindDueScheduleRuns finds pending runs of all tenants whose next attempt is at or before the given time
+func (s *GormScheduleStore) FindDueScheduleRuns(ctx context.Context, now time.Time, limit int) ([]ScheduleRun, error) {
+	var models []ScheduleRunModel
+	result := s.db.WithContext(ctx).Table(s.scheduleRunTable).
+		Where("status = ? AND next_attempt_at <= ?", ScheduleRunStatusPending, now).
+		Order("occurrence_at ASC").
+		Limit(limit).
+		Find(&models)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	runs := make([]ScheduleRun, len(models))
+	for i, model := range models {
+		run := model.ToScheduleRun()
+		runs[i] = *run
+	}
+	return runs, nil
+}
+
+// UpdateScheduleRun updates an existing schedule run
+func (s *GormScheduleStore) UpdateScheduleRun(ctx context.Context, run *ScheduleRun) error {
+	run.UpdatedAt = s.clock.Now()
+	run.TenantID = TenantFromContext(ctx)
+
+	model := &ScheduleRunModel{}
+	model.FromScheduleRun(run)
+
+	// Update all columns of the row only if it belongs to the tenant
+	return s.runs(ctx).Select("*").Updates(model).Error
+}
@@ -0,0 +1,183 @@
+package wallethub
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// setupTestGormScheduleStore creates a new GormScheduleStore with an in-memory SQLite database for testing
+func setupTestGormScheduleStore(t *testing.T) *GormScheduleStore {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+
+	store := NewGormScheduleStore(db, "", "")
+
+	// Migrate tables using store's method
+	ctx := context.Background()
+	err = store.AutoMigrate(ctx)
+	require.NoError(t, err)
+
+	return store
+}
+
+// TestGormScheduleStore_Schedules tests saving, finding and updating schedules
+func TestGormScheduleStore_Schedules(t *testing.T) {
+	store := setupTestGormScheduleStore(t)
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+
+	schedule := &Schedule{
+		ID:         "test-schedule-id",
+		Operation:  ScheduleOperationTransfer,
+		WalletID:   "from-wallet-id",
+		ToWalletID: "to-wallet-id",
+		Amount:     100,
+		Data:       map[string]interface{}{"plan": "monthly"},
+		Cron:       "@monthly",
+		Status:     ScheduleStatusActive,
+		NextRunAt:  now,
+	}
+
+	// Test saving schedule
+	err := store.SaveSchedule(ctx, schedule)
+	assert.NoError(t, err)
+
+	// Test finding schedule
+	found, err := store.FindSchedule(ctx, schedule.ID)
+	assert.NoError(t, err)
+	require.NotNil(t, found)
+	assert.Equal(t, schedule.Operation, found.Operation)
+	assert.Equal(t, "monthly", found.Data["plan"])
+
+	notFound, err := store.FindSchedule(ctx, "non-existent-id")
+	assert.NoError(t, err)
+	assert.Nil(t, notFound)
+
+	// Test finding by source and destination wallet
+	schedules, err := store.FindSchedulesByWalletID(ctx, "from-wallet-id", 10, 0)
+	assert.NoError(t, err)
+	assert.Len(t, schedules, 1)
+
+	schedules, err = store.FindSchedulesByWalletID(ctx, "to-wallet-id", 10, 0)
+	assert.NoError(t, err)
+	assert.Len(t, schedules, 1)
+
+	// Test finding due schedules
+	due, err := store.FindDueSchedules(ctx, now.Add(-time.Minute), 10)
+	assert.NoError(t, err)
+	assert.Len(t, due, 0)
+
+	due, err = store.FindDueSchedules(ctx, now, 10)
+	assert.NoError(t, err)
+	assert.Len(t, due, 1)
+
+	// Test updating schedule
+	found.Status = ScheduleStatusPaused
+	err = store.UpdateSchedule(ctx, found)
+	assert.NoError(t, err)
+
+	due, err = store.FindDueSchedules(ctx, now, 10)
+	assert.NoError(t, err)
+	assert.Len(t, due, 0)
+
+	// Test transitioning only from the stored status
+	found.Status = ScheduleStatusCancelled
+	updated, err := store.TransitionSchedule(ctx, found, ScheduleStatusActive)
+	assert.NoError(t, err)
+	assert.False(t, updated)
+
+	updated, err = store.TransitionSchedule(ctx, found, ScheduleStatusPaused)
+	assert.NoError(t, err)
+	assert.True(t, updated)
+
+	found, err = store.FindSchedule(ctx, found.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, ScheduleStatusCancelled, found.Status)
+}
+
+// TestGormScheduleStore_ScheduleRuns tests saving, finding and updating schedule runs
+func TestGormScheduleStore_ScheduleRuns(t *testing.T) {
+	store := setupTestGormScheduleStore(t)
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+
+	run := &ScheduleRun{
+		ID:            "test-run-id",
+		ScheduleID:    "test-schedule-id",
+		OccurrenceAt:  now,
+		Status:        ScheduleRunStatusPending,
+		NextAttemptAt: now,
+	}
+
+	// Test saving run
+	err := store.SaveScheduleRun(ctx, run)
+	assert.NoError(t, err)
+
+	// Saving the same run again is ignored
+	duplicate := *run
+	duplicate.Attempts = 3
+	err = store.SaveScheduleRun(ctx, &duplicate)
+	assert.NoError(t, err)
+
+	found, err := store.FindScheduleRun(ctx, run.ID)
+	assert.NoError(t, err)
+	require.NotNil(t, found)
+	assert.Equal(t, 0, found.Attempts)
+
+	// Test finding runs of a schedule
+	runs, err := store.FindScheduleRunsByScheduleID(ctx, "test-schedule-id", 10, 0)
+	assert.NoError(t, err)
+	assert.Len(t, runs, 1)
+
+	// Test finding due runs
+	due, err := store.FindDueScheduleRuns(ctx, now, 10)
+	assert.NoError(t, err)
+	assert.Len(t, due, 1)
+
+	// Test updating run
+	found.Status = ScheduleRunStatusSucceeded
+	err = store.UpdateScheduleRun(ctx, found)
+	assert.NoError(t, err)
+
+	due, err = store.FindDueScheduleRuns(ctx, now, 10)
+	assert.NoError(t, err)
+	assert.Len(t, due, 0)
+}
+
+// TestGormScheduleStoreClock tests that schedules and their runs are timestamped by the store clock
+func TestGormScheduleStoreClock(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	store := NewGormScheduleStore(db, "", "", WithScheduleStoreClock(ClockFunc(func() time.Time { return now })))
+	ctx := context.Background()
+	require.NoError(t, store.AutoMigrate(ctx))
+
+	schedule := &Schedule{ID: "schedule-id", Operation: ScheduleOperationCredit, WalletID: "wallet-id", Amount: 100, Status: ScheduleStatusActive, NextRunAt: now}
+	require.NoError(t, store.SaveSchedule(ctx, schedule))
+	assert.True(t, now.Equal(schedule.CreatedAt))
+	assert.True(t, now.Equal(schedule.UpdatedAt))
+
+	now = now.Add(time.Hour)
+	schedule.Status = ScheduleStatusPaused
+	updated, err := store.TransitionSchedule(ctx, schedule, ScheduleStatusActive)
+	require.NoError(t, err)
+	require.True(t, updated)
+
+	found, err := store.FindSchedule(ctx, schedule.ID)
+	require.NoError(t, err)
+	assert.True(t, now.Add(-time.Hour).Equal(found.CreatedAt))
+	assert.True(t, now.Equal(found.UpdatedAt))
+
+	run := &ScheduleRun{ID: "run-id", ScheduleID: schedule.ID, OccurrenceAt: now, Status: ScheduleRunStatusPending, NextAttemptAt: now}
+	require.NoError(t, store.SaveScheduleRun(ctx, run))
+	assert.True(t, now.Equal(run.CreatedAt))
+	assert.True(t, now.Equal(run.UpdatedAt))
+}
@@ -0,0 +1,515 @@
+package wallethub
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Scheduler error definitions
+var (
+	ErrScheduleNotFound  = errors.New("schedule not found")
+	ErrInvalidSchedule   = errors.New("invalid schedule")
+	ErrScheduleNotActive = errors.New("schedule is not active")
+	ErrScheduleNotPaused = errors.New("schedule is not paused")
+)
+
+// Scheduler defaults
+const (
+	DefaultScheduleMaxAttempts = 5
+	DefaultScheduleRetryDelay  = time.Minute
+	scheduleBatchSize          = 100
+)
+
+// scheduleNamespace is the UUID namespace used to derive deterministic schedule run and transaction IDs
+var scheduleNamespace = uuid.MustParse("3b0f3c9e-5f7a-4d38-9a51-2c6f0d0a4e17")
+
+// ScheduleOperation defines the wallet operation performed by a schedule
+type ScheduleOperation string
+
+const (
+	ScheduleOperationCredit   ScheduleOperation = "credit"
+	ScheduleOperationDebit    ScheduleOperation = "debit"
+	ScheduleOperationTransfer ScheduleOperation = "transfer"
+)
+
+// ScheduleStatus defines the possible statuses of a schedule
+type ScheduleStatus string
+
+const (
+	ScheduleStatusActive    ScheduleStatus = "active"
+	ScheduleStatusPaused    ScheduleStatus = "paused"
+	ScheduleStatusCancelled ScheduleStatus = "cancelled"
+	ScheduleStatusCompleted ScheduleStatus = "completed" // One-off schedule whose occurrence has been queued
+)
+
+// ScheduleRunStatus defines the possible statuses of a single schedule occurrence
+type ScheduleRunStatus string
+
+const (
+	ScheduleRunStatusPending   ScheduleRunStatus = "pending"
+	ScheduleRunStatusSucceeded ScheduleRunStatus = "succeeded"
+	ScheduleRunStatusFailed    ScheduleRunStatus = "failed"  // Gave up after the maximum number of attempts
+	ScheduleRunStatusSkipped   ScheduleRunStatus = "skipped" // Schedule was paused or cancelled before the run executed
+)
+
+// Schedule represents a one-off or recurring wallet operation
+type Schedule struct {
+	ID          string                 `json:"id"`
+	TenantID    string                 `json:"tenant_id,omitempty"` // Set by the store from the context
+	Operation   ScheduleOperation      `json:"operation"`
+	WalletID    string                 `json:"wallet_id"`              // Target wallet, or source wallet for transfers
+	ToWalletID  string                 `json:"to_wallet_id,omitempty"` // Destination wallet for transfers
+	Amount      int64                  `json:"amount"`
+	Description string                 `json:"description"`
+	Note        string                 `json:"note"`
+	Reference   string                 `json:"reference"`
+	Data        map[string]interface{} `json:"data"`
+	RunAt       time.Time              `json:"run_at,omitempty"` // When a one-off schedule runs
+	Cron        string                 `json:"cron,omitempty"`   // Cron expression for recurring schedules
+	Status      ScheduleStatus         `json:"status"`
+	NextRunAt   time.Time              `json:"next_run_at,omitempty"`
+	LastRunAt   time.Time              `json:"last_run_at,omitempty"`
+	CreatedAt   time.Time              `json:"created_at"`
+	UpdatedAt   time.Time              `json:"updated_at"`
+}
+
+// ScheduleRun represents a single occurrence of a schedule and its execution attempts
+type ScheduleRun struct {
+	ID            string            `json:"id"`                  // Deterministic per schedule occurrence
+	TenantID      string            `json:"tenant_id,omitempty"` // Set by the store from the context
+	ScheduleID    string            `json:"schedule_id"`
+	OccurrenceAt  time.Time         `json:"occurrence_at"`
+	Status        ScheduleRunStatus `json:"status"`
+	Attempts      int               `json:"attempts"`
+	LastError     string            `json:"last_error,omitempty"`
+	TransactionID string            `json:"transaction_id,omitempty"` // Resulting transaction (the debit for transfers)
+	NextAttemptAt time.Time         `json:"next_attempt_at,omitempty"`
+	CompletedAt   time.Time         `json:"completed_at,omitempty"`
+	CreatedAt     time.Time         `json:"created_at"`
+	UpdatedAt     time.Time         `json:"updated_at"`
+}
+
+// ScheduleStore defines the data access layer interface for schedules. Like the wallet store it is
+// scoped to the tenant of the context, except for the due lookups used by the scheduler, which span
+// all tenants.
+type ScheduleStore interface {
+	// Schedule operations
+	SaveSchedule(ctx context.Context, schedule *Schedule) error
+	FindSchedule(ctx context.Context, scheduleID string) (*Schedule, error)
+	FindSchedulesByWalletID(ctx context.Context, walletID string, limit int, offset int) ([]Schedule, error)
+	FindDueSchedules(ctx context.Context, now time.Time, limit int) ([]Schedule, error)
+	UpdateSchedule(ctx context.Context, schedule *Schedule) error
+	TransitionSchedule(ctx context.Context, schedule *Schedule, from ScheduleStatus) (bool, error) // Updates status and occurrence times only if the stored status is still from
+
+	// Schedule run operations
+	SaveScheduleRun(ctx context.Context, run *ScheduleRun) error // Must ignore runs that already exist
+	FindScheduleRun(ctx context.Context, runID string) (*ScheduleRun, error)
+	FindScheduleRunsByScheduleID(ctx context.Context, scheduleID string, limit int, offset int) ([]ScheduleRun, error)
+	FindDueScheduleRuns(ctx context.Context, now time.Time, limit int) ([]ScheduleRun, error)
+	UpdateScheduleRun(ctx context.Context, run *ScheduleRun) error
+}
+
+// Scheduler executes scheduled wallet operations
+type Scheduler struct {
+	manager      *DefaultWalletManager
+	store        ScheduleStore
+	location     *time.Location
+	maxAttempts  int
+	retryDelay   time.Duration
+	errorHandler func(error)
+}
+
+// SchedulerOption defines a functional option pattern for configuring the scheduler
+type SchedulerOption func(*Scheduler)
+
+// WithScheduleLocation sets the time zone in which cron expressions are evaluated (UTC by default)
+func WithScheduleLocation(location *time.Location) SchedulerOption {
+	return func(s *Scheduler) {
+		s.location = location
+	}
+}
+
+// WithScheduleMaxAttempts sets how many times a failed occurrence is attempted before giving up
+func WithScheduleMaxAttempts(attempts int) SchedulerOption {
+	return func(s *Scheduler) {
+		s.maxAttempts = attempts
+	}
+}
+
+// WithScheduleRetryDelay sets the initial delay between attempts, doubled after each failure
+func WithScheduleRetryDelay(delay time.Duration) SchedulerOption {
+	return func(s *Scheduler) {
+		s.retryDelay = delay
+	}
+}
+
+// WithScheduleErrorHandler sets a handler for errors encountered by Start
+func WithScheduleErrorHandler(handler func(error)) SchedulerOption {
+	return func(s *Scheduler) {
+		s.errorHandler = handler
+	}
+}
+
+// NewScheduler creates a new scheduler executing operations through the given wallet manager
+func NewScheduler(manager *DefaultWalletManager, store ScheduleStore, options ...SchedulerOption) *Scheduler {
+	scheduler := &Scheduler{
+		manager:     manager,
+		store:       store,
+		location:    time.UTC,
+		maxAttempts: DefaultScheduleMaxAttempts,
+		retryDelay:  DefaultScheduleRetryDelay,
+	}
+
+	for _, option := range options {
+		option(scheduler)
+	}
+
+	return scheduler
+}
+
+// ScheduleRunID returns the deterministic ID of a schedule occurrence
+func ScheduleRunID(scheduleID string, occurrenceAt time.Time) string {
+	return uuid.NewSHA1(scheduleNamespace, []byte(scheduleID+"\x00"+occurrenceAt.UTC().Format(time.RFC3339))).String()
+}
+
+// scheduledTransactionID returns the deterministic ID of a transaction created by a schedule run
+func scheduledTransactionID(runID string, leg TransactionType) string {
+	return uuid.NewSHA1(scheduleNamespace, []byte(runID+"\x00"+string(leg))).String()
+}
+
+// CreateSchedule validates and stores a new schedule. Exactly one of RunAt or Cron must be set.
+func (s *Scheduler) CreateSchedule(ctx context.Context, schedule *Schedule) (*Schedule, error) {
+	if schedule.Amount <= 0 {
+		return nil, ErrInvalidAmount
+	}
+	if schedule.WalletID == "" {
+		return nil, ErrInvalidSchedule
+	}
+
+	switch schedule.Operation {
+	case ScheduleOperationCredit, ScheduleOperationDebit:
+	case ScheduleOperationTransfer:
+		if schedule.ToWalletID == "" || schedule.ToWalletID == schedule.WalletID {
+			return nil, ErrInvalidSchedule
+		}
+	default:
+		return nil, ErrInvalidSchedule
+	}
+
+	now := s.manager.clock.Now()
+	switch {
+	case schedule.Cron != "" && schedule.RunAt.IsZero():
+		next, err := s.nextOccurrence(schedule.Cron, now)
+		if err != nil {
+			return nil, err
+		}
+		schedule.NextRunAt = next
+	case schedule.Cron == "" && !schedule.RunAt.IsZero():
+		schedule.NextRunAt = schedule.RunAt
+	default:
+		return nil, ErrInvalidSchedule
+	}
+
+	if schedule.ID == "" {
+		schedule.ID = s.manager.ids.NewID()
+	}
+	schedule.Status = ScheduleStatusActive
+	schedule.CreatedAt = now
+	schedule.UpdatedAt = now
+
+	if err := s.store.SaveSchedule(ctx, schedule); err != nil {
+		return nil, err
+	}
+
+	return schedule, nil
+}
+
+// GetSchedule gets a schedule by ID
+func (s *Scheduler) GetSchedule(ctx context.Context, scheduleID string) (*Schedule, error) {
+	return s.store.FindSchedule(ctx, scheduleID)
+}
+
+// ListSchedules lists schedules involving a wallet with pagination
+func (s *Scheduler) ListSchedules(ctx context.Context, walletID string, limit int, offset int) ([]Schedule, error) {
+	return s.store.FindSchedulesByWalletID(ctx, walletID, limit, offset)
+}
+
+// ListScheduleRuns lists the occurrences of a schedule with pagination
+func (s *Scheduler) ListScheduleRuns(ctx context.Context, scheduleID string, limit int, offset int) ([]ScheduleRun, error) {
+	return s.store.FindScheduleRunsByScheduleID(ctx, scheduleID, limit, offset)
+}
+
+// PauseSchedule pauses an active schedule. Occurrences that come due while paused are skipped.
+func (s *Scheduler) PauseSchedule(ctx context.Context, scheduleID string) error {
+	schedule, err := s.store.FindSchedule(ctx, scheduleID)
+	if err != nil {
+		return err
+	}
+	if schedule == nil {
+		return ErrScheduleNotFound
+	}
+	if schedule.Status != ScheduleStatusActive {
+		return ErrScheduleNotActive
+	}
+
+	schedule.Status = ScheduleStatusPaused
+	return s.transition(ctx, schedule, ScheduleStatusActive, ErrScheduleNotActive)
+}
+
+// ResumeSchedule resumes a paused schedule from its next occurrence after now
+func (s *Scheduler) ResumeSchedule(ctx context.Context, scheduleID string) error {
+	schedule, err := s.store.FindSchedule(ctx, scheduleID)
+	if err != nil {
+		return err
+	}
+	if schedule == nil {
+		return ErrScheduleNotFound
+	}
+	if schedule.Status != ScheduleStatusPaused {
+		return ErrScheduleNotPaused
+	}
+
+	// Recurring schedules continue from now rather than catching up on the paused period
+	if schedule.Cron != "" {
+		next, err := s.nextOccurrence(schedule.Cron, s.manager.clock.Now())
+		if err != nil {
+			return err
+		}
+		schedule.NextRunAt = next
+	}
+
+	schedule.Status = ScheduleStatusActive
+	return s.transition(ctx, schedule, ScheduleStatusPaused, ErrScheduleNotPaused)
+}
+
+// CancelSchedule cancels a schedule permanently
+func (s *Scheduler) CancelSchedule(ctx context.Context, scheduleID string) error {
+	for {
+		schedule, err := s.store.FindSchedule(ctx, scheduleID)
+		if err != nil {
+			return err
+		}
+		if schedule == nil {
+			return ErrScheduleNotFound
+		}
+
+		// Read the schedule again if its status changed since it was read
+		from := schedule.Status
+		schedule.Status = ScheduleStatusCancelled
+		schedule.NextRunAt = time.Time{}
+		updated, err := s.store.TransitionSchedule(ctx, schedule, from)
+		if err != nil || updated {
+			return err
+		}
+	}
+}
+
+// transition saves a change to the status of a schedule unless its status changed from the given one
+// since it was read, in which case err is returned
+func (s *Scheduler) transition(ctx context.Context, schedule *Schedule, from ScheduleStatus, err error) error {
+	updated, updateErr := s.store.TransitionSchedule(ctx, schedule, from)
+	if updateErr != nil {
+		return updateErr
+	}
+	if !updated {
+		return err
+	}
+	return nil
+}
+
+// Start runs due schedules every interval until the context is cancelled
+func (s *Scheduler) Start(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.RunDue(ctx, s.manager.clock.Now()); err != nil && s.errorHandler != nil {
+			s.errorHandler(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunDue queues an occurrence for every schedule that is due at the given time and then executes
+// all pending occurrences whose next attempt is due. Each occurrence is applied at most once, even
+// when RunDue is interrupted or runs concurrently in several processes.
+func (s *Scheduler) RunDue(ctx context.Context, now time.Time) error {
+	if err := s.queueDueRuns(ctx, now); err != nil {
+		return err
+	}
+
+	for {
+		runs, err := s.store.FindDueScheduleRuns(ctx, now, scheduleBatchSize)
+		if err != nil {
+			return err
+		}
+
+		for i := range runs {
+			if err := s.executeRun(ctx, &runs[i], now); err != nil {
+				return err
+			}
+		}
+
+		if len(runs) < scheduleBatchSize {
+			return nil
+		}
+	}
+}
+
+// queueDueRuns creates a pending run for every missed occurrence of the due schedules
+func (s *Scheduler) queueDueRuns(ctx context.Context, now time.Time) error {
+	for {
+		schedules, err := s.store.FindDueSchedules(ctx, now, scheduleBatchSize)
+		if err != nil {
+			return err
+		}
+
+		for i := range schedules {
+			schedule := &schedules[i]
+			tenantCtx := WithTenant(ctx, schedule.TenantID)
+			for schedule.Status == ScheduleStatusActive && !schedule.NextRunAt.IsZero() && !schedule.NextRunAt.After(now) {
+				occurrenceAt := schedule.NextRunAt
+				run := &ScheduleRun{
+					ID:            ScheduleRunID(schedule.ID, occurrenceAt),
+					ScheduleID:    schedule.ID,
+					OccurrenceAt:  occurrenceAt,
+					Status:        ScheduleRunStatusPending,
+					NextAttemptAt: occurrenceAt,
+					CreatedAt:     now,
+					UpdatedAt:     now,
+				}
+				if err := s.store.SaveScheduleRun(tenantCtx, run); err != nil {
+					return err
+				}
+
+				// Advance to the next occurrence
+				schedule.LastRunAt = occurrenceAt
+				if schedule.Cron == "" {
+					schedule.Status = ScheduleStatusCompleted
+					schedule.NextRunAt = time.Time{}
+				} else {
+					next, err := s.nextOccurrence(schedule.Cron, occurrenceAt)
+					if err != nil {
+						return err
+					}
+					schedule.NextRunAt = next
+				}
+			}
+
+			// Advance only schedules still active, so a concurrent pause or cancellation is kept
+			if _, err := s.store.TransitionSchedule(tenantCtx, schedule, ScheduleStatusActive); err != nil {
+				return err
+			}
+		}
+
+		if len(schedules) < scheduleBatchSize {
+			return nil
+		}
+	}
+}
+
+// executeRun attempts a single pending occurrence within the tenant of its schedule and records the outcome
+func (s *Scheduler) executeRun(ctx context.Context, run *ScheduleRun, now time.Time) error {
+	ctx = WithTenant(ctx, run.TenantID)
+
+	schedule, err := s.store.FindSchedule(ctx, run.ScheduleID)
+	if err != nil {
+		return err
+	}
+
+	run.UpdatedAt = now
+	if schedule == nil || schedule.Status == ScheduleStatusPaused || schedule.Status == ScheduleStatusCancelled {
+		run.Status = ScheduleRunStatusSkipped
+		run.CompletedAt = now
+		return s.store.UpdateScheduleRun(ctx, run)
+	}
+
+	run.Attempts++
+	transactionID, err := s.apply(ctx, schedule, run)
+	if err != nil {
+		run.LastError = err.Error()
+		if run.Attempts >= s.maxAttempts {
+			run.Status = ScheduleRunStatusFailed
+			run.CompletedAt = now
+		} else {
+			run.NextAttemptAt = now.Add(s.retryDelay << (run.Attempts - 1))
+		}
+		return s.store.UpdateScheduleRun(ctx, run)
+	}
+
+	run.Status = ScheduleRunStatusSucceeded
+	run.TransactionID = transactionID
+	run.LastError = ""
+	run.CompletedAt = now
+	return s.store.UpdateScheduleRun(ctx, run)
+}
+
+// apply performs the wallet operation of a schedule run. The resulting transactions use IDs derived
+// from the run ID, so a run that was already applied is detected instead of being applied again.
+func (s *Scheduler) apply(ctx context.Context, schedule *Schedule, run *ScheduleRun) (string, error) {
+	transactionID := scheduledTransactionID(run.ID, TransactionTypeDebit)
+	if schedule.Operation == ScheduleOperationCredit {
+		transactionID = scheduledTransactionID(run.ID, TransactionTypeCredit)
+	}
+
+	walletIDs := []string{schedule.WalletID}
+	if schedule.Operation == ScheduleOperationTransfer {
+		walletIDs = append(walletIDs, schedule.ToWalletID)
+	}
+	unlock, err := s.manager.lockWallets(ctx, walletIDs...)
+	if err != nil {
+		return "", err
+	}
+	defer unlock()
+
+	err = s.manager.withinTx(ctx, func(o *walletOps) error {
+		// Check whether an earlier attempt already applied this run
+		existing, err := o.txn.FindTransaction(transactionID)
+		if err != nil {
+			return err
+		}
+		if existing != nil {
+			return nil
+		}
+
+		switch schedule.Operation {
+		case ScheduleOperationCredit:
+			_, err = o.creditBucket(transactionID, schedule.WalletID, DefaultBucket, schedule.Amount, schedule.Description, schedule.Note, schedule.Reference, schedule.Data)
+		case ScheduleOperationDebit:
+			_, err = o.debit(transactionID, schedule.WalletID, schedule.Amount, schedule.Description, schedule.Note, schedule.Reference, schedule.Data)
+		case ScheduleOperationTransfer:
+			creditID := scheduledTransactionID(run.ID, TransactionTypeCredit)
+			err = o.transfer(transactionID, creditID, run.ID, schedule.WalletID, schedule.ToWalletID, schedule.Amount, schedule.Description, schedule.Note, schedule.Data)
+		default:
+			err = ErrInvalidSchedule
+		}
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return transactionID, nil
+}
+
+// nextOccurrence returns the next occurrence of a cron expression after the given time
+func (s *Scheduler) nextOccurrence(expr string, after time.Time) (time.Time, error) {
+	cron, err := ParseCron(expr)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	next := cron.Next(after.In(s.location))
+	if next.IsZero() {
+		return time.Time{}, ErrInvalidCronExpression
+	}
+	return next, nil
+}
@@ -0,0 +1,428 @@
+package wallethub
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestSchedulerOneOff tests a one-off scheduled credit
+func TestSchedulerOneOff(t *testing.T) {
+	manager := NewWalletManager(WithStore(setupTestGormWalletStore(t)))
+	scheduler := NewScheduler(manager, setupTestGormScheduleStore(t))
+	ctx := context.Background()
+
+	wallet, err := manager.CreateWallet(ctx, "test-user", "Test Wallet", "Test Description", "test-ref")
+	require.NoError(t, err)
+
+	runAt := time.Now().UTC().Add(time.Hour).Truncate(time.Second)
+	schedule, err := scheduler.CreateSchedule(ctx, &Schedule{
+		Operation:   ScheduleOperationCredit,
+		WalletID:    wallet.ID,
+		Amount:      100,
+		Description: "Welcome bonus",
+		Reference:   "welcome",
+		RunAt:       runAt,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, ScheduleStatusActive, schedule.Status)
+	assert.True(t, runAt.Equal(schedule.NextRunAt))
+
+	// Nothing happens before the schedule is due
+	err = scheduler.RunDue(ctx, runAt.Add(-time.Minute))
+	assert.NoError(t, err)
+
+	updatedWallet, err := manager.GetWallet(ctx, wallet.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), updatedWallet.Balance)
+
+	// The credit is applied once due
+	err = scheduler.RunDue(ctx, runAt)
+	assert.NoError(t, err)
+
+	err = scheduler.RunDue(ctx, runAt.Add(time.Hour))
+	assert.NoError(t, err)
+
+	updatedWallet, err = manager.GetWallet(ctx, wallet.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(100), updatedWallet.Balance)
+
+	updatedSchedule, err := scheduler.GetSchedule(ctx, schedule.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, ScheduleStatusCompleted, updatedSchedule.Status)
+
+	runs, err := scheduler.ListScheduleRuns(ctx, schedule.ID, 10, 0)
+	assert.NoError(t, err)
+	require.Len(t, runs, 1)
+	assert.Equal(t, ScheduleRunStatusSucceeded, runs[0].Status)
+	assert.Equal(t, 1, runs[0].Attempts)
+
+	tx, err := manager.GetTransaction(ctx, runs[0].TransactionID)
+	assert.NoError(t, err)
+	require.NotNil(t, tx)
+	assert.Equal(t, "welcome", tx.Reference)
+}
+
+// TestSchedulerRecurring tests a recurring transfer catching up on missed occurrences
+func TestSchedulerRecurring(t *testing.T) {
+	manager := NewWalletManager(WithStore(setupTestGormWalletStore(t)))
+	scheduler := NewScheduler(manager, setupTestGormScheduleStore(t))
+	ctx := context.Background()
+
+	fromWallet, err := manager.CreateWallet(ctx, "test-user", "Wallet 1", "Description 1", "ref-1")
+	require.NoError(t, err)
+	toWallet, err := manager.CreateWallet(ctx, "test-user", "Wallet 2", "Description 2", "ref-2")
+	require.NoError(t, err)
+
+	_, err = manager.Credit(ctx, fromWallet.ID, 1000, "Initial Credit", "Note", "credit-ref", nil)
+	require.NoError(t, err)
+
+	schedule, err := scheduler.CreateSchedule(ctx, &Schedule{
+		Operation:   ScheduleOperationTransfer,
+		WalletID:    fromWallet.ID,
+		ToWalletID:  toWallet.ID,
+		Amount:      100,
+		Description: "Standing transfer",
+		Cron:        "@monthly",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1, schedule.NextRunAt.Day())
+
+	// Three monthly occurrences are due
+	err = scheduler.RunDue(ctx, schedule.NextRunAt.AddDate(0, 2, 0))
+	assert.NoError(t, err)
+
+	updatedWallet, err := manager.GetWallet(ctx, toWallet.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(300), updatedWallet.Balance)
+
+	runs, err := scheduler.ListScheduleRuns(ctx, schedule.ID, 10, 0)
+	assert.NoError(t, err)
+	assert.Len(t, runs, 3)
+
+	updatedSchedule, err := scheduler.GetSchedule(ctx, schedule.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, ScheduleStatusActive, updatedSchedule.Status)
+	assert.True(t, updatedSchedule.NextRunAt.Equal(schedule.NextRunAt.AddDate(0, 3, 0)))
+
+	schedules, err := scheduler.ListSchedules(ctx, toWallet.ID, 10, 0)
+	assert.NoError(t, err)
+	assert.Len(t, schedules, 1)
+}
+
+// TestSchedulerRetry tests that failed occurrences are retried with backoff and eventually given up
+func TestSchedulerRetry(t *testing.T) {
+	manager := NewWalletManager(WithStore(setupTestGormWalletStore(t)))
+	scheduler := NewScheduler(manager, setupTestGormScheduleStore(t), WithScheduleMaxAttempts(3), WithScheduleRetryDelay(time.Minute))
+	ctx := context.Background()
+
+	wallet, err := manager.CreateWallet(ctx, "test-user", "Test Wallet", "Test Description", "test-ref")
+	require.NoError(t, err)
+
+	runAt := time.Now().UTC().Add(time.Hour).Truncate(time.Second)
+	schedule, err := scheduler.CreateSchedule(ctx, &Schedule{
+		Operation: ScheduleOperationDebit,
+		WalletID:  wallet.ID,
+		Amount:    100,
+		RunAt:     runAt,
+	})
+	require.NoError(t, err)
+
+	// First attempt fails for lack of funds
+	err = scheduler.RunDue(ctx, runAt)
+	assert.NoError(t, err)
+
+	runs, err := scheduler.ListScheduleRuns(ctx, schedule.ID, 10, 0)
+	assert.NoError(t, err)
+	require.Len(t, runs, 1)
+	assert.Equal(t, ScheduleRunStatusPending, runs[0].Status)
+	assert.Equal(t, 1, runs[0].Attempts)
+	assert.Contains(t, runs[0].LastError, ErrInsufficientBalance.Error())
+	assert.True(t, runs[0].NextAttemptAt.Equal(runAt.Add(time.Minute)))
+
+	// Second attempt succeeds once funds arrive
+	_, err = manager.Credit(ctx, wallet.ID, 150, "Top up", "Note", "top-up", nil)
+	require.NoError(t, err)
+
+	err = scheduler.RunDue(ctx, runAt.Add(time.Minute))
+	assert.NoError(t, err)
+
+	run, err := scheduler.store.FindScheduleRun(ctx, runs[0].ID)
+	assert.NoError(t, err)
+	assert.Equal(t, ScheduleRunStatusSucceeded, run.Status)
+	assert.Equal(t, 2, run.Attempts)
+	assert.Empty(t, run.LastError)
+
+	updatedWallet, err := manager.GetWallet(ctx, wallet.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(50), updatedWallet.Balance)
+
+	// A run that keeps failing is given up after the maximum number of attempts
+	failing, err := scheduler.CreateSchedule(ctx, &Schedule{
+		Operation: ScheduleOperationDebit,
+		WalletID:  wallet.ID,
+		Amount:    1000,
+		RunAt:     runAt,
+	})
+	require.NoError(t, err)
+
+	for _, delay := range []time.Duration{0, time.Minute, 3 * time.Minute} {
+		err = scheduler.RunDue(ctx, runAt.Add(delay))
+		assert.NoError(t, err)
+	}
+
+	runs, err = scheduler.ListScheduleRuns(ctx, failing.ID, 10, 0)
+	assert.NoError(t, err)
+	require.Len(t, runs, 1)
+	assert.Equal(t, ScheduleRunStatusFailed, runs[0].Status)
+	assert.Equal(t, 3, runs[0].Attempts)
+}
+
+// TestSchedulerIdempotency tests that an occurrence applied before a crash is not applied again
+func TestSchedulerIdempotency(t *testing.T) {
+	manager := NewWalletManager(WithStore(setupTestGormWalletStore(t)))
+	scheduler := NewScheduler(manager, setupTestGormScheduleStore(t))
+	ctx := context.Background()
+
+	wallet, err := manager.CreateWallet(ctx, "test-user", "Test Wallet", "Test Description", "test-ref")
+	require.NoError(t, err)
+
+	runAt := time.Now().UTC().Add(time.Hour).Truncate(time.Second)
+	schedule, err := scheduler.CreateSchedule(ctx, &Schedule{
+		Operation: ScheduleOperationCredit,
+		WalletID:  wallet.ID,
+		Amount:    100,
+		RunAt:     runAt,
+	})
+	require.NoError(t, err)
+
+	// Simulate a crash after the credit was applied but before the run was recorded
+	run := &ScheduleRun{ID: ScheduleRunID(schedule.ID, runAt), ScheduleID: schedule.ID}
+	_, err = scheduler.apply(ctx, schedule, run)
+	require.NoError(t, err)
+
+	err = scheduler.RunDue(ctx, runAt)
+	assert.NoError(t, err)
+
+	updatedWallet, err := manager.GetWallet(ctx, wallet.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(100), updatedWallet.Balance)
+
+	runs, err := scheduler.ListScheduleRuns(ctx, schedule.ID, 10, 0)
+	assert.NoError(t, err)
+	require.Len(t, runs, 1)
+	assert.Equal(t, ScheduleRunStatusSucceeded, runs[0].Status)
+}
+
+// TestSchedulerRiskRules tests that scheduled operations are evaluated by the risk rules
+func TestSchedulerRiskRules(t *testing.T) {
+	manager := NewWalletManager(
+		WithStore(setupTestGormWalletStore(t)),
+		WithRiskRules(UnusualAmountRule{Type: TransactionTypeCredit, Threshold: 1000}),
+	)
+	scheduler := NewScheduler(manager, setupTestGormScheduleStore(t))
+	ctx := context.Background()
+
+	wallet, err := manager.CreateWallet(ctx, "test-user", "Test Wallet", "Test Description", "test-ref")
+	require.NoError(t, err)
+
+	runAt := time.Now().UTC().Add(time.Hour).Truncate(time.Second)
+	_, err = scheduler.CreateSchedule(ctx, &Schedule{
+		Operation: ScheduleOperationCredit,
+		WalletID:  wallet.ID,
+		Amount:    1000,
+		RunAt:     runAt,
+	})
+	require.NoError(t, err)
+
+	err = scheduler.RunDue(ctx, runAt)
+	assert.NoError(t, err)
+
+	updatedWallet, err := manager.GetWallet(ctx, wallet.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1000), updatedWallet.Balance)
+	assert.True(t, updatedWallet.RiskFlagged)
+}
+
+// TestSchedulerPauseResumeCancel tests schedule lifecycle operations
+func TestSchedulerPauseResumeCancel(t *testing.T) {
+	manager := NewWalletManager(WithStore(setupTestGormWalletStore(t)))
+	scheduler := NewScheduler(manager, setupTestGormScheduleStore(t))
+	ctx := context.Background()
+
+	wallet, err := manager.CreateWallet(ctx, "test-user", "Test Wallet", "Test Description", "test-ref")
+	require.NoError(t, err)
+
+	schedule, err := scheduler.CreateSchedule(ctx, &Schedule{
+		Operation: ScheduleOperationCredit,
+		WalletID:  wallet.ID,
+		Amount:    100,
+		Cron:      "0 0 * * *",
+	})
+	require.NoError(t, err)
+
+	// Paused schedules do not run
+	err = scheduler.PauseSchedule(ctx, schedule.ID)
+	assert.NoError(t, err)
+
+	err = scheduler.PauseSchedule(ctx, schedule.ID)
+	assert.ErrorIs(t, err, ErrScheduleNotActive)
+
+	err = scheduler.RunDue(ctx, schedule.NextRunAt.AddDate(0, 0, 1))
+	assert.NoError(t, err)
+
+	updatedWallet, err := manager.GetWallet(ctx, wallet.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), updatedWallet.Balance)
+
+	// Resumed schedules continue from the next occurrence
+	err = scheduler.ResumeSchedule(ctx, schedule.ID)
+	assert.NoError(t, err)
+
+	err = scheduler.ResumeSchedule(ctx, schedule.ID)
+	assert.ErrorIs(t, err, ErrScheduleNotPaused)
+
+	resumed, err := scheduler.GetSchedule(ctx, schedule.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, ScheduleStatusActive, resumed.Status)
+	assert.True(t, resumed.NextRunAt.After(time.Now()))
+
+	// Cancelled schedules stop for good
+	err = scheduler.CancelSchedule(ctx, schedule.ID)
+	assert.NoError(t, err)
+
+	cancelled, err := scheduler.GetSchedule(ctx, schedule.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, ScheduleStatusCancelled, cancelled.Status)
+
+	err = scheduler.CancelSchedule(ctx, "non-existent-id")
+	assert.ErrorIs(t, err, ErrScheduleNotFound)
+}
+
+// racingScheduleStore runs a function after schedules are read, like a change committing concurrently
+type racingScheduleStore struct {
+	ScheduleStore
+	race func()
+}
+
+// FindSchedule reads a schedule and then runs the racing change
+func (s *racingScheduleStore) FindSchedule(ctx context.Context, scheduleID string) (*Schedule, error) {
+	schedule, err := s.ScheduleStore.FindSchedule(ctx, scheduleID)
+	s.runRace()
+	return schedule, err
+}
+
+// FindDueSchedules reads due schedules and then runs the racing change
+func (s *racingScheduleStore) FindDueSchedules(ctx context.Context, now time.Time, limit int) ([]Schedule, error) {
+	schedules, err := s.ScheduleStore.FindDueSchedules(ctx, now, limit)
+	s.runRace()
+	return schedules, err
+}
+
+func (s *racingScheduleStore) runRace() {
+	if race := s.race; race != nil {
+		s.race = nil
+		race()
+	}
+}
+
+// TestSchedulerConcurrentChanges tests that changes to a schedule made after it was read are kept
+func TestSchedulerConcurrentChanges(t *testing.T) {
+	manager := NewWalletManager(WithStore(setupTestGormWalletStore(t)))
+	store := setupTestGormScheduleStore(t)
+	racing := &racingScheduleStore{ScheduleStore: store}
+	scheduler := NewScheduler(manager, store)
+	racingScheduler := NewScheduler(manager, racing)
+	ctx := context.Background()
+
+	wallet, err := manager.CreateWallet(ctx, "test-user", "Test Wallet", "", "")
+	require.NoError(t, err)
+
+	runAt := time.Now().UTC().Add(time.Hour).Truncate(time.Second)
+	schedule, err := scheduler.CreateSchedule(ctx, &Schedule{
+		Operation: ScheduleOperationCredit,
+		WalletID:  wallet.ID,
+		Amount:    100,
+		RunAt:     runAt,
+	})
+	require.NoError(t, err)
+
+	// A schedule cancelled while it is queued stays cancelled and does not run
+	racing.race = func() {
+		require.NoError(t, scheduler.CancelSchedule(ctx, schedule.ID))
+	}
+	require.NoError(t, racingScheduler.RunDue(ctx, runAt))
+
+	cancelled, err := scheduler.GetSchedule(ctx, schedule.ID)
+	require.NoError(t, err)
+	assert.Equal(t, ScheduleStatusCancelled, cancelled.Status)
+
+	updatedWallet, err := manager.GetWallet(ctx, wallet.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), updatedWallet.Balance)
+
+	// A schedule cannot be paused or resumed once cancelled concurrently
+	schedule, err = scheduler.CreateSchedule(ctx, &Schedule{
+		Operation: ScheduleOperationCredit,
+		WalletID:  wallet.ID,
+		Amount:    100,
+		Cron:      "0 0 * * *",
+	})
+	require.NoError(t, err)
+
+	racing.race = func() {
+		require.NoError(t, scheduler.CancelSchedule(ctx, schedule.ID))
+	}
+	assert.ErrorIs(t, racingScheduler.PauseSchedule(ctx, schedule.ID), ErrScheduleNotActive)
+
+	cancelled, err = scheduler.GetSchedule(ctx, schedule.ID)
+	require.NoError(t, err)
+	assert.Equal(t, ScheduleStatusCancelled, cancelled.Status)
+
+	// A cancellation racing a pause still cancels the schedule
+	schedule, err = scheduler.CreateSchedule(ctx, &Schedule{
+		Operation: ScheduleOperationCredit,
+		WalletID:  wallet.ID,
+		Amount:    100,
+		Cron:      "0 0 * * *",
+	})
+	require.NoError(t, err)
+
+	racing.race = func() {
+		require.NoError(t, scheduler.PauseSchedule(ctx, schedule.ID))
+	}
+	require.NoError(t, racingScheduler.CancelSchedule(ctx, schedule.ID))
+
+	cancelled, err = scheduler.GetSchedule(ctx, schedule.ID)
+	require.NoError(t, err)
+	assert.Equal(t, ScheduleStatusCancelled, cancelled.Status)
+}
+
+// TestSchedulerCreateValidation tests schedule validation
+func TestSchedulerCreateValidation(t *testing.T) {
+	manager := NewWalletManager(WithStore(setupTestGormWalletStore(t)))
+	scheduler := NewScheduler(manager, setupTestGormScheduleStore(t))
+	ctx := context.Background()
+	runAt := time.Now().Add(time.Hour)
+
+	_, err := scheduler.CreateSchedule(ctx, &Schedule{Operation: ScheduleOperationCredit, WalletID: "wallet", Amount: 0, RunAt: runAt})
+	assert.ErrorIs(t, err, ErrInvalidAmount)
+
+	_, err = scheduler.CreateSchedule(ctx, &Schedule{Operation: "refund", WalletID: "wallet", Amount: 100, RunAt: runAt})
+	assert.ErrorIs(t, err, ErrInvalidSchedule)
+
+	_, err = scheduler.CreateSchedule(ctx, &Schedule{Operation: ScheduleOperationTransfer, WalletID: "wallet", Amount: 100, RunAt: runAt})
+	assert.ErrorIs(t, err, ErrInvalidSchedule)
+
+	_, err = scheduler.CreateSchedule(ctx, &Schedule{Operation: ScheduleOperationCredit, WalletID: "wallet", Amount: 100})
+	assert.ErrorIs(t, err, ErrInvalidSchedule)
+
+	_, err = scheduler.CreateSchedule(ctx, &Schedule{Operation: ScheduleOperationCredit, WalletID: "wallet", Amount: 100, RunAt: runAt, Cron: "@daily"})
+	assert.ErrorIs(t, err, ErrInvalidSchedule)
+
+	_, err = scheduler.CreateSchedule(ctx, &Schedule{Operation: ScheduleOperationCredit, WalletID: "wallet", Amount: 100, Cron: "bad"})
+	assert.ErrorIs(t, err, ErrInvalidCronExpression)
+}
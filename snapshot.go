@@ -0,0 +1,174 @@
+package wallethub
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Snapshot error definitions
+var (
+	ErrSnapshotStoreRequired = errors.New("snapshot store is required")
+	ErrSnapshotInFuture      = errors.New("snapshot time is in the future")
+)
+
+// snapshotPageSize is the number of wallets read per query by TakeBalanceSnapshots
+const snapshotPageSize = 100
+
+// snapshotNamespace is the UUID namespace used to derive deterministic snapshot IDs
+var snapshotNamespace = uuid.MustParse("9d3c1f4e-7b2a-4e86-8c55-0f1e6a3b7d29")
+
+// BalanceSnapshot records the balance of a wallet, or the total of all wallets, at a point in time
+type BalanceSnapshot struct {
+	ID        string    `json:"id"`
+	TenantID  string    `json:"tenant_id,omitempty"` // Set by the store from the context
+	WalletID  string    `json:"wallet_id"`           // Empty for the total across all wallets
+	Balance   int64     `json:"balance"`
+	TakenAt   time.Time `json:"taken_at"` // The balance includes transactions completed at or before this time
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// SnapshotStore defines the data access layer interface for balance snapshots
+type SnapshotStore interface {
+	SaveBalanceSnapshot(ctx context.Context, snapshot *BalanceSnapshot) error // Must ignore snapshots that already exist
+	FindLatestBalanceSnapshot(ctx context.Context, walletID string, at time.Time) (*BalanceSnapshot, error)
+}
+
+// WithSnapshotStore sets the snapshot store used to speed up as-of balance queries
+func WithSnapshotStore(store SnapshotStore) Option {
+	return func(m *DefaultWalletManager) {
+		m.snapshotStore = store
+	}
+}
+
+// BalanceSnapshotID returns the deterministic ID of a snapshot, so taking the same snapshot twice is harmless
+func BalanceSnapshotID(tenantID string, walletID string, at time.Time) string {
+	name := walletID + "\x00" + at.UTC().Format(time.RFC3339Nano)
+	if tenantID != "" {
+		name = tenantID + "\x00" + name
+	}
+	return uuid.NewSHA1(snapshotNamespace, []byte(name)).String()
+}
+
+// TakeBalanceSnapshots records the balance of every wallet as of the given time, followed by the total
+// across all wallets. It is meant to run periodically, e.g. at every month end, and can safely be
+// repeated if interrupted. It returns the number of wallet snapshots taken. Snapshots cannot be taken
+// in the future, as transactions completed later would be missing from them.
+func (m *DefaultWalletManager) TakeBalanceSnapshots(ctx context.Context, at time.Time) (int, error) {
+	if m.snapshotStore == nil {
+		return 0, ErrSnapshotStoreRequired
+	}
+	if at.After(m.clock.Now()) {
+		return 0, ErrSnapshotInFuture
+	}
+
+	count := 0
+	var total int64
+	for offset := 0; ; offset += snapshotPageSize {
+		wallets, err := m.store.FindWallets(ctx, snapshotPageSize, offset)
+		if err != nil {
+			return count, err
+		}
+
+		for _, wallet := range wallets {
+			balance, err := m.balanceAt(ctx, wallet.ID, at)
+			if err != nil {
+				return count, err
+			}
+			total += balance
+
+			if err := m.saveBalanceSnapshot(ctx, wallet.ID, balance, at); err != nil {
+				return count, err
+			}
+			count++
+		}
+
+		if len(wallets) < snapshotPageSize {
+			break
+		}
+	}
+
+	// The total is saved last, so it only exists for complete snapshot runs
+	if err := m.saveBalanceSnapshot(ctx, "", total, at); err != nil {
+		return count, err
+	}
+
+	return count, nil
+}
+
+// GetBalanceAt returns the balance of a wallet as of the given time, including transactions completed
+// at or before it. The nearest earlier snapshot is combined with the transactions completed since.
+func (m *DefaultWalletManager) GetBalanceAt(ctx context.Context, walletID string, at time.Time) (int64, error) {
+	wallet, err := m.store.FindWallet(ctx, walletID)
+	if err != nil {
+		return 0, newWalletError(err, walletID)
+	}
+	if wallet == nil {
+		return 0, newWalletError(ErrWalletNotFound, walletID)
+	}
+
+	balance, err := m.balanceAt(ctx, walletID, at)
+	return balance, newWalletError(err, walletID)
+}
+
+// GetTotalBalanceAt returns the total balance across all wallets as of the given time, such as the
+// outstanding liability at a month end.
+func (m *DefaultWalletManager) GetTotalBalanceAt(ctx context.Context, at time.Time) (int64, error) {
+	var after time.Time
+	var total int64
+
+	if m.snapshotStore != nil {
+		snapshot, err := m.snapshotStore.FindLatestBalanceSnapshot(ctx, "", at)
+		if err != nil {
+			return 0, err
+		}
+		if snapshot != nil {
+			after = snapshot.TakenAt
+			total = snapshot.Balance
+		}
+	}
+
+	sum, err := m.store.SumTransactionAmounts(ctx, after, at)
+	if err != nil {
+		return 0, err
+	}
+
+	return total + sum, nil
+}
+
+// balanceAt computes the balance of a wallet as of the given time
+func (m *DefaultWalletManager) balanceAt(ctx context.Context, walletID string, at time.Time) (int64, error) {
+	var after time.Time
+	var balance int64
+
+	if m.snapshotStore != nil {
+		snapshot, err := m.snapshotStore.FindLatestBalanceSnapshot(ctx, walletID, at)
+		if err != nil {
+			return 0, err
+		}
+		if snapshot != nil {
+			after = snapshot.TakenAt
+			balance = snapshot.Balance
+		}
+	}
+
+	sum, err := m.store.SumTransactionAmountsByWalletID(ctx, walletID, after, at)
+	if err != nil {
+		return 0, err
+	}
+
+	return balance + sum, nil
+}
+
+// saveBalanceSnapshot stores a single snapshot
+func (m *DefaultWalletManager) saveBalanceSnapshot(ctx context.Context, walletID string, balance int64, at time.Time) error {
+	return m.snapshotStore.SaveBalanceSnapshot(ctx, &BalanceSnapshot{
+		ID:        BalanceSnapshotID(TenantFromContext(ctx), walletID, at),
+		WalletID:  walletID,
+		Balance:   balance,
+		TakenAt:   at,
+		CreatedAt: m.clock.Now(),
+	})
+}
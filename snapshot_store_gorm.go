@@ -0,0 +1,120 @@
+package wallethub
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// BalanceSnapshotModel is the GORM model for BalanceSnapshot entity
+type BalanceSnapshotModel struct {
+	ID        string    `gorm:"primaryKey;type:varchar(36)"`
+	TenantID  string    `gorm:"index:idx_snapshot_wallet_taken_at,priority:1;type:varchar(36);not null;default:''"`
+	WalletID  string    `gorm:"index:idx_snapshot_wallet_taken_at,priority:2;type:varchar(36)"`
+	Balance   int64     `gorm:"type:bigint;not null"`
+	TakenAt   time.Time `gorm:"index:idx_snapshot_wallet_taken_at,priority:3;type:timestamp;not null"`
+	CreatedAt time.Time `gorm:"type:timestamp;not null;default:CURRENT_TIMESTAMP"`
+}
+
+// ToBalanceSnapshot converts a BalanceSnapshotModel to a BalanceSnapshot entity
+func (m *BalanceSnapshotModel) ToBalanceSnapshot() *BalanceSnapshot {
+	return &BalanceSnapshot{
+		ID:        m.ID,
+		TenantID:  m.TenantID,
+		WalletID:  m.WalletID,
+		Balance:   m.Balance,
+		TakenAt:   m.TakenAt,
+		CreatedAt: m.CreatedAt,
+	}
+}
+
+// FromBalanceSnapshot initializes a BalanceSnapshotModel from a BalanceSnapshot entity
+func (m *BalanceSnapshotModel) FromBalanceSnapshot(snapshot *BalanceSnapshot) {
+	m.ID = snapshot.ID
+	m.TenantID = snapshot.TenantID
+	m.WalletID = snapshot.WalletID
+	m.Balance = snapshot.Balance
+	m.TakenAt = snapshot.TakenAt
+	m.CreatedAt = snapshot.CreatedAt
+}
+
+// GormSnapshotStore implements SnapshotStore interface using GORM
+type GormSnapshotStore struct {
+	db            *gorm.DB
+	snapshotTable string
+	clock         Clock
+}
+
+// GormSnapshotStoreOption defines a function type for configuring GormSnapshotStore
+type GormSnapshotStoreOption func(*GormSnapshotStore)
+
+// WithSnapshotStoreClock sets the clock timestamping snapshots saved without a creation time,
+// SystemClock by default
+func WithSnapshotStoreClock(clock Clock) GormSnapshotStoreOption {
+	return func(s *GormSnapshotStore) {
+		s.clock = clock
+	}
+}
+
+// NewGormSnapshotStore creates a new instance of GormSnapshotStore with a custom table name
+func NewGormSnapshotStore(db *gorm.DB, snapshotTable string, options ...GormSnapshotStoreOption) *GormSnapshotStore {
+	if snapshotTable == "" {
+		snapshotTable = "wallet_balance_snapshots"
+	}
+
+	store := &GormSnapshotStore{
+		db:            db,
+		snapshotTable: snapshotTable,
+		clock:         SystemClock,
+	}
+
+	// Apply all options
+	for _, option := range options {
+		option(store)
+	}
+
+	return store
+}
+
+// snapshots returns a query on the snapshots of the context's tenant
+func (s *GormSnapshotStore) snapshots(ctx context.Context) *gorm.DB {
+	return s.db.WithContext(ctx).Table(s.snapshotTable).Where("tenant_id = ?", TenantFromContext(ctx))
+}
+
+// AutoMigrate creates or updates the necessary database tables
+func (s *GormSnapshotStore) AutoMigrate(ctx context.Context) error {
+	return s.db.WithContext(ctx).Table(s.snapshotTable).AutoMigrate(&BalanceSnapshotModel{})
+}
+
+// SaveBalanceSnapshot saves a snapshot to the database, ignoring snapshots that already exist
+func (s *GormSnapshotStore) SaveBalanceSnapshot(ctx context.Context, snapshot *BalanceSnapshot) error {
+	if snapshot.CreatedAt.IsZero() {
+		snapshot.CreatedAt = s.clock.Now()
+	}
+	snapshot.TenantID = TenantFromContext(ctx)
+
+	model := &BalanceSnapshotModel{}
+	model.FromBalanceSnapshot(snapshot)
+
+	return s.snapshots(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(model).Error
+}
+
+// FindLatestBalanceSnapshot finds the latest snapshot of a wallet taken at or before the given time.
+// An empty wallet ID finds the latest snapshot of the total across all wallets of the tenant.
+func (s *GormSnapshotStore) FindLatestBalanceSnapshot(ctx context.Context, walletID string, at time.Time) (*BalanceSnapshot, error) {
+	var model BalanceSnapshotModel
+	result := s.snapshots(ctx).
+		Where("wallet_id = ? AND taken_at <= ?", walletID, at).
+		Order("taken_at DESC").
+		First(&model)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, result.Error
+	}
+	return model.ToBalanceSnapshot(), nil
+}
@@ -0,0 +1,81 @@
+package wallethub
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// TestGormSnapshotStore tests saving and finding balance snapshots
+func TestGormSnapshotStore(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+
+	store := NewGormSnapshotStore(db, "")
+	ctx := context.Background()
+	err = store.AutoMigrate(ctx)
+	require.NoError(t, err)
+
+	january := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+	february := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	for _, snapshot := range []*BalanceSnapshot{
+		{ID: BalanceSnapshotID("", "wallet-id", january), WalletID: "wallet-id", Balance: 100, TakenAt: january},
+		{ID: BalanceSnapshotID("", "wallet-id", february), WalletID: "wallet-id", Balance: 200, TakenAt: february},
+		{ID: BalanceSnapshotID("", "", january), WalletID: "", Balance: 1000, TakenAt: january},
+	} {
+		err = store.SaveBalanceSnapshot(ctx, snapshot)
+		assert.NoError(t, err)
+	}
+
+	// Saving an existing snapshot again is ignored
+	err = store.SaveBalanceSnapshot(ctx, &BalanceSnapshot{ID: BalanceSnapshotID("", "wallet-id", january), WalletID: "wallet-id", Balance: 999, TakenAt: january})
+	assert.NoError(t, err)
+
+	// Test finding the latest snapshot at or before a time
+	snapshot, err := store.FindLatestBalanceSnapshot(ctx, "wallet-id", february.Add(-time.Second))
+	assert.NoError(t, err)
+	require.NotNil(t, snapshot)
+	assert.Equal(t, int64(100), snapshot.Balance)
+
+	snapshot, err = store.FindLatestBalanceSnapshot(ctx, "wallet-id", february)
+	assert.NoError(t, err)
+	require.NotNil(t, snapshot)
+	assert.Equal(t, int64(200), snapshot.Balance)
+
+	snapshot, err = store.FindLatestBalanceSnapshot(ctx, "", february)
+	assert.NoError(t, err)
+	require.NotNil(t, snapshot)
+	assert.Equal(t, int64(1000), snapshot.Balance)
+
+	// Test finding before any snapshot
+	snapshot, err = store.FindLatestBalanceSnapshot(ctx, "wallet-id", january.Add(-time.Second))
+	assert.NoError(t, err)
+	assert.Nil(t, snapshot)
+}
+
+// TestGormSnapshotStoreClock tests that snapshots without a creation time are timestamped by the store clock
+func TestGormSnapshotStoreClock(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	store := NewGormSnapshotStore(db, "", WithSnapshotStoreClock(ClockFunc(func() time.Time { return now })))
+	ctx := context.Background()
+	require.NoError(t, store.AutoMigrate(ctx))
+
+	at := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	snapshot := &BalanceSnapshot{ID: BalanceSnapshotID("", "wallet-id", at), WalletID: "wallet-id", Balance: 100, TakenAt: at}
+	require.NoError(t, store.SaveBalanceSnapshot(ctx, snapshot))
+	assert.True(t, now.Equal(snapshot.CreatedAt))
+
+	found, err := store.FindLatestBalanceSnapshot(ctx, "wallet-id", at)
+	require.NoError(t, err)
+	require.NotNil(t, found)
+	assert.True(t, now.Equal(found.CreatedAt))
+}
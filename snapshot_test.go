@@ -0,0 +1,131 @@
+package wallethub
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// saveTestCompletedTransaction saves a completed transaction with the given completion time
+func saveTestCompletedTransaction(t *testing.T, store *GormWalletStore, walletID string, transactionType TransactionType, amount int64, completedAt time.Time) {
+	err := store.SaveTransaction(context.Background(), &Transaction{
+		ID:          GenerateID(),
+		WalletID:    walletID,
+		Type:        transactionType,
+		Amount:      amount,
+		Status:      TransactionStatusCompleted,
+		CreatedAt:   completedAt,
+		CompletedAt: completedAt,
+	})
+	require.NoError(t, err)
+}
+
+// TestGetBalanceAt tests as-of balance queries with and without snapshots
+func TestGetBalanceAt(t *testing.T) {
+	store := setupTestGormWalletStore(t)
+	snapshotStore := NewGormSnapshotStore(store.db, "")
+	require.NoError(t, snapshotStore.AutoMigrate(context.Background()))
+	manager := NewWalletManager(WithStore(store), WithSnapshotStore(snapshotStore))
+	ctx := context.Background()
+
+	wallet1, err := manager.CreateWallet(ctx, "user-1", "Wallet 1", "Description 1", "ref-1")
+	require.NoError(t, err)
+	wallet2, err := manager.CreateWallet(ctx, "user-2", "Wallet 2", "Description 2", "ref-2")
+	require.NoError(t, err)
+
+	saveTestCompletedTransaction(t, store, wallet1.ID, TransactionTypeCredit, 1000, time.Date(2025, 1, 10, 12, 0, 0, 0, time.UTC))
+	saveTestCompletedTransaction(t, store, wallet1.ID, TransactionTypeDebit, 300, time.Date(2025, 2, 10, 12, 0, 0, 0, time.UTC))
+	saveTestCompletedTransaction(t, store, wallet1.ID, TransactionTypeCredit, 500, time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC))
+	saveTestCompletedTransaction(t, store, wallet2.ID, TransactionTypeCredit, 200, time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC))
+
+	endOfJanuary := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+	endOfFebruary := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	endOfMarch := time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)
+
+	// Without snapshots balances are computed from the full history
+	balance, err := manager.GetBalanceAt(ctx, wallet1.ID, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), balance)
+
+	balance, err = manager.GetBalanceAt(ctx, wallet1.ID, endOfJanuary)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1000), balance)
+
+	balance, err = manager.GetBalanceAt(ctx, wallet1.ID, endOfFebruary)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(700), balance)
+
+	// Transactions completed exactly at the given time are included
+	balance, err = manager.GetBalanceAt(ctx, wallet2.ID, endOfJanuary)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(200), balance)
+
+	total, err := manager.GetTotalBalanceAt(ctx, endOfMarch)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1400), total)
+
+	// Take month-end snapshots
+	count, err := manager.TakeBalanceSnapshots(ctx, endOfJanuary)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	// Taking the same snapshot again is harmless
+	_, err = manager.TakeBalanceSnapshots(ctx, endOfJanuary)
+	assert.NoError(t, err)
+
+	snapshot, err := snapshotStore.FindLatestBalanceSnapshot(ctx, "", endOfMarch)
+	assert.NoError(t, err)
+	require.NotNil(t, snapshot)
+	assert.Equal(t, int64(1200), snapshot.Balance)
+	assert.True(t, endOfJanuary.Equal(snapshot.TakenAt))
+
+	// Later queries start from the snapshot, so a backdated transaction before it is not picked up
+	saveTestCompletedTransaction(t, store, wallet1.ID, TransactionTypeCredit, 50, time.Date(2025, 1, 20, 0, 0, 0, 0, time.UTC))
+
+	balance, err = manager.GetBalanceAt(ctx, wallet1.ID, endOfFebruary)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(700), balance)
+
+	balance, err = manager.GetBalanceAt(ctx, wallet1.ID, endOfMarch)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1200), balance)
+
+	total, err = manager.GetTotalBalanceAt(ctx, endOfMarch)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1400), total)
+
+	// Queries before the snapshot still use the history
+	balance, err = manager.GetBalanceAt(ctx, wallet1.ID, time.Date(2025, 1, 31, 0, 0, 0, 0, time.UTC))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1050), balance)
+
+	// Unknown wallets are reported as not found
+	_, err = manager.GetBalanceAt(ctx, "non-existent-id", endOfMarch)
+	assert.ErrorIs(t, err, ErrWalletNotFound)
+}
+
+// TestTakeBalanceSnapshotsRequiresStore tests that snapshots need a snapshot store
+func TestTakeBalanceSnapshotsRequiresStore(t *testing.T) {
+	manager := NewWalletManager(WithStore(setupTestGormWalletStore(t)))
+
+	_, err := manager.TakeBalanceSnapshots(context.Background(), time.Now())
+	assert.ErrorIs(t, err, ErrSnapshotStoreRequired)
+}
+
+// TestTakeBalanceSnapshotsInFuture tests that snapshots cannot be taken in the future
+func TestTakeBalanceSnapshotsInFuture(t *testing.T) {
+	store := setupTestGormWalletStore(t)
+	snapshotStore := NewGormSnapshotStore(store.db, "")
+	require.NoError(t, snapshotStore.AutoMigrate(context.Background()))
+	now := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	manager := NewWalletManager(WithStore(store), WithSnapshotStore(snapshotStore), WithClock(ClockFunc(func() time.Time { return now })))
+
+	_, err := manager.TakeBalanceSnapshots(context.Background(), now.Add(time.Second))
+	assert.ErrorIs(t, err, ErrSnapshotInFuture)
+
+	_, err = manager.TakeBalanceSnapshots(context.Background(), now)
+	assert.NoError(t, err)
+}
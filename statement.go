@@ -0,0 +1,310 @@
+package wallethub
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	htmltemplate "html/template"
+	"io"
+	"iter"
+	"strconv"
+	"text/template"
+	"time"
+)
+
+// Statement error definitions
+var (
+	ErrInvalidStatementPeriod     = errors.New("statement period must end after it starts")
+	ErrUnsupportedStatementFormat = errors.New("unsupported statement format")
+	ErrAccountIDTooLong           = errors.New("wallet ID does not fit the account identifier of the statement format")
+)
+
+// errStopStatement stops walking the ledger once the consumer of a statement stops reading lines
+var errStopStatement = errors.New("statement iteration stopped")
+
+// StatementFormat defines the formats a statement can be rendered in
+type StatementFormat string
+
+const (
+	// StatementFormatJSON renders the statement as a single JSON document
+	StatementFormatJSON StatementFormat = "json"
+	// StatementFormatCSV renders the statement as CSV with opening and closing balance rows
+	StatementFormatCSV StatementFormat = "csv"
+	// StatementFormatText renders the statement with TextStatementTemplate
+	StatementFormatText StatementFormat = "text"
+	// StatementFormatHTML renders the statement with HTMLStatementTemplate
+	StatementFormatHTML StatementFormat = "html"
+)
+
+// Statement summarizes a wallet over a period, covering the transactions completed after From and at
+// or before To. Its lines are not held in memory but read page by page through Lines.
+type Statement struct {
+	WalletID       string    `json:"wallet_id"`
+	UserID         string    `json:"user_id"`
+	WalletName     string    `json:"wallet_name"`
+	From           time.Time `json:"from"`
+	To             time.Time `json:"to"`
+	OpeningBalance int64     `json:"opening_balance"`
+	TotalCredits   int64     `json:"total_credits"`
+	TotalDebits    int64     `json:"total_debits"`
+	ClosingBalance int64     `json:"closing_balance"`
+	GeneratedAt    time.Time `json:"generated_at"`
+
+	store WalletStore
+}
+
+// StatementLine is a single transaction on a statement with the running balance after it
+type StatementLine struct {
+	TransactionID string          `json:"transaction_id"`
+	Type          TransactionType `json:"type"`
+	Amount        int64           `json:"amount"`
+	Balance       int64           `json:"balance"`
+	Description   string          `json:"description"`
+	Reference     string          `json:"reference"`
+	CompletedAt   time.Time       `json:"completed_at"`
+}
+
+// StatementTemplate renders a statement through the named templates "header" and "footer", which
+// receive the *Statement, and "line", which receives each StatementLine. Both text/template and
+// html/template templates satisfy it.
+type StatementTemplate interface {
+	ExecuteTemplate(w io.Writer, name string, data any) error
+}
+
+// TextStatementTemplate is the default plain-text statement layout
+var TextStatementTemplate = template.Must(template.New("statement").Parse(`
+{{- define "header" -}}
+Statement for {{.WalletName}} ({{.WalletID}})
+Period: {{.From.Format "2006-01-02 15:04:05"}} to {{.To.Format "2006-01-02 15:04:05"}}
+
+Opening balance: {{.OpeningBalance}}
+
+{{printf "%-19s  %-6s  %12s  %12s  %s" "Date" "Type" "Amount" "Balance" "Description"}}
+{{end -}}
+{{- define "line" -}}
+{{printf "%-19s  %-6s  %12d  %12d  %s" (.CompletedAt.Format "2006-01-02 15:04:05") .Type .Amount .Balance .Description}}
+{{end -}}
+{{- define "footer" }}
+Total credits:   {{.TotalCredits}}
+Total debits:    {{.TotalDebits}}
+Closing balance: {{.ClosingBalance}}
+{{end -}}
+`))
+
+// HTMLStatementTemplate is the default HTML statement layout
+var HTMLStatementTemplate = htmltemplate.Must(htmltemplate.New("statement").Parse(`
+{{- define "header" -}}
+<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Statement for {{.WalletName}}</title></head>
+<body>
+<h1>Statement for {{.WalletName}}</h1>
+<p>Period: {{.From.Format "2006-01-02 15:04:05"}} to {{.To.Format "2006-01-02 15:04:05"}}</p>
+<p>Opening balance: {{.OpeningBalance}}</p>
+<table>
+<thead><tr><th>Date</th><th>Type</th><th>Amount</th><th>Balance</th><th>Description</th></tr></thead>
+<tbody>
+{{end -}}
+{{- define "line" -}}
+<tr><td>{{.CompletedAt.Format "2006-01-02 15:04:05"}}</td><td>{{.Type}}</td><td>{{.Amount}}</td><td>{{.Balance}}</td><td>{{.Description}}</td></tr>
+{{end -}}
+{{- define "footer" -}}
+</tbody>
+</table>
+<p>Total credits: {{.TotalCredits}}</p>
+<p>Total debits: {{.TotalDebits}}</p>
+<p>Closing balance: {{.ClosingBalance}}</p>
+</body>
+</html>
+{{end -}}
+`))
+
+// GenerateStatement prepares a statement of a wallet over the given period. The opening balance is
+// the balance as of from, so a transaction completed exactly at from is not listed.
+func (m *DefaultWalletManager) GenerateStatement(ctx context.Context, walletID string, from time.Time, to time.Time) (*Statement, error) {
+	if !to.After(from) {
+		return nil, newWalletError(ErrInvalidStatementPeriod, walletID)
+	}
+
+	wallet, err := m.store.FindWallet(ctx, walletID)
+	if err != nil {
+		return nil, newWalletError(err, walletID)
+	}
+	if wallet == nil {
+		return nil, newWalletError(ErrWalletNotFound, walletID)
+	}
+
+	opening, err := m.balanceAt(ctx, walletID, from)
+	if err != nil {
+		return nil, newWalletError(err, walletID)
+	}
+
+	credits, debits, err := m.store.SumTransactionTotalsByWalletID(ctx, walletID, from, to)
+	if err != nil {
+		return nil, newWalletError(err, walletID)
+	}
+
+	return &Statement{
+		WalletID:       wallet.ID,
+		UserID:         wallet.UserID,
+		WalletName:     wallet.Name,
+		From:           from,
+		To:             to,
+		OpeningBalance: opening,
+		TotalCredits:   credits,
+		TotalDebits:    debits,
+		ClosingBalance: opening + credits - debits,
+		GeneratedAt:    m.clock.Now(),
+		store:          m.store,
+	}, nil
+}
+
+// Lines returns the statement's transactions in the order they were applied, reading them page by page
+func (s *Statement) Lines(ctx context.Context) iter.Seq2[StatementLine, error] {
+	return func(yield func(StatementLine, error) bool) {
+		fetch := func(limit int, offset int) ([]Transaction, error) {
+			return s.store.FindCompletedTransactionsByWalletIDBetween(ctx, s.WalletID, s.From, s.To, limit, offset)
+		}
+
+		_, err := walkLedger(s.OpeningBalance, fetch, func(transaction *Transaction, balance int64) error {
+			line := StatementLine{
+				TransactionID: transaction.ID,
+				Type:          transaction.Type,
+				Amount:        transaction.Amount,
+				Balance:       balance,
+				Description:   transaction.Description,
+				Reference:     transaction.Reference,
+				CompletedAt:   transaction.CompletedAt,
+			}
+			if !yield(line, nil) {
+				return errStopStatement
+			}
+			return nil
+		})
+		if err != nil && err != errStopStatement {
+			yield(StatementLine{}, err)
+		}
+	}
+}
+
+// WriteStatement renders a statement in the given format. Bank statement formats use the default
+// BankStatementOptions.
+func WriteStatement(ctx context.Context, w io.Writer, statement *Statement, format StatementFormat) error {
+	switch format {
+	case StatementFormatJSON:
+		return WriteStatementJSON(ctx, w, statement)
+	case StatementFormatCSV:
+		return WriteStatementCSV(ctx, w, statement)
+	case StatementFormatText:
+		return WriteStatementTemplate(ctx, w, statement, TextStatementTemplate)
+	case StatementFormatHTML:
+		return WriteStatementTemplate(ctx, w, statement, HTMLStatementTemplate)
+	case StatementFormatCamt053:
+		return WriteStatementCamt053(ctx, w, statement, BankStatementOptions{})
+	case StatementFormatOFX:
+		return WriteStatementOFX(ctx, w, statement, BankStatementOptions{})
+	default:
+		return ErrUnsupportedStatementFormat
+	}
+}
+
+// WriteStatementJSON renders a statement as a JSON object with its lines in a "lines" array
+func WriteStatementJSON(ctx context.Context, w io.Writer, statement *Statement) error {
+	header, err := json.Marshal(statement)
+	if err != nil {
+		return err
+	}
+
+	// Reopen the summary object to stream the lines into it
+	if _, err := w.Write(header[:len(header)-1]); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, `,"lines":[`); err != nil {
+		return err
+	}
+
+	first := true
+	for line, err := range statement.Lines(ctx) {
+		if err != nil {
+			return err
+		}
+
+		if !first {
+			if _, err := io.WriteString(w, ","); err != nil {
+				return err
+			}
+		}
+		first = false
+
+		encoded, err := json.Marshal(line)
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(encoded); err != nil {
+			return err
+		}
+	}
+
+	_, err = io.WriteString(w, "]}\n")
+	return err
+}
+
+// WriteStatementCSV renders a statement as CSV. The first and last rows carry the opening and the
+// closing balance, the latter together with the credit and debit totals.
+func WriteStatementCSV(ctx context.Context, w io.Writer, statement *Statement) error {
+	writer := csv.NewWriter(w)
+
+	rows := [][]string{
+		{"date", "transaction_id", "reference", "description", "credit", "debit", "balance"},
+		{formatStatementTime(statement.From), "", "", "Opening balance", "", "", strconv.FormatInt(statement.OpeningBalance, 10)},
+	}
+	if err := writer.WriteAll(rows); err != nil {
+		return err
+	}
+
+	for line, err := range statement.Lines(ctx) {
+		if err != nil {
+			return err
+		}
+
+		credit, debit := "", ""
+		if line.Type == TransactionTypeDebit {
+			debit = strconv.FormatInt(line.Amount, 10)
+		} else {
+			credit = strconv.FormatInt(line.Amount, 10)
+		}
+
+		row := []string{formatStatementTime(line.CompletedAt), line.TransactionID, line.Reference, line.Description, credit, debit, strconv.FormatInt(line.Balance, 10)}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+
+	return writer.WriteAll([][]string{
+		{formatStatementTime(statement.To), "", "", "Closing balance", strconv.FormatInt(statement.TotalCredits, 10), strconv.FormatInt(statement.TotalDebits, 10), strconv.FormatInt(statement.ClosingBalance, 10)},
+	})
+}
+
+// WriteStatementTemplate renders a statement through a custom template. See StatementTemplate.
+func WriteStatementTemplate(ctx context.Context, w io.Writer, statement *Statement, tmpl StatementTemplate) error {
+	if err := tmpl.ExecuteTemplate(w, "header", statement); err != nil {
+		return err
+	}
+
+	for line, err := range statement.Lines(ctx) {
+		if err != nil {
+			return err
+		}
+		if err := tmpl.ExecuteTemplate(w, "line", line); err != nil {
+			return err
+		}
+	}
+
+	return tmpl.ExecuteTemplate(w, "footer", statement)
+}
+
+// formatStatementTime formats a time for CSV statements
+func formatStatementTime(t time.Time) string {
+	return t.Format(time.RFC3339)
+}
@@ -0,0 +1,132 @@
+package wallethub
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupTestStatement creates a wallet with transactions before, during and after January 2025
+func setupTestStatement(t *testing.T) (*DefaultWalletManager, *Wallet) {
+	store := setupTestGormWalletStore(t)
+	manager := NewWalletManager(WithStore(store))
+
+	wallet, err := manager.CreateWallet(context.Background(), "test-user", "Main <Wallet>", "Test Description", "test-ref")
+	require.NoError(t, err)
+
+	saveTestCompletedTransaction(t, store, wallet.ID, TransactionTypeCredit, 1000, time.Date(2024, 12, 20, 0, 0, 0, 0, time.UTC))
+	saveTestCompletedTransaction(t, store, wallet.ID, TransactionTypeCredit, 500, time.Date(2025, 1, 5, 0, 0, 0, 0, time.UTC))
+	saveTestCompletedTransaction(t, store, wallet.ID, TransactionTypeDebit, 200, time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC))
+	saveTestCompletedTransaction(t, store, wallet.ID, TransactionTypeCredit, 50, time.Date(2025, 1, 25, 0, 0, 0, 0, time.UTC))
+	saveTestCompletedTransaction(t, store, wallet.ID, TransactionTypeDebit, 100, time.Date(2025, 2, 3, 0, 0, 0, 0, time.UTC))
+
+	return manager, wallet
+}
+
+// TestGenerateStatement tests statement balances, totals and lines
+func TestGenerateStatement(t *testing.T) {
+	manager, wallet := setupTestStatement(t)
+	ctx := context.Background()
+
+	from := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	statement, err := manager.GenerateStatement(ctx, wallet.ID, from, to)
+	require.NoError(t, err)
+	assert.Equal(t, wallet.ID, statement.WalletID)
+	assert.Equal(t, "test-user", statement.UserID)
+	assert.Equal(t, int64(1000), statement.OpeningBalance)
+	assert.Equal(t, int64(550), statement.TotalCredits)
+	assert.Equal(t, int64(200), statement.TotalDebits)
+	assert.Equal(t, int64(1350), statement.ClosingBalance)
+
+	var balances []int64
+	for line, err := range statement.Lines(ctx) {
+		require.NoError(t, err)
+		balances = append(balances, line.Balance)
+	}
+	assert.Equal(t, []int64{1500, 1300, 1350}, balances)
+
+	// Stopping early is supported
+	count := 0
+	for range statement.Lines(ctx) {
+		count++
+		break
+	}
+	assert.Equal(t, 1, count)
+
+	// Test invalid input
+	_, err = manager.GenerateStatement(ctx, wallet.ID, to, from)
+	assert.ErrorIs(t, err, ErrInvalidStatementPeriod)
+
+	_, err = manager.GenerateStatement(ctx, "non-existent-id", from, to)
+	assert.ErrorIs(t, err, ErrWalletNotFound)
+}
+
+// TestWriteStatement tests rendering statements in every format
+func TestWriteStatement(t *testing.T) {
+	manager, wallet := setupTestStatement(t)
+	ctx := context.Background()
+
+	statement, err := manager.GenerateStatement(ctx, wallet.ID, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+
+	// JSON
+	var buf bytes.Buffer
+	err = WriteStatement(ctx, &buf, statement, StatementFormatJSON)
+	require.NoError(t, err)
+
+	var decoded struct {
+		WalletID       string          `json:"wallet_id"`
+		OpeningBalance int64           `json:"opening_balance"`
+		ClosingBalance int64           `json:"closing_balance"`
+		Lines          []StatementLine `json:"lines"`
+	}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+	assert.Equal(t, wallet.ID, decoded.WalletID)
+	assert.Equal(t, int64(1000), decoded.OpeningBalance)
+	assert.Equal(t, int64(1350), decoded.ClosingBalance)
+	require.Len(t, decoded.Lines, 3)
+	assert.Equal(t, TransactionTypeDebit, decoded.Lines[1].Type)
+	assert.Equal(t, int64(1300), decoded.Lines[1].Balance)
+
+	// CSV
+	buf.Reset()
+	err = WriteStatement(ctx, &buf, statement, StatementFormatCSV)
+	require.NoError(t, err)
+
+	rows, err := csv.NewReader(&buf).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, rows, 6)
+	assert.Equal(t, []string{"date", "transaction_id", "reference", "description", "credit", "debit", "balance"}, rows[0])
+	assert.Equal(t, "Opening balance", rows[1][3])
+	assert.Equal(t, "1000", rows[1][6])
+	assert.Equal(t, []string{"", "200", "1300"}, rows[3][4:])
+	assert.Equal(t, []string{"Closing balance", "550", "200", "1350"}, rows[5][3:])
+
+	// Plain text
+	buf.Reset()
+	err = WriteStatement(ctx, &buf, statement, StatementFormatText)
+	require.NoError(t, err)
+	assert.Contains(t, buf.String(), "Statement for Main <Wallet>")
+	assert.Contains(t, buf.String(), "Opening balance: 1000")
+	assert.Contains(t, buf.String(), "2025-01-15 00:00:00  debit            200          1300")
+	assert.Contains(t, buf.String(), "Closing balance: 1350")
+
+	// HTML escapes user content
+	buf.Reset()
+	err = WriteStatement(ctx, &buf, statement, StatementFormatHTML)
+	require.NoError(t, err)
+	assert.Contains(t, buf.String(), "<h1>Statement for Main &lt;Wallet&gt;</h1>")
+	assert.Contains(t, buf.String(), "<td>1350</td>")
+	assert.Contains(t, buf.String(), "<p>Closing balance: 1350</p>")
+
+	err = WriteStatement(ctx, &buf, statement, StatementFormat("pdf"))
+	assert.ErrorIs(t, err, ErrUnsupportedStatementFormat)
+}
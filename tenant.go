@@ -0,0 +1,20 @@
+package wallethub
+
+import "context"
+
+// tenantContextKey is the context key under which the tenant ID is stored
+type tenantContextKey struct{}
+
+// WithTenant returns a context that scopes wallet operations to the given tenant. Stores read and
+// write only the records of the tenant carried by the context, so records of other tenants can
+// neither be read nor modified through it.
+func WithTenant(ctx context.Context, tenantID string) context.Context {
+	return context.WithValue(ctx, tenantContextKey{}, tenantID)
+}
+
+// TenantFromContext returns the tenant ID carried by a context, or an empty string for the default
+// tenant used by contexts without one
+func TenantFromContext(ctx context.Context) string {
+	tenantID, _ := ctx.Value(tenantContextKey{}).(string)
+	return tenantID
+}
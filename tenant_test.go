@@ -0,0 +1,228 @@
+package wallethub
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestTenantFromContext tests carrying the tenant in a context
+func TestTenantFromContext(t *testing.T) {
+	assert.Equal(t, "", TenantFromContext(context.Background()))
+	assert.Equal(t, "tenant-a", TenantFromContext(WithTenant(context.Background(), "tenant-a")))
+}
+
+// TestTenantWalletIsolation tests that wallets and transactions of one tenant are invisible to others
+func TestTenantWalletIsolation(t *testing.T) {
+	manager := NewWalletManager(WithStore(setupTestGormWalletStore(t)))
+	ctxA := WithTenant(context.Background(), "tenant-a")
+	ctxB := WithTenant(context.Background(), "tenant-b")
+	ctxDefault := context.Background()
+
+	walletA, err := manager.CreateWallet(ctxA, "user-1", "Wallet A", "Description A", "ref-1")
+	require.NoError(t, err)
+	assert.Equal(t, "tenant-a", walletA.TenantID)
+	walletB, err := manager.CreateWallet(ctxB, "user-1", "Wallet B", "Description B", "ref-1")
+	require.NoError(t, err)
+
+	txA, err := manager.Credit(ctxA, walletA.ID, 1000, "Deposit", "", "deposit-1", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "tenant-a", txA.TenantID)
+
+	// Wallets of another tenant are not found
+	wallet, err := manager.GetWallet(ctxB, walletA.ID)
+	assert.NoError(t, err)
+	assert.Nil(t, wallet)
+
+	wallet, err = manager.GetWallet(ctxDefault, walletA.ID)
+	assert.NoError(t, err)
+	assert.Nil(t, wallet)
+
+	wallets, err := manager.GetWalletsByUserID(ctxA, "user-1")
+	assert.NoError(t, err)
+	require.Len(t, wallets, 1)
+	assert.Equal(t, walletA.ID, wallets[0].ID)
+
+	wallet, err = manager.GetWalletByUserIDAndReference(ctxB, "user-1", "ref-1")
+	assert.NoError(t, err)
+	require.NotNil(t, wallet)
+	assert.Equal(t, walletB.ID, wallet.ID)
+
+	// Transactions of another tenant are not found
+	tx, err := manager.GetTransaction(ctxB, txA.ID)
+	assert.NoError(t, err)
+	assert.Nil(t, tx)
+
+	transactions, err := manager.ListTransactions(ctxB, walletA.ID, 10, 0)
+	assert.NoError(t, err)
+	assert.Empty(t, transactions)
+
+	transactions, err = manager.ListUserTransactions(ctxB, "user-1", 10, 0)
+	assert.NoError(t, err)
+	assert.Empty(t, transactions)
+
+	total, err := manager.GetUserWalletSummary(ctxB, "user-1")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), total)
+
+	// Wallets of another tenant can neither be used nor modified
+	_, err = manager.Credit(ctxB, walletA.ID, 100, "Deposit", "", "", nil)
+	assert.Error(t, err)
+
+	_, err = manager.Debit(ctxB, walletA.ID, 100, "Withdrawal", "", "", nil)
+	assert.Error(t, err)
+
+	err = manager.UpdateWalletName(ctxB, walletA.ID, "Hijacked")
+	assert.Error(t, err)
+
+	err = manager.FreezeWallet(ctxB, walletA.ID, "Hijacked")
+	assert.Error(t, err)
+
+	err = manager.CancelTransaction(ctxB, txA.ID, "Hijacked")
+	assert.Error(t, err)
+
+	wallet, err = manager.GetWallet(ctxA, walletA.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Wallet A", wallet.Name)
+	assert.Equal(t, int64(1000), wallet.Balance)
+	assert.False(t, wallet.Frozen)
+}
+
+// TestTenantTransferIsolation tests that transfers cannot cross tenants
+func TestTenantTransferIsolation(t *testing.T) {
+	manager := NewWalletManager(WithStore(setupTestGormWalletStore(t)))
+	ctxA := WithTenant(context.Background(), "tenant-a")
+	ctxB := WithTenant(context.Background(), "tenant-b")
+
+	walletA1, err := manager.CreateWallet(ctxA, "user-1", "Wallet A1", "", "")
+	require.NoError(t, err)
+	walletA2, err := manager.CreateWallet(ctxA, "user-2", "Wallet A2", "", "")
+	require.NoError(t, err)
+	walletB, err := manager.CreateWallet(ctxB, "user-3", "Wallet B", "", "")
+	require.NoError(t, err)
+
+	_, err = manager.Credit(ctxA, walletA1.ID, 1000, "Deposit", "", "", nil)
+	require.NoError(t, err)
+	_, err = manager.Credit(ctxB, walletB.ID, 1000, "Deposit", "", "", nil)
+	require.NoError(t, err)
+
+	// Transfers within a tenant work
+	err = manager.Transfer(ctxA, walletA1.ID, walletA2.ID, 100, "Transfer", "", nil)
+	assert.NoError(t, err)
+
+	// Neither end of a transfer may belong to another tenant
+	err = manager.Transfer(ctxA, walletA1.ID, walletB.ID, 100, "Transfer", "", nil)
+	assert.EqualError(t, err, "wallet "+walletB.ID+": wallet not found")
+
+	err = manager.Transfer(ctxA, walletB.ID, walletA1.ID, 100, "Transfer", "", nil)
+	assert.EqualError(t, err, "wallet "+walletB.ID+": wallet not found")
+
+	err = manager.Transfer(ctxB, walletA1.ID, walletB.ID, 100, "Transfer", "", nil)
+	assert.EqualError(t, err, "wallet "+walletA1.ID+": wallet not found")
+
+	wallet, err := manager.GetWallet(ctxA, walletA1.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(900), wallet.Balance)
+
+	wallet, err = manager.GetWallet(ctxB, walletB.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1000), wallet.Balance)
+}
+
+// TestTenantSnapshotIsolation tests that total balances and their snapshots are kept per tenant
+func TestTenantSnapshotIsolation(t *testing.T) {
+	store := setupTestGormWalletStore(t)
+	snapshotStore := NewGormSnapshotStore(store.db, "")
+	require.NoError(t, snapshotStore.AutoMigrate(context.Background()))
+	manager := NewWalletManager(WithStore(store), WithSnapshotStore(snapshotStore))
+	ctxA := WithTenant(context.Background(), "tenant-a")
+	ctxB := WithTenant(context.Background(), "tenant-b")
+
+	walletA, err := manager.CreateWallet(ctxA, "user-1", "Wallet A", "", "")
+	require.NoError(t, err)
+	walletB, err := manager.CreateWallet(ctxB, "user-1", "Wallet B", "", "")
+	require.NoError(t, err)
+
+	_, err = manager.Credit(ctxA, walletA.ID, 1000, "Deposit", "", "", nil)
+	require.NoError(t, err)
+	_, err = manager.Credit(ctxB, walletB.ID, 300, "Deposit", "", "", nil)
+	require.NoError(t, err)
+
+	at := time.Now()
+	count, err := manager.TakeBalanceSnapshots(ctxA, at)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+	count, err = manager.TakeBalanceSnapshots(ctxB, at)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	// The total snapshots taken at the same time do not collide
+	snapshotA, err := snapshotStore.FindLatestBalanceSnapshot(ctxA, "", at)
+	require.NoError(t, err)
+	require.NotNil(t, snapshotA)
+	assert.Equal(t, int64(1000), snapshotA.Balance)
+
+	snapshotB, err := snapshotStore.FindLatestBalanceSnapshot(ctxB, "", at)
+	require.NoError(t, err)
+	require.NotNil(t, snapshotB)
+	assert.Equal(t, int64(300), snapshotB.Balance)
+	assert.NotEqual(t, snapshotA.ID, snapshotB.ID)
+
+	snapshot, err := snapshotStore.FindLatestBalanceSnapshot(ctxB, walletA.ID, at)
+	assert.NoError(t, err)
+	assert.Nil(t, snapshot)
+
+	total, err := manager.GetTotalBalanceAt(ctxA, at)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1000), total)
+
+	total, err = manager.GetTotalBalanceAt(context.Background(), at)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), total)
+}
+
+// TestTenantSchedulerIsolation tests that due schedules run within the tenant they were created in
+func TestTenantSchedulerIsolation(t *testing.T) {
+	manager := NewWalletManager(WithStore(setupTestGormWalletStore(t)))
+	scheduler := NewScheduler(manager, setupTestGormScheduleStore(t))
+	ctxA := WithTenant(context.Background(), "tenant-a")
+	ctxB := WithTenant(context.Background(), "tenant-b")
+
+	walletA, err := manager.CreateWallet(ctxA, "user-1", "Wallet A", "", "")
+	require.NoError(t, err)
+
+	runAt := time.Now().UTC().Add(time.Hour).Truncate(time.Second)
+	schedule, err := scheduler.CreateSchedule(ctxA, &Schedule{
+		Operation: ScheduleOperationCredit,
+		WalletID:  walletA.ID,
+		Amount:    100,
+		RunAt:     runAt,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "tenant-a", schedule.TenantID)
+
+	found, err := scheduler.GetSchedule(ctxB, schedule.ID)
+	assert.NoError(t, err)
+	assert.Nil(t, found)
+
+	// The worker picks up schedules of all tenants
+	err = scheduler.RunDue(context.Background(), runAt)
+	require.NoError(t, err)
+
+	wallet, err := manager.GetWallet(ctxA, walletA.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(100), wallet.Balance)
+
+	runs, err := scheduler.ListScheduleRuns(ctxA, schedule.ID, 10, 0)
+	assert.NoError(t, err)
+	require.Len(t, runs, 1)
+	assert.Equal(t, ScheduleRunStatusSucceeded, runs[0].Status)
+	assert.Equal(t, "tenant-a", runs[0].TenantID)
+
+	runs, err = scheduler.ListScheduleRuns(ctxB, schedule.ID, 10, 0)
+	assert.NoError(t, err)
+	assert.Empty(t, runs)
+}
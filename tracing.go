@@ -0,0 +1,845 @@
+package wallethub
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName is the instrumentation name of the spans created by this package
+const tracerName = "github.com/weedbox/wallethub"
+
+// Span attribute keys
+const (
+	AttributeWalletID      = attribute.Key("wallethub.wallet_id")
+	AttributeToWalletID    = attribute.Key("wallethub.to_wallet_id")
+	AttributeUserID        = attribute.Key("wallethub.user_id")
+	AttributeTransactionID = attribute.Key("wallethub.transaction_id")
+	AttributeAmount        = attribute.Key("wallethub.amount")
+	AttributeBucket        = attribute.Key("wallethub.bucket")
+	AttributeDelegateID    = attribute.Key("wallethub.delegate_id")
+	AttributeOutcome       = attribute.Key("wallethub.outcome") // "success" or the error code
+)
+
+// TracingOption defines a function type for configuring the tracing decorators
+type TracingOption func(*tracing)
+
+// WithTracerProvider sets the tracer provider of the tracing decorators. The global tracer provider is
+// used by default.
+func WithTracerProvider(provider trace.TracerProvider) TracingOption {
+	return func(t *tracing) {
+		t.tracer = provider.Tracer(tracerName)
+	}
+}
+
+// tracing holds the tracer shared by the tracing decorators
+type tracing struct {
+	tracer trace.Tracer
+}
+
+// newTracing creates the tracing configuration from options
+func newTracing(options []TracingOption) tracing {
+	t := tracing{tracer: otel.GetTracerProvider().Tracer(tracerName)}
+	for _, option := range options {
+		option(&t)
+	}
+	return t
+}
+
+// start starts a span as a child of the span in the context
+func (t tracing) start(ctx context.Context, name string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	return t.tracer.Start(ctx, name, trace.WithAttributes(attributes...))
+}
+
+// endSpan records the outcome of an operation and ends its span
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		span.SetAttributes(AttributeOutcome.String(string(ErrorCodeOf(err))))
+	} else {
+		span.SetAttributes(AttributeOutcome.String("success"))
+	}
+	span.End()
+}
+
+// TracingWalletManager is a WalletManager that creates a span for every operation before passing it
+// on to the wrapped manager. Spans are children of the span carried by the caller's context, which is
+// passed on so that spans of the store become children of the operation's span.
+type TracingWalletManager struct {
+	tracing
+	next WalletManager
+}
+
+// NewTracingWalletManager wraps a wallet manager with tracing
+func NewTracingWalletManager(next WalletManager, options ...TracingOption) *TracingWalletManager {
+	return &TracingWalletManager{
+		tracing: newTracing(options),
+		next:    next,
+	}
+}
+
+// CreateWallet creates a new wallet for a user
+func (m *TracingWalletManager) CreateWallet(ctx context.Context, userID string, name string, description string, reference string) (*Wallet, error) {
+	ctx, span := m.start(ctx, "WalletManager.CreateWallet", AttributeUserID.String(userID))
+	wallet, err := m.next.CreateWallet(ctx, userID, name, description, reference)
+	if wallet != nil {
+		span.SetAttributes(AttributeWalletID.String(wallet.ID))
+	}
+	endSpan(span, err)
+	return wallet, err
+}
+
+// GetWallet retrieves a wallet by ID
+func (m *TracingWalletManager) GetWallet(ctx context.Context, walletID string) (*Wallet, error) {
+	ctx, span := m.start(ctx, "WalletManager.GetWallet", AttributeWalletID.String(walletID))
+	wallet, err := m.next.GetWallet(ctx, walletID)
+	endSpan(span, err)
+	return wallet, err
+}
+
+// GetWalletsByUserID retrieves all wallets for a user
+func (m *TracingWalletManager) GetWalletsByUserID(ctx context.Context, userID string) ([]Wallet, error) {
+	ctx, span := m.start(ctx, "WalletManager.GetWalletsByUserID", AttributeUserID.String(userID))
+	wallets, err := m.next.GetWalletsByUserID(ctx, userID)
+	endSpan(span, err)
+	return wallets, err
+}
+
+// GetWalletByUserIDAndReference retrieves a wallet by user ID and reference
+func (m *TracingWalletManager) GetWalletByUserIDAndReference(ctx context.Context, userID string, reference string) (*Wallet, error) {
+	ctx, span := m.start(ctx, "WalletManager.GetWalletByUserIDAndReference", AttributeUserID.String(userID))
+	wallet, err := m.next.GetWalletByUserIDAndReference(ctx, userID, reference)
+	endSpan(span, err)
+	return wallet, err
+}
+
+// GetPrimaryWallet retrieves the primary wallet of a user
+func (m *TracingWalletManager) GetPrimaryWallet(ctx context.Context, userID string) (*Wallet, error) {
+	ctx, span := m.start(ctx, "WalletManager.GetPrimaryWallet", AttributeUserID.String(userID))
+	wallet, err := m.next.GetPrimaryWallet(ctx, userID)
+	endSpan(span, err)
+	return wallet, err
+}
+
+// SetPrimaryWallet sets a wallet as the primary wallet of its user
+func (m *TracingWalletManager) SetPrimaryWallet(ctx context.Context, walletID string) error {
+	ctx, span := m.start(ctx, "WalletManager.SetPrimaryWallet", AttributeWalletID.String(walletID))
+	err := m.next.SetPrimaryWallet(ctx, walletID)
+	endSpan(span, err)
+	return err
+}
+
+// UpdateWalletActive updates the active status of a wallet
+func (m *TracingWalletManager) UpdateWalletActive(ctx context.Context, walletID string, active bool) error {
+	ctx, span := m.start(ctx, "WalletManager.UpdateWalletActive", AttributeWalletID.String(walletID))
+	err := m.next.UpdateWalletActive(ctx, walletID, active)
+	endSpan(span, err)
+	return err
+}
+
+// UpdateWalletName updates the name of a wallet
+func (m *TracingWalletManager) UpdateWalletName(ctx context.Context, walletID string, name string) error {
+	ctx, span := m.start(ctx, "WalletManager.UpdateWalletName", AttributeWalletID.String(walletID))
+	err := m.next.UpdateWalletName(ctx, walletID, name)
+	endSpan(span, err)
+	return err
+}
+
+// UpdateWalletDescription updates the description of a wallet
+func (m *TracingWalletManager) UpdateWalletDescription(ctx context.Context, walletID string, description string) error {
+	ctx, span := m.start(ctx, "WalletManager.UpdateWalletDescription", AttributeWalletID.String(walletID))
+	err := m.next.UpdateWalletDescription(ctx, walletID, description)
+	endSpan(span, err)
+	return err
+}
+
+// UpdateWalletReference updates the reference of a wallet
+func (m *TracingWalletManager) UpdateWalletReference(ctx context.Context, walletID string, reference string) error {
+	ctx, span := m.start(ctx, "WalletManager.UpdateWalletReference", AttributeWalletID.String(walletID))
+	err := m.next.UpdateWalletReference(ctx, walletID, reference)
+	endSpan(span, err)
+	return err
+}
+
+// Credit adds funds to a wallet
+func (m *TracingWalletManager) Credit(ctx context.Context, walletID string, amount int64, description string, note string, reference string, data map[string]interface{}) (*Transaction, error) {
+	ctx, span := m.start(ctx, "WalletManager.Credit", AttributeWalletID.String(walletID), AttributeAmount.Int64(amount))
+	transaction, err := m.next.Credit(ctx, walletID, amount, description, note, reference, data)
+	if transaction != nil {
+		span.SetAttributes(AttributeTransactionID.String(transaction.ID))
+	}
+	endSpan(span, err)
+	return transaction, err
+}
+
+// CreditBucket adds funds to a balance bucket of a wallet
+func (m *TracingWalletManager) CreditBucket(ctx context.Context, walletID string, bucket string, amount int64, description string, note string, reference string, data map[string]interface{}) (*Transaction, error) {
+	ctx, span := m.start(ctx, "WalletManager.CreditBucket", AttributeWalletID.String(walletID), AttributeBucket.String(bucket), AttributeAmount.Int64(amount))
+	transaction, err := m.next.CreditBucket(ctx, walletID, bucket, amount, description, note, reference, data)
+	if transaction != nil {
+		span.SetAttributes(AttributeTransactionID.String(transaction.ID))
+	}
+	endSpan(span, err)
+	return transaction, err
+}
+
+// Debit removes funds from a wallet
+func (m *TracingWalletManager) Debit(ctx context.Context, walletID string, amount int64, description string, note string, reference string, data map[string]interface{}) (*Transaction, error) {
+	ctx, span := m.start(ctx, "WalletManager.Debit", AttributeWalletID.String(walletID), AttributeAmount.Int64(amount))
+	transaction, err := m.next.Debit(ctx, walletID, amount, description, note, reference, data)
+	if transaction != nil {
+		span.SetAttributes(AttributeTransactionID.String(transaction.ID))
+	}
+	endSpan(span, err)
+	return transaction, err
+}
+
+// GetTransaction retrieves a transaction by ID
+func (m *TracingWalletManager) GetTransaction(ctx context.Context, transactionID string) (*Transaction, error) {
+	ctx, span := m.start(ctx, "WalletManager.GetTransaction", AttributeTransactionID.String(transactionID))
+	transaction, err := m.next.GetTransaction(ctx, transactionID)
+	endSpan(span, err)
+	return transaction, err
+}
+
+// ListTransactions retrieves transactions for a wallet with pagination
+func (m *TracingWalletManager) ListTransactions(ctx context.Context, walletID string, limit int, offset int) ([]Transaction, error) {
+	ctx, span := m.start(ctx, "WalletManager.ListTransactions", AttributeWalletID.String(walletID))
+	transactions, err := m.next.ListTransactions(ctx, walletID, limit, offset)
+	endSpan(span, err)
+	return transactions, err
+}
+
+// ListUserTransactions retrieves transactions for all wallets of a user with pagination
+func (m *TracingWalletManager) ListUserTransactions(ctx context.Context, userID string, limit int, offset int) ([]Transaction, error) {
+	ctx, span := m.start(ctx, "WalletManager.ListUserTransactions", AttributeUserID.String(userID))
+	transactions, err := m.next.ListUserTransactions(ctx, userID, limit, offset)
+	endSpan(span, err)
+	return transactions, err
+}
+
+// Transfer moves funds from one wallet to another
+func (m *TracingWalletManager) Transfer(ctx context.Context, fromWalletID string, toWalletID string, amount int64, description string, note string, data map[string]interface{}) error {
+	ctx, span := m.start(ctx, "WalletManager.Transfer",
+		AttributeWalletID.String(fromWalletID),
+		AttributeToWalletID.String(toWalletID),
+		AttributeAmount.Int64(amount),
+	)
+	err := m.next.Transfer(ctx, fromWalletID, toWalletID, amount, description, note, data)
+	endSpan(span, err)
+	return err
+}
+
+// FreezeWallet fully freezes a wallet
+func (m *TracingWalletManager) FreezeWallet(ctx context.Context, walletID string, reason string) error {
+	ctx, span := m.start(ctx, "WalletManager.FreezeWallet", AttributeWalletID.String(walletID))
+	err := m.next.FreezeWallet(ctx, walletID, reason)
+	endSpan(span, err)
+	return err
+}
+
+// FreezeWalletWithMode freezes a wallet in the given mode
+func (m *TracingWalletManager) FreezeWalletWithMode(ctx context.Context, walletID string, mode FreezeMode, reason string) error {
+	ctx, span := m.start(ctx, "WalletManager.FreezeWalletWithMode", AttributeWalletID.String(walletID))
+	err := m.next.FreezeWalletWithMode(ctx, walletID, mode, reason)
+	endSpan(span, err)
+	return err
+}
+
+// UnfreezeWallet lifts the freeze mode of a wallet
+func (m *TracingWalletManager) UnfreezeWallet(ctx context.Context, walletID string) error {
+	ctx, span := m.start(ctx, "WalletManager.UnfreezeWallet", AttributeWalletID.String(walletID))
+	err := m.next.UnfreezeWallet(ctx, walletID)
+	endSpan(span, err)
+	return err
+}
+
+// SetFrozenAmount freezes part of a wallet's balance
+func (m *TracingWalletManager) SetFrozenAmount(ctx context.Context, walletID string, amount int64, reason string) error {
+	ctx, span := m.start(ctx, "WalletManager.SetFrozenAmount", AttributeWalletID.String(walletID), AttributeAmount.Int64(amount))
+	err := m.next.SetFrozenAmount(ctx, walletID, amount, reason)
+	endSpan(span, err)
+	return err
+}
+
+// CancelTransaction cancels a pending transaction
+func (m *TracingWalletManager) CancelTransaction(ctx context.Context, transactionID string, reason string) error {
+	ctx, span := m.start(ctx, "WalletManager.CancelTransaction", AttributeTransactionID.String(transactionID))
+	err := m.next.CancelTransaction(ctx, transactionID, reason)
+	endSpan(span, err)
+	return err
+}
+
+// CompleteTransaction completes a pending transaction
+func (m *TracingWalletManager) CompleteTransaction(ctx context.Context, transactionID string) error {
+	ctx, span := m.start(ctx, "WalletManager.CompleteTransaction", AttributeTransactionID.String(transactionID))
+	err := m.next.CompleteTransaction(ctx, transactionID)
+	endSpan(span, err)
+	return err
+}
+
+// GetUserWalletSummary returns the total balance of all wallets of a user
+func (m *TracingWalletManager) GetUserWalletSummary(ctx context.Context, userID string) (int64, error) {
+	ctx, span := m.start(ctx, "WalletManager.GetUserWalletSummary", AttributeUserID.String(userID))
+	total, err := m.next.GetUserWalletSummary(ctx, userID)
+	endSpan(span, err)
+	return total, err
+}
+
+// FlagWalletRisk flags a wallet as risky
+func (m *TracingWalletManager) FlagWalletRisk(ctx context.Context, walletID string, reason string) error {
+	ctx, span := m.start(ctx, "WalletManager.FlagWalletRisk", AttributeWalletID.String(walletID))
+	err := m.next.FlagWalletRisk(ctx, walletID, reason)
+	endSpan(span, err)
+	return err
+}
+
+// ClearWalletRiskFlag clears the risk flag of a wallet
+func (m *TracingWalletManager) ClearWalletRiskFlag(ctx context.Context, walletID string) error {
+	ctx, span := m.start(ctx, "WalletManager.ClearWalletRiskFlag", AttributeWalletID.String(walletID))
+	err := m.next.ClearWalletRiskFlag(ctx, walletID)
+	endSpan(span, err)
+	return err
+}
+
+// GrantAllowance lets a delegate spend up to an amount from a wallet
+func (m *TracingWalletManager) GrantAllowance(ctx context.Context, walletID string, delegateID string, amount int64) (*Allowance, error) {
+	ctx, span := m.start(ctx, "WalletManager.GrantAllowance",
+		AttributeWalletID.String(walletID),
+		AttributeDelegateID.String(delegateID),
+		AttributeAmount.Int64(amount),
+	)
+	allowance, err := m.next.GrantAllowance(ctx, walletID, delegateID, amount)
+	endSpan(span, err)
+	return allowance, err
+}
+
+// RevokeAllowance withdraws the allowance of a delegate on a wallet
+func (m *TracingWalletManager) RevokeAllowance(ctx context.Context, walletID string, delegateID string) error {
+	ctx, span := m.start(ctx, "WalletManager.RevokeAllowance", AttributeWalletID.String(walletID), AttributeDelegateID.String(delegateID))
+	err := m.next.RevokeAllowance(ctx, walletID, delegateID)
+	endSpan(span, err)
+	return err
+}
+
+// GetAllowance gets the allowance of a delegate on a wallet
+func (m *TracingWalletManager) GetAllowance(ctx context.Context, walletID string, delegateID string) (*Allowance, error) {
+	ctx, span := m.start(ctx, "WalletManager.GetAllowance", AttributeWalletID.String(walletID), AttributeDelegateID.String(delegateID))
+	allowance, err := m.next.GetAllowance(ctx, walletID, delegateID)
+	endSpan(span, err)
+	return allowance, err
+}
+
+// ListAllowances lists the allowances on a wallet
+func (m *TracingWalletManager) ListAllowances(ctx context.Context, walletID string) ([]Allowance, error) {
+	ctx, span := m.start(ctx, "WalletManager.ListAllowances", AttributeWalletID.String(walletID))
+	allowances, err := m.next.ListAllowances(ctx, walletID)
+	endSpan(span, err)
+	return allowances, err
+}
+
+// DebitAsDelegate removes funds from a wallet on behalf of a delegate
+func (m *TracingWalletManager) DebitAsDelegate(ctx context.Context, walletID string, delegateID string, amount int64, description string, note string, reference string, data map[string]interface{}) (*Transaction, error) {
+	ctx, span := m.start(ctx, "WalletManager.DebitAsDelegate",
+		AttributeWalletID.String(walletID),
+		AttributeDelegateID.String(delegateID),
+		AttributeAmount.Int64(amount),
+	)
+	transaction, err := m.next.DebitAsDelegate(ctx, walletID, delegateID, amount, description, note, reference, data)
+	if transaction != nil {
+		span.SetAttributes(AttributeTransactionID.String(transaction.ID))
+	}
+	endSpan(span, err)
+	return transaction, err
+}
+
+// TransferFrom moves funds between wallets on behalf of a delegate
+func (m *TracingWalletManager) TransferFrom(ctx context.Context, fromWalletID string, toWalletID string, delegateID string, amount int64, description string, note string, data map[string]interface{}) error {
+	ctx, span := m.start(ctx, "WalletManager.TransferFrom",
+		AttributeWalletID.String(fromWalletID),
+		AttributeToWalletID.String(toWalletID),
+		AttributeDelegateID.String(delegateID),
+		AttributeAmount.Int64(amount),
+	)
+	err := m.next.TransferFrom(ctx, fromWalletID, toWalletID, delegateID, amount, description, note, data)
+	endSpan(span, err)
+	return err
+}
+
+// TracingWalletStore is a WalletStore that creates a span for every call before passing it on to the
+// wrapped store. A transaction gets a span from Begin until its first Commit or Rollback, and the calls
+// made within it become children of that span.
+type TracingWalletStore struct {
+	tracing
+	next WalletStore
+}
+
+// NewTracingWalletStore wraps a wallet store with tracing
+func NewTracingWalletStore(next WalletStore, options ...TracingOption) *TracingWalletStore {
+	return &TracingWalletStore{
+		tracing: newTracing(options),
+		next:    next,
+	}
+}
+
+// Begin starts a new transaction
+func (s *TracingWalletStore) Begin(ctx context.Context) Txn {
+	ctx, span := s.start(ctx, "Txn")
+	return &tracingTxn{
+		tracing: s.tracing,
+		next:    s.next.Begin(ctx),
+		ctx:     ctx,
+		span:    span,
+	}
+}
+
+// SaveWallet saves a wallet (non-transactional)
+func (s *TracingWalletStore) SaveWallet(ctx context.Context, wallet *Wallet) error {
+	ctx, span := s.start(ctx, "WalletStore.SaveWallet", AttributeWalletID.String(wallet.ID))
+	err := s.next.SaveWallet(ctx, wallet)
+	endSpan(span, err)
+	return err
+}
+
+// FindWallet finds a wallet by ID (non-transactional)
+func (s *TracingWalletStore) FindWallet(ctx context.Context, walletID string) (*Wallet, error) {
+	ctx, span := s.start(ctx, "WalletStore.FindWallet", AttributeWalletID.String(walletID))
+	wallet, err := s.next.FindWallet(ctx, walletID)
+	endSpan(span, err)
+	return wallet, err
+}
+
+// FindWalletsByUserID finds all wallets of a user (non-transactional)
+func (s *TracingWalletStore) FindWalletsByUserID(ctx context.Context, userID string) ([]Wallet, error) {
+	ctx, span := s.start(ctx, "WalletStore.FindWalletsByUserID", AttributeUserID.String(userID))
+	wallets, err := s.next.FindWalletsByUserID(ctx, userID)
+	endSpan(span, err)
+	return wallets, err
+}
+
+// FindWalletByUserIDAndReference finds a wallet by user ID and reference (non-transactional)
+func (s *TracingWalletStore) FindWalletByUserIDAndReference(ctx context.Context, userID string, reference string) (*Wallet, error) {
+	ctx, span := s.start(ctx, "WalletStore.FindWalletByUserIDAndReference", AttributeUserID.String(userID))
+	wallet, err := s.next.FindWalletByUserIDAndReference(ctx, userID, reference)
+	endSpan(span, err)
+	return wallet, err
+}
+
+// FindPrimaryWalletByUserID finds the primary wallet of a user (non-transactional)
+func (s *TracingWalletStore) FindPrimaryWalletByUserID(ctx context.Context, userID string) (*Wallet, error) {
+	ctx, span := s.start(ctx, "WalletStore.FindPrimaryWalletByUserID", AttributeUserID.String(userID))
+	wallet, err := s.next.FindPrimaryWalletByUserID(ctx, userID)
+	endSpan(span, err)
+	return wallet, err
+}
+
+// FindWallets finds wallets with pagination (non-transactional)
+func (s *TracingWalletStore) FindWallets(ctx context.Context, limit int, offset int) ([]Wallet, error) {
+	ctx, span := s.start(ctx, "WalletStore.FindWallets")
+	wallets, err := s.next.FindWallets(ctx, limit, offset)
+	endSpan(span, err)
+	return wallets, err
+}
+
+// FindWalletsAfter finds the wallets following a wallet in ID order (non-transactional)
+func (s *TracingWalletStore) FindWalletsAfter(ctx context.Context, afterID string, limit int) ([]Wallet, error) {
+	ctx, span := s.start(ctx, "WalletStore.FindWalletsAfter")
+	wallets, err := s.next.FindWalletsAfter(ctx, afterID, limit)
+	endSpan(span, err)
+	return wallets, err
+}
+
+// UpdateWallet updates an existing wallet (non-transactional)
+func (s *TracingWalletStore) UpdateWallet(ctx context.Context, wallet *Wallet) error {
+	ctx, span := s.start(ctx, "WalletStore.UpdateWallet", AttributeWalletID.String(wallet.ID))
+	err := s.next.UpdateWallet(ctx, wallet)
+	endSpan(span, err)
+	return err
+}
+
+// SaveTransaction saves a transaction (non-transactional)
+func (s *TracingWalletStore) SaveTransaction(ctx context.Context, transaction *Transaction) error {
+	ctx, span := s.start(ctx, "WalletStore.SaveTransaction",
+		AttributeTransactionID.String(transaction.ID),
+		AttributeWalletID.String(transaction.WalletID),
+		AttributeAmount.Int64(transaction.Amount),
+	)
+	err := s.next.SaveTransaction(ctx, transaction)
+	endSpan(span, err)
+	return err
+}
+
+// FindTransaction finds a transaction by ID (non-transactional)
+func (s *TracingWalletStore) FindTransaction(ctx context.Context, transactionID string) (*Transaction, error) {
+	ctx, span := s.start(ctx, "WalletStore.FindTransaction", AttributeTransactionID.String(transactionID))
+	transaction, err := s.next.FindTransaction(ctx, transactionID)
+	endSpan(span, err)
+	return transaction, err
+}
+
+// FindTransactionsByWalletID finds the transactions of a wallet with pagination (non-transactional)
+func (s *TracingWalletStore) FindTransactionsByWalletID(ctx context.Context, walletID string, limit int, offset int) ([]Transaction, error) {
+	ctx, span := s.start(ctx, "WalletStore.FindTransactionsByWalletID", AttributeWalletID.String(walletID))
+	transactions, err := s.next.FindTransactionsByWalletID(ctx, walletID, limit, offset)
+	endSpan(span, err)
+	return transactions, err
+}
+
+// FindTransactionsByUserID finds the transactions of a user with pagination (non-transactional)
+func (s *TracingWalletStore) FindTransactionsByUserID(ctx context.Context, userID string, limit int, offset int) ([]Transaction, error) {
+	ctx, span := s.start(ctx, "WalletStore.FindTransactionsByUserID", AttributeUserID.String(userID))
+	transactions, err := s.next.FindTransactionsByUserID(ctx, userID, limit, offset)
+	endSpan(span, err)
+	return transactions, err
+}
+
+// FindTransactionsAfter finds the transactions following a transaction (non-transactional)
+func (s *TracingWalletStore) FindTransactionsAfter(ctx context.Context, after *Transaction, limit int) ([]Transaction, error) {
+	ctx, span := s.start(ctx, "WalletStore.FindTransactionsAfter")
+	transactions, err := s.next.FindTransactionsAfter(ctx, after, limit)
+	endSpan(span, err)
+	return transactions, err
+}
+
+// FindCompletedTransactionsByWalletID finds the completed transactions of a wallet (non-transactional)
+func (s *TracingWalletStore) FindCompletedTransactionsByWalletID(ctx context.Context, walletID string, limit int, offset int) ([]Transaction, error) {
+	ctx, span := s.start(ctx, "WalletStore.FindCompletedTransactionsByWalletID", AttributeWalletID.String(walletID))
+	transactions, err := s.next.FindCompletedTransactionsByWalletID(ctx, walletID, limit, offset)
+	endSpan(span, err)
+	return transactions, err
+}
+
+// FindCompletedTransactionsByWalletIDBetween finds the completed transactions of a wallet within a period (non-transactional)
+func (s *TracingWalletStore) FindCompletedTransactionsByWalletIDBetween(ctx context.Context, walletID string, after time.Time, until time.Time, limit int, offset int) ([]Transaction, error) {
+	ctx, span := s.start(ctx, "WalletStore.FindCompletedTransactionsByWalletIDBetween", AttributeWalletID.String(walletID))
+	transactions, err := s.next.FindCompletedTransactionsByWalletIDBetween(ctx, walletID, after, until, limit, offset)
+	endSpan(span, err)
+	return transactions, err
+}
+
+// FindChainedTransactionsByWalletID finds the chained transactions of a wallet (non-transactional)
+func (s *TracingWalletStore) FindChainedTransactionsByWalletID(ctx context.Context, walletID string, limit int, offset int) ([]Transaction, error) {
+	ctx, span := s.start(ctx, "WalletStore.FindChainedTransactionsByWalletID", AttributeWalletID.String(walletID))
+	transactions, err := s.next.FindChainedTransactionsByWalletID(ctx, walletID, limit, offset)
+	endSpan(span, err)
+	return transactions, err
+}
+
+// CountTransactionsByStatus counts the transactions with the given status (non-transactional)
+func (s *TracingWalletStore) CountTransactionsByStatus(ctx context.Context, status TransactionStatus) (int64, error) {
+	ctx, span := s.start(ctx, "WalletStore.CountTransactionsByStatus")
+	count, err := s.next.CountTransactionsByStatus(ctx, status)
+	endSpan(span, err)
+	return count, err
+}
+
+// UpdateTransaction updates an existing transaction (non-transactional)
+func (s *TracingWalletStore) UpdateTransaction(ctx context.Context, transaction *Transaction) error {
+	ctx, span := s.start(ctx, "WalletStore.UpdateTransaction",
+		AttributeTransactionID.String(transaction.ID),
+		AttributeWalletID.String(transaction.WalletID),
+	)
+	err := s.next.UpdateTransaction(ctx, transaction)
+	endSpan(span, err)
+	return err
+}
+
+// FindAllowance finds the allowance of a delegate on a wallet (non-transactional)
+func (s *TracingWalletStore) FindAllowance(ctx context.Context, walletID string, delegateID string) (*Allowance, error) {
+	ctx, span := s.start(ctx, "WalletStore.FindAllowance", AttributeWalletID.String(walletID), AttributeDelegateID.String(delegateID))
+	allowance, err := s.next.FindAllowance(ctx, walletID, delegateID)
+	endSpan(span, err)
+	return allowance, err
+}
+
+// FindAllowancesByWalletID finds all allowances on a wallet (non-transactional)
+func (s *TracingWalletStore) FindAllowancesByWalletID(ctx context.Context, walletID string) ([]Allowance, error) {
+	ctx, span := s.start(ctx, "WalletStore.FindAllowancesByWalletID", AttributeWalletID.String(walletID))
+	allowances, err := s.next.FindAllowancesByWalletID(ctx, walletID)
+	endSpan(span, err)
+	return allowances, err
+}
+
+// SumTransactionAmountsByWalletID sums the balance changes of a wallet's completed transactions (non-transactional)
+func (s *TracingWalletStore) SumTransactionAmountsByWalletID(ctx context.Context, walletID string, after time.Time, until time.Time) (int64, error) {
+	ctx, span := s.start(ctx, "WalletStore.SumTransactionAmountsByWalletID", AttributeWalletID.String(walletID))
+	sum, err := s.next.SumTransactionAmountsByWalletID(ctx, walletID, after, until)
+	endSpan(span, err)
+	return sum, err
+}
+
+// SumTransactionAmounts sums the balance changes of all completed transactions (non-transactional)
+func (s *TracingWalletStore) SumTransactionAmounts(ctx context.Context, after time.Time, until time.Time) (int64, error) {
+	ctx, span := s.start(ctx, "WalletStore.SumTransactionAmounts")
+	sum, err := s.next.SumTransactionAmounts(ctx, after, until)
+	endSpan(span, err)
+	return sum, err
+}
+
+// SumTransactionTotalsByWalletID sums the credits and debits of a wallet's completed transactions (non-transactional)
+func (s *TracingWalletStore) SumTransactionTotalsByWalletID(ctx context.Context, walletID string, after time.Time, until time.Time) (int64, int64, error) {
+	ctx, span := s.start(ctx, "WalletStore.SumTransactionTotalsByWalletID", AttributeWalletID.String(walletID))
+	credits, debits, err := s.next.SumTransactionTotalsByWalletID(ctx, walletID, after, until)
+	endSpan(span, err)
+	return credits, debits, err
+}
+
+// AggregateTransactions computes per-bucket transaction totals (non-transactional)
+func (s *TracingWalletStore) AggregateTransactions(ctx context.Context, interval ReportInterval, from time.Time, to time.Time, filter ReportFilter) ([]TransactionAggregate, error) {
+	ctx, span := s.start(ctx, "WalletStore.AggregateTransactions", AttributeWalletID.String(filter.WalletID))
+	aggregates, err := s.next.AggregateTransactions(ctx, interval, from, to, filter)
+	endSpan(span, err)
+	return aggregates, err
+}
+
+// tracingTxn is a Txn that creates a span for every call as a child of the transaction's span
+type tracingTxn struct {
+	tracing
+	next Txn
+	ctx  context.Context // Context carrying the transaction's span
+	span trace.Span
+	done bool // Whether the transaction's span has ended
+}
+
+// end ends the transaction's span on the first Commit or Rollback
+func (t *tracingTxn) end(err error) {
+	if t.done {
+		return
+	}
+	t.done = true
+	endSpan(t.span, err)
+}
+
+// SaveWallet saves a wallet (transactional)
+func (t *tracingTxn) SaveWallet(wallet *Wallet) error {
+	_, span := t.start(t.ctx, "Txn.SaveWallet", AttributeWalletID.String(wallet.ID))
+	err := t.next.SaveWallet(wallet)
+	endSpan(span, err)
+	return err
+}
+
+// FindWallet finds a wallet by ID (transactional)
+func (t *tracingTxn) FindWallet(walletID string) (*Wallet, error) {
+	_, span := t.start(t.ctx, "Txn.FindWallet", AttributeWalletID.String(walletID))
+	wallet, err := t.next.FindWallet(walletID)
+	endSpan(span, err)
+	return wallet, err
+}
+
+// FindWalletsByUserID finds all wallets of a user (transactional)
+func (t *tracingTxn) FindWalletsByUserID(userID string) ([]Wallet, error) {
+	_, span := t.start(t.ctx, "Txn.FindWalletsByUserID", AttributeUserID.String(userID))
+	wallets, err := t.next.FindWalletsByUserID(userID)
+	endSpan(span, err)
+	return wallets, err
+}
+
+// FindWalletByUserIDAndReference finds a wallet by user ID and reference (transactional)
+func (t *tracingTxn) FindWalletByUserIDAndReference(userID string, reference string) (*Wallet, error) {
+	_, span := t.start(t.ctx, "Txn.FindWalletByUserIDAndReference", AttributeUserID.String(userID))
+	wallet, err := t.next.FindWalletByUserIDAndReference(userID, reference)
+	endSpan(span, err)
+	return wallet, err
+}
+
+// FindPrimaryWalletByUserID finds the primary wallet of a user (transactional)
+func (t *tracingTxn) FindPrimaryWalletByUserID(userID string) (*Wallet, error) {
+	_, span := t.start(t.ctx, "Txn.FindPrimaryWalletByUserID", AttributeUserID.String(userID))
+	wallet, err := t.next.FindPrimaryWalletByUserID(userID)
+	endSpan(span, err)
+	return wallet, err
+}
+
+// LockWallets finds wallets by their IDs and locks them for update (transactional)
+func (t *tracingTxn) LockWallets(walletIDs []string) ([]Wallet, error) {
+	_, span := t.start(t.ctx, "Txn.LockWallets")
+	wallets, err := t.next.LockWallets(walletIDs)
+	endSpan(span, err)
+	return wallets, err
+}
+
+// FindWalletsByIDs finds wallets by their IDs (transactional)
+func (t *tracingTxn) FindWalletsByIDs(walletIDs []string) ([]Wallet, error) {
+	_, span := t.start(t.ctx, "Txn.FindWalletsByIDs")
+	wallets, err := t.next.FindWalletsByIDs(walletIDs)
+	endSpan(span, err)
+	return wallets, err
+}
+
+// FindWallets finds wallets with pagination (transactional)
+func (t *tracingTxn) FindWallets(limit int, offset int) ([]Wallet, error) {
+	_, span := t.start(t.ctx, "Txn.FindWallets")
+	wallets, err := t.next.FindWallets(limit, offset)
+	endSpan(span, err)
+	return wallets, err
+}
+
+// UpdateWallet updates an existing wallet (transactional)
+func (t *tracingTxn) UpdateWallet(wallet *Wallet) error {
+	_, span := t.start(t.ctx, "Txn.UpdateWallet", AttributeWalletID.String(wallet.ID))
+	err := t.next.UpdateWallet(wallet)
+	endSpan(span, err)
+	return err
+}
+
+// SaveTransaction saves a transaction (transactional)
+func (t *tracingTxn) SaveTransaction(transaction *Transaction) error {
+	_, span := t.start(t.ctx, "Txn.SaveTransaction",
+		AttributeTransactionID.String(transaction.ID),
+		AttributeWalletID.String(transaction.WalletID),
+		AttributeAmount.Int64(transaction.Amount),
+	)
+	err := t.next.SaveTransaction(transaction)
+	endSpan(span, err)
+	return err
+}
+
+// SaveTransactions saves multiple transactions (transactional)
+func (t *tracingTxn) SaveTransactions(transactions []Transaction) error {
+	_, span := t.start(t.ctx, "Txn.SaveTransactions")
+	err := t.next.SaveTransactions(transactions)
+	endSpan(span, err)
+	return err
+}
+
+// FindTransaction finds a transaction by ID (transactional)
+func (t *tracingTxn) FindTransaction(transactionID string) (*Transaction, error) {
+	_, span := t.start(t.ctx, "Txn.FindTransaction", AttributeTransactionID.String(transactionID))
+	transaction, err := t.next.FindTransaction(transactionID)
+	endSpan(span, err)
+	return transaction, err
+}
+
+// FindTransactionsByIDs finds transactions by their IDs (transactional)
+func (t *tracingTxn) FindTransactionsByIDs(transactionIDs []string) ([]Transaction, error) {
+	_, span := t.start(t.ctx, "Txn.FindTransactionsByIDs")
+	transactions, err := t.next.FindTransactionsByIDs(transactionIDs)
+	endSpan(span, err)
+	return transactions, err
+}
+
+// FindTransactionsByWalletID finds the transactions of a wallet with pagination (transactional)
+func (t *tracingTxn) FindTransactionsByWalletID(walletID string, limit int, offset int) ([]Transaction, error) {
+	_, span := t.start(t.ctx, "Txn.FindTransactionsByWalletID", AttributeWalletID.String(walletID))
+	transactions, err := t.next.FindTransactionsByWalletID(walletID, limit, offset)
+	endSpan(span, err)
+	return transactions, err
+}
+
+// FindTransactionsByUserID finds the transactions of a user with pagination (transactional)
+func (t *tracingTxn) FindTransactionsByUserID(userID string, limit int, offset int) ([]Transaction, error) {
+	_, span := t.start(t.ctx, "Txn.FindTransactionsByUserID", AttributeUserID.String(userID))
+	transactions, err := t.next.FindTransactionsByUserID(userID, limit, offset)
+	endSpan(span, err)
+	return transactions, err
+}
+
+// FindCompletedTransactionsByWalletID finds the completed transactions of a wallet (transactional)
+func (t *tracingTxn) FindCompletedTransactionsByWalletID(walletID string, limit int, offset int) ([]Transaction, error) {
+	_, span := t.start(t.ctx, "Txn.FindCompletedTransactionsByWalletID", AttributeWalletID.String(walletID))
+	transactions, err := t.next.FindCompletedTransactionsByWalletID(walletID, limit, offset)
+	endSpan(span, err)
+	return transactions, err
+}
+
+// FindChainedTransactionsByWalletID finds the chained transactions of a wallet (transactional)
+func (t *tracingTxn) FindChainedTransactionsByWalletID(walletID string, limit int, offset int) ([]Transaction, error) {
+	_, span := t.start(t.ctx, "Txn.FindChainedTransactionsByWalletID", AttributeWalletID.String(walletID))
+	transactions, err := t.next.FindChainedTransactionsByWalletID(walletID, limit, offset)
+	endSpan(span, err)
+	return transactions, err
+}
+
+// SumTransactionTotalsByWalletID sums the credits and debits of a wallet's completed transactions (transactional)
+func (t *tracingTxn) SumTransactionTotalsByWalletID(walletID string, after time.Time, until time.Time) (int64, int64, error) {
+	_, span := t.start(t.ctx, "Txn.SumTransactionTotalsByWalletID", AttributeWalletID.String(walletID))
+	credits, debits, err := t.next.SumTransactionTotalsByWalletID(walletID, after, until)
+	endSpan(span, err)
+	return credits, debits, err
+}
+
+// UpdateTransaction updates an existing transaction (transactional)
+func (t *tracingTxn) UpdateTransaction(transaction *Transaction) error {
+	_, span := t.start(t.ctx, "Txn.UpdateTransaction",
+		AttributeTransactionID.String(transaction.ID),
+		AttributeWalletID.String(transaction.WalletID),
+	)
+	err := t.next.UpdateTransaction(transaction)
+	endSpan(span, err)
+	return err
+}
+
+// SaveAllowance creates or replaces an allowance (transactional)
+func (t *tracingTxn) SaveAllowance(allowance *Allowance) error {
+	_, span := t.start(t.ctx, "Txn.SaveAllowance",
+		AttributeWalletID.String(allowance.WalletID),
+		AttributeDelegateID.String(allowance.DelegateID),
+	)
+	err := t.next.SaveAllowance(allowance)
+	endSpan(span, err)
+	return err
+}
+
+// FindAllowance finds the allowance of a delegate on a wallet (transactional)
+func (t *tracingTxn) FindAllowance(walletID string, delegateID string) (*Allowance, error) {
+	_, span := t.start(t.ctx, "Txn.FindAllowance", AttributeWalletID.String(walletID), AttributeDelegateID.String(delegateID))
+	allowance, err := t.next.FindAllowance(walletID, delegateID)
+	endSpan(span, err)
+	return allowance, err
+}
+
+// DeleteAllowance deletes the allowance of a delegate on a wallet (transactional)
+func (t *tracingTxn) DeleteAllowance(walletID string, delegateID string) error {
+	_, span := t.start(t.ctx, "Txn.DeleteAllowance", AttributeWalletID.String(walletID), AttributeDelegateID.String(delegateID))
+	err := t.next.DeleteAllowance(walletID, delegateID)
+	endSpan(span, err)
+	return err
+}
+
+// Commit commits the transaction
+func (t *tracingTxn) Commit() error {
+	if t.done {
+		return t.next.Commit()
+	}
+
+	_, span := t.start(t.ctx, "Txn.Commit")
+	err := t.next.Commit()
+	endSpan(span, err)
+	t.end(err)
+	return err
+}
+
+// Rollback rolls back the transaction. Rollbacks after the transaction ended, such as deferred
+// rollbacks after a commit, are not traced.
+func (t *tracingTxn) Rollback() error {
+	if t.done {
+		return t.next.Rollback()
+	}
+
+	_, span := t.start(t.ctx, "Txn.Rollback")
+	err := t.next.Rollback()
+	endSpan(span, err)
+	t.end(err)
+	return err
+}
+
+// SavePoint marks a point within the transaction that RollbackTo can return to
+func (t *tracingTxn) SavePoint(name string) error {
+	_, span := t.start(t.ctx, "Txn.SavePoint")
+	err := t.next.SavePoint(name)
+	endSpan(span, err)
+	return err
+}
+
+// RollbackTo undoes the changes made since the named savepoint, keeping the transaction open
+func (t *tracingTxn) RollbackTo(name string) error {
+	_, span := t.start(t.ctx, "Txn.RollbackTo")
+	err := t.next.RollbackTo(name)
+	endSpan(span, err)
+	return err
+}
@@ -0,0 +1,126 @@
+package wallethub
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// setupTestTracing creates a traced manager over a traced store and the exporter receiving its spans
+func setupTestTracing(t *testing.T) (*TracingWalletManager, *tracetest.InMemoryExporter) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	t.Cleanup(func() {
+		provider.Shutdown(context.Background())
+	})
+
+	store := NewTracingWalletStore(setupTestGormWalletStore(t), WithTracerProvider(provider))
+	manager := NewTracingWalletManager(NewWalletManager(WithStore(store)), WithTracerProvider(provider))
+
+	return manager, exporter
+}
+
+// spanAttributes returns the attributes of a span as a map
+func spanAttributes(span tracetest.SpanStub) map[attribute.Key]attribute.Value {
+	attributes := make(map[attribute.Key]attribute.Value)
+	for _, kv := range span.Attributes {
+		attributes[kv.Key] = kv.Value
+	}
+	return attributes
+}
+
+// TestTracingWalletManager tests the spans of manager operations and the store calls within them
+func TestTracingWalletManager(t *testing.T) {
+	manager, exporter := setupTestTracing(t)
+
+	// Spans are children of the caller's span
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	ctx, parent := provider.Tracer("test").Start(context.Background(), "request")
+
+	wallet, err := manager.CreateWallet(ctx, "user-1", "Wallet", "", "")
+	require.NoError(t, err)
+	_, err = manager.Credit(ctx, wallet.ID, 1000, "Deposit", "", "", nil)
+	require.NoError(t, err)
+	exporter.Reset()
+
+	_, err = manager.Debit(ctx, wallet.ID, 300, "Purchase", "", "", nil)
+	require.NoError(t, err)
+	parent.End()
+
+	spans := exporter.GetSpans()
+	byName := make(map[string]tracetest.SpanStub)
+	for _, span := range spans {
+		byName[span.Name] = span
+	}
+
+	debit, ok := byName["WalletManager.Debit"]
+	require.True(t, ok)
+	assert.Equal(t, parent.SpanContext().SpanID(), debit.Parent.SpanID())
+	attributes := spanAttributes(debit)
+	assert.Equal(t, wallet.ID, attributes[AttributeWalletID].AsString())
+	assert.Equal(t, int64(300), attributes[AttributeAmount].AsInt64())
+	assert.Equal(t, "success", attributes[AttributeOutcome].AsString())
+	assert.NotEmpty(t, attributes[AttributeTransactionID].AsString())
+
+	txn, ok := byName["Txn"]
+	require.True(t, ok)
+	assert.Equal(t, debit.SpanContext.SpanID(), txn.Parent.SpanID())
+
+	for _, name := range []string{"Txn.LockWallets", "Txn.UpdateWallet", "Txn.SaveTransaction", "Txn.Commit"} {
+		span, ok := byName[name]
+		require.True(t, ok, name)
+		assert.Equal(t, txn.SpanContext.SpanID(), span.Parent.SpanID(), name)
+	}
+	assert.Equal(t, wallet.ID, spanAttributes(byName["Txn.UpdateWallet"])[AttributeWalletID].AsString())
+
+	// The deferred rollback after the commit is not traced
+	_, ok = byName["Txn.Rollback"]
+	assert.False(t, ok)
+}
+
+// TestTracingWalletManagerError tests that failed operations are recorded on their spans
+func TestTracingWalletManagerError(t *testing.T) {
+	manager, exporter := setupTestTracing(t)
+	ctx := context.Background()
+
+	wallet, err := manager.CreateWallet(ctx, "user-1", "Wallet", "", "")
+	require.NoError(t, err)
+	exporter.Reset()
+
+	_, err = manager.Debit(ctx, wallet.ID, 100, "Purchase", "", "", nil)
+	assert.ErrorIs(t, err, ErrInsufficientBalance)
+
+	byName := make(map[string]tracetest.SpanStub)
+	for _, span := range exporter.GetSpans() {
+		byName[span.Name] = span
+	}
+
+	debit := byName["WalletManager.Debit"]
+	assert.Equal(t, codes.Error, debit.Status.Code)
+	assert.Equal(t, "insufficient_balance", spanAttributes(debit)[AttributeOutcome].AsString())
+	require.Len(t, debit.Events, 1)
+	assert.Equal(t, "exception", debit.Events[0].Name)
+
+	// Failed operations roll the transaction back
+	_, ok := byName["Txn.Rollback"]
+	assert.True(t, ok)
+	_, ok = byName["Txn.Commit"]
+	assert.False(t, ok)
+
+	// Non-transactional store calls are traced too
+	exporter.Reset()
+	_, err = manager.GetWallet(ctx, wallet.ID)
+	require.NoError(t, err)
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 2)
+	assert.Equal(t, "WalletStore.FindWallet", spans[0].Name)
+	assert.Equal(t, "WalletManager.GetWallet", spans[1].Name)
+	assert.Equal(t, spans[1].SpanContext.SpanID(), spans[0].Parent.SpanID())
+}
@@ -0,0 +1,121 @@
+package wallethub
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// unitOfWork is the state shared by the wallet operations of one WithinTx call and those nested in it
+type unitOfWork struct {
+	savepoints int
+	attempts   []riskAttempt
+}
+
+// riskAttempt is a credit or debit attempt awaiting evaluation by the risk rules
+type riskAttempt struct {
+	walletID        string
+	transactionType TransactionType
+	amount          int64
+	err             error
+}
+
+// attempt records a credit or debit attempt for evaluation once the store transaction is closed
+func (u *unitOfWork) attempt(walletID string, transactionType TransactionType, amount int64, err error) {
+	u.attempts = append(u.attempts, riskAttempt{walletID, transactionType, amount, err})
+}
+
+// walletOps implements WalletOps on an open store transaction
+type walletOps struct {
+	m    *DefaultWalletManager
+	ctx  context.Context
+	txn  Txn
+	unit *unitOfWork
+}
+
+// WithinTx runs fn with wallet operations bound to one store transaction, so they succeed or fail
+// together. The transaction is committed if fn returns nil and rolled back otherwise. The operations
+// must not be used after fn returns. Risk rules are evaluated once the transaction is closed.
+//
+// Under a retry policy, fn is run again in a new transaction after retryable database errors, so it
+// must not have effects outside the operations.
+func (m *DefaultWalletManager) WithinTx(ctx context.Context, fn func(ops WalletOps) error) error {
+	return m.withinTx(ctx, func(o *walletOps) error {
+		return fn(o)
+	})
+}
+
+// withinTx implements WithinTx, giving fn access to the operations that take explicit transaction IDs
+func (m *DefaultWalletManager) withinTx(ctx context.Context, fn func(o *walletOps) error) error {
+	var unit *unitOfWork
+	defer func() {
+		// Only the attempts of the last run are evaluated
+		for _, attempt := range unit.attempts {
+			m.evaluateRiskRules(ctx, attempt.walletID, attempt.transactionType, attempt.amount, attempt.err)
+		}
+	}()
+
+	return m.retry(ctx, func() error {
+		unit = &unitOfWork{}
+
+		// Start a transaction
+		txn := m.store.Begin(ctx)
+		defer txn.Rollback()
+
+		if err := fn(&walletOps{m: m, ctx: ctx, txn: txn, unit: unit}); err != nil {
+			return err
+		}
+
+		// Commit the transaction
+		return txn.Commit()
+	})
+}
+
+// WithinTx runs fn as a nested unit of work. If fn returns an error, the changes it made are rolled
+// back to a savepoint and the error is returned, leaving the enclosing unit of work free to continue.
+func (o *walletOps) WithinTx(fn func(ops WalletOps) error) error {
+	o.unit.savepoints++
+	savepoint := fmt.Sprintf("wallethub_%d", o.unit.savepoints)
+	if err := o.txn.SavePoint(savepoint); err != nil {
+		return newWalletError(err, "")
+	}
+
+	if err := fn(o); err != nil {
+		if rollbackErr := o.txn.RollbackTo(savepoint); rollbackErr != nil {
+			return errors.Join(err, newWalletError(rollbackErr, ""))
+		}
+		return err
+	}
+
+	return nil
+}
+
+// GetWallet gets a wallet by ID
+func (o *walletOps) GetWallet(walletID string) (*Wallet, error) {
+	wallet, err := o.txn.FindWallet(walletID)
+	if err != nil {
+		return nil, newWalletError(err, walletID)
+	}
+	return wallet, nil
+}
+
+// GetTransaction gets a transaction by ID
+func (o *walletOps) GetTransaction(transactionID string) (*Transaction, error) {
+	transaction, err := o.txn.FindTransaction(transactionID)
+	if err != nil {
+		return nil, newTransactionError(err, transactionID, "")
+	}
+	return transaction, nil
+}
+
+// updateWallet applies a change to a wallet and saves it. The wallet is locked while it is changed,
+// as every column is written back and a concurrent balance change would otherwise be lost.
+func (o *walletOps) updateWallet(walletID string, update func(wallet *Wallet)) error {
+	wallet, err := lockWallet(o.txn, walletID)
+	if err != nil {
+		return err
+	}
+
+	update(wallet)
+	return o.txn.UpdateWallet(wallet)
+}
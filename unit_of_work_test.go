@@ -0,0 +1,131 @@
+package wallethub
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestWithinTx tests that the operations of a unit of work are committed together
+func TestWithinTx(t *testing.T) {
+	manager := NewWalletManager(WithStore(setupTestGormWalletStore(t)))
+	ctx := context.Background()
+
+	first, err := manager.CreateWallet(ctx, "user-1", "Main", "", "main")
+	require.NoError(t, err)
+
+	var wallet *Wallet
+	err = manager.WithinTx(ctx, func(ops WalletOps) error {
+		var err error
+		wallet, err = ops.CreateWallet("user-1", "Bonus", "", "bonus")
+		if err != nil {
+			return err
+		}
+		if _, err := ops.Credit(wallet.ID, 100, "Welcome bonus", "", "", nil); err != nil {
+			return err
+		}
+		if err := ops.SetPrimaryWallet(wallet.ID); err != nil {
+			return err
+		}
+
+		// Reads see the changes of the unit of work
+		current, err := ops.GetWallet(wallet.ID)
+		require.NoError(t, err)
+		assert.Equal(t, int64(100), current.Balance)
+		assert.True(t, current.Primary)
+		return nil
+	})
+	require.NoError(t, err)
+
+	primary, err := manager.GetPrimaryWallet(ctx, "user-1")
+	require.NoError(t, err)
+	assert.Equal(t, wallet.ID, primary.ID)
+	assert.Equal(t, int64(100), primary.Balance)
+
+	first, err = manager.GetWallet(ctx, first.ID)
+	require.NoError(t, err)
+	assert.False(t, first.Primary)
+}
+
+// TestWithinTxRollback tests that a failing unit of work leaves no changes behind
+func TestWithinTxRollback(t *testing.T) {
+	manager := NewWalletManager(WithStore(setupTestGormWalletStore(t)))
+	ctx := context.Background()
+
+	var walletID string
+	err := manager.WithinTx(ctx, func(ops WalletOps) error {
+		wallet, err := ops.CreateWallet("user-1", "Wallet", "", "")
+		if err != nil {
+			return err
+		}
+		walletID = wallet.ID
+		if _, err := ops.Credit(wallet.ID, 100, "Welcome bonus", "", "", nil); err != nil {
+			return err
+		}
+		_, err = ops.Debit(wallet.ID, 500, "Purchase", "", "", nil)
+		return err
+	})
+	assert.ErrorIs(t, err, ErrInsufficientBalance)
+
+	wallet, err := manager.GetWallet(ctx, walletID)
+	require.NoError(t, err)
+	assert.Nil(t, wallet)
+
+	// Errors returned by the function are returned unchanged
+	errAbort := errors.New("abort")
+	err = manager.WithinTx(ctx, func(ops WalletOps) error {
+		return errAbort
+	})
+	assert.Equal(t, errAbort, err)
+}
+
+// TestWithinTxNested tests that a failing nested unit of work is rolled back to its savepoint
+func TestWithinTxNested(t *testing.T) {
+	manager := NewWalletManager(WithStore(setupTestGormWalletStore(t)))
+	ctx := context.Background()
+
+	wallet1, err := manager.CreateWallet(ctx, "user-1", "Wallet 1", "", "")
+	require.NoError(t, err)
+	wallet2, err := manager.CreateWallet(ctx, "user-2", "Wallet 2", "", "")
+	require.NoError(t, err)
+
+	err = manager.WithinTx(ctx, func(ops WalletOps) error {
+		if _, err := ops.Credit(wallet1.ID, 1000, "Deposit", "", "", nil); err != nil {
+			return err
+		}
+
+		// The changes of the failed nested unit of work are undone while the deposit stays
+		err := ops.WithinTx(func(ops WalletOps) error {
+			if _, err := ops.Debit(wallet1.ID, 300, "Fee", "", "", nil); err != nil {
+				return err
+			}
+			if err := ops.FreezeWallet(wallet2.ID, "Investigation"); err != nil {
+				return err
+			}
+			return ops.Transfer(wallet1.ID, wallet2.ID, 300, "Transfer", "", nil)
+		})
+		assert.ErrorIs(t, err, ErrWalletFrozen)
+
+		// A successful nested unit of work is kept
+		return ops.WithinTx(func(ops WalletOps) error {
+			return ops.Transfer(wallet1.ID, wallet2.ID, 200, "Transfer", "", nil)
+		})
+	})
+	require.NoError(t, err)
+
+	wallet1, err = manager.GetWallet(ctx, wallet1.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(800), wallet1.Balance)
+
+	wallet2, err = manager.GetWallet(ctx, wallet2.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(200), wallet2.Balance)
+	assert.False(t, wallet2.Frozen)
+
+	transactions, err := manager.ListTransactions(ctx, wallet1.ID, 10, 0)
+	require.NoError(t, err)
+	assert.Len(t, transactions, 2)
+}
@@ -0,0 +1,73 @@
+package wallethub
+
+import (
+	"context"
+	"hash/fnv"
+	"slices"
+)
+
+// walletLocks serializes operations per wallet within the process. Wallets are hashed onto a fixed
+// number of shards, each a lock that waiting operations queue on.
+type walletLocks struct {
+	shards []chan struct{}
+}
+
+// WithWalletLocks serializes credits, debits and transfers per wallet within the process before they
+// reach the store, so operations on a busy wallet queue in memory instead of contending on its row.
+// Wallets are spread over the given number of shards; wallets sharing a shard are serialized together.
+func WithWalletLocks(shards int) Option {
+	return func(m *DefaultWalletManager) {
+		if shards <= 0 {
+			m.locks = nil
+			return
+		}
+
+		locks := &walletLocks{shards: make([]chan struct{}, shards)}
+		for i := range locks.shards {
+			locks.shards[i] = make(chan struct{}, 1)
+		}
+		m.locks = locks
+	}
+}
+
+// shard returns the index of the shard of a wallet of the context's tenant
+func (l *walletLocks) shard(ctx context.Context, walletID string) int {
+	hash := fnv.New32a()
+	hash.Write([]byte(TenantFromContext(ctx)))
+	hash.Write([]byte{0})
+	hash.Write([]byte(walletID))
+	return int(hash.Sum32() % uint32(len(l.shards)))
+}
+
+// lockWallets locks the shards of the given wallets in ascending shard order, so operations locking
+// several wallets cannot deadlock. It waits until the locks are acquired or the context ends, and
+// returns the function releasing them. Without wallet locks, nothing is locked.
+func (m *DefaultWalletManager) lockWallets(ctx context.Context, walletIDs ...string) (func(), error) {
+	if m.locks == nil {
+		return func() {}, nil
+	}
+
+	shards := make([]int, 0, len(walletIDs))
+	for _, walletID := range walletIDs {
+		shards = append(shards, m.locks.shard(ctx, walletID))
+	}
+	slices.Sort(shards)
+	shards = slices.Compact(shards)
+
+	unlock := func(locked []int) {
+		for i := len(locked) - 1; i >= 0; i-- {
+			<-m.locks.shards[locked[i]]
+		}
+	}
+
+	for i, shard := range shards {
+		select {
+		case m.locks.shards[shard] <- struct{}{}:
+		case <-ctx.Done():
+			unlock(shards[:i])
+			return nil, ctx.Err()
+		}
+	}
+
+	return func() { unlock(shards) }, nil
+}
@@ -0,0 +1,105 @@
+package wallethub
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestWalletLocks tests that operations on the same wallet are serialized within the process
+func TestWalletLocks(t *testing.T) {
+	manager := NewWalletManager(WithWalletLocks(16))
+	ctx := context.Background()
+
+	unlock, err := manager.lockWallets(ctx, "wallet-1")
+	require.NoError(t, err)
+
+	// A second lock on the wallet waits until the first is released
+	locked := make(chan struct{})
+	go func() {
+		unlock, err := manager.lockWallets(ctx, "wallet-1")
+		if assert.NoError(t, err) {
+			unlock()
+		}
+		close(locked)
+	}()
+
+	select {
+	case <-locked:
+		t.Fatal("wallet locked twice")
+	case <-time.After(20 * time.Millisecond):
+	}
+	unlock()
+	<-locked
+
+	// Locking the same wallet twice in one call does not deadlock
+	unlock, err = manager.lockWallets(ctx, "wallet-1", "wallet-1")
+	require.NoError(t, err)
+	unlock()
+
+	// Copies of the manager share its locks
+	approving := manager.withRiskApproval()
+	assert.Same(t, manager.locks, approving.locks)
+}
+
+// TestWalletLocksContext tests that waiting for a lock ends with the context
+func TestWalletLocksContext(t *testing.T) {
+	manager := NewWalletManager(WithWalletLocks(1))
+
+	unlock, err := manager.lockWallets(context.Background(), "wallet-1")
+	require.NoError(t, err)
+	defer unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	_, err = manager.lockWallets(ctx, "wallet-2")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// Without wallet locks, nothing is locked
+	manager = NewWalletManager(WithWalletLocks(0))
+	unlock, err = manager.lockWallets(ctx, "wallet-1")
+	require.NoError(t, err)
+	unlock()
+}
+
+// TestWalletLocksTransfers tests that opposite transfers between the same wallets do not deadlock
+func TestWalletLocksTransfers(t *testing.T) {
+	manager := NewWalletManager(WithStore(setupTestGormWalletStore(t)), WithWalletLocks(4))
+	ctx := context.Background()
+
+	wallet1, err := manager.CreateWallet(ctx, "user-1", "Wallet 1", "", "")
+	require.NoError(t, err)
+	wallet2, err := manager.CreateWallet(ctx, "user-2", "Wallet 2", "", "")
+	require.NoError(t, err)
+	_, err = manager.Credit(ctx, wallet1.ID, 1000, "Deposit", "", "", nil)
+	require.NoError(t, err)
+	_, err = manager.Credit(ctx, wallet2.ID, 1000, "Deposit", "", "", nil)
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, manager.Transfer(ctx, wallet1.ID, wallet2.ID, 1, "Transfer", "", nil))
+		}()
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, manager.Transfer(ctx, wallet2.ID, wallet1.ID, 1, "Transfer", "", nil))
+		}()
+	}
+	wg.Wait()
+
+	wallet1, err = manager.GetWallet(ctx, wallet1.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1000), wallet1.Balance)
+
+	wallet2, err = manager.GetWallet(ctx, wallet2.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1000), wallet2.Balance)
+}
@@ -3,7 +3,8 @@ package wallethub
 import (
 	"context"
 	"errors"
-	"time"
+	"log/slog"
+	"slices"
 
 	"github.com/google/uuid"
 )
@@ -21,7 +22,17 @@ var (
 
 // DefaultWalletManager implements the WalletManager interface
 type DefaultWalletManager struct {
-	store WalletStore
+	store         WalletStore
+	snapshotStore SnapshotStore
+	bulkChunkSize int
+	riskPolicy    RiskPolicy
+	riskRules     []RiskRule
+	log           walletLogger
+	clock         Clock
+	ids           IDGenerator
+	retryPolicy   RetryPolicy
+	locks         *walletLocks
+	bucketPolicy  BucketPolicy
 }
 
 // Option defines a functional option pattern for configuring the wallet manager
@@ -36,7 +47,11 @@ func WithStore(store WalletStore) Option {
 
 // NewWalletManager creates a new instance of WalletManager with provided options
 func NewWalletManager(options ...Option) *DefaultWalletManager {
-	manager := &DefaultWalletManager{}
+	manager := &DefaultWalletManager{
+		log:   newWalletLogger(),
+		clock: SystemClock,
+		ids:   UUIDv4Generator,
+	}
 
 	for _, option := range options {
 		option(manager)
@@ -46,9 +61,30 @@ func NewWalletManager(options ...Option) *DefaultWalletManager {
 }
 
 // CreateWallet creates a new wallet for a user
-func (m *DefaultWalletManager) CreateWallet(ctx context.Context, userID string, name string, description string, reference string) (*Wallet, error) {
+func (m *DefaultWalletManager) CreateWallet(ctx context.Context, userID string, name string, description string, reference string) (wallet *Wallet, err error) {
+	err = m.WithinTx(ctx, func(ops WalletOps) error {
+		wallet, err = ops.CreateWallet(userID, name, description, reference)
+		return err
+	})
+	if err != nil {
+		return nil, newWalletError(err, "")
+	}
+	return wallet, nil
+}
+
+// CreateWallet creates a new wallet for a user
+func (o *walletOps) CreateWallet(userID string, name string, description string, reference string) (wallet *Wallet, err error) {
+	defer func() {
+		err = newWalletError(err, "")
+		attrs := []slog.Attr{slog.String("user_id", userID), slog.String("reference", reference)}
+		if wallet != nil {
+			attrs = append(attrs, slog.String("wallet_id", wallet.ID))
+		}
+		o.m.log.operation(o.ctx, "create_wallet", err, attrs...)
+	}()
+
 	// Check if a wallet with the same reference already exists
-	existingWallet, err := m.store.FindWalletByUserIDAndReference(ctx, userID, reference)
+	existingWallet, err := o.txn.FindWalletByUserIDAndReference(userID, reference)
 	if err != nil {
 		return nil, err
 	}
@@ -56,12 +92,8 @@ func (m *DefaultWalletManager) CreateWallet(ctx context.Context, userID string,
 		return existingWallet, nil
 	}
 
-	// Start a transaction
-	txn := m.store.Begin(ctx)
-	defer txn.Rollback()
-
 	// Check if this is the first wallet for the user (to set as primary)
-	wallets, err := txn.FindWalletsByUserID(userID)
+	wallets, err := o.txn.FindWalletsByUserID(userID)
 	if err != nil {
 		return nil, err
 	}
@@ -69,9 +101,9 @@ func (m *DefaultWalletManager) CreateWallet(ctx context.Context, userID string,
 	isPrimary := len(wallets) == 0
 
 	// Create the new wallet
-	now := time.Now()
-	wallet := &Wallet{
-		ID:          GenerateID(), // Assuming a helper function exists
+	now := o.m.clock.Now()
+	wallet = &Wallet{
+		ID:          o.m.ids.NewID(),
 		UserID:      userID,
 		Name:        name,
 		Description: description,
@@ -86,12 +118,7 @@ func (m *DefaultWalletManager) CreateWallet(ctx context.Context, userID string,
 	}
 
 	// Save the wallet
-	if err := txn.SaveWallet(wallet); err != nil {
-		return nil, err
-	}
-
-	// Commit the transaction
-	if err := txn.Commit(); err != nil {
+	if err := o.txn.SaveWallet(wallet); err != nil {
 		return nil, err
 	}
 
@@ -100,32 +127,57 @@ func (m *DefaultWalletManager) CreateWallet(ctx context.Context, userID string,
 
 // GetWallet gets a wallet by ID
 func (m *DefaultWalletManager) GetWallet(ctx context.Context, walletID string) (*Wallet, error) {
-	return m.store.FindWallet(ctx, walletID)
+	wallet, err := m.store.FindWallet(ctx, walletID)
+	if err != nil {
+		return nil, newWalletError(err, walletID)
+	}
+	return wallet, nil
 }
 
 // GetWalletsByUserID gets all wallets for a user
 func (m *DefaultWalletManager) GetWalletsByUserID(ctx context.Context, userID string) ([]Wallet, error) {
-	return m.store.FindWalletsByUserID(ctx, userID)
+	wallets, err := m.store.FindWalletsByUserID(ctx, userID)
+	if err != nil {
+		return nil, newWalletError(err, "")
+	}
+	return wallets, nil
 }
 
 // GetWalletByUserIDAndReference gets a wallet by user ID and reference
 func (m *DefaultWalletManager) GetWalletByUserIDAndReference(ctx context.Context, userID string, reference string) (*Wallet, error) {
-	return m.store.FindWalletByUserIDAndReference(ctx, userID, reference)
+	wallet, err := m.store.FindWalletByUserIDAndReference(ctx, userID, reference)
+	if err != nil {
+		return nil, newWalletError(err, "")
+	}
+	return wallet, nil
 }
 
 // GetPrimaryWallet gets the primary wallet for a user
 func (m *DefaultWalletManager) GetPrimaryWallet(ctx context.Context, userID string) (*Wallet, error) {
-	return m.store.FindPrimaryWalletByUserID(ctx, userID)
+	wallet, err := m.store.FindPrimaryWalletByUserID(ctx, userID)
+	if err != nil {
+		return nil, newWalletError(err, "")
+	}
+	return wallet, nil
 }
 
 // SetPrimaryWallet sets a wallet as the primary wallet for its user
 func (m *DefaultWalletManager) SetPrimaryWallet(ctx context.Context, walletID string) error {
-	// Start a transaction
-	txn := m.store.Begin(ctx)
-	defer txn.Rollback()
+	err := m.WithinTx(ctx, func(ops WalletOps) error {
+		return ops.SetPrimaryWallet(walletID)
+	})
+	return newWalletError(err, walletID)
+}
+
+// SetPrimaryWallet sets a wallet as the primary wallet for its user
+func (o *walletOps) SetPrimaryWallet(walletID string) (err error) {
+	defer func() {
+		err = newWalletError(err, walletID)
+		o.m.log.operation(o.ctx, "set_primary_wallet", err, slog.String("wallet_id", walletID))
+	}()
 
 	// Get the wallet to be set as primary
-	wallet, err := txn.FindWallet(walletID)
+	wallet, err := o.txn.FindWallet(walletID)
 	if err != nil {
 		return err
 	}
@@ -134,133 +186,159 @@ func (m *DefaultWalletManager) SetPrimaryWallet(ctx context.Context, walletID st
 	}
 
 	// Get the current primary wallet
-	currentPrimary, err := txn.FindPrimaryWalletByUserID(wallet.UserID)
+	currentPrimary, err := o.txn.FindPrimaryWalletByUserID(wallet.UserID)
 	if err != nil {
 		return err
 	}
-
-	// Update the current primary wallet (if exists)
+	walletIDs := []string{walletID}
 	if currentPrimary != nil && currentPrimary.ID != walletID {
-		currentPrimary.Primary = false
-		if err := txn.UpdateWallet(currentPrimary); err != nil {
-			return err
-		}
+		walletIDs = append(walletIDs, currentPrimary.ID)
 	}
 
-	// Set the new wallet as primary
-	wallet.Primary = true
-	if err := txn.UpdateWallet(wallet); err != nil {
+	// Lock the wallets in ascending ID order and update the locked rows, so concurrent balance changes are kept
+	slices.Sort(walletIDs)
+	locked, err := o.txn.LockWallets(walletIDs)
+	if err != nil {
 		return err
 	}
-
-	// Commit the transaction
-	return txn.Commit()
+	if len(locked) != len(walletIDs) {
+		return ErrWalletNotFound
+	}
+	for i := range locked {
+		// Set the new wallet as primary and unset the current primary wallet (if exists)
+		locked[i].Primary = locked[i].ID == walletID
+		if err := o.txn.UpdateWallet(&locked[i]); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // UpdateWalletActive updates the active status of a wallet
 func (m *DefaultWalletManager) UpdateWalletActive(ctx context.Context, walletID string, active bool) error {
-	// Get the wallet
-	wallet, err := m.store.FindWallet(ctx, walletID)
-	if err != nil {
-		return err
-	}
-	if wallet == nil {
-		return errors.New("wallet not found")
-	}
+	err := m.WithinTx(ctx, func(ops WalletOps) error {
+		return ops.UpdateWalletActive(walletID, active)
+	})
+	return newWalletError(err, walletID)
+}
 
-	// Update the active status
-	wallet.Active = active
-	return m.store.UpdateWallet(ctx, wallet)
+// UpdateWalletActive updates the active status of a wallet
+func (o *walletOps) UpdateWalletActive(walletID string, active bool) (err error) {
+	defer func() {
+		err = newWalletError(err, walletID)
+		o.m.log.operation(o.ctx, "update_wallet_active", err, slog.String("wallet_id", walletID), slog.Bool("active", active))
+	}()
+
+	return o.updateWallet(walletID, func(wallet *Wallet) {
+		wallet.Active = active
+	})
 }
 
 // UpdateWalletName updates the name of a wallet
 func (m *DefaultWalletManager) UpdateWalletName(ctx context.Context, walletID string, name string) error {
-	// Get the wallet
-	wallet, err := m.store.FindWallet(ctx, walletID)
-	if err != nil {
-		return err
-	}
-	if wallet == nil {
-		return errors.New("wallet not found")
-	}
+	err := m.WithinTx(ctx, func(ops WalletOps) error {
+		return ops.UpdateWalletName(walletID, name)
+	})
+	return newWalletError(err, walletID)
+}
 
-	// Update the name
-	wallet.Name = name
-	return m.store.UpdateWallet(ctx, wallet)
+// UpdateWalletName updates the name of a wallet
+func (o *walletOps) UpdateWalletName(walletID string, name string) (err error) {
+	defer func() {
+		err = newWalletError(err, walletID)
+		o.m.log.operation(o.ctx, "update_wallet_name", err, slog.String("wallet_id", walletID))
+	}()
+
+	return o.updateWallet(walletID, func(wallet *Wallet) {
+		wallet.Name = name
+	})
 }
 
 // UpdateWalletDescription updates the description of a wallet
 func (m *DefaultWalletManager) UpdateWalletDescription(ctx context.Context, walletID string, description string) error {
-	// Get the wallet
-	wallet, err := m.store.FindWallet(ctx, walletID)
-	if err != nil {
-		return err
-	}
-	if wallet == nil {
-		return errors.New("wallet not found")
-	}
+	err := m.WithinTx(ctx, func(ops WalletOps) error {
+		return ops.UpdateWalletDescription(walletID, description)
+	})
+	return newWalletError(err, walletID)
+}
 
-	// Update the description
-	wallet.Description = description
-	return m.store.UpdateWallet(ctx, wallet)
+// UpdateWalletDescription updates the description of a wallet
+func (o *walletOps) UpdateWalletDescription(walletID string, description string) (err error) {
+	defer func() {
+		err = newWalletError(err, walletID)
+		o.m.log.operation(o.ctx, "update_wallet_description", err, slog.String("wallet_id", walletID))
+	}()
+
+	return o.updateWallet(walletID, func(wallet *Wallet) {
+		wallet.Description = description
+	})
 }
 
 // UpdateWalletReference updates the reference of a wallet
 func (m *DefaultWalletManager) UpdateWalletReference(ctx context.Context, walletID string, reference string) error {
-	// Get the wallet
-	wallet, err := m.store.FindWallet(ctx, walletID)
-	if err != nil {
-		return err
-	}
-	if wallet == nil {
-		return errors.New("wallet not found")
-	}
+	err := m.WithinTx(ctx, func(ops WalletOps) error {
+		return ops.UpdateWalletReference(walletID, reference)
+	})
+	return newWalletError(err, walletID)
+}
 
-	// Update the reference
-	wallet.Reference = reference
-	return m.store.UpdateWallet(ctx, wallet)
+// UpdateWalletReference updates the reference of a wallet
+func (o *walletOps) UpdateWalletReference(walletID string, reference string) (err error) {
+	defer func() {
+		err = newWalletError(err, walletID)
+		o.m.log.operation(o.ctx, "update_wallet_reference", err, slog.String("wallet_id", walletID), slog.String("reference", reference))
+	}()
+
+	return o.updateWallet(walletID, func(wallet *Wallet) {
+		wallet.Reference = reference
+	})
 }
 
-// Credit adds points to a wallet
+// Credit adds points to the default bucket of a wallet
 func (m *DefaultWalletManager) Credit(ctx context.Context, walletID string, amount int64, description string, note string, reference string, data map[string]interface{}) (*Transaction, error) {
+	return m.CreditBucket(ctx, walletID, DefaultBucket, amount, description, note, reference, data)
+}
+
+// Credit adds points to the default bucket of a wallet
+func (o *walletOps) Credit(walletID string, amount int64, description string, note string, reference string, data map[string]interface{}) (*Transaction, error) {
+	return o.CreditBucket(walletID, DefaultBucket, amount, description, note, reference, data)
+}
+
+// creditTxn adds points to a bucket of a wallet within an open store transaction
+func (m *DefaultWalletManager) creditTxn(txn Txn, transactionID string, walletID string, bucket string, amount int64, description string, note string, reference string, data map[string]interface{}) (transaction *Transaction, err error) {
+	defer func() { err = newWalletError(err, walletID) }()
+
 	if amount <= 0 {
 		return nil, ErrInvalidAmount
 	}
 
-	// Start a transaction
-	txn := m.store.Begin(ctx)
-	defer txn.Rollback()
-
-	// Get the wallet
-	wallet, err := txn.FindWallet(walletID)
+	// Lock the wallet, so concurrent balance changes wait for this one
+	wallet, err := lockWallet(txn, walletID)
 	if err != nil {
 		return nil, err
 	}
-	if wallet == nil {
-		return nil, ErrWalletNotFound
-	}
 	if !wallet.Active {
 		return nil, ErrWalletInactive
 	}
-	if wallet.Frozen {
+	if wallet.CreditBlocked() {
 		return nil, ErrWalletFrozen
 	}
 
 	// Update wallet balance
-	newBalance := wallet.Balance + amount
-	wallet.Balance = newBalance
+	buckets := wallet.deposit(bucket, amount)
 	if err := txn.UpdateWallet(wallet); err != nil {
 		return nil, err
 	}
 
 	// Create the transaction
-	now := time.Now()
-	transaction := &Transaction{
-		ID:          GenerateID(), // Assuming a helper function exists
+	now := m.clock.Now()
+	transaction = &Transaction{
+		ID:          transactionID,
 		WalletID:    walletID,
 		Type:        TransactionTypeCredit,
 		Amount:      amount,
-		Balance:     newBalance,
+		Balance:     wallet.Balance,
+		Buckets:     buckets,
 		Description: description,
 		Note:        note,
 		Reference:   reference,
@@ -275,57 +353,108 @@ func (m *DefaultWalletManager) Credit(ctx context.Context, walletID string, amou
 		return nil, err
 	}
 
-	// Commit the transaction
-	if err := txn.Commit(); err != nil {
-		return nil, err
+	return transaction, nil
+}
+
+// Debit removes points from a wallet
+func (m *DefaultWalletManager) Debit(ctx context.Context, walletID string, amount int64, description string, note string, reference string, data map[string]interface{}) (transaction *Transaction, err error) {
+	unlock, err := m.lockWallets(ctx, walletID)
+	if err != nil {
+		return nil, newWalletError(err, walletID)
 	}
+	defer unlock()
 
+	err = m.WithinTx(ctx, func(ops WalletOps) error {
+		transaction, err = ops.Debit(walletID, amount, description, note, reference, data)
+		return err
+	})
+	if err != nil {
+		return nil, newWalletError(err, walletID)
+	}
 	return transaction, nil
 }
 
 // Debit removes points from a wallet
-func (m *DefaultWalletManager) Debit(ctx context.Context, walletID string, amount int64, description string, note string, reference string, data map[string]interface{}) (*Transaction, error) {
+func (o *walletOps) Debit(walletID string, amount int64, description string, note string, reference string, data map[string]interface{}) (*Transaction, error) {
+	return o.debit(o.m.ids.NewID(), walletID, amount, description, note, reference, data)
+}
+
+// debit removes points from a wallet, recording a transaction with the given ID
+func (o *walletOps) debit(transactionID string, walletID string, amount int64, description string, note string, reference string, data map[string]interface{}) (transaction *Transaction, err error) {
+	defer func() {
+		err = newWalletError(err, walletID)
+		attrs := []slog.Attr{
+			slog.String("wallet_id", walletID),
+			slog.Int64("amount", amount),
+			slog.String("reference", reference),
+			o.m.log.note(note),
+			o.m.log.data(data),
+		}
+		if transaction != nil {
+			attrs = append(attrs, slog.String("transaction_id", transaction.ID))
+		}
+		o.m.log.operation(o.ctx, "debit", err, attrs...)
+	}()
+
 	if amount <= 0 {
 		return nil, ErrInvalidAmount
 	}
 
-	// Start a transaction
-	txn := m.store.Begin(ctx)
-	defer txn.Rollback()
+	// Evaluate the risk rules once the store transaction is closed
+	defer func() { o.unit.attempt(walletID, TransactionTypeDebit, amount, err) }()
+
+	return o.m.debitTxn(o.txn, transactionID, walletID, "", amount, description, note, reference, data)
+}
+
+// debitTxn removes points from a wallet within an open store transaction. Debits by a delegate spend
+// its allowance on the wallet.
+func (m *DefaultWalletManager) debitTxn(txn Txn, transactionID string, walletID string, delegateID string, amount int64, description string, note string, reference string, data map[string]interface{}) (transaction *Transaction, err error) {
+	defer func() { err = newWalletError(err, walletID) }()
 
-	// Get the wallet
-	wallet, err := txn.FindWallet(walletID)
+	if amount <= 0 {
+		return nil, ErrInvalidAmount
+	}
+
+	// Lock the wallet, so concurrent balance changes wait for this one
+	wallet, err := lockWallet(txn, walletID)
 	if err != nil {
 		return nil, err
 	}
-	if wallet == nil {
-		return nil, errors.New("wallet not found")
-	}
 	if !wallet.Active {
-		return nil, errors.New("wallet is not active")
+		return nil, ErrWalletInactive
 	}
-	if wallet.Frozen {
-		return nil, errors.New("wallet is frozen")
+	if wallet.DebitBlocked() {
+		return nil, ErrWalletFrozen
+	}
+	if err := m.checkOutgoingRisk(txn, wallet, amount); err != nil {
+		return nil, err
 	}
-	if wallet.Balance < amount {
+	if wallet.AvailableBalance() < amount {
 		return nil, ErrInsufficientBalance
 	}
+	if err := m.spendAllowance(txn, walletID, delegateID, amount); err != nil {
+		return nil, err
+	}
 
-	// Update wallet balance
-	newBalance := wallet.Balance - amount
-	wallet.Balance = newBalance
+	// Update wallet balance, spending its buckets by priority
+	buckets, err := wallet.withdraw(m.spendOrder(wallet, false), amount)
+	if err != nil {
+		return nil, err
+	}
 	if err := txn.UpdateWallet(wallet); err != nil {
 		return nil, err
 	}
 
 	// Create the transaction
-	now := time.Now()
-	transaction := &Transaction{
-		ID:          GenerateID(), // Assuming a helper function exists
+	now := m.clock.Now()
+	transaction = &Transaction{
+		ID:          transactionID,
 		WalletID:    walletID,
 		Type:        TransactionTypeDebit,
 		Amount:      amount,
-		Balance:     newBalance,
+		Balance:     wallet.Balance,
+		Buckets:     buckets,
+		DelegateID:  delegateID,
 		Description: description,
 		Note:        note,
 		Reference:   reference,
@@ -340,95 +469,165 @@ func (m *DefaultWalletManager) Debit(ctx context.Context, walletID string, amoun
 		return nil, err
 	}
 
-	// Commit the transaction
-	if err := txn.Commit(); err != nil {
-		return nil, err
-	}
-
 	return transaction, nil
 }
 
 // GetTransaction gets a transaction by ID
 func (m *DefaultWalletManager) GetTransaction(ctx context.Context, transactionID string) (*Transaction, error) {
-	return m.store.FindTransaction(ctx, transactionID)
+	transaction, err := m.store.FindTransaction(ctx, transactionID)
+	if err != nil {
+		return nil, newTransactionError(err, transactionID, "")
+	}
+	return transaction, nil
 }
 
 // ListTransactions lists transactions for a wallet with pagination
 func (m *DefaultWalletManager) ListTransactions(ctx context.Context, walletID string, limit int, offset int) ([]Transaction, error) {
-	return m.store.FindTransactionsByWalletID(ctx, walletID, limit, offset)
+	transactions, err := m.store.FindTransactionsByWalletID(ctx, walletID, limit, offset)
+	if err != nil {
+		return nil, newWalletError(err, walletID)
+	}
+	return transactions, nil
 }
 
 // ListUserTransactions lists transactions for a user with pagination
 func (m *DefaultWalletManager) ListUserTransactions(ctx context.Context, userID string, limit int, offset int) ([]Transaction, error) {
-	return m.store.FindTransactionsByUserID(ctx, userID, limit, offset)
+	transactions, err := m.store.FindTransactionsByUserID(ctx, userID, limit, offset)
+	if err != nil {
+		return nil, newWalletError(err, "")
+	}
+	return transactions, nil
 }
 
 // Transfer transfers points from one wallet to another
 func (m *DefaultWalletManager) Transfer(ctx context.Context, fromWalletID string, toWalletID string, amount int64, description string, note string, data map[string]interface{}) error {
+	unlock, err := m.lockWallets(ctx, fromWalletID, toWalletID)
+	if err != nil {
+		return newWalletError(err, fromWalletID)
+	}
+	defer unlock()
+
+	err = m.WithinTx(ctx, func(ops WalletOps) error {
+		return ops.Transfer(fromWalletID, toWalletID, amount, description, note, data)
+	})
+	return newWalletError(err, fromWalletID)
+}
+
+// Transfer transfers points from one wallet to another
+func (o *walletOps) Transfer(fromWalletID string, toWalletID string, amount int64, description string, note string, data map[string]interface{}) error {
+	// Common reference for linked transactions
+	return o.transfer(o.m.ids.NewID(), o.m.ids.NewID(), o.m.ids.NewID(), fromWalletID, toWalletID, amount, description, note, data)
+}
+
+// transfer transfers points from one wallet to another, recording the debit and credit transactions
+// with the given IDs and linking them by the given reference
+func (o *walletOps) transfer(debitID string, creditID string, reference string, fromWalletID string, toWalletID string, amount int64, description string, note string, data map[string]interface{}) (err error) {
+	defer func() {
+		err = newWalletError(err, fromWalletID)
+		o.m.log.operation(o.ctx, "transfer", err,
+			slog.String("wallet_id", fromWalletID),
+			slog.String("to_wallet_id", toWalletID),
+			slog.Int64("amount", amount),
+			o.m.log.note(note),
+			o.m.log.data(data),
+		)
+	}()
+
 	if amount <= 0 {
 		return ErrInvalidAmount
 	}
 
-	// Start a transaction
-	txn := m.store.Begin(ctx)
-	defer txn.Rollback()
+	// Evaluate the risk rules once the store transaction is closed
+	defer func() {
+		o.unit.attempt(fromWalletID, TransactionTypeDebit, amount, err)
+		if err == nil {
+			o.unit.attempt(toWalletID, TransactionTypeCredit, amount, nil)
+		}
+	}()
+
+	_, _, err = o.m.transferTxn(o.txn, debitID, creditID, reference, fromWalletID, toWalletID, "", amount, description, note, data)
+	return err
+}
+
+// transferTxn transfers points from one wallet to another within an open store transaction.
+// It returns the debit transaction of the source wallet and the credit transaction of the destination wallet.
+// Transfers by a delegate spend its allowance on the source wallet.
+func (m *DefaultWalletManager) transferTxn(txn Txn, debitID string, creditID string, reference string, fromWalletID string, toWalletID string, delegateID string, amount int64, description string, note string, data map[string]interface{}) (*Transaction, *Transaction, error) {
+	if amount <= 0 {
+		return nil, nil, newWalletError(ErrInvalidAmount, fromWalletID)
+	}
 
-	// Get the source wallet
-	fromWallet, err := txn.FindWallet(fromWalletID)
+	// Lock both wallets in ascending ID order, so transfers in opposite directions cannot deadlock
+	locked, err := txn.LockWallets(slices.Sorted(slices.Values([]string{fromWalletID, toWalletID})))
 	if err != nil {
-		return err
+		return nil, nil, newWalletError(err, fromWalletID)
+	}
+	wallets := make(map[string]*Wallet, len(locked))
+	for i := range locked {
+		wallets[locked[i].ID] = &locked[i]
 	}
+
+	// Check the source wallet
+	fromWallet := wallets[fromWalletID]
 	if fromWallet == nil {
-		return errors.New("source wallet not found")
+		return nil, nil, newWalletError(ErrWalletNotFound, fromWalletID)
 	}
 	if !fromWallet.Active {
-		return ErrWalletInactive
+		return nil, nil, newWalletError(ErrWalletInactive, fromWalletID)
 	}
-	if fromWallet.Frozen {
-		return ErrWalletFrozen
+	if fromWallet.DebitBlocked() {
+		return nil, nil, newWalletError(ErrWalletFrozen, fromWalletID)
 	}
-	if fromWallet.Balance < amount {
-		return ErrInsufficientBalance
+	if err := m.checkOutgoingRisk(txn, fromWallet, amount); err != nil {
+		return nil, nil, newWalletError(err, fromWalletID)
 	}
-
-	// Get the destination wallet
-	toWallet, err := txn.FindWallet(toWalletID)
-	if err != nil {
-		return err
+	if fromWallet.AvailableBalance() < amount {
+		return nil, nil, newWalletError(ErrInsufficientBalance, fromWalletID)
 	}
+
+	// Check the destination wallet
+	toWallet := wallets[toWalletID]
 	if toWallet == nil {
-		return errors.New("destination wallet not found")
+		return nil, nil, newWalletError(ErrWalletNotFound, toWalletID)
 	}
 	if !toWallet.Active {
-		return ErrWalletInactive
+		return nil, nil, newWalletError(ErrWalletInactive, toWalletID)
 	}
-	if toWallet.Frozen {
-		return ErrWalletFrozen
+	if toWallet.CreditBlocked() {
+		return nil, nil, newWalletError(ErrWalletFrozen, toWalletID)
+	}
+	if err := m.spendAllowance(txn, fromWalletID, delegateID, amount); err != nil {
+		return nil, nil, newWalletError(err, fromWalletID)
 	}
 
-	// Update source wallet balance
-	fromWallet.Balance -= amount
+	// Update source wallet balance, leaving non-withdrawable buckets in the wallet
+	debitBuckets, err := fromWallet.withdraw(m.spendOrder(fromWallet, true), amount)
+	if err != nil {
+		return nil, nil, newWalletError(err, fromWalletID)
+	}
 	if err := txn.UpdateWallet(fromWallet); err != nil {
-		return err
+		return nil, nil, newWalletError(err, fromWalletID)
 	}
 
 	// Update destination wallet balance
-	toWallet.Balance += amount
+	creditBuckets := toWallet.deposit(DefaultBucket, amount)
 	if err := txn.UpdateWallet(toWallet); err != nil {
-		return err
+		return nil, nil, newWalletError(err, toWalletID)
 	}
 
 	// Create debit transaction for source wallet
-	now := time.Now()
+	now := m.clock.Now()
 	debitTransaction := &Transaction{
-		ID:          GenerateID(), // Assuming a helper function exists
+		ID:          debitID,
 		WalletID:    fromWalletID,
 		Type:        TransactionTypeDebit,
 		Amount:      amount,
 		Balance:     fromWallet.Balance,
+		Buckets:     debitBuckets,
+		DelegateID:  delegateID,
 		Description: description + " (Transfer to " + toWalletID + ")",
 		Note:        note,
-		Reference:   GenerateID(), // Common reference for linked transactions
+		Reference:   reference,
 		Status:      TransactionStatusCompleted,
 		Data:        data,
 		CreatedAt:   now,
@@ -436,19 +635,21 @@ func (m *DefaultWalletManager) Transfer(ctx context.Context, fromWalletID string
 	}
 
 	if err := txn.SaveTransaction(debitTransaction); err != nil {
-		return err
+		return nil, nil, newTransactionError(err, debitID, fromWalletID)
 	}
 
 	// Create credit transaction for destination wallet
 	creditTransaction := &Transaction{
-		ID:          GenerateID(), // Assuming a helper function exists
+		ID:          creditID,
 		WalletID:    toWalletID,
 		Type:        TransactionTypeCredit,
 		Amount:      amount,
 		Balance:     toWallet.Balance,
+		Buckets:     creditBuckets,
+		DelegateID:  delegateID,
 		Description: description + " (Transfer from " + fromWalletID + ")",
 		Note:        note,
-		Reference:   debitTransaction.Reference, // Same reference for linked transactions
+		Reference:   reference, // Same reference for linked transactions
 		Status:      TransactionStatusCompleted,
 		Data:        data,
 		CreatedAt:   now,
@@ -456,53 +657,70 @@ func (m *DefaultWalletManager) Transfer(ctx context.Context, fromWalletID string
 	}
 
 	if err := txn.SaveTransaction(creditTransaction); err != nil {
-		return err
+		return nil, nil, newTransactionError(err, creditID, toWalletID)
 	}
 
-	// Commit the transaction
-	return txn.Commit()
+	return debitTransaction, creditTransaction, nil
 }
 
-// FreezeWallet freezes a wallet
+// FreezeWallet freezes a wallet fully, blocking all transactions
 func (m *DefaultWalletManager) FreezeWallet(ctx context.Context, walletID string, reason string) error {
-	// Get the wallet
-	wallet, err := m.store.FindWallet(ctx, walletID)
-	if err != nil {
-		return err
-	}
-	if wallet == nil {
-		return errors.New("wallet not found")
-	}
-
-	// Update the frozen status
-	wallet.Frozen = true
-	return m.store.UpdateWallet(ctx, wallet)
+	return m.FreezeWalletWithMode(ctx, walletID, FreezeModeFull, reason)
 }
 
-// UnfreezeWallet unfreezes a wallet
+// UnfreezeWallet lifts the freeze mode of a wallet. A frozen amount stays until released with SetFrozenAmount.
 func (m *DefaultWalletManager) UnfreezeWallet(ctx context.Context, walletID string) error {
-	// Get the wallet
-	wallet, err := m.store.FindWallet(ctx, walletID)
-	if err != nil {
-		return err
-	}
-	if wallet == nil {
-		return errors.New("wallet not found")
-	}
+	err := m.WithinTx(ctx, func(ops WalletOps) error {
+		return ops.UnfreezeWallet(walletID)
+	})
+	return newWalletError(err, walletID)
+}
 
-	// Update the frozen status
-	wallet.Frozen = false
-	return m.store.UpdateWallet(ctx, wallet)
+// UnfreezeWallet lifts the freeze mode of a wallet
+func (o *walletOps) UnfreezeWallet(walletID string) (err error) {
+	defer func() {
+		err = newWalletError(err, walletID)
+		o.m.log.operation(o.ctx, "unfreeze_wallet", err, slog.String("wallet_id", walletID))
+	}()
+
+	return o.updateWallet(walletID, func(wallet *Wallet) {
+		wallet.Frozen = false
+		wallet.FreezeMode = FreezeModeNone
+		wallet.FreezeReason = ""
+	})
 }
 
 // CancelTransaction cancels a pending transaction
 func (m *DefaultWalletManager) CancelTransaction(ctx context.Context, transactionID string, reason string) error {
-	// Start a transaction
-	txn := m.store.Begin(ctx)
-	defer txn.Rollback()
+	err := m.WithinTx(ctx, func(ops WalletOps) error {
+		return ops.CancelTransaction(transactionID, reason)
+	})
+	return newTransactionError(err, transactionID, "")
+}
+
+// CancelTransaction cancels a pending transaction
+func (o *walletOps) CancelTransaction(transactionID string, reason string) (err error) {
+	var walletID string
+	defer func() {
+		err = newTransactionError(err, transactionID, walletID)
+		o.m.log.operation(o.ctx, "cancel_transaction", err, slog.String("transaction_id", transactionID), slog.String("wallet_id", walletID))
+	}()
 
 	// Get the transaction
-	transaction, err := txn.FindTransaction(transactionID)
+	transaction, err := o.txn.FindTransaction(transactionID)
+	if err != nil {
+		return err
+	}
+	if transaction == nil {
+		return ErrTransactionNotFound
+	}
+	walletID = transaction.WalletID
+
+	// Lock the wallet, then read the transaction again, so it cannot be completed while it is cancelled
+	if _, err := lockWallet(o.txn, walletID); err != nil {
+		return err
+	}
+	transaction, err = o.txn.FindTransaction(transactionID)
 	if err != nil {
 		return err
 	}
@@ -516,80 +734,94 @@ func (m *DefaultWalletManager) CancelTransaction(ctx context.Context, transactio
 	// Update the transaction status
 	transaction.Status = TransactionStatusCancelled
 	transaction.FailedReason = reason
-	if err := txn.UpdateTransaction(transaction); err != nil {
-		return err
-	}
-
-	// Commit the transaction
-	return txn.Commit()
+	return o.txn.UpdateTransaction(transaction)
 }
 
 // CompleteTransaction completes a pending transaction
 func (m *DefaultWalletManager) CompleteTransaction(ctx context.Context, transactionID string) error {
-	// Start a transaction
-	txn := m.store.Begin(ctx)
-	defer txn.Rollback()
+	err := m.WithinTx(ctx, func(ops WalletOps) error {
+		return ops.CompleteTransaction(transactionID)
+	})
+	return newTransactionError(err, transactionID, "")
+}
+
+// CompleteTransaction completes a pending transaction
+func (o *walletOps) CompleteTransaction(transactionID string) (err error) {
+	var walletID string
+	defer func() {
+		err = newTransactionError(err, transactionID, walletID)
+		o.m.log.operation(o.ctx, "complete_transaction", err, slog.String("transaction_id", transactionID), slog.String("wallet_id", walletID))
+	}()
 
 	// Get the transaction
-	transaction, err := txn.FindTransaction(transactionID)
+	transaction, err := o.txn.FindTransaction(transactionID)
 	if err != nil {
 		return err
 	}
 	if transaction == nil {
 		return ErrTransactionNotFound
 	}
-	if transaction.Status != TransactionStatusPending {
-		return ErrPendingTransactionOnly
-	}
+	walletID = transaction.WalletID
 
-	// Get the wallet
-	wallet, err := txn.FindWallet(transaction.WalletID)
+	// Lock the wallet, then read the transaction again, so it is completed only once
+	wallet, err := lockWallet(o.txn, walletID)
 	if err != nil {
 		return err
 	}
-	if wallet == nil {
-		return errors.New("wallet not found")
+	transaction, err = o.txn.FindTransaction(transactionID)
+	if err != nil {
+		return err
+	}
+	if transaction == nil {
+		return ErrTransactionNotFound
+	}
+	if transaction.Status != TransactionStatusPending {
+		return ErrPendingTransactionOnly
 	}
 
 	// Update the wallet balance based on transaction type
 	if transaction.Type == TransactionTypeCredit {
-		wallet.Balance += transaction.Amount
+		if wallet.CreditBlocked() {
+			return ErrWalletFrozen
+		}
+		transaction.Buckets = wallet.depositSplit(transaction.Buckets, transaction.Amount)
 	} else if transaction.Type == TransactionTypeDebit {
-		if wallet.Balance < transaction.Amount {
+		if wallet.DebitBlocked() {
+			return ErrWalletFrozen
+		}
+		if wallet.AvailableBalance() < transaction.Amount {
 			return ErrInsufficientBalance
 		}
-		wallet.Balance -= transaction.Amount
+		if transaction.Buckets, err = wallet.withdraw(o.m.spendOrder(wallet, false), transaction.Amount); err != nil {
+			return err
+		}
 	}
 
 	// Update the wallet
-	if err := txn.UpdateWallet(wallet); err != nil {
+	if err := o.txn.UpdateWallet(wallet); err != nil {
 		return err
 	}
 
 	// Update the transaction
 	transaction.Status = TransactionStatusCompleted
-	transaction.CompletedAt = time.Now()
+	transaction.CompletedAt = o.m.clock.Now()
 	transaction.Balance = wallet.Balance
-	if err := txn.UpdateTransaction(transaction); err != nil {
-		return err
-	}
-
-	// Commit the transaction
-	return txn.Commit()
+	return o.txn.UpdateTransaction(transaction)
 }
 
-// GetUserWalletSummary gets the total balance across all wallets for a user
+// GetUserWalletSummary gets the total balance across the active wallets of a user. Fully frozen
+// wallets are left out, while wallets frozen in one direction or for an amount still count.
 func (m *DefaultWalletManager) GetUserWalletSummary(ctx context.Context, userID string) (int64, error) {
 	// Get all wallets for the user
 	wallets, err := m.store.FindWalletsByUserID(ctx, userID)
 	if err != nil {
-		return 0, err
+		return 0, newWalletError(err, "")
 	}
 
 	// Calculate the total balance
 	var totalBalance int64 = 0
 	for _, wallet := range wallets {
-		if wallet.Active && !wallet.Frozen {
+		if wallet.Active && wallet.freezeMode() != FreezeModeFull {
 			totalBalance += wallet.Balance
 		}
 	}
@@ -599,34 +831,44 @@ func (m *DefaultWalletManager) GetUserWalletSummary(ctx context.Context, userID
 
 // FlagWalletRisk flags a wallet for risk
 func (m *DefaultWalletManager) FlagWalletRisk(ctx context.Context, walletID string, reason string) error {
-	// Get the wallet
-	wallet, err := m.store.FindWallet(ctx, walletID)
-	if err != nil {
-		return err
-	}
-	if wallet == nil {
-		return errors.New("wallet not found")
-	}
+	err := m.WithinTx(ctx, func(ops WalletOps) error {
+		return ops.FlagWalletRisk(walletID, reason)
+	})
+	return newWalletError(err, walletID)
+}
 
-	// Update the risk flag
-	wallet.RiskFlagged = true
-	return m.store.UpdateWallet(ctx, wallet)
+// FlagWalletRisk flags a wallet for risk
+func (o *walletOps) FlagWalletRisk(walletID string, reason string) (err error) {
+	defer func() {
+		err = newWalletError(err, walletID)
+		o.m.log.operation(o.ctx, "flag_wallet_risk", err, slog.String("wallet_id", walletID), slog.String("reason", reason))
+	}()
+
+	return o.updateWallet(walletID, func(wallet *Wallet) {
+		wallet.RiskFlagged = true
+		wallet.RiskReason = reason
+	})
 }
 
 // ClearWalletRiskFlag clears the risk flag from a wallet
 func (m *DefaultWalletManager) ClearWalletRiskFlag(ctx context.Context, walletID string) error {
-	// Get the wallet
-	wallet, err := m.store.FindWallet(ctx, walletID)
-	if err != nil {
-		return err
-	}
-	if wallet == nil {
-		return errors.New("wallet not found")
-	}
+	err := m.WithinTx(ctx, func(ops WalletOps) error {
+		return ops.ClearWalletRiskFlag(walletID)
+	})
+	return newWalletError(err, walletID)
+}
 
-	// Clear the risk flag
-	wallet.RiskFlagged = false
-	return m.store.UpdateWallet(ctx, wallet)
+// ClearWalletRiskFlag clears the risk flag from a wallet
+func (o *walletOps) ClearWalletRiskFlag(walletID string) (err error) {
+	defer func() {
+		err = newWalletError(err, walletID)
+		o.m.log.operation(o.ctx, "clear_wallet_risk_flag", err, slog.String("wallet_id", walletID))
+	}()
+
+	return o.updateWallet(walletID, func(wallet *Wallet) {
+		wallet.RiskFlagged = false
+		wallet.RiskReason = ""
+	})
 }
 
 // GenerateID generates a unique ID for wallets and transactions using UUID v4
@@ -2,11 +2,19 @@ package wallethub
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
 )
 
 // TestCreateWallet tests creating a new wallet
@@ -152,7 +160,7 @@ func TestSetPrimaryWallet(t *testing.T) {
 	// Test setting non-existent wallet as primary
 	err = manager.SetPrimaryWallet(ctx, "non-existent-id")
 	assert.Error(t, err)
-	assert.Equal(t, ErrWalletNotFound, err)
+	assert.ErrorIs(t, err, ErrWalletNotFound)
 }
 
 // TestWalletUpdates tests updating wallet properties
@@ -238,16 +246,16 @@ func TestCreditDebit(t *testing.T) {
 	// Test insufficient balance
 	_, err = manager.Debit(ctx, wallet.ID, 1000, "Invalid Debit", "Insufficient Funds", "invalid-ref", nil)
 	assert.Error(t, err)
-	assert.Equal(t, ErrInsufficientBalance, err)
+	assert.ErrorIs(t, err, ErrInsufficientBalance)
 
 	// Test invalid amount
 	_, err = manager.Credit(ctx, wallet.ID, -500, "Invalid Credit", "Negative Amount", "invalid-ref", nil)
 	assert.Error(t, err)
-	assert.Equal(t, ErrInvalidAmount, err)
+	assert.ErrorIs(t, err, ErrInvalidAmount)
 
 	_, err = manager.Debit(ctx, wallet.ID, 0, "Invalid Debit", "Zero Amount", "invalid-ref", nil)
 	assert.Error(t, err)
-	assert.Equal(t, ErrInvalidAmount, err)
+	assert.ErrorIs(t, err, ErrInvalidAmount)
 }
 
 // TestTransactionListing tests listing transactions
@@ -333,7 +341,108 @@ func TestTransfer(t *testing.T) {
 	// Test transfer with insufficient balance
 	err = manager.Transfer(ctx, wallet1.ID, wallet2.ID, 500, "Invalid Transfer", "Insufficient Funds", nil)
 	assert.Error(t, err)
-	assert.Equal(t, ErrInsufficientBalance, err)
+	assert.ErrorIs(t, err, ErrInsufficientBalance)
+}
+
+// TestConcurrentTransfers tests that concurrent transfers in opposite directions neither deadlock nor
+// lose updates, also while wallet settings change. A file database is used, as every connection to an
+// in-memory database gets its own.
+// SQLite serializes write transactions and ignores FOR UPDATE, so this test cannot detect lock order
+// deadlocks; TestTransferLockOrder covers the order wallets are locked in.
+func TestConcurrentTransfers(t *testing.T) {
+	dsn := filepath.Join(t.TempDir(), "wallets.db") + "?_txlock=immediate&_busy_timeout=30000&_journal_mode=WAL"
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Discard})
+	require.NoError(t, err)
+	store := NewGormWalletStore(db, "", "")
+	require.NoError(t, store.AutoMigrate(context.Background()))
+
+	manager := NewWalletManager(WithStore(store))
+	ctx := context.Background()
+
+	const wallets = 4
+	const workers = 8
+	const transfersPerWorker = 250
+	const initialBalance = 100000
+
+	walletIDs := make([]string, wallets)
+	for i := range walletIDs {
+		wallet, err := manager.CreateWallet(ctx, fmt.Sprintf("user-%d", i), "Wallet", "", "")
+		require.NoError(t, err)
+		_, err = manager.Credit(ctx, wallet.ID, initialBalance, "Deposit", "", "", nil)
+		require.NoError(t, err)
+		walletIDs[i] = wallet.ID
+	}
+
+	// Every pair of wallets sees transfers in both directions at the same time
+	var wg sync.WaitGroup
+	errs := make(chan error, workers*transfersPerWorker)
+	for worker := 0; worker < workers; worker++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			for i := 0; i < transfersPerWorker; i++ {
+				from := walletIDs[(worker+i)%wallets]
+				to := walletIDs[(worker+i+1+worker%2)%wallets]
+				if worker%2 == 1 {
+					from, to = to, from
+				}
+				if err := manager.Transfer(ctx, from, to, int64(1+i%10), "Transfer", "", nil); err != nil {
+					errs <- err
+				}
+			}
+		}(worker)
+	}
+
+	// Wallet settings change at the same time, writing back whole wallet rows
+	settingErrs := make(chan error, wallets*transfersPerWorker)
+	for _, walletID := range walletIDs {
+		wg.Add(1)
+		go func(walletID string) {
+			defer wg.Done()
+			for i := 0; i < transfersPerWorker/10; i++ {
+				for _, err := range []error{
+					manager.UpdateWalletName(ctx, walletID, fmt.Sprintf("Wallet %d", i)),
+					manager.FlagWalletRisk(ctx, walletID, "review"),
+					manager.ClearWalletRiskFlag(ctx, walletID),
+					manager.SetFrozenAmount(ctx, walletID, 1, "hold"),
+					manager.SetFrozenAmount(ctx, walletID, 0, ""),
+					manager.FreezeWallet(ctx, walletID, "hold"),
+					manager.UnfreezeWallet(ctx, walletID),
+					manager.SetPrimaryWallet(ctx, walletID),
+				} {
+					if err != nil {
+						settingErrs <- err
+					}
+				}
+			}
+		}(walletID)
+	}
+	wg.Wait()
+	close(errs)
+	close(settingErrs)
+
+	for err := range errs {
+		// Transfers are refused while a wallet is frozen
+		if !errors.Is(err, ErrWalletFrozen) {
+			t.Fatalf("transfer failed: %v", err)
+		}
+	}
+	for err := range settingErrs {
+		t.Fatalf("setting change failed: %v", err)
+	}
+
+	// No points were created or lost and every balance matches its history
+	var total int64
+	for _, walletID := range walletIDs {
+		wallet, err := manager.GetWallet(ctx, walletID)
+		require.NoError(t, err)
+		total += wallet.Balance
+
+		report, err := manager.ReconcileWallet(ctx, walletID, false)
+		require.NoError(t, err)
+		assert.True(t, report.Consistent(), walletID)
+	}
+	assert.Equal(t, int64(wallets*initialBalance), total)
 }
 
 // TestWalletFreeze tests freezing and unfreezing a wallet
@@ -361,7 +470,7 @@ func TestWalletFreeze(t *testing.T) {
 	// Test that operations on frozen wallet fail
 	_, err = manager.Credit(ctx, wallet.ID, 500, "Should Fail", "Frozen Wallet", "fail-ref", nil)
 	assert.Error(t, err)
-	assert.Equal(t, ErrWalletFrozen, err)
+	assert.ErrorIs(t, err, ErrWalletFrozen)
 
 	// Unfreeze the wallet
 	err = manager.UnfreezeWallet(ctx, wallet.ID)
@@ -439,6 +548,20 @@ func TestUserWalletSummary(t *testing.T) {
 	totalBalance, err = manager.GetUserWalletSummary(ctx, "test-user")
 	assert.NoError(t, err)
 	assert.Equal(t, int64(500), totalBalance) // Only the active wallet balance
+
+	// Debit-only and partial freezes still count, full freezes do not
+	require.NoError(t, manager.FreezeWalletWithMode(ctx, wallet2.ID, FreezeModeDebit, "Legal hold"))
+	require.NoError(t, manager.SetFrozenAmount(ctx, wallet2.ID, 200, "Dispute"))
+
+	totalBalance, err = manager.GetUserWalletSummary(ctx, "test-user")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(500), totalBalance)
+
+	require.NoError(t, manager.FreezeWallet(ctx, wallet2.ID, "Investigation"))
+
+	totalBalance, err = manager.GetUserWalletSummary(ctx, "test-user")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), totalBalance)
 }
 
 // TestPendingTransactions tests handling of pending transactions
@@ -514,3 +637,119 @@ func TestPendingTransactions(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, int64(1000), updatedWallet.Balance) // Still 1000 from first transaction
 }
+
+// lockRecordingStore is a store recording the wallet IDs passed to LockWallets
+type lockRecordingStore struct {
+	WalletStore
+	locks [][]string
+}
+
+// lockRecordingTxn is a transaction of a lockRecordingStore
+type lockRecordingTxn struct {
+	Txn
+	store *lockRecordingStore
+}
+
+func (s *lockRecordingStore) Begin(ctx context.Context) Txn {
+	return &lockRecordingTxn{Txn: s.WalletStore.Begin(ctx), store: s}
+}
+
+func (t *lockRecordingTxn) LockWallets(walletIDs []string) ([]Wallet, error) {
+	t.store.locks = append(t.store.locks, slices.Clone(walletIDs))
+	return t.Txn.LockWallets(walletIDs)
+}
+
+// TestTransferLockOrder tests that transfers lock their wallets in ascending ID order in both directions,
+// so databases taking row locks in the order requested cannot deadlock
+func TestTransferLockOrder(t *testing.T) {
+	store := &lockRecordingStore{WalletStore: setupTestGormWalletStore(t)}
+	manager := NewWalletManager(WithStore(store))
+	ctx := context.Background()
+
+	wallet1, err := manager.CreateWallet(ctx, "user-1", "Wallet 1", "", "")
+	require.NoError(t, err)
+	wallet2, err := manager.CreateWallet(ctx, "user-2", "Wallet 2", "", "")
+	require.NoError(t, err)
+	_, err = manager.Credit(ctx, wallet1.ID, 1000, "Deposit", "", "", nil)
+	require.NoError(t, err)
+	_, err = manager.Credit(ctx, wallet2.ID, 1000, "Deposit", "", "", nil)
+	require.NoError(t, err)
+
+	store.locks = nil
+	require.NoError(t, manager.Transfer(ctx, wallet1.ID, wallet2.ID, 100, "Transfer", "", nil))
+	require.NoError(t, manager.Transfer(ctx, wallet2.ID, wallet1.ID, 100, "Transfer", "", nil))
+
+	expected := slices.Sorted(slices.Values([]string{wallet1.ID, wallet2.ID}))
+	require.Len(t, store.locks, 2)
+	for _, walletIDs := range store.locks {
+		assert.Equal(t, expected, walletIDs)
+	}
+}
+
+// TestWalletSettingsLock tests that changes to wallet settings lock the wallet, as they write back
+// the whole wallet row including its balance
+func TestWalletSettingsLock(t *testing.T) {
+	store := &lockRecordingStore{WalletStore: setupTestGormWalletStore(t)}
+	manager := NewWalletManager(WithStore(store))
+	ctx := context.Background()
+
+	wallet, err := manager.CreateWallet(ctx, "user-1", "Wallet", "", "")
+	require.NoError(t, err)
+
+	for name, change := range map[string]func() error{
+		"UpdateWalletName":        func() error { return manager.UpdateWalletName(ctx, wallet.ID, "Renamed") },
+		"UpdateWalletDescription": func() error { return manager.UpdateWalletDescription(ctx, wallet.ID, "Description") },
+		"UpdateWalletReference":   func() error { return manager.UpdateWalletReference(ctx, wallet.ID, "ref") },
+		"UpdateWalletActive":      func() error { return manager.UpdateWalletActive(ctx, wallet.ID, true) },
+		"SetPrimaryWallet":        func() error { return manager.SetPrimaryWallet(ctx, wallet.ID) },
+		"FreezeWallet":            func() error { return manager.FreezeWallet(ctx, wallet.ID, "hold") },
+		"UnfreezeWallet":          func() error { return manager.UnfreezeWallet(ctx, wallet.ID) },
+		"SetFrozenAmount":         func() error { return manager.SetFrozenAmount(ctx, wallet.ID, 0, "") },
+		"FlagWalletRisk":          func() error { return manager.FlagWalletRisk(ctx, wallet.ID, "review") },
+		"ClearWalletRiskFlag":     func() error { return manager.ClearWalletRiskFlag(ctx, wallet.ID) },
+	} {
+		store.locks = nil
+		require.NoError(t, change(), name)
+		assert.Equal(t, [][]string{{wallet.ID}}, store.locks, name)
+	}
+}
+
+// TestPendingTransactionLock tests that completing and cancelling pending transactions lock their wallet,
+// so a transaction cannot be both completed and cancelled
+func TestPendingTransactionLock(t *testing.T) {
+	store := &lockRecordingStore{WalletStore: setupTestGormWalletStore(t)}
+	manager := NewWalletManager(WithStore(store))
+	ctx := context.Background()
+
+	wallet, err := manager.CreateWallet(ctx, "user-1", "Wallet", "", "")
+	require.NoError(t, err)
+
+	pending := func() string {
+		transaction := &Transaction{
+			ID:        GenerateID(),
+			WalletID:  wallet.ID,
+			Type:      TransactionTypeCredit,
+			Amount:    100,
+			Status:    TransactionStatusPending,
+			CreatedAt: time.Now(),
+		}
+		require.NoError(t, store.SaveTransaction(ctx, transaction))
+		return transaction.ID
+	}
+
+	cancelled := pending()
+	store.locks = nil
+	require.NoError(t, manager.CancelTransaction(ctx, cancelled, "declined"))
+	assert.Equal(t, [][]string{{wallet.ID}}, store.locks)
+	assert.ErrorIs(t, manager.CompleteTransaction(ctx, cancelled), ErrPendingTransactionOnly)
+
+	completed := pending()
+	store.locks = nil
+	require.NoError(t, manager.CompleteTransaction(ctx, completed))
+	assert.Equal(t, [][]string{{wallet.ID}}, store.locks)
+	assert.ErrorIs(t, manager.CancelTransaction(ctx, completed, "declined"), ErrPendingTransactionOnly)
+
+	wallet, err = manager.GetWallet(ctx, wallet.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(100), wallet.Balance)
+}
@@ -4,36 +4,49 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"log/slog"
+	"slices"
 	"time"
 
 	"gorm.io/datatypes"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // WalletModel is the GORM model for Wallet entity
 type WalletModel struct {
-	ID          string    `gorm:"primaryKey;type:varchar(36)"`
-	UserID      string    `gorm:"index;type:varchar(36)"`
-	Name        string    `gorm:"type:varchar(100)"`
-	Description string    `gorm:"type:text"`
-	Reference   string    `gorm:"index;type:varchar(100)"`
-	Balance     int64     `gorm:"type:bigint"`
-	IsPrimary   bool      `gorm:"default:false"`
-	Active      bool      `gorm:"default:true"`
-	Frozen      bool      `gorm:"default:false"`
-	RiskFlagged bool      `gorm:"default:false"`
-	ClosedAt    time.Time `gorm:"type:timestamp"`
-	CreatedAt   time.Time `gorm:"type:timestamp;not null;default:CURRENT_TIMESTAMP"`
-	UpdatedAt   time.Time `gorm:"type:timestamp;not null;default:CURRENT_TIMESTAMP"`
+	ID                 string         `gorm:"primaryKey;type:varchar(36)"`
+	TenantID           string         `gorm:"index;type:varchar(36);not null;default:''"`
+	UserID             string         `gorm:"index;type:varchar(36)"`
+	Name               string         `gorm:"type:varchar(100)"`
+	Description        string         `gorm:"type:text"`
+	Reference          string         `gorm:"index;type:varchar(100)"`
+	Balance            int64          `gorm:"type:bigint"`
+	Buckets            datatypes.JSON `gorm:"type:json"`
+	IsPrimary          bool           `gorm:"default:false"`
+	Active             bool           `gorm:"default:true"`
+	Frozen             bool           `gorm:"default:false"`
+	FreezeMode         FreezeMode     `gorm:"type:varchar(10)"`
+	FreezeReason       string         `gorm:"type:text"`
+	FrozenAmount       int64          `gorm:"type:bigint;not null;default:0"`
+	FrozenAmountReason string         `gorm:"type:text"`
+	RiskFlagged        bool           `gorm:"default:false"`
+	RiskReason         string         `gorm:"type:text"`
+	ClosedAt           time.Time      `gorm:"type:timestamp"`
+	CreatedAt          time.Time      `gorm:"type:timestamp;not null;default:CURRENT_TIMESTAMP"`
+	UpdatedAt          time.Time      `gorm:"type:timestamp;not null;default:CURRENT_TIMESTAMP"`
 }
 
 // TransactionModel is the GORM model for Transaction entity
 type TransactionModel struct {
 	ID           string            `gorm:"primaryKey;type:varchar(36)"`
-	WalletID     string            `gorm:"index;type:varchar(36)"`
+	TenantID     string            `gorm:"index;type:varchar(36);not null;default:''"`
+	WalletID     string            `gorm:"index;uniqueIndex:idx_wallet_chain_sequence,priority:1;type:varchar(36)"`
 	Type         TransactionType   `gorm:"type:varchar(10);not null"`
 	Amount       int64             `gorm:"type:bigint;not null"`
 	Balance      int64             `gorm:"type:bigint;not null"`
+	Buckets      datatypes.JSON    `gorm:"type:json"`
+	DelegateID   string            `gorm:"index;type:varchar(36)"`
 	Description  string            `gorm:"type:varchar(255)"`
 	Note         string            `gorm:"type:text"`
 	Reference    string            `gorm:"index;type:varchar(100)"`
@@ -42,39 +55,58 @@ type TransactionModel struct {
 	CreatedAt    time.Time         `gorm:"type:timestamp;not null;default:CURRENT_TIMESTAMP"`
 	CompletedAt  time.Time         `gorm:"type:timestamp"`
 	FailedReason string            `gorm:"type:text"`
+
+	// Hash chain columns
+	ChainSequence *int64 `gorm:"uniqueIndex:idx_wallet_chain_sequence,priority:2"`
+	PrevHash      string `gorm:"type:varchar(64)"`
+	Hash          string `gorm:"type:varchar(64)"`
 }
 
 // ToWallet converts a WalletModel to a Wallet entity
 func (m *WalletModel) ToWallet() *Wallet {
 	return &Wallet{
-		ID:          m.ID,
-		UserID:      m.UserID,
-		Name:        m.Name,
-		Description: m.Description,
-		Reference:   m.Reference,
-		Balance:     m.Balance,
-		Primary:     m.IsPrimary,
-		Active:      m.Active,
-		Frozen:      m.Frozen,
-		RiskFlagged: m.RiskFlagged,
-		ClosedAt:    m.ClosedAt,
-		CreatedAt:   m.CreatedAt,
-		UpdatedAt:   m.UpdatedAt,
+		ID:                 m.ID,
+		TenantID:           m.TenantID,
+		UserID:             m.UserID,
+		Name:               m.Name,
+		Description:        m.Description,
+		Reference:          m.Reference,
+		Balance:            m.Balance,
+		Buckets:            decodeBuckets(m.Buckets),
+		Primary:            m.IsPrimary,
+		Active:             m.Active,
+		Frozen:             m.Frozen,
+		FreezeMode:         m.FreezeMode,
+		FreezeReason:       m.FreezeReason,
+		FrozenAmount:       m.FrozenAmount,
+		FrozenAmountReason: m.FrozenAmountReason,
+		RiskFlagged:        m.RiskFlagged,
+		RiskReason:         m.RiskReason,
+		ClosedAt:           m.ClosedAt,
+		CreatedAt:          m.CreatedAt,
+		UpdatedAt:          m.UpdatedAt,
 	}
 }
 
 // FromWallet initializes a WalletModel from a Wallet entity
 func (m *WalletModel) FromWallet(wallet *Wallet) {
 	m.ID = wallet.ID
+	m.TenantID = wallet.TenantID
 	m.UserID = wallet.UserID
 	m.Name = wallet.Name
 	m.Description = wallet.Description
 	m.Reference = wallet.Reference
 	m.Balance = wallet.Balance
+	m.Buckets = encodeBuckets(wallet.Buckets)
 	m.IsPrimary = wallet.Primary
 	m.Active = wallet.Active
 	m.Frozen = wallet.Frozen
+	m.FreezeMode = wallet.FreezeMode
+	m.FreezeReason = wallet.FreezeReason
+	m.FrozenAmount = wallet.FrozenAmount
+	m.FrozenAmountReason = wallet.FrozenAmountReason
 	m.RiskFlagged = wallet.RiskFlagged
+	m.RiskReason = wallet.RiskReason
 	m.ClosedAt = wallet.ClosedAt
 	m.CreatedAt = wallet.CreatedAt
 	m.UpdatedAt = wallet.UpdatedAt
@@ -91,12 +123,15 @@ func (m *TransactionModel) ToTransaction() *Transaction {
 		}
 	}
 
-	return &Transaction{
+	transaction := &Transaction{
 		ID:           m.ID,
+		TenantID:     m.TenantID,
 		WalletID:     m.WalletID,
 		Type:         m.Type,
 		Amount:       m.Amount,
 		Balance:      m.Balance,
+		Buckets:      decodeBuckets(m.Buckets),
+		DelegateID:   m.DelegateID,
 		Description:  m.Description,
 		Note:         m.Note,
 		Reference:    m.Reference,
@@ -106,6 +141,14 @@ func (m *TransactionModel) ToTransaction() *Transaction {
 		CompletedAt:  m.CompletedAt,
 		FailedReason: m.FailedReason,
 	}
+
+	if m.ChainSequence != nil {
+		transaction.ChainSequence = *m.ChainSequence
+	}
+	transaction.PrevHash = m.PrevHash
+	transaction.Hash = m.Hash
+
+	return transaction
 }
 
 // FromTransaction initializes a TransactionModel from a Transaction entity
@@ -124,10 +167,13 @@ func (m *TransactionModel) FromTransaction(transaction *Transaction) error {
 	}
 
 	m.ID = transaction.ID
+	m.TenantID = transaction.TenantID
 	m.WalletID = transaction.WalletID
 	m.Type = transaction.Type
 	m.Amount = transaction.Amount
 	m.Balance = transaction.Balance
+	m.Buckets = encodeBuckets(transaction.Buckets)
+	m.DelegateID = transaction.DelegateID
 	m.Description = transaction.Description
 	m.Note = transaction.Note
 	m.Reference = transaction.Reference
@@ -135,19 +181,151 @@ func (m *TransactionModel) FromTransaction(transaction *Transaction) error {
 	m.CreatedAt = transaction.CreatedAt
 	m.CompletedAt = transaction.CompletedAt
 	m.FailedReason = transaction.FailedReason
+	m.ChainSequence = nil
+	if transaction.ChainSequence > 0 {
+		sequence := transaction.ChainSequence
+		m.ChainSequence = &sequence
+	}
+	m.PrevHash = transaction.PrevHash
+	m.Hash = transaction.Hash
+
+	return nil
+}
+
+// encodeBuckets encodes bucket amounts as a JSON object, leaving no buckets empty
+func encodeBuckets(buckets map[string]int64) datatypes.JSON {
+	if len(buckets) == 0 {
+		return nil
+	}
+	encoded, _ := json.Marshal(buckets) // A map of integers always encodes
+	return datatypes.JSON(encoded)
+}
+
+// decodeBuckets decodes bucket amounts, returning nil for no buckets
+func decodeBuckets(encoded datatypes.JSON) map[string]int64 {
+	var buckets map[string]int64
+	if len(encoded) == 0 || json.Unmarshal(encoded, &buckets) != nil || len(buckets) == 0 {
+		return nil
+	}
+	return buckets
+}
+
+// chainTransactions links transactions that are no longer pending into their wallets' hash chains,
+// in the given order. Transactions that are pending or already chained are left untouched.
+//
+// The wallets whose chains grow are locked first, in ascending ID order, so concurrent writers to the
+// same wallet wait for each other instead of reading the same chain head.
+func chainTransactions(db *gorm.DB, walletTable string, table string, transactions []*Transaction) error {
+	type link struct {
+		sequence int64
+		hash     string
+	}
+	heads := make(map[string]link)
+
+	walletIDs := make([]string, 0, len(transactions))
+	for _, transaction := range transactions {
+		if chainable(transaction) && !slices.Contains(walletIDs, transaction.WalletID) {
+			walletIDs = append(walletIDs, transaction.WalletID)
+		}
+	}
+	if len(walletIDs) == 0 {
+		return nil
+	}
+	slices.Sort(walletIDs)
+
+	var locked []string
+	result := db.Table(walletTable).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id IN ?", walletIDs).
+		Order("id").
+		Pluck("id", &locked)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	for _, transaction := range transactions {
+		if !chainable(transaction) {
+			continue
+		}
+
+		// Find the current head of the wallet's chain
+		head, ok := heads[transaction.WalletID]
+		if !ok {
+			var models []TransactionModel
+			result := db.Table(table).
+				Where("wallet_id = ? AND chain_sequence IS NOT NULL", transaction.WalletID).
+				Order("chain_sequence DESC").
+				Limit(1).
+				Find(&models)
+			if result.Error != nil {
+				return result.Error
+			}
+			if len(models) > 0 {
+				head = link{sequence: *models[0].ChainSequence, hash: models[0].Hash}
+			}
+		}
+
+		transaction.ChainSequence = head.sequence + 1
+		transaction.PrevHash = head.hash
+		hash, err := TransactionHash(transaction)
+		if err != nil {
+			return err
+		}
+		transaction.Hash = hash
+
+		heads[transaction.WalletID] = link{sequence: transaction.ChainSequence, hash: transaction.Hash}
+	}
 
 	return nil
 }
 
+// chainable reports whether a transaction still has to be linked into its wallet's hash chain
+func chainable(transaction *Transaction) bool {
+	return transaction.Status != TransactionStatusPending && transaction.Hash == ""
+}
+
+// transactionInsertBatchSize is the number of rows per INSERT statement when saving transactions in bulk
+const transactionInsertBatchSize = 100
+
 // GormWalletStore implements WalletStore interface using GORM
 type GormWalletStore struct {
 	db               *gorm.DB
 	walletTable      string
 	transactionTable string
+	allowanceTable   string
+	log              walletLogger
+	clock            Clock
+}
+
+// GormWalletStoreOption defines a function type for configuring GormWalletStore
+type GormWalletStoreOption func(*GormWalletStore)
+
+// WithStoreLogger sets the logger receiving a debug record for every write and commit, and an error
+// record for every failed one. Nothing is logged without a logger.
+func WithStoreLogger(logger *slog.Logger) GormWalletStoreOption {
+	return func(s *GormWalletStore) {
+		s.log.logger = logger
+	}
+}
+
+// WithStoreLogRedaction sets the fields redacted in log records, DefaultLogRedaction by default
+func WithStoreLogRedaction(redaction LogRedaction) GormWalletStoreOption {
+	return func(s *GormWalletStore) {
+		s.log.redaction = redaction
+	}
+}
+
+// WithStoreClock sets the clock timestamping saved and updated records, SystemClock by default.
+// GORM's automatic timestamps use the clock too.
+func WithStoreClock(clock Clock) GormWalletStoreOption {
+	return func(s *GormWalletStore) {
+		s.clock = clock
+		s.db = s.db.Session(&gorm.Session{NowFunc: clock.Now})
+	}
 }
 
 // NewGormWalletStore creates a new instance of GormWalletStore with custom table names
-func NewGormWalletStore(db *gorm.DB, walletTable, transactionTable string) *GormWalletStore {
+func NewGormWalletStore(db *gorm.DB, walletTable, transactionTable string, options ...GormWalletStoreOption) *GormWalletStore {
 	if walletTable == "" {
 		walletTable = "wallets"
 	}
@@ -155,11 +333,31 @@ func NewGormWalletStore(db *gorm.DB, walletTable, transactionTable string) *Gorm
 		transactionTable = "transactions"
 	}
 
-	return &GormWalletStore{
+	store := &GormWalletStore{
 		db:               db,
 		walletTable:      walletTable,
 		transactionTable: transactionTable,
+		allowanceTable:   "wallet_allowances",
+		log:              newWalletLogger(),
+		clock:            SystemClock,
 	}
+
+	// Apply all options
+	for _, option := range options {
+		option(store)
+	}
+
+	return store
+}
+
+// wallets returns a query on the wallets of the context's tenant
+func (s *GormWalletStore) wallets(ctx context.Context) *gorm.DB {
+	return s.db.WithContext(ctx).Table(s.walletTable).Where(s.walletTable+".tenant_id = ?", TenantFromContext(ctx))
+}
+
+// transactions returns a query on the transactions of the context's tenant
+func (s *GormWalletStore) transactions(ctx context.Context) *gorm.DB {
+	return s.db.WithContext(ctx).Table(s.transactionTable).Where(s.transactionTable+".tenant_id = ?", TenantFromContext(ctx))
 }
 
 // AutoMigrate creates or updates the necessary database tables
@@ -177,27 +375,54 @@ func (s *GormWalletStore) AutoMigrate(ctx context.Context) error {
 		return err
 	}
 
+	// Create or update the allowance table
+	if err := db.Table(s.allowanceTable).AutoMigrate(&AllowanceModel{}); err != nil {
+		return err
+	}
+
 	return nil
 }
 
 // GormTxn implements Txn interface using GORM
 type GormTxn struct {
+	ctx              context.Context
 	tx               *gorm.DB
+	tenantID         string
 	walletTable      string
 	transactionTable string
+	allowanceTable   string
+	log              walletLogger
+	clock            Clock
 }
 
-// Begin starts a new database transaction
+// Begin starts a new database transaction scoped to the tenant of the context
 func (s *GormWalletStore) Begin(ctx context.Context) Txn {
 	return &GormTxn{
+		ctx:              ctx,
 		tx:               s.db.WithContext(ctx).Begin(),
+		tenantID:         TenantFromContext(ctx),
 		walletTable:      s.walletTable,
 		transactionTable: s.transactionTable,
+		allowanceTable:   s.allowanceTable,
+		log:              s.log,
+		clock:            s.clock,
 	}
 }
 
+// wallets returns a query on the wallets of the transaction's tenant
+func (t *GormTxn) wallets() *gorm.DB {
+	return t.tx.Table(t.walletTable).Where(t.walletTable+".tenant_id = ?", t.tenantID)
+}
+
+// transactions returns a query on the transactions of the transaction's tenant
+func (t *GormTxn) transactions() *gorm.DB {
+	return t.tx.Table(t.transactionTable).Where(t.transactionTable+".tenant_id = ?", t.tenantID)
+}
+
 // Commit commits the transaction
-func (t *GormTxn) Commit() error {
+func (t *GormTxn) Commit() (err error) {
+	defer func() { t.log.mutation(t.ctx, "Commit", true, err) }()
+
 	return t.tx.Commit().Error
 }
 
@@ -206,24 +431,41 @@ func (t *GormTxn) Rollback() error {
 	return t.tx.Rollback().Error
 }
 
+// SavePoint marks a point within the transaction that RollbackTo can return to
+func (t *GormTxn) SavePoint(name string) error {
+	return t.tx.SavePoint(name).Error
+}
+
+// RollbackTo undoes the changes made since the named savepoint, keeping the transaction open
+func (t *GormTxn) RollbackTo(name string) (err error) {
+	defer func() { t.log.mutation(t.ctx, "RollbackTo", true, err, slog.String("savepoint", name)) }()
+
+	return t.tx.RollbackTo(name).Error
+}
+
 // SaveWallet saves a wallet to the database (transactional)
-func (t *GormTxn) SaveWallet(wallet *Wallet) error {
+func (t *GormTxn) SaveWallet(wallet *Wallet) (err error) {
+	defer func() { t.log.mutation(t.ctx, "SaveWallet", true, err, t.log.walletAttrs(wallet)...) }()
+
 	if wallet.CreatedAt.IsZero() {
-		wallet.CreatedAt = time.Now()
+		wallet.CreatedAt = t.clock.Now()
 	}
-	wallet.UpdatedAt = time.Now()
+	if wallet.UpdatedAt.IsZero() {
+		wallet.UpdatedAt = wallet.CreatedAt
+	}
+
+	wallet.TenantID = t.tenantID
 
 	model := &WalletModel{}
 	model.FromWallet(wallet)
 
-	err := t.tx.Table(t.walletTable).Create(model).Error
-	return err
+	return t.wallets().Create(model).Error
 }
 
 // FindWallet finds a wallet by ID (transactional)
 func (t *GormTxn) FindWallet(walletID string) (*Wallet, error) {
 	var model WalletModel
-	result := t.tx.Table(t.walletTable).Where("id = ?", walletID).First(&model)
+	result := t.wallets().Where("id = ?", walletID).First(&model)
 	if result.Error != nil {
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
 			return nil, nil
@@ -236,7 +478,7 @@ func (t *GormTxn) FindWallet(walletID string) (*Wallet, error) {
 // FindWalletsByUserID finds all wallets for a user (transactional)
 func (t *GormTxn) FindWalletsByUserID(userID string) ([]Wallet, error) {
 	var models []WalletModel
-	result := t.tx.Table(t.walletTable).Where("user_id = ?", userID).Find(&models)
+	result := t.wallets().Where("user_id = ?", userID).Find(&models)
 	if result.Error != nil {
 		return nil, result.Error
 	}
@@ -252,7 +494,7 @@ func (t *GormTxn) FindWalletsByUserID(userID string) ([]Wallet, error) {
 // FindWalletByUserIDAndReference finds a wallet by user ID and reference (transactional)
 func (t *GormTxn) FindWalletByUserIDAndReference(userID string, reference string) (*Wallet, error) {
 	var model WalletModel
-	result := t.tx.Table(t.walletTable).Where("user_id = ? AND reference = ?", userID, reference).First(&model)
+	result := t.wallets().Where("user_id = ? AND reference = ?", userID, reference).First(&model)
 	if result.Error != nil {
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
 			return nil, nil
@@ -265,7 +507,7 @@ func (t *GormTxn) FindWalletByUserIDAndReference(userID string, reference string
 // FindPrimaryWalletByUserID finds the primary wallet for a user (transactional)
 func (t *GormTxn) FindPrimaryWalletByUserID(userID string) (*Wallet, error) {
 	var model WalletModel
-	result := t.tx.Table(t.walletTable).Where("user_id = ? AND is_primary = ? AND active = ?", userID, true, true).First(&model)
+	result := t.wallets().Where("user_id = ? AND is_primary = ? AND active = ?", userID, true, true).First(&model)
 	if result.Error != nil {
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
 			return nil, nil
@@ -275,20 +517,91 @@ func (t *GormTxn) FindPrimaryWalletByUserID(userID string) (*Wallet, error) {
 	return model.ToWallet(), nil
 }
 
+// LockWallets finds all wallets matching the given IDs and locks them for update in ascending ID
+// order, so transactions locking overlapping sets of wallets cannot deadlock (transactional)
+func (t *GormTxn) LockWallets(walletIDs []string) ([]Wallet, error) {
+	if len(walletIDs) == 0 {
+		return []Wallet{}, nil
+	}
+
+	var models []WalletModel
+	result := t.wallets().
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id IN ?", walletIDs).
+		Order(t.walletTable + ".id").
+		Find(&models)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	wallets := make([]Wallet, len(models))
+	for i, model := range models {
+		wallets[i] = *model.ToWallet()
+	}
+	return wallets, nil
+}
+
+// FindWalletsByIDs finds all wallets matching the given IDs (transactional)
+func (t *GormTxn) FindWalletsByIDs(walletIDs []string) ([]Wallet, error) {
+	if len(walletIDs) == 0 {
+		return []Wallet{}, nil
+	}
+
+	var models []WalletModel
+	result := t.wallets().Where("id IN ?", walletIDs).Find(&models)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	wallets := make([]Wallet, len(models))
+	for i, model := range models {
+		wallet := model.ToWallet()
+		wallets[i] = *wallet
+	}
+	return wallets, nil
+}
+
+// FindWallets finds all wallets ordered by ID with pagination (transactional)
+func (t *GormTxn) FindWallets(limit int, offset int) ([]Wallet, error) {
+	var models []WalletModel
+	result := t.wallets().Order("id ASC").Limit(limit).Offset(offset).Find(&models)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	wallets := make([]Wallet, len(models))
+	for i, model := range models {
+		wallet := model.ToWallet()
+		wallets[i] = *wallet
+	}
+	return wallets, nil
+}
+
 // UpdateWallet updates an existing wallet (transactional)
-func (t *GormTxn) UpdateWallet(wallet *Wallet) error {
-	wallet.UpdatedAt = time.Now()
+func (t *GormTxn) UpdateWallet(wallet *Wallet) (err error) {
+	defer func() { t.log.mutation(t.ctx, "UpdateWallet", true, err, t.log.walletAttrs(wallet)...) }()
+
+	wallet.UpdatedAt = t.clock.Now()
+	wallet.TenantID = t.tenantID
 
 	model := &WalletModel{}
 	model.FromWallet(wallet)
 
-	return t.tx.Table(t.walletTable).Save(model).Error
+	// Update all columns of the row only if it belongs to the tenant
+	return t.wallets().Select("*").Updates(model).Error
 }
 
 // SaveTransaction saves a transaction to the database (transactional)
-func (t *GormTxn) SaveTransaction(transaction *Transaction) error {
+func (t *GormTxn) SaveTransaction(transaction *Transaction) (err error) {
+	defer func() { t.log.mutation(t.ctx, "SaveTransaction", true, err, t.log.transactionAttrs(transaction)...) }()
+
 	if transaction.CreatedAt.IsZero() {
-		transaction.CreatedAt = time.Now()
+		transaction.CreatedAt = t.clock.Now()
+	}
+	transaction.TenantID = t.tenantID
+
+	if err := chainTransactions(t.tx, t.walletTable, t.transactionTable, []*Transaction{transaction}); err != nil {
+		return err
 	}
 
 	model := &TransactionModel{}
@@ -296,13 +609,44 @@ func (t *GormTxn) SaveTransaction(transaction *Transaction) error {
 		return err
 	}
 
-	return t.tx.Table(t.transactionTable).Create(model).Error
+	return t.transactions().Create(model).Error
+}
+
+// SaveTransactions saves multiple transactions using batched inserts (transactional)
+func (t *GormTxn) SaveTransactions(transactions []Transaction) (err error) {
+	defer func() { t.log.mutation(t.ctx, "SaveTransactions", true, err, slog.Int("count", len(transactions))) }()
+
+	if len(transactions) == 0 {
+		return nil
+	}
+
+	pointers := make([]*Transaction, len(transactions))
+	for i := range transactions {
+		if transactions[i].CreatedAt.IsZero() {
+			transactions[i].CreatedAt = t.clock.Now()
+		}
+		transactions[i].TenantID = t.tenantID
+		pointers[i] = &transactions[i]
+	}
+
+	if err := chainTransactions(t.tx, t.walletTable, t.transactionTable, pointers); err != nil {
+		return err
+	}
+
+	models := make([]TransactionModel, len(transactions))
+	for i := range transactions {
+		if err := models[i].FromTransaction(&transactions[i]); err != nil {
+			return err
+		}
+	}
+
+	return t.transactions().CreateInBatches(models, transactionInsertBatchSize).Error
 }
 
 // FindTransaction finds a transaction by ID (transactional)
 func (t *GormTxn) FindTransaction(transactionID string) (*Transaction, error) {
 	var model TransactionModel
-	result := t.tx.Table(t.transactionTable).Where("id = ?", transactionID).First(&model)
+	result := t.transactions().Where("id = ?", transactionID).First(&model)
 	if result.Error != nil {
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
 			return nil, nil
@@ -312,10 +656,30 @@ func (t *GormTxn) FindTransaction(transactionID string) (*Transaction, error) {
 	return model.ToTransaction(), nil
 }
 
+// FindTransactionsByIDs finds all transactions matching the given IDs (transactional)
+func (t *GormTxn) FindTransactionsByIDs(transactionIDs []string) ([]Transaction, error) {
+	if len(transactionIDs) == 0 {
+		return []Transaction{}, nil
+	}
+
+	var models []TransactionModel
+	result := t.transactions().Where("id IN ?", transactionIDs).Find(&models)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	transactions := make([]Transaction, len(models))
+	for i, model := range models {
+		transaction := model.ToTransaction()
+		transactions[i] = *transaction
+	}
+	return transactions, nil
+}
+
 // FindTransactionsByWalletID finds transactions for a wallet with pagination (transactional)
 func (t *GormTxn) FindTransactionsByWalletID(walletID string, limit int, offset int) ([]Transaction, error) {
 	var models []TransactionModel
-	result := t.tx.Table(t.transactionTable).Where("wallet_id = ?", walletID).Order("created_at DESC").Limit(limit).Offset(offset).Find(&models)
+	result := t.transactions().Where("wallet_id = ?", walletID).Order("created_at DESC").Limit(limit).Offset(offset).Find(&models)
 	if result.Error != nil {
 		return nil, result.Error
 	}
@@ -331,7 +695,7 @@ func (t *GormTxn) FindTransactionsByWalletID(walletID string, limit int, offset
 // FindTransactionsByUserID finds transactions for a user with pagination (transactional)
 func (t *GormTxn) FindTransactionsByUserID(userID string, limit int, offset int) ([]Transaction, error) {
 	var models []TransactionModel
-	result := t.tx.Table(t.transactionTable).
+	result := t.transactions().
 		Joins("JOIN "+t.walletTable+" ON "+t.transactionTable+".wallet_id = "+t.walletTable+".id").
 		Where(t.walletTable+".user_id = ?", userID).
 		Order(t.transactionTable + ".created_at DESC").
@@ -350,33 +714,109 @@ func (t *GormTxn) FindTransactionsByUserID(userID string, limit int, offset int)
 	return transactions, nil
 }
 
+// FindCompletedTransactionsByWalletID finds the completed transactions of a wallet in the order
+// they were applied, with pagination (transactional)
+func (t *GormTxn) FindCompletedTransactionsByWalletID(walletID string, limit int, offset int) ([]Transaction, error) {
+	var models []TransactionModel
+	result := t.transactions().
+		Where("wallet_id = ? AND status = ?", walletID, TransactionStatusCompleted).
+		Order("completed_at ASC, created_at ASC, id ASC").
+		Limit(limit).
+		Offset(offset).
+		Find(&models)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	transactions := make([]Transaction, len(models))
+	for i, model := range models {
+		transaction := model.ToTransaction()
+		transactions[i] = *transaction
+	}
+	return transactions, nil
+}
+
+// FindChainedTransactionsByWalletID finds the transactions in a wallet's hash chain in chain order,
+// with pagination (transactional)
+func (t *GormTxn) FindChainedTransactionsByWalletID(walletID string, limit int, offset int) ([]Transaction, error) {
+	var models []TransactionModel
+	result := t.transactions().
+		Where("wallet_id = ? AND chain_sequence IS NOT NULL", walletID).
+		Order("chain_sequence ASC").
+		Limit(limit).
+		Offset(offset).
+		Find(&models)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	transactions := make([]Transaction, len(models))
+	for i, model := range models {
+		transaction := model.ToTransaction()
+		transactions[i] = *transaction
+	}
+	return transactions, nil
+}
+
+// SumTransactionTotalsByWalletID sums the credits and debits of a wallet's completed transactions
+// completed after the first and at or before the second time (transactional)
+func (t *GormTxn) SumTransactionTotalsByWalletID(walletID string, after time.Time, until time.Time) (int64, int64, error) {
+	var totals struct {
+		Credits int64
+		Debits  int64
+	}
+	result := t.transactions().
+		Select("COALESCE(SUM(CASE WHEN type = 'credit' THEN amount ELSE 0 END), 0) AS credits, "+
+			"COALESCE(SUM(CASE WHEN type = 'debit' THEN amount ELSE 0 END), 0) AS debits").
+		Where("wallet_id = ? AND status = ? AND completed_at > ? AND completed_at <= ?", walletID, TransactionStatusCompleted, after, until).
+		Scan(&totals)
+	if result.Error != nil {
+		return 0, 0, result.Error
+	}
+	return totals.Credits, totals.Debits, nil
+}
+
 // UpdateTransaction updates an existing transaction (transactional)
-func (t *GormTxn) UpdateTransaction(transaction *Transaction) error {
+func (t *GormTxn) UpdateTransaction(transaction *Transaction) (err error) {
+	defer func() { t.log.mutation(t.ctx, "UpdateTransaction", true, err, t.log.transactionAttrs(transaction)...) }()
+
+	transaction.TenantID = t.tenantID
+	if err := chainTransactions(t.tx, t.walletTable, t.transactionTable, []*Transaction{transaction}); err != nil {
+		return err
+	}
+
 	model := &TransactionModel{}
 	if err := model.FromTransaction(transaction); err != nil {
 		return err
 	}
 
-	return t.tx.Table(t.transactionTable).Save(model).Error
+	// Update all columns of the row only if it belongs to the tenant
+	return t.transactions().Select("*").Updates(model).Error
 }
 
 // SaveWallet saves a wallet to the database (non-transactional)
-func (s *GormWalletStore) SaveWallet(ctx context.Context, wallet *Wallet) error {
+func (s *GormWalletStore) SaveWallet(ctx context.Context, wallet *Wallet) (err error) {
+	defer func() { s.log.mutation(ctx, "SaveWallet", false, err, s.log.walletAttrs(wallet)...) }()
+
 	if wallet.CreatedAt.IsZero() {
-		wallet.CreatedAt = time.Now()
+		wallet.CreatedAt = s.clock.Now()
+	}
+	if wallet.UpdatedAt.IsZero() {
+		wallet.UpdatedAt = wallet.CreatedAt
 	}
-	wallet.UpdatedAt = time.Now()
+
+	wallet.TenantID = TenantFromContext(ctx)
 
 	model := &WalletModel{}
 	model.FromWallet(wallet)
 
-	return s.db.WithContext(ctx).Table(s.walletTable).Create(model).Error
+	return s.wallets(ctx).Create(model).Error
 }
 
 // FindWallet finds a wallet by ID (non-transactional)
 func (s *GormWalletStore) FindWallet(ctx context.Context, walletID string) (*Wallet, error) {
 	var model WalletModel
-	result := s.db.WithContext(ctx).Table(s.walletTable).Where("id = ?", walletID).First(&model)
+	result := s.wallets(ctx).Where("id = ?", walletID).First(&model)
 	if result.Error != nil {
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
 			return nil, nil
@@ -389,7 +829,7 @@ func (s *GormWalletStore) FindWallet(ctx context.Context, walletID string) (*Wal
 // FindWalletsByUserID finds all wallets for a user (non-transactional)
 func (s *GormWalletStore) FindWalletsByUserID(ctx context.Context, userID string) ([]Wallet, error) {
 	var models []WalletModel
-	result := s.db.WithContext(ctx).Table(s.walletTable).Where("user_id = ?", userID).Find(&models)
+	result := s.wallets(ctx).Where("user_id = ?", userID).Find(&models)
 	if result.Error != nil {
 		return nil, result.Error
 	}
@@ -405,7 +845,7 @@ func (s *GormWalletStore) FindWalletsByUserID(ctx context.Context, userID string
 // FindWalletByUserIDAndReference finds a wallet by user ID and reference (non-transactional)
 func (s *GormWalletStore) FindWalletByUserIDAndReference(ctx context.Context, userID string, reference string) (*Wallet, error) {
 	var model WalletModel
-	result := s.db.WithContext(ctx).Table(s.walletTable).Where("user_id = ? AND reference = ?", userID, reference).First(&model)
+	result := s.wallets(ctx).Where("user_id = ? AND reference = ?", userID, reference).First(&model)
 	if result.Error != nil {
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
 			return nil, nil
@@ -418,7 +858,7 @@ func (s *GormWalletStore) FindWalletByUserIDAndReference(ctx context.Context, us
 // FindPrimaryWalletByUserID finds the primary wallet for a user (non-transactional)
 func (s *GormWalletStore) FindPrimaryWalletByUserID(ctx context.Context, userID string) (*Wallet, error) {
 	var model WalletModel
-	result := s.db.WithContext(ctx).Table(s.walletTable).Where("user_id = ? AND is_primary = ? AND active = ?", userID, true, true).First(&model)
+	result := s.wallets(ctx).Where("user_id = ? AND is_primary = ? AND active = ?", userID, true, true).First(&model)
 	if result.Error != nil {
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
 			return nil, nil
@@ -428,20 +868,65 @@ func (s *GormWalletStore) FindPrimaryWalletByUserID(ctx context.Context, userID
 	return model.ToWallet(), nil
 }
 
+// FindWallets finds all wallets ordered by ID with pagination (non-transactional)
+func (s *GormWalletStore) FindWallets(ctx context.Context, limit int, offset int) ([]Wallet, error) {
+	var models []WalletModel
+	result := s.wallets(ctx).Order("id ASC").Limit(limit).Offset(offset).Find(&models)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	wallets := make([]Wallet, len(models))
+	for i, model := range models {
+		wallet := model.ToWallet()
+		wallets[i] = *wallet
+	}
+	return wallets, nil
+}
+
+// FindWalletsAfter finds the wallets following the wallet with the given ID in ID order, from the
+// first wallet for an empty ID. Unlike offsets, the position is kept while wallets are created
+// concurrently (non-transactional)
+func (s *GormWalletStore) FindWalletsAfter(ctx context.Context, afterID string, limit int) ([]Wallet, error) {
+	var models []WalletModel
+	result := s.wallets(ctx).Where("id > ?", afterID).Order("id ASC").Limit(limit).Find(&models)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	wallets := make([]Wallet, len(models))
+	for i, model := range models {
+		wallets[i] = *model.ToWallet()
+	}
+	return wallets, nil
+}
+
 // UpdateWallet updates an existing wallet (non-transactional)
-func (s *GormWalletStore) UpdateWallet(ctx context.Context, wallet *Wallet) error {
-	wallet.UpdatedAt = time.Now()
+func (s *GormWalletStore) UpdateWallet(ctx context.Context, wallet *Wallet) (err error) {
+	defer func() { s.log.mutation(ctx, "UpdateWallet", false, err, s.log.walletAttrs(wallet)...) }()
+
+	wallet.UpdatedAt = s.clock.Now()
+	wallet.TenantID = TenantFromContext(ctx)
 
 	model := &WalletModel{}
 	model.FromWallet(wallet)
 
-	return s.db.WithContext(ctx).Table(s.walletTable).Save(model).Error
+	// Update all columns of the row only if it belongs to the tenant
+	return s.wallets(ctx).Select("*").Updates(model).Error
 }
 
 // SaveTransaction saves a transaction to the database (non-transactional)
-func (s *GormWalletStore) SaveTransaction(ctx context.Context, transaction *Transaction) error {
+func (s *GormWalletStore) SaveTransaction(ctx context.Context, transaction *Transaction) (err error) {
+	defer func() { s.log.mutation(ctx, "SaveTransaction", false, err, s.log.transactionAttrs(transaction)...) }()
+
 	if transaction.CreatedAt.IsZero() {
-		transaction.CreatedAt = time.Now()
+		transaction.CreatedAt = s.clock.Now()
+	}
+	transaction.TenantID = TenantFromContext(ctx)
+
+	db := s.db.WithContext(ctx)
+	if err := chainTransactions(db, s.walletTable, s.transactionTable, []*Transaction{transaction}); err != nil {
+		return err
 	}
 
 	model := &TransactionModel{}
@@ -449,13 +934,13 @@ func (s *GormWalletStore) SaveTransaction(ctx context.Context, transaction *Tran
 		return err
 	}
 
-	return s.db.WithContext(ctx).Table(s.transactionTable).Create(model).Error
+	return s.transactions(ctx).Create(model).Error
 }
 
 // FindTransaction finds a transaction by ID (non-transactional)
 func (s *GormWalletStore) FindTransaction(ctx context.Context, transactionID string) (*Transaction, error) {
 	var model TransactionModel
-	result := s.db.WithContext(ctx).Table(s.transactionTable).Where("id = ?", transactionID).First(&model)
+	result := s.transactions(ctx).Where("id = ?", transactionID).First(&model)
 	if result.Error != nil {
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
 			return nil, nil
@@ -468,7 +953,7 @@ func (s *GormWalletStore) FindTransaction(ctx context.Context, transactionID str
 // FindTransactionsByWalletID finds transactions for a wallet with pagination (non-transactional)
 func (s *GormWalletStore) FindTransactionsByWalletID(ctx context.Context, walletID string, limit int, offset int) ([]Transaction, error) {
 	var models []TransactionModel
-	result := s.db.WithContext(ctx).Table(s.transactionTable).Where("wallet_id = ?", walletID).Order("created_at DESC").Limit(limit).Offset(offset).Find(&models)
+	result := s.transactions(ctx).Where("wallet_id = ?", walletID).Order("created_at DESC").Limit(limit).Offset(offset).Find(&models)
 	if result.Error != nil {
 		return nil, result.Error
 	}
@@ -484,7 +969,7 @@ func (s *GormWalletStore) FindTransactionsByWalletID(ctx context.Context, wallet
 // FindTransactionsByUserID finds transactions for a user with pagination (non-transactional)
 func (s *GormWalletStore) FindTransactionsByUserID(ctx context.Context, userID string, limit int, offset int) ([]Transaction, error) {
 	var models []TransactionModel
-	result := s.db.WithContext(ctx).Table(s.transactionTable).
+	result := s.transactions(ctx).
 		Joins("JOIN "+s.walletTable+" ON "+s.transactionTable+".wallet_id = "+s.walletTable+".id").
 		Where(s.walletTable+".user_id = ?", userID).
 		Order(s.transactionTable + ".created_at DESC").
@@ -503,12 +988,278 @@ func (s *GormWalletStore) FindTransactionsByUserID(ctx context.Context, userID s
 	return transactions, nil
 }
 
+// FindCompletedTransactionsByWalletID finds the completed transactions of a wallet in the order
+// they were applied, with pagination (non-transactional)
+func (s *GormWalletStore) FindCompletedTransactionsByWalletID(ctx context.Context, walletID string, limit int, offset int) ([]Transaction, error) {
+	var models []TransactionModel
+	result := s.transactions(ctx).
+		Where("wallet_id = ? AND status = ?", walletID, TransactionStatusCompleted).
+		Order("completed_at ASC, created_at ASC, id ASC").
+		Limit(limit).
+		Offset(offset).
+		Find(&models)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	transactions := make([]Transaction, len(models))
+	for i, model := range models {
+		transaction := model.ToTransaction()
+		transactions[i] = *transaction
+	}
+	return transactions, nil
+}
+
+// FindTransactionsAfter lists the transactions following the given one, from the first transaction
+// for nil, grouped by wallet and each wallet's in the order they were applied. Unlike offsets, the
+// position is kept while transactions are saved concurrently (non-transactional)
+func (s *GormWalletStore) FindTransactionsAfter(ctx context.Context, after *Transaction, limit int) ([]Transaction, error) {
+	query := s.transactions(ctx)
+	if after != nil {
+		query = query.Where(
+			"wallet_id > ? OR (wallet_id = ? AND (completed_at > ? OR (completed_at = ? AND (created_at > ? OR (created_at = ? AND id > ?)))))",
+			after.WalletID, after.WalletID, after.CompletedAt, after.CompletedAt, after.CreatedAt, after.CreatedAt, after.ID,
+		)
+	}
+
+	var models []TransactionModel
+	result := query.
+		Order("wallet_id ASC, completed_at ASC, created_at ASC, id ASC").
+		Limit(limit).
+		Find(&models)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	transactions := make([]Transaction, len(models))
+	for i, model := range models {
+		transaction := model.ToTransaction()
+		transactions[i] = *transaction
+	}
+	return transactions, nil
+}
+
+// FindCompletedTransactionsByWalletIDBetween finds the completed transactions of a wallet completed
+// after the first and at or before the second time, in the order they were applied, with pagination
+// (non-transactional)
+func (s *GormWalletStore) FindCompletedTransactionsByWalletIDBetween(ctx context.Context, walletID string, after time.Time, until time.Time, limit int, offset int) ([]Transaction, error) {
+	var models []TransactionModel
+	result := s.transactions(ctx).
+		Where("wallet_id = ? AND status = ? AND completed_at > ? AND completed_at <= ?", walletID, TransactionStatusCompleted, after, until).
+		Order("completed_at ASC, created_at ASC, id ASC").
+		Limit(limit).
+		Offset(offset).
+		Find(&models)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	transactions := make([]Transaction, len(models))
+	for i, model := range models {
+		transaction := model.ToTransaction()
+		transactions[i] = *transaction
+	}
+	return transactions, nil
+}
+
+// FindChainedTransactionsByWalletID finds the transactions in a wallet's hash chain in chain order,
+// with pagination (non-transactional)
+func (s *GormWalletStore) FindChainedTransactionsByWalletID(ctx context.Context, walletID string, limit int, offset int) ([]Transaction, error) {
+	var models []TransactionModel
+	result := s.transactions(ctx).
+		Where("wallet_id = ? AND chain_sequence IS NOT NULL", walletID).
+		Order("chain_sequence ASC").
+		Limit(limit).
+		Offset(offset).
+		Find(&models)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	transactions := make([]Transaction, len(models))
+	for i, model := range models {
+		transaction := model.ToTransaction()
+		transactions[i] = *transaction
+	}
+	return transactions, nil
+}
+
+// CountTransactionsByStatus counts the transactions with the given status (non-transactional)
+func (s *GormWalletStore) CountTransactionsByStatus(ctx context.Context, status TransactionStatus) (int64, error) {
+	var count int64
+	result := s.transactions(ctx).Where("status = ?", status).Count(&count)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	return count, nil
+}
+
 // UpdateTransaction updates an existing transaction (non-transactional)
-func (s *GormWalletStore) UpdateTransaction(ctx context.Context, transaction *Transaction) error {
+func (s *GormWalletStore) UpdateTransaction(ctx context.Context, transaction *Transaction) (err error) {
+	defer func() { s.log.mutation(ctx, "UpdateTransaction", false, err, s.log.transactionAttrs(transaction)...) }()
+
+	transaction.TenantID = TenantFromContext(ctx)
+
+	db := s.db.WithContext(ctx)
+	if err := chainTransactions(db, s.walletTable, s.transactionTable, []*Transaction{transaction}); err != nil {
+		return err
+	}
+
 	model := &TransactionModel{}
 	if err := model.FromTransaction(transaction); err != nil {
 		return err
 	}
 
-	return s.db.WithContext(ctx).Table(s.transactionTable).Save(model).Error
+	// Update all columns of the row only if it belongs to the tenant
+	return s.transactions(ctx).Select("*").Updates(model).Error
+}
+
+// signedAmountExpression is the SQL expression for the change in balance caused by a transaction
+const signedAmountExpression = "COALESCE(SUM(CASE WHEN type = 'debit' THEN -amount ELSE amount END), 0)"
+
+// SumTransactionAmountsByWalletID sums the balance changes of a wallet's completed transactions
+// completed after the first and at or before the second time (non-transactional)
+func (s *GormWalletStore) SumTransactionAmountsByWalletID(ctx context.Context, walletID string, after time.Time, until time.Time) (int64, error) {
+	var sum int64
+	result := s.transactions(ctx).
+		Select(signedAmountExpression).
+		Where("wallet_id = ? AND status = ? AND completed_at > ? AND completed_at <= ?", walletID, TransactionStatusCompleted, after, until).
+		Scan(&sum)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	return sum, nil
+}
+
+// SumTransactionAmounts sums the balance changes of all completed transactions completed after the
+// first and at or before the second time (non-transactional)
+func (s *GormWalletStore) SumTransactionAmounts(ctx context.Context, after time.Time, until time.Time) (int64, error) {
+	var sum int64
+	result := s.transactions(ctx).
+		Select(signedAmountExpression).
+		Where("status = ? AND completed_at > ? AND completed_at <= ?", TransactionStatusCompleted, after, until).
+		Scan(&sum)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	return sum, nil
+}
+
+// SumTransactionTotalsByWalletID sums the credits and debits of a wallet's completed transactions
+// completed after the first and at or before the second time (non-transactional)
+func (s *GormWalletStore) SumTransactionTotalsByWalletID(ctx context.Context, walletID string, after time.Time, until time.Time) (int64, int64, error) {
+	var totals struct {
+		Credits int64
+		Debits  int64
+	}
+	result := s.transactions(ctx).
+		Select("COALESCE(SUM(CASE WHEN type = 'credit' THEN amount ELSE 0 END), 0) AS credits, "+
+			"COALESCE(SUM(CASE WHEN type = 'debit' THEN amount ELSE 0 END), 0) AS debits").
+		Where("wallet_id = ? AND status = ? AND completed_at > ? AND completed_at <= ?", walletID, TransactionStatusCompleted, after, until).
+		Scan(&totals)
+	if result.Error != nil {
+		return 0, 0, result.Error
+	}
+	return totals.Credits, totals.Debits, nil
+}
+
+// reportBucketLayout is the layout of the bucket start times returned by reportBucketExpression
+const reportBucketLayout = "2006-01-02 15:04:05"
+
+// reportBucketFormats are the strftime-style formats truncating a timestamp to its bucket
+var reportBucketFormats = map[ReportInterval]string{
+	ReportIntervalHour:  "%Y-%m-%d %H:00:00",
+	ReportIntervalDay:   "%Y-%m-%d 00:00:00",
+	ReportIntervalMonth: "%Y-%m-01 00:00:00",
+}
+
+// reportTimeExpression is the time transactions are reported at: completed_at for completed
+// transactions, which may have been created pending in an earlier bucket, and created_at otherwise
+const reportTimeExpression = "CASE WHEN status = 'completed' THEN completed_at ELSE created_at END"
+
+// reportBucketExpression returns the SQL expression truncating reportTimeExpression to the start of its
+// bucket, formatted with reportBucketLayout, in the dialect of the database
+func (s *GormWalletStore) reportBucketExpression(interval ReportInterval) (string, error) {
+	if _, ok := reportBucketFormats[interval]; !ok {
+		return "", ErrInvalidReportInterval
+	}
+
+	switch s.db.Dialector.Name() {
+	case "postgres":
+		return "to_char(date_trunc('" + string(interval) + "', " + reportTimeExpression + "), 'YYYY-MM-DD HH24:MI:SS')", nil
+	case "mysql":
+		return "DATE_FORMAT(" + reportTimeExpression + ", '" + reportBucketFormats[interval] + "')", nil
+	case "sqlserver":
+		return "CONVERT(varchar(19), DATEADD(" + string(interval) + ", DATEDIFF(" + string(interval) + ", 0, " + reportTimeExpression + "), 0), 120)", nil
+	default:
+		return "strftime('" + reportBucketFormats[interval] + "', " + reportTimeExpression + ")", nil
+	}
+}
+
+// AggregateTransactions computes per-bucket totals of the transactions completed, or created if not
+// completed, at or after from and before to, in bucket order. Buckets without transactions are omitted.
+// Bucket boundaries follow the database time zone, which is UTC for SQLite (non-transactional)
+func (s *GormWalletStore) AggregateTransactions(ctx context.Context, interval ReportInterval, from time.Time, to time.Time, filter ReportFilter) ([]TransactionAggregate, error) {
+	bucket, err := s.reportBucketExpression(interval)
+	if err != nil {
+		return nil, err
+	}
+
+	query := s.transactions(ctx).
+		Select(bucket+" AS bucket, "+
+			"COALESCE(SUM(CASE WHEN type = 'credit' THEN amount ELSE 0 END), 0) AS credited, "+
+			"COALESCE(SUM(CASE WHEN type = 'debit' THEN amount ELSE 0 END), 0) AS debited, "+
+			"COUNT(CASE WHEN type = 'credit' THEN 1 END) AS credit_count, "+
+			"COUNT(CASE WHEN type = 'debit' THEN 1 END) AS debit_count, "+
+			"COUNT(*) AS transaction_count, "+
+			"COUNT(DISTINCT wallet_id) AS active_wallets").
+		Where(reportTimeExpression+" >= ? AND "+reportTimeExpression+" < ?", from, to)
+
+	if filter.WalletID != "" {
+		query = query.Where("wallet_id = ?", filter.WalletID)
+	}
+	if filter.UserID != "" {
+		query = query.Where("wallet_id IN (?)", s.wallets(ctx).Select("id").Where("user_id = ?", filter.UserID))
+	}
+	if len(filter.Types) > 0 {
+		query = query.Where("type IN ?", filter.Types)
+	}
+	statuses := filter.Statuses
+	if len(statuses) == 0 {
+		statuses = []TransactionStatus{TransactionStatusCompleted}
+	}
+	query = query.Where("status IN ?", statuses)
+
+	var rows []struct {
+		Bucket           string
+		Credited         int64
+		Debited          int64
+		CreditCount      int64
+		DebitCount       int64
+		TransactionCount int64
+		ActiveWallets    int64
+	}
+	result := query.Group(bucket).Order("bucket ASC").Scan(&rows)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	aggregates := make([]TransactionAggregate, len(rows))
+	for i, row := range rows {
+		start, err := time.Parse(reportBucketLayout, row.Bucket)
+		if err != nil {
+			return nil, err
+		}
+
+		aggregates[i] = TransactionAggregate{
+			Bucket:           start,
+			Credited:         row.Credited,
+			Debited:          row.Debited,
+			CreditCount:      row.CreditCount,
+			DebitCount:       row.DebitCount,
+			TransactionCount: row.TransactionCount,
+			ActiveWallets:    row.ActiveWallets,
+		}
+	}
+	return aggregates, nil
 }
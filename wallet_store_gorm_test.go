@@ -170,6 +170,33 @@ func TestGormTxn_FindWallet(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+// TestGormTxn_LockWallets tests the LockWallets method of GormTxn
+func TestGormTxn_LockWallets(t *testing.T) {
+	store := setupTestGormWalletStore(t)
+
+	ctx := context.Background()
+	txn := store.Begin(ctx)
+	defer txn.Rollback()
+
+	for _, id := range []string{"wallet-c", "wallet-a", "wallet-b"} {
+		wallet := createTestWallet()
+		wallet.ID = id
+		wallet.Reference = id
+		require.NoError(t, txn.SaveWallet(wallet))
+	}
+
+	// Wallets are returned in ascending ID order whatever the order requested
+	wallets, err := txn.LockWallets([]string{"wallet-c", "non-existent-id", "wallet-a"})
+	assert.NoError(t, err)
+	require.Len(t, wallets, 2)
+	assert.Equal(t, "wallet-a", wallets[0].ID)
+	assert.Equal(t, "wallet-c", wallets[1].ID)
+
+	wallets, err = txn.LockWallets(nil)
+	assert.NoError(t, err)
+	assert.Empty(t, wallets)
+}
+
 // TestGormTxn_FindWalletsByUserID tests the FindWalletsByUserID method of GormTxn
 func TestGormTxn_FindWalletsByUserID(t *testing.T) {
 	store := setupTestGormWalletStore(t)
@@ -514,6 +541,131 @@ func TestGormTxn_UpdateTransaction(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+// TestGormTxn_FindWalletsByIDs tests the FindWalletsByIDs method of GormTxn
+func TestGormTxn_FindWalletsByIDs(t *testing.T) {
+	store := setupTestGormWalletStore(t)
+
+	ctx := context.Background()
+	txn := store.Begin(ctx)
+
+	wallet1 := createTestWallet()
+	err := txn.SaveWallet(wallet1)
+	require.NoError(t, err)
+
+	wallet2 := createTestWallet()
+	wallet2.ID = "test-wallet-id-2"
+	err = txn.SaveWallet(wallet2)
+	require.NoError(t, err)
+
+	// Test finding existing and non-existent wallets together
+	wallets, err := txn.FindWalletsByIDs([]string{wallet1.ID, wallet2.ID, "non-existent-id"})
+	assert.NoError(t, err)
+	assert.Len(t, wallets, 2)
+
+	// Test finding with no IDs
+	noWallets, err := txn.FindWalletsByIDs(nil)
+	assert.NoError(t, err)
+	assert.Empty(t, noWallets)
+
+	err = txn.Commit()
+	assert.NoError(t, err)
+}
+
+// TestGormTxn_SaveTransactions tests the SaveTransactions method of GormTxn
+func TestGormTxn_SaveTransactions(t *testing.T) {
+	store := setupTestGormWalletStore(t)
+
+	ctx := context.Background()
+	txn := store.Begin(ctx)
+
+	wallet := createTestWallet()
+	err := txn.SaveWallet(wallet)
+	require.NoError(t, err)
+
+	// Save more transactions than fit in a single insert batch
+	transactions := make([]Transaction, transactionInsertBatchSize+5)
+	for i := range transactions {
+		transaction := createTestTransaction(wallet.ID)
+		transaction.ID = GenerateID()
+		transactions[i] = *transaction
+	}
+
+	err = txn.SaveTransactions(transactions)
+	assert.NoError(t, err)
+
+	// Verify all transactions were saved with their data
+	found, err := txn.FindTransactionsByWalletID(wallet.ID, len(transactions)+1, 0)
+	assert.NoError(t, err)
+	assert.Len(t, found, len(transactions))
+	assert.Equal(t, "test_value", found[0].Data["test_key"])
+
+	// Test saving an empty slice
+	err = txn.SaveTransactions(nil)
+	assert.NoError(t, err)
+
+	err = txn.Commit()
+	assert.NoError(t, err)
+}
+
+// TestGormTxn_FindTransactionsByIDs tests the FindTransactionsByIDs method of GormTxn
+func TestGormTxn_FindTransactionsByIDs(t *testing.T) {
+	store := setupTestGormWalletStore(t)
+
+	ctx := context.Background()
+	txn := store.Begin(ctx)
+
+	wallet := createTestWallet()
+	err := txn.SaveWallet(wallet)
+	require.NoError(t, err)
+
+	transaction := createTestTransaction(wallet.ID)
+	err = txn.SaveTransaction(transaction)
+	require.NoError(t, err)
+
+	// Test finding existing and non-existent transactions together
+	transactions, err := txn.FindTransactionsByIDs([]string{transaction.ID, "non-existent-id"})
+	assert.NoError(t, err)
+	require.Len(t, transactions, 1)
+	assert.Equal(t, transaction.ID, transactions[0].ID)
+
+	// Test finding with no IDs
+	noTransactions, err := txn.FindTransactionsByIDs(nil)
+	assert.NoError(t, err)
+	assert.Empty(t, noTransactions)
+
+	err = txn.Commit()
+	assert.NoError(t, err)
+}
+
+// TestGormTxn_FindWallets tests the FindWallets method of GormTxn
+func TestGormTxn_FindWallets(t *testing.T) {
+	store := setupTestGormWalletStore(t)
+
+	ctx := context.Background()
+	txn := store.Begin(ctx)
+
+	for i := 0; i < 3; i++ {
+		wallet := createTestWallet()
+		wallet.ID = "wallet-id-" + string(rune('1'+i))
+		err := txn.SaveWallet(wallet)
+		require.NoError(t, err)
+	}
+
+	// Test finding wallets with pagination, ordered by ID
+	wallets, err := txn.FindWallets(2, 0)
+	assert.NoError(t, err)
+	require.Len(t, wallets, 2)
+	assert.Equal(t, "wallet-id-1", wallets[0].ID)
+
+	wallets, err = txn.FindWallets(2, 2)
+	assert.NoError(t, err)
+	require.Len(t, wallets, 1)
+	assert.Equal(t, "wallet-id-3", wallets[0].ID)
+
+	err = txn.Commit()
+	assert.NoError(t, err)
+}
+
 // TestGormWalletStore_SaveWallet tests the non-transactional SaveWallet method
 func TestGormWalletStore_SaveWallet(t *testing.T) {
 	store := setupTestGormWalletStore(t)
@@ -838,3 +990,415 @@ func TestGormWalletStore_UpdateTransaction(t *testing.T) {
 	assert.Equal(t, "Test failure reason", updatedTransaction.FailedReason)
 	assert.Equal(t, "Updated description", updatedTransaction.Description)
 }
+
+// TestGormWalletStore_FindCompletedTransactionsByWalletID tests the FindCompletedTransactionsByWalletID method of GormWalletStore
+func TestGormWalletStore_FindCompletedTransactionsByWalletID(t *testing.T) {
+	store := setupTestGormWalletStore(t)
+	ctx := context.Background()
+
+	wallet := createTestWallet()
+	err := store.SaveWallet(ctx, wallet)
+	require.NoError(t, err)
+
+	// Create transactions completed in reverse order of creation
+	base := time.Now()
+	for i := 0; i < 3; i++ {
+		transaction := createTestTransaction(wallet.ID)
+		transaction.ID = "tx-id-" + string(rune('1'+i))
+		transaction.CreatedAt = base.Add(time.Duration(i) * time.Second)
+		transaction.CompletedAt = base.Add(time.Duration(10-i) * time.Second)
+		err = store.SaveTransaction(ctx, transaction)
+		require.NoError(t, err)
+	}
+
+	pending := createTestTransaction(wallet.ID)
+	pending.ID = "tx-id-pending"
+	pending.Status = TransactionStatusPending
+	pending.CompletedAt = time.Time{}
+	err = store.SaveTransaction(ctx, pending)
+	require.NoError(t, err)
+
+	// Test finding completed transactions in completion order
+	transactions, err := store.FindCompletedTransactionsByWalletID(ctx, wallet.ID, 10, 0)
+	assert.NoError(t, err)
+	require.Len(t, transactions, 3)
+	assert.Equal(t, "tx-id-3", transactions[0].ID)
+	assert.Equal(t, "tx-id-1", transactions[2].ID)
+
+	// Test pagination
+	transactions, err = store.FindCompletedTransactionsByWalletID(ctx, wallet.ID, 2, 2)
+	assert.NoError(t, err)
+	require.Len(t, transactions, 1)
+	assert.Equal(t, "tx-id-1", transactions[0].ID)
+}
+
+// TestGormWalletStore_SumTransactionAmounts tests the SumTransactionAmountsByWalletID and SumTransactionAmounts methods of GormWalletStore
+func TestGormWalletStore_SumTransactionAmounts(t *testing.T) {
+	store := setupTestGormWalletStore(t)
+	ctx := context.Background()
+
+	wallet := createTestWallet()
+	err := store.SaveWallet(ctx, wallet)
+	require.NoError(t, err)
+
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, amount := range []int64{500, 200, 100} {
+		transaction := createTestTransaction(wallet.ID)
+		transaction.ID = "tx-id-" + string(rune('1'+i))
+		transaction.Amount = amount
+		if i == 1 {
+			transaction.Type = TransactionTypeDebit
+		}
+		transaction.CompletedAt = base.Add(time.Duration(i+1) * time.Hour)
+		err = store.SaveTransaction(ctx, transaction)
+		require.NoError(t, err)
+	}
+
+	other := createTestTransaction("other-wallet-id")
+	other.ID = "tx-id-other"
+	other.CompletedAt = base.Add(time.Hour)
+	err = store.SaveTransaction(ctx, other)
+	require.NoError(t, err)
+
+	pending := createTestTransaction(wallet.ID)
+	pending.ID = "tx-id-pending"
+	pending.Status = TransactionStatusPending
+	pending.CompletedAt = base.Add(time.Hour)
+	err = store.SaveTransaction(ctx, pending)
+	require.NoError(t, err)
+
+	// Test summing with debits subtracted and pending transactions ignored
+	sum, err := store.SumTransactionAmountsByWalletID(ctx, wallet.ID, time.Time{}, base.Add(3*time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(400), sum)
+
+	// Test that the lower bound is exclusive and the upper bound inclusive
+	sum, err = store.SumTransactionAmountsByWalletID(ctx, wallet.ID, base.Add(time.Hour), base.Add(2*time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(-200), sum)
+
+	// Test summing with no matching transactions
+	sum, err = store.SumTransactionAmountsByWalletID(ctx, wallet.ID, time.Time{}, base)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), sum)
+
+	// Test summing across all wallets
+	sum, err = store.SumTransactionAmounts(ctx, time.Time{}, base.Add(3*time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(900), sum)
+}
+
+// TestGormWalletStore_FindCompletedTransactionsByWalletIDBetween tests the FindCompletedTransactionsByWalletIDBetween and SumTransactionTotalsByWalletID methods of GormWalletStore
+func TestGormWalletStore_FindCompletedTransactionsByWalletIDBetween(t *testing.T) {
+	store := setupTestGormWalletStore(t)
+	ctx := context.Background()
+
+	wallet := createTestWallet()
+	err := store.SaveWallet(ctx, wallet)
+	require.NoError(t, err)
+
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 4; i++ {
+		transaction := createTestTransaction(wallet.ID)
+		transaction.ID = "tx-id-" + string(rune('1'+i))
+		transaction.Amount = int64(100 * (i + 1))
+		if i%2 == 1 {
+			transaction.Type = TransactionTypeDebit
+		}
+		transaction.CompletedAt = base.Add(time.Duration(i) * time.Hour)
+		err = store.SaveTransaction(ctx, transaction)
+		require.NoError(t, err)
+	}
+
+	// Test that the lower bound is exclusive and the upper bound inclusive
+	transactions, err := store.FindCompletedTransactionsByWalletIDBetween(ctx, wallet.ID, base, base.Add(2*time.Hour), 10, 0)
+	assert.NoError(t, err)
+	require.Len(t, transactions, 2)
+	assert.Equal(t, "tx-id-2", transactions[0].ID)
+	assert.Equal(t, "tx-id-3", transactions[1].ID)
+
+	// Test pagination
+	transactions, err = store.FindCompletedTransactionsByWalletIDBetween(ctx, wallet.ID, time.Time{}, base.Add(3*time.Hour), 2, 2)
+	assert.NoError(t, err)
+	require.Len(t, transactions, 2)
+	assert.Equal(t, "tx-id-3", transactions[0].ID)
+
+	// Test credit and debit totals
+	credits, debits, err := store.SumTransactionTotalsByWalletID(ctx, wallet.ID, base, base.Add(3*time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(300), credits)
+	assert.Equal(t, int64(600), debits)
+
+	credits, debits, err = store.SumTransactionTotalsByWalletID(ctx, "non-existent-id", time.Time{}, base.Add(3*time.Hour))
+	assert.NoError(t, err)
+	assert.Zero(t, credits)
+	assert.Zero(t, debits)
+}
+
+// TestGormWalletStore_FindTransactionsAfter tests the FindTransactionsAfter method of GormWalletStore
+func TestGormWalletStore_FindTransactionsAfter(t *testing.T) {
+	store := setupTestGormWalletStore(t)
+	ctx := context.Background()
+
+	base := time.Now()
+	for i, walletID := range []string{"wallet-b", "wallet-a", "wallet-b", "wallet-a"} {
+		transaction := createTestTransaction(walletID)
+		transaction.ID = "tx-id-" + string(rune('1'+i))
+		transaction.CompletedAt = base.Add(time.Duration(10-i) * time.Second)
+		err := store.SaveTransaction(ctx, transaction)
+		require.NoError(t, err)
+	}
+
+	// Test that transactions are grouped by wallet in completion order
+	transactions, err := store.FindTransactionsAfter(ctx, nil, 10)
+	assert.NoError(t, err)
+	require.Len(t, transactions, 4)
+	ids := make([]string, len(transactions))
+	for i, transaction := range transactions {
+		ids[i] = transaction.ID
+	}
+	assert.Equal(t, []string{"tx-id-4", "tx-id-2", "tx-id-3", "tx-id-1"}, ids)
+
+	// Test continuing after a transaction
+	transactions, err = store.FindTransactionsAfter(ctx, &transactions[2], 3)
+	assert.NoError(t, err)
+	require.Len(t, transactions, 1)
+	assert.Equal(t, "tx-id-1", transactions[0].ID)
+
+	transactions, err = store.FindTransactionsAfter(ctx, &transactions[0], 3)
+	assert.NoError(t, err)
+	assert.Empty(t, transactions)
+}
+
+// TestGormWalletStore_FindTransactionsAfterTies tests paging through transactions with equal timestamps
+func TestGormWalletStore_FindTransactionsAfterTies(t *testing.T) {
+	store := setupTestGormWalletStore(t)
+	ctx := context.Background()
+
+	at := time.Now()
+	for _, id := range []string{"tx-id-3", "tx-id-1", "tx-id-2"} {
+		transaction := createTestTransaction("wallet-a")
+		transaction.ID = id
+		transaction.CreatedAt = at
+		transaction.CompletedAt = at
+		require.NoError(t, store.SaveTransaction(ctx, transaction))
+	}
+
+	ids := make([]string, 0)
+	var after *Transaction
+	for {
+		transactions, err := store.FindTransactionsAfter(ctx, after, 1)
+		require.NoError(t, err)
+		if len(transactions) == 0 {
+			break
+		}
+		ids = append(ids, transactions[0].ID)
+		after = &transactions[0]
+	}
+	assert.Equal(t, []string{"tx-id-1", "tx-id-2", "tx-id-3"}, ids)
+}
+
+// TestGormWalletStore_FindWalletsAfter tests the non-transactional FindWalletsAfter method
+func TestGormWalletStore_FindWalletsAfter(t *testing.T) {
+	store := setupTestGormWalletStore(t)
+	ctx := context.Background()
+
+	for _, id := range []string{"wallet-c", "wallet-a", "wallet-b"} {
+		wallet := createTestWallet()
+		wallet.ID = id
+		wallet.Reference = id
+		require.NoError(t, store.SaveWallet(ctx, wallet))
+	}
+
+	wallets, err := store.FindWalletsAfter(ctx, "", 2)
+	assert.NoError(t, err)
+	require.Len(t, wallets, 2)
+	assert.Equal(t, "wallet-a", wallets[0].ID)
+	assert.Equal(t, "wallet-b", wallets[1].ID)
+
+	wallets, err = store.FindWalletsAfter(ctx, "wallet-b", 2)
+	assert.NoError(t, err)
+	require.Len(t, wallets, 1)
+	assert.Equal(t, "wallet-c", wallets[0].ID)
+
+	// Other tenants' wallets are skipped
+	wallets, err = store.FindWalletsAfter(WithTenant(ctx, "tenant-b"), "", 2)
+	assert.NoError(t, err)
+	assert.Empty(t, wallets)
+}
+
+// TestGormWalletStore_AggregateTransactions tests the AggregateTransactions method of GormWalletStore
+func TestGormWalletStore_AggregateTransactions(t *testing.T) {
+	store := setupTestGormWalletStore(t)
+	ctx := context.Background()
+
+	wallet1 := createTestWallet()
+	require.NoError(t, store.SaveWallet(ctx, wallet1))
+	wallet2 := createTestWallet()
+	wallet2.ID = "test-wallet-id-2"
+	wallet2.UserID = "other-user-id"
+	require.NoError(t, store.SaveWallet(ctx, wallet2))
+
+	for i, entry := range []struct {
+		walletID        string
+		transactionType TransactionType
+		amount          int64
+		status          TransactionStatus
+		createdAt       time.Time
+	}{
+		{wallet1.ID, TransactionTypeCredit, 100, TransactionStatusCompleted, time.Date(2025, 1, 1, 9, 15, 0, 0, time.UTC)},
+		{wallet1.ID, TransactionTypeDebit, 30, TransactionStatusCompleted, time.Date(2025, 1, 1, 9, 45, 0, 0, time.UTC)},
+		{wallet2.ID, TransactionTypeCredit, 200, TransactionStatusCompleted, time.Date(2025, 1, 1, 17, 0, 0, 0, time.UTC)},
+		{wallet2.ID, TransactionTypeCredit, 50, TransactionStatusPending, time.Date(2025, 1, 2, 8, 0, 0, 0, time.UTC)},
+		{wallet1.ID, TransactionTypeDebit, 40, TransactionStatusFailed, time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)},
+		{wallet1.ID, TransactionTypeDebit, 20, TransactionStatusCompleted, time.Date(2025, 2, 3, 12, 0, 0, 0, time.FixedZone("UTC+8", 8*60*60))},
+	} {
+		transaction := createTestTransaction(entry.walletID)
+		transaction.ID = "tx-id-" + string(rune('1'+i))
+		transaction.Type = entry.transactionType
+		transaction.Amount = entry.amount
+		transaction.Status = entry.status
+		transaction.CreatedAt = entry.createdAt
+		transaction.CompletedAt = entry.createdAt
+		require.NoError(t, store.SaveTransaction(ctx, transaction))
+	}
+
+	from := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	// Test daily buckets, which only include completed transactions by default
+	aggregates, err := store.AggregateTransactions(ctx, ReportIntervalDay, from, to, ReportFilter{})
+	assert.NoError(t, err)
+	require.Len(t, aggregates, 2)
+	assert.Equal(t, TransactionAggregate{
+		Bucket:           time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		Credited:         300,
+		Debited:          30,
+		CreditCount:      2,
+		DebitCount:       1,
+		TransactionCount: 3,
+		ActiveWallets:    2,
+	}, aggregates[0])
+	assert.Equal(t, time.Date(2025, 2, 3, 0, 0, 0, 0, time.UTC), aggregates[1].Bucket)
+	assert.Equal(t, int64(20), aggregates[1].Debited)
+
+	// Test hourly buckets
+	aggregates, err = store.AggregateTransactions(ctx, ReportIntervalHour, from, time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC), ReportFilter{})
+	assert.NoError(t, err)
+	require.Len(t, aggregates, 2)
+	assert.Equal(t, time.Date(2025, 1, 1, 9, 0, 0, 0, time.UTC), aggregates[0].Bucket)
+	assert.Equal(t, int64(2), aggregates[0].TransactionCount)
+
+	// Test monthly buckets with filters
+	aggregates, err = store.AggregateTransactions(ctx, ReportIntervalMonth, from, to, ReportFilter{Statuses: []TransactionStatus{TransactionStatusCompleted, TransactionStatusPending, TransactionStatusFailed}})
+	assert.NoError(t, err)
+	require.Len(t, aggregates, 2)
+	assert.Equal(t, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), aggregates[0].Bucket)
+	assert.Equal(t, int64(350), aggregates[0].Credited)
+	assert.Equal(t, int64(70), aggregates[0].Debited)
+	assert.Equal(t, int64(5), aggregates[0].TransactionCount)
+
+	aggregates, err = store.AggregateTransactions(ctx, ReportIntervalMonth, from, to, ReportFilter{Statuses: []TransactionStatus{TransactionStatusFailed}})
+	assert.NoError(t, err)
+	require.Len(t, aggregates, 1)
+	assert.Equal(t, int64(40), aggregates[0].Debited)
+
+	aggregates, err = store.AggregateTransactions(ctx, ReportIntervalMonth, from, to, ReportFilter{UserID: "other-user-id"})
+	assert.NoError(t, err)
+	require.Len(t, aggregates, 1)
+	assert.Equal(t, int64(200), aggregates[0].Credited)
+	assert.Equal(t, int64(1), aggregates[0].ActiveWallets)
+
+	aggregates, err = store.AggregateTransactions(ctx, ReportIntervalMonth, from, to, ReportFilter{WalletID: wallet1.ID, Types: []TransactionType{TransactionTypeDebit}})
+	assert.NoError(t, err)
+	require.Len(t, aggregates, 2)
+	assert.Equal(t, int64(0), aggregates[0].Credited)
+	assert.Equal(t, int64(30), aggregates[0].Debited)
+
+	// Test the range is half-open
+	aggregates, err = store.AggregateTransactions(ctx, ReportIntervalDay, from, time.Date(2025, 1, 1, 9, 15, 0, 0, time.UTC), ReportFilter{})
+	assert.NoError(t, err)
+	assert.Empty(t, aggregates)
+
+	// Test a pending transaction completed on a later day is reported on the day it completed
+	transaction, err := store.FindTransaction(ctx, "tx-id-4")
+	require.NoError(t, err)
+	transaction.Status = TransactionStatusCompleted
+	transaction.CompletedAt = time.Date(2025, 2, 4, 6, 0, 0, 0, time.UTC)
+	require.NoError(t, store.UpdateTransaction(ctx, transaction))
+
+	aggregates, err = store.AggregateTransactions(ctx, ReportIntervalDay, from, to, ReportFilter{})
+	assert.NoError(t, err)
+	require.Len(t, aggregates, 3)
+	assert.Equal(t, int64(300), aggregates[0].Credited)
+	assert.Equal(t, time.Date(2025, 2, 4, 0, 0, 0, 0, time.UTC), aggregates[2].Bucket)
+	assert.Equal(t, int64(50), aggregates[2].Credited)
+
+	aggregates, err = store.AggregateTransactions(ctx, ReportIntervalDay, from, time.Date(2025, 2, 4, 0, 0, 0, 0, time.UTC), ReportFilter{})
+	assert.NoError(t, err)
+	require.Len(t, aggregates, 2)
+
+	// Test an invalid interval
+	_, err = store.AggregateTransactions(ctx, ReportInterval("week"), from, to, ReportFilter{})
+	assert.ErrorIs(t, err, ErrInvalidReportInterval)
+}
+
+// TestGormWalletStore_TenantScope tests that the store reads and writes only the records of the context's tenant
+func TestGormWalletStore_TenantScope(t *testing.T) {
+	store := setupTestGormWalletStore(t)
+	ctxA := WithTenant(context.Background(), "tenant-a")
+	ctxB := WithTenant(context.Background(), "tenant-b")
+
+	wallet := &Wallet{
+		ID:     GenerateID(),
+		UserID: "user-id",
+		Name:   "Wallet A",
+		Active: true,
+	}
+	require.NoError(t, store.SaveWallet(ctxA, wallet))
+	assert.Equal(t, "tenant-a", wallet.TenantID)
+
+	transaction := &Transaction{
+		ID:       GenerateID(),
+		WalletID: wallet.ID,
+		Type:     TransactionTypeCredit,
+		Amount:   100,
+		Status:   TransactionStatusPending,
+	}
+	require.NoError(t, store.SaveTransaction(ctxA, transaction))
+
+	// Updates through another tenant neither modify nor insert rows
+	hijacked := *wallet
+	hijacked.Name = "Hijacked"
+	assert.NoError(t, store.UpdateWallet(ctxB, &hijacked))
+
+	hijackedTransaction := *transaction
+	hijackedTransaction.Status = TransactionStatusCancelled
+	assert.NoError(t, store.UpdateTransaction(ctxB, &hijackedTransaction))
+
+	found, err := store.FindWallet(ctxA, wallet.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Wallet A", found.Name)
+	assert.Equal(t, "tenant-a", found.TenantID)
+
+	foundTransaction, err := store.FindTransaction(ctxA, transaction.ID)
+	require.NoError(t, err)
+	assert.Equal(t, TransactionStatusPending, foundTransaction.Status)
+
+	found, err = store.FindWallet(ctxB, wallet.ID)
+	assert.NoError(t, err)
+	assert.Nil(t, found)
+
+	// Transactions see only the records of their tenant
+	txn := store.Begin(ctxB)
+	found, err = txn.FindWallet(wallet.ID)
+	assert.NoError(t, err)
+	assert.Nil(t, found)
+	require.NoError(t, txn.Rollback())
+
+	txn = store.Begin(ctxA)
+	found, err = txn.FindWallet(wallet.ID)
+	assert.NoError(t, err)
+	assert.NotNil(t, found)
+	require.NoError(t, txn.Rollback())
+}
@@ -26,35 +26,50 @@ const (
 // Transaction represents a wallet transaction
 type Transaction struct {
 	ID           string                 `json:"id"`
+	TenantID     string                 `json:"tenant_id,omitempty"` // Set by the store from the context
 	WalletID     string                 `json:"wallet_id"`
 	Type         TransactionType        `json:"type"`
-	Amount       int64                  `json:"amount"`      // Points amount (positive number)
-	Balance      int64                  `json:"balance"`     // Balance after transaction
-	Description  string                 `json:"description"` // Brief description of the transaction
-	Note         string                 `json:"note"`        // Additional notes or remarks
-	Reference    string                 `json:"reference"`   // External reference (order ID, etc.)
+	Amount       int64                  `json:"amount"`                // Points amount (positive number)
+	Balance      int64                  `json:"balance"`               // Balance after transaction
+	Buckets      map[string]int64       `json:"buckets,omitempty"`     // Amount per balance bucket
+	DelegateID   string                 `json:"delegate_id,omitempty"` // Delegate who spent the amount under an allowance
+	Description  string                 `json:"description"`           // Brief description of the transaction
+	Note         string                 `json:"note"`                  // Additional notes or remarks
+	Reference    string                 `json:"reference"`             // External reference (order ID, etc.)
 	Status       TransactionStatus      `json:"status"`
 	Data         map[string]interface{} `json:"data"` // Flexible field for additional data
 	CreatedAt    time.Time              `json:"created_at"`
 	CompletedAt  time.Time              `json:"completed_at,omitempty"`
 	FailedReason string                 `json:"failed_reason,omitempty"`
+
+	// Hash chain fields, set by the store once the transaction leaves the pending state
+	ChainSequence int64  `json:"chain_sequence,omitempty"` // Position in the wallet's hash chain, starting at 1
+	PrevHash      string `json:"prev_hash,omitempty"`      // Hash of the previous transaction in the chain
+	Hash          string `json:"hash,omitempty"`           // Hash of this transaction's canonical content and PrevHash
 }
 
 // Wallet represents a point wallet
 type Wallet struct {
-	ID          string    `json:"id"`
-	UserID      string    `json:"user_id"`
-	Name        string    `json:"name"`                // Custom name for the wallet
-	Description string    `json:"description"`         // Detailed description of the wallet
-	Reference   string    `json:"reference"`           // External reference for associating with external systems
-	Balance     int64     `json:"balance"`             // Current balance
-	Primary     bool      `json:"primary"`             // Whether this is the primary/default wallet for the user
-	Active      bool      `json:"active"`              // Whether the wallet is active
-	Frozen      bool      `json:"frozen"`              // Whether the wallet is frozen
-	RiskFlagged bool      `json:"risk_flagged"`        // Whether the wallet is flagged for risk control
-	ClosedAt    time.Time `json:"closed_at,omitempty"` // When the wallet was closed, if applicable
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID                 string           `json:"id"`
+	TenantID           string           `json:"tenant_id,omitempty"` // Set by the store from the context
+	UserID             string           `json:"user_id"`
+	Name               string           `json:"name"`                  // Custom name for the wallet
+	Description        string           `json:"description"`           // Detailed description of the wallet
+	Reference          string           `json:"reference"`             // External reference for associating with external systems
+	Balance            int64            `json:"balance"`               // Current balance
+	Buckets            map[string]int64 `json:"buckets,omitempty"`     // Balances of named buckets; the rest is in the default bucket
+	Primary            bool             `json:"primary"`               // Whether this is the primary/default wallet for the user
+	Active             bool             `json:"active"`                // Whether the wallet is active
+	Frozen             bool             `json:"frozen"`                // Whether the wallet is frozen in any mode
+	FreezeMode         FreezeMode       `json:"freeze_mode,omitempty"` // Which transactions the freeze blocks
+	FreezeReason       string           `json:"freeze_reason,omitempty"`
+	FrozenAmount       int64            `json:"frozen_amount,omitempty"` // Part of the balance that cannot be debited
+	FrozenAmountReason string           `json:"frozen_amount_reason,omitempty"`
+	RiskFlagged        bool             `json:"risk_flagged"`          // Whether the wallet is flagged for risk control
+	RiskReason         string           `json:"risk_reason,omitempty"` // Why the wallet was flagged
+	ClosedAt           time.Time        `json:"closed_at,omitempty"`   // When the wallet was closed, if applicable
+	CreatedAt          time.Time        `json:"created_at"`
+	UpdatedAt          time.Time        `json:"updated_at"`
 }
 
 // WalletManager defines the interface for wallet operations
@@ -73,6 +88,7 @@ type WalletManager interface {
 
 	// Transaction operations
 	Credit(ctx context.Context, walletID string, amount int64, description string, note string, reference string, data map[string]interface{}) (*Transaction, error)
+	CreditBucket(ctx context.Context, walletID string, bucket string, amount int64, description string, note string, reference string, data map[string]interface{}) (*Transaction, error)
 	Debit(ctx context.Context, walletID string, amount int64, description string, note string, reference string, data map[string]interface{}) (*Transaction, error)
 	GetTransaction(ctx context.Context, transactionID string) (*Transaction, error)
 	ListTransactions(ctx context.Context, walletID string, limit int, offset int) ([]Transaction, error)
@@ -81,7 +97,9 @@ type WalletManager interface {
 	// Advanced operations
 	Transfer(ctx context.Context, fromWalletID string, toWalletID string, amount int64, description string, note string, data map[string]interface{}) error
 	FreezeWallet(ctx context.Context, walletID string, reason string) error
+	FreezeWalletWithMode(ctx context.Context, walletID string, mode FreezeMode, reason string) error
 	UnfreezeWallet(ctx context.Context, walletID string) error
+	SetFrozenAmount(ctx context.Context, walletID string, amount int64, reason string) error
 
 	// Transaction lifecycle
 	CancelTransaction(ctx context.Context, transactionID string, reason string) error
@@ -93,6 +111,57 @@ type WalletManager interface {
 	// Risk management
 	FlagWalletRisk(ctx context.Context, walletID string, reason string) error
 	ClearWalletRiskFlag(ctx context.Context, walletID string) error
+
+	// Delegated spending
+	GrantAllowance(ctx context.Context, walletID string, delegateID string, amount int64) (*Allowance, error)
+	RevokeAllowance(ctx context.Context, walletID string, delegateID string) error
+	GetAllowance(ctx context.Context, walletID string, delegateID string) (*Allowance, error)
+	ListAllowances(ctx context.Context, walletID string) ([]Allowance, error)
+	DebitAsDelegate(ctx context.Context, walletID string, delegateID string, amount int64, description string, note string, reference string, data map[string]interface{}) (*Transaction, error)
+	TransferFrom(ctx context.Context, fromWalletID string, toWalletID string, delegateID string, amount int64, description string, note string, data map[string]interface{}) error
+}
+
+// WalletOps defines the wallet operations of a unit of work, bound to its store transaction
+type WalletOps interface {
+	// Wallet management
+	CreateWallet(userID string, name string, description string, reference string) (*Wallet, error)
+	GetWallet(walletID string) (*Wallet, error)
+	SetPrimaryWallet(walletID string) error
+	UpdateWalletActive(walletID string, active bool) error
+	UpdateWalletName(walletID string, name string) error
+	UpdateWalletDescription(walletID string, description string) error
+	UpdateWalletReference(walletID string, reference string) error
+
+	// Transaction operations
+	Credit(walletID string, amount int64, description string, note string, reference string, data map[string]interface{}) (*Transaction, error)
+	CreditBucket(walletID string, bucket string, amount int64, description string, note string, reference string, data map[string]interface{}) (*Transaction, error)
+	Debit(walletID string, amount int64, description string, note string, reference string, data map[string]interface{}) (*Transaction, error)
+	GetTransaction(transactionID string) (*Transaction, error)
+
+	// Advanced operations
+	Transfer(fromWalletID string, toWalletID string, amount int64, description string, note string, data map[string]interface{}) error
+	FreezeWallet(walletID string, reason string) error
+	FreezeWalletWithMode(walletID string, mode FreezeMode, reason string) error
+	UnfreezeWallet(walletID string) error
+	SetFrozenAmount(walletID string, amount int64, reason string) error
+
+	// Transaction lifecycle
+	CancelTransaction(transactionID string, reason string) error
+	CompleteTransaction(transactionID string) error
+
+	// Risk management
+	FlagWalletRisk(walletID string, reason string) error
+	ClearWalletRiskFlag(walletID string) error
+
+	// Delegated spending
+	GrantAllowance(walletID string, delegateID string, amount int64) (*Allowance, error)
+	RevokeAllowance(walletID string, delegateID string) error
+	GetAllowance(walletID string, delegateID string) (*Allowance, error)
+	DebitAsDelegate(walletID string, delegateID string, amount int64, description string, note string, reference string, data map[string]interface{}) (*Transaction, error)
+	TransferFrom(fromWalletID string, toWalletID string, delegateID string, amount int64, description string, note string, data map[string]interface{}) error
+
+	// Nested unit of work, rolled back to a savepoint if it fails
+	WithinTx(fn func(ops WalletOps) error) error
 }
 
 // Txn defines transaction operations for wallet data
@@ -103,18 +172,33 @@ type Txn interface {
 	FindWalletsByUserID(userID string) ([]Wallet, error)
 	FindWalletByUserIDAndReference(userID string, reference string) (*Wallet, error)
 	FindPrimaryWalletByUserID(userID string) (*Wallet, error)
+	FindWalletsByIDs(walletIDs []string) ([]Wallet, error)
+	LockWallets(walletIDs []string) ([]Wallet, error)
+	FindWallets(limit int, offset int) ([]Wallet, error)
 	UpdateWallet(wallet *Wallet) error
 
 	// Transaction operations
 	SaveTransaction(transaction *Transaction) error
+	SaveTransactions(transactions []Transaction) error
 	FindTransaction(transactionID string) (*Transaction, error)
+	FindTransactionsByIDs(transactionIDs []string) ([]Transaction, error)
 	FindTransactionsByWalletID(walletID string, limit int, offset int) ([]Transaction, error)
 	FindTransactionsByUserID(userID string, limit int, offset int) ([]Transaction, error)
+	FindCompletedTransactionsByWalletID(walletID string, limit int, offset int) ([]Transaction, error)
+	FindChainedTransactionsByWalletID(walletID string, limit int, offset int) ([]Transaction, error)
+	SumTransactionTotalsByWalletID(walletID string, after time.Time, until time.Time) (int64, int64, error)
 	UpdateTransaction(transaction *Transaction) error
 
+	// Allowance operations
+	SaveAllowance(allowance *Allowance) error
+	FindAllowance(walletID string, delegateID string) (*Allowance, error)
+	DeleteAllowance(walletID string, delegateID string) error
+
 	// Transaction control
 	Commit() error
 	Rollback() error
+	SavePoint(name string) error
+	RollbackTo(name string) error
 }
 
 // WalletStore defines the data access layer interface
@@ -128,6 +212,8 @@ type WalletStore interface {
 	FindWalletsByUserID(ctx context.Context, userID string) ([]Wallet, error)
 	FindWalletByUserIDAndReference(ctx context.Context, userID string, reference string) (*Wallet, error)
 	FindPrimaryWalletByUserID(ctx context.Context, userID string) (*Wallet, error)
+	FindWallets(ctx context.Context, limit int, offset int) ([]Wallet, error)
+	FindWalletsAfter(ctx context.Context, afterID string, limit int) ([]Wallet, error)
 	UpdateWallet(ctx context.Context, wallet *Wallet) error
 
 	// Non-transactional transaction operations
@@ -135,5 +221,22 @@ type WalletStore interface {
 	FindTransaction(ctx context.Context, transactionID string) (*Transaction, error)
 	FindTransactionsByWalletID(ctx context.Context, walletID string, limit int, offset int) ([]Transaction, error)
 	FindTransactionsByUserID(ctx context.Context, userID string, limit int, offset int) ([]Transaction, error)
+	FindTransactionsAfter(ctx context.Context, after *Transaction, limit int) ([]Transaction, error)
+	FindCompletedTransactionsByWalletID(ctx context.Context, walletID string, limit int, offset int) ([]Transaction, error)
+	FindCompletedTransactionsByWalletIDBetween(ctx context.Context, walletID string, after time.Time, until time.Time, limit int, offset int) ([]Transaction, error)
+	FindChainedTransactionsByWalletID(ctx context.Context, walletID string, limit int, offset int) ([]Transaction, error)
+	CountTransactionsByStatus(ctx context.Context, status TransactionStatus) (int64, error)
 	UpdateTransaction(ctx context.Context, transaction *Transaction) error
+
+	// Non-transactional allowance operations
+	FindAllowance(ctx context.Context, walletID string, delegateID string) (*Allowance, error)
+	FindAllowancesByWalletID(ctx context.Context, walletID string) ([]Allowance, error)
+
+	// Aggregations over completed transactions, completed after the first and at or before the second time
+	SumTransactionAmountsByWalletID(ctx context.Context, walletID string, after time.Time, until time.Time) (int64, error)
+	SumTransactionAmounts(ctx context.Context, after time.Time, until time.Time) (int64, error)
+	SumTransactionTotalsByWalletID(ctx context.Context, walletID string, after time.Time, until time.Time) (credits int64, debits int64, err error)
+
+	// Reporting
+	AggregateTransactions(ctx context.Context, interval ReportInterval, from time.Time, to time.Time, filter ReportFilter) ([]TransactionAggregate, error)
 }